@@ -0,0 +1,262 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"sync"
+	"time"
+)
+
+// AliasMetric makes every emission to the metrics named canonical of the
+// scope s also emit the same value to a metric named alias of s, e.g. to
+// keep emitting a metric under its old name during a rename, until the alias
+// is removed with RemoveMetricAlias. It applies to all metric kinds: the
+// counter, gauge, timer and histogram named canonical, whether they already
+// exist or are created later, each get an alias of the same kind, with the
+// same buckets for histograms but none of the other options.
+//
+// Aliases apply only to s, not to the scopes created from it, and double the
+// series emitted for each aliased metric while active. An alias can not be
+// aliased itself, nor can a canonical name be used as an alias, such calls
+// are ignored. On Scope implementations other than those created by
+// NewRootScope this is a no-op.
+func AliasMetric(s Scope, canonical, alias string) {
+	if as, ok := s.(aliasScope); ok {
+		as.aliasMetric(canonical, alias)
+	}
+}
+
+// RemoveMetricAlias stops the emissions to the metrics named canonical of
+// the scope s from being emitted to the alias added by AliasMetric. The
+// alias metrics are kept by the scope but no longer updated.
+func RemoveMetricAlias(s Scope, canonical, alias string) {
+	if as, ok := s.(aliasScope); ok {
+		as.removeMetricAlias(canonical, alias)
+	}
+}
+
+type aliasScope interface {
+	aliasMetric(canonical, alias string)
+	removeMetricAlias(canonical, alias string)
+}
+
+// metricAliases are the aliases of the metrics of a scope.
+type metricAliases struct {
+	sync.RWMutex
+	names   map[string][]string
+	aliased map[string]struct{}
+
+	// link serializes linking the aliases to the metrics, so that the
+	// metrics always end up with the latest aliases.
+	link sync.Mutex
+}
+
+func (a *metricAliases) get(name string) []string {
+	a.RLock()
+	defer a.RUnlock()
+
+	return a.names[name]
+}
+
+func (s *scope) aliasMetric(canonical, alias string) {
+	canonical = s.sanitizer.Name(canonical)
+	alias = s.sanitizer.Name(alias)
+
+	a := &s.aliases
+	a.Lock()
+	_, isAlias := a.aliased[canonical]
+	_, isCanonical := a.names[alias]
+	if canonical == alias || isAlias || isCanonical {
+		a.Unlock()
+		return
+	}
+	for _, existing := range a.names[canonical] {
+		if existing == alias {
+			a.Unlock()
+			return
+		}
+	}
+	if a.names == nil {
+		a.names = make(map[string][]string)
+		a.aliased = make(map[string]struct{})
+	}
+	a.names[canonical] = append(a.names[canonical], alias)
+	a.aliased[alias] = struct{}{}
+	a.Unlock()
+
+	s.relinkAliases(canonical)
+}
+
+func (s *scope) removeMetricAlias(canonical, alias string) {
+	canonical = s.sanitizer.Name(canonical)
+	alias = s.sanitizer.Name(alias)
+
+	a := &s.aliases
+	a.Lock()
+	names := a.names[canonical]
+	remaining := make([]string, 0, len(names))
+	for _, name := range names {
+		if name != alias {
+			remaining = append(remaining, name)
+		}
+	}
+	if len(remaining) == len(names) {
+		a.Unlock()
+		return
+	}
+	if len(remaining) == 0 {
+		delete(a.names, canonical)
+	} else {
+		a.names[canonical] = remaining
+	}
+	delete(a.aliased, alias)
+	a.Unlock()
+
+	s.relinkAliases(canonical)
+}
+
+// relinkAliases links the current aliases of name to the existing metrics
+// named name.
+func (s *scope) relinkAliases(name string) {
+	if c, ok := s.counter(name); ok {
+		s.linkCounterAliases(name, c.(*counter))
+	}
+	if g, ok := s.gauge(name); ok {
+		s.linkGaugeAliases(name, g.(*gauge))
+	}
+	if t, ok := s.timer(name); ok {
+		s.linkTimerAliases(name, t.(*timer))
+	}
+	if h, ok := s.histogram(name); ok {
+		s.linkHistogramAliases(name, h.(*histogram))
+	}
+}
+
+// NB: The link functions are also called when a metric is created, a nil
+// metric was not created and the aliases are only linked if there are any or
+// were before. An alias is never aliased itself, so creating the alias
+// metrics never links recursively.
+
+func (s *scope) linkCounterAliases(name string, c *counter) {
+	if c == nil || (len(s.aliases.get(name)) == 0 && c.aliases.Load() == nil) {
+		return
+	}
+
+	s.aliases.link.Lock()
+	defer s.aliases.link.Unlock()
+
+	names := s.aliases.get(name)
+	aliases := make([]Counter, 0, len(names))
+	for _, alias := range names {
+		aliases = append(aliases, s.Counter(alias))
+	}
+	c.aliases.Store(aliases)
+}
+
+func (s *scope) linkGaugeAliases(name string, g *gauge) {
+	if g == nil || (len(s.aliases.get(name)) == 0 && g.aliases.Load() == nil) {
+		return
+	}
+
+	s.aliases.link.Lock()
+	defer s.aliases.link.Unlock()
+
+	names := s.aliases.get(name)
+	aliases := make([]Gauge, 0, len(names))
+	for _, alias := range names {
+		aliases = append(aliases, s.Gauge(alias))
+	}
+	g.aliases.Store(aliases)
+}
+
+func (s *scope) linkTimerAliases(name string, t *timer) {
+	if t == nil || (len(s.aliases.get(name)) == 0 && t.aliases.Load() == nil) {
+		return
+	}
+
+	s.aliases.link.Lock()
+	defer s.aliases.link.Unlock()
+
+	names := s.aliases.get(name)
+	aliases := make([]Timer, 0, len(names))
+	for _, alias := range names {
+		aliases = append(aliases, s.Timer(alias))
+	}
+	t.aliases.Store(aliases)
+}
+
+func (s *scope) linkHistogramAliases(name string, h *histogram) {
+	if h == nil || (len(s.aliases.get(name)) == 0 && h.aliases.Load() == nil) {
+		return
+	}
+
+	s.aliases.link.Lock()
+	defer s.aliases.link.Unlock()
+
+	names := s.aliases.get(name)
+	aliases := make([]Histogram, 0, len(names))
+	for _, alias := range names {
+		aliases = append(aliases, s.Histogram(alias, h.specification))
+	}
+	h.aliases.Store(aliases)
+}
+
+func (c *counter) incAliases(v int64) {
+	aliases, _ := c.aliases.Load().([]Counter)
+	for _, alias := range aliases {
+		alias.Inc(v)
+	}
+}
+
+func (c *counter) incFloatAliases(v float64) {
+	aliases, _ := c.aliases.Load().([]Counter)
+	for _, alias := range aliases {
+		alias.IncFloat(v)
+	}
+}
+
+func (g *gauge) updateAliases(v float64) {
+	aliases, _ := g.aliases.Load().([]Gauge)
+	for _, alias := range aliases {
+		alias.Update(v)
+	}
+}
+
+func (t *timer) recordAliases(interval time.Duration) {
+	aliases, _ := t.aliases.Load().([]Timer)
+	for _, alias := range aliases {
+		alias.Record(interval)
+	}
+}
+
+func (h *histogram) recordValueAliases(value float64) {
+	aliases, _ := h.aliases.Load().([]Histogram)
+	for _, alias := range aliases {
+		alias.RecordValue(value)
+	}
+}
+
+func (h *histogram) recordDurationAliases(value time.Duration) {
+	aliases, _ := h.aliases.Load().([]Histogram)
+	for _, alias := range aliases {
+		alias.RecordDuration(value)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAliasMetric(t *testing.T) {
+	s := NewTestScope("", map[string]string{"env": "test"})
+	AliasMetric(s, "requests", "old_requests")
+
+	s.Counter("requests").Inc(3)
+	s.Gauge("requests").Update(4)
+	s.Timer("requests").Record(time.Second)
+	s.Histogram("requests", ValueBuckets{1, 10}).RecordValue(5)
+
+	snap := s.Snapshot()
+	for _, name := range []string{"requests+env=test", "old_requests+env=test"} {
+		require.Contains(t, snap.Counters(), name)
+		assert.EqualValues(t, 3, snap.Counters()[name].Value())
+		require.Contains(t, snap.Gauges(), name)
+		assert.Equal(t, 4.0, snap.Gauges()[name].Value())
+		require.Contains(t, snap.Timers(), name)
+		assert.Equal(t, []time.Duration{time.Second}, snap.Timers()[name].Values())
+		require.Contains(t, snap.Histograms(), name)
+		assert.Equal(t, map[float64]int64{1: 0, 10: 1, math.MaxFloat64: 0}, snap.Histograms()[name].Values())
+	}
+}
+
+func TestAliasMetricExisting(t *testing.T) {
+	s := NewTestScope("", nil)
+	c := s.Counter("requests")
+	c.Inc(1)
+
+	AliasMetric(s, "requests", "old_requests")
+	c.Inc(2)
+
+	counters := s.Snapshot().Counters()
+	assert.EqualValues(t, 3, counters["requests+"].Value())
+	assert.EqualValues(t, 2, counters["old_requests+"].Value())
+}
+
+func TestRemoveMetricAlias(t *testing.T) {
+	s := NewTestScope("", nil)
+	AliasMetric(s, "requests", "old_requests")
+	AliasMetric(s, "requests", "legacy_requests")
+
+	c := s.Counter("requests")
+	c.Inc(1)
+	RemoveMetricAlias(s, "requests", "old_requests")
+	c.Inc(1)
+
+	counters := s.Snapshot().Counters()
+	assert.EqualValues(t, 2, counters["requests+"].Value())
+	assert.EqualValues(t, 1, counters["old_requests+"].Value())
+	assert.EqualValues(t, 2, counters["legacy_requests+"].Value())
+}
+
+func TestAliasMetricScopeOnly(t *testing.T) {
+	s := NewTestScope("", nil)
+	AliasMetric(s, "requests", "old_requests")
+
+	s.SubScope("child").Counter("requests").Inc(1)
+	s.Tagged(map[string]string{"a": "b"}).Counter("requests").Inc(1)
+
+	assert.Len(t, s.Snapshot().Counters(), 2)
+}
+
+func TestAliasMetricIgnoresChains(t *testing.T) {
+	s := NewTestScope("", nil)
+	AliasMetric(s, "requests", "old_requests")
+	AliasMetric(s, "old_requests", "older_requests")
+	AliasMetric(s, "other", "requests")
+	AliasMetric(s, "requests", "requests")
+
+	s.Counter("requests").Inc(1)
+	s.Counter("old_requests").Inc(1)
+	s.Counter("other").Inc(1)
+
+	counters := s.Snapshot().Counters()
+	assert.Len(t, counters, 3)
+	assert.EqualValues(t, 1, counters["requests+"].Value())
+	assert.EqualValues(t, 2, counters["old_requests+"].Value())
+}
@@ -0,0 +1,314 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// autoBucketsMaxSamples is the number of values retained during the warm-up
+// of a histogram with AutoBuckets, the warm-up ends early once reached.
+const autoBucketsMaxSamples = 10000
+
+// AutoBuckets returns buckets for a value histogram that are derived from
+// the values it records, for when good static buckets are hard to choose.
+// The histogram retains the values recorded during the warmup, then derives
+// count bucket bounds from them by equal-frequency partitioning, so that each
+// bucket holds about as many of the values, records the retained values into
+// the derived buckets and uses those from then on. The warm-up ends early
+// once 10000 values are retained, and the buckets are derived on the first
+// value recorded or the first report after it ended. Nothing is reported or
+// visible in snapshots during the warm-up, and since the bounds fit the
+// warm-up values the histograms reported for the first interval after it
+// may be approximate for later values. Fewer bounds are derived if values
+// repeat, and at least one is derived for a count below one.
+//
+// The buckets have no bounds themselves, so that e.g. BucketPairs sees them
+// as empty, and are only derived by the Histogram and HistogramWithOptions
+// of scopes created by NewRootScope.
+func AutoBuckets(warmup time.Duration, count int) Buckets {
+	return autoBuckets{warmup: warmup, count: count}
+}
+
+// AutoDurationBuckets returns buckets for a duration histogram that are
+// derived from the durations it records like AutoBuckets.
+func AutoDurationBuckets(warmup time.Duration, count int) Buckets {
+	return autoBuckets{warmup: warmup, count: count, durations: true}
+}
+
+type autoBuckets struct {
+	warmup    time.Duration
+	count     int
+	durations bool
+}
+
+func (b autoBuckets) String() string {
+	return fmt.Sprintf("auto(%v, %d)", b.warmup, b.count)
+}
+
+func (b autoBuckets) Len() int {
+	return 0
+}
+
+func (b autoBuckets) Less(i, j int) bool {
+	return false
+}
+
+func (b autoBuckets) Swap(i, j int) {
+}
+
+func (b autoBuckets) AsValues() []float64 {
+	return nil
+}
+
+func (b autoBuckets) AsDurations() []time.Duration {
+	return nil
+}
+
+// autoHistogram is a histogram with AutoBuckets, which records into the
+// histogram with the derived buckets once the warm-up ended.
+type autoHistogram struct {
+	scope   *scope
+	name    string
+	buckets autoBuckets
+	opts    HistogramOptions
+
+	mu       sync.Mutex
+	deadline time.Time
+	samples  []float64    // nanoseconds for durations
+	derived  atomic.Value // Histogram
+}
+
+func (s *scope) autoHistogram(name string, b autoBuckets, opts HistogramOptions) Histogram {
+	s.hm.RLock()
+	h, ok := s.autoHistograms[name]
+	s.hm.RUnlock()
+	if ok {
+		return h
+	}
+
+	s.hm.Lock()
+	defer s.hm.Unlock()
+
+	if h, ok := s.autoHistograms[name]; ok {
+		return h
+	}
+	h = &autoHistogram{
+		scope:    s,
+		name:     name,
+		buckets:  b,
+		opts:     opts,
+		deadline: s.clock.Now().Add(b.warmup),
+	}
+	if s.autoHistograms == nil {
+		s.autoHistograms = make(map[string]*autoHistogram)
+	}
+	s.autoHistograms[name] = h
+	s.registry.addAutoHistogram(h)
+	return h
+}
+
+// addAutoHistogram adds a histogram in warm-up to derive the buckets of when
+// due on report.
+func (r *scopeRegistry) addAutoHistogram(h *autoHistogram) {
+	r.autoMu.Lock()
+	r.autoHistograms = append(r.autoHistograms, h)
+	r.autoMu.Unlock()
+}
+
+// deriveAutoHistograms derives the buckets of the histograms whose warm-up
+// ended, must be called without holding the locks of the registry or the
+// scopes since it creates the histograms.
+func (r *scopeRegistry) deriveAutoHistograms(now time.Time) {
+	r.autoMu.Lock()
+	var due []*autoHistogram
+	pending := r.autoHistograms[:0]
+	for _, h := range r.autoHistograms {
+		if h.scope.closed.Load() {
+			continue
+		}
+		if h.due(now) {
+			due = append(due, h)
+		} else {
+			pending = append(pending, h)
+		}
+	}
+	r.autoHistograms = pending
+	r.autoMu.Unlock()
+
+	for _, h := range due {
+		h.derive()
+	}
+}
+
+func (h *autoHistogram) RecordValue(value float64) {
+	if !h.buckets.durations {
+		h.record(value)
+	}
+}
+
+func (h *autoHistogram) RecordDuration(value time.Duration) {
+	if h.buckets.durations {
+		h.record(float64(value))
+	}
+}
+
+func (h *autoHistogram) Start() Stopwatch {
+	return NewStopwatch(h.scope.clock.Now(), h)
+}
+
+func (h *autoHistogram) RecordDurationFunc(f func()) {
+	defer h.Start().Stop()
+	f()
+}
+
+func (h *autoHistogram) StartContext(ctx context.Context) Stopwatch {
+	return NewStopwatch(h.scope.clock.Now(), NewContextRecorder(ctx, h, ContextRecorderOptions{}))
+}
+
+func (h *autoHistogram) RecordStopwatch(stopwatchStart time.Time) {
+	h.RecordDuration(h.scope.clock.Now().Sub(stopwatchStart))
+}
+
+func (h *autoHistogram) recordElapsed(stopwatchStart, now time.Time) {
+	h.RecordDuration(now.Sub(stopwatchStart))
+}
+
+func (h *autoHistogram) recorderClock() Clock {
+	return h.scope.clock
+}
+
+func (h *autoHistogram) record(value float64) {
+	if h.recordDerived(value) {
+		return
+	}
+
+	h.mu.Lock()
+	if h.derived.Load() != nil {
+		h.mu.Unlock()
+		h.recordDerived(value)
+		return
+	}
+	h.samples = append(h.samples, value)
+	h.mu.Unlock()
+
+	if h.due(h.scope.clock.Now()) {
+		h.derive()
+	}
+}
+
+// recordDerived records value into the histogram with the derived buckets
+// and returns whether there is one yet.
+func (h *autoHistogram) recordDerived(value float64) bool {
+	derived, ok := h.derived.Load().(Histogram)
+	if !ok {
+		return false
+	}
+	if h.buckets.durations {
+		derived.RecordDuration(time.Duration(value))
+	} else {
+		derived.RecordValue(value)
+	}
+	return true
+}
+
+// due returns whether the warm-up ended with values to derive buckets from.
+func (h *autoHistogram) due(now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.derived.Load() != nil || len(h.samples) == 0 {
+		return false
+	}
+	return len(h.samples) >= autoBucketsMaxSamples || !now.Before(h.deadline)
+}
+
+// derive creates the histogram with the buckets derived from the retained
+// values and records them into it.
+func (h *autoHistogram) derive() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.derived.Load() != nil || len(h.samples) == 0 {
+		return
+	}
+
+	var derived Histogram
+	if h.buckets.durations {
+		bounds := equalFrequencyBounds(h.samples, h.buckets.count, 1)
+		buckets := make(DurationBuckets, 0, len(bounds))
+		for _, b := range bounds {
+			buckets = append(buckets, time.Duration(b))
+		}
+		derived = h.scope.histogramWithOptions(h.name, buckets, h.opts)
+		for _, v := range h.samples {
+			derived.RecordDuration(time.Duration(v))
+		}
+	} else {
+		// NB: The bounds are in the storage unit the values are scaled to.
+		scale, _ := unitScale(h.opts.InputUnit, h.opts.StorageUnit)
+		if scale == 0 {
+			scale = 1
+		}
+		buckets := ValueBuckets(equalFrequencyBounds(h.samples, h.buckets.count, scale))
+		derived = h.scope.histogramWithOptions(h.name, buckets, h.opts)
+		for _, v := range h.samples {
+			derived.RecordValue(v)
+		}
+	}
+	pinHistogram(derived)
+	h.derived.Store(derived)
+	h.samples = nil
+}
+
+// equalFrequencyBounds returns up to count sorted bucket upper bounds that
+// partition the scaled samples into buckets of about equal counts, using
+// the samples themselves as the bounds.
+func equalFrequencyBounds(samples []float64, count int, scale float64) []float64 {
+	if count < 1 {
+		count = 1
+	}
+	sorted := make([]float64, len(samples))
+	for i, v := range samples {
+		sorted[i] = v * scale
+	}
+	sort.Float64s(sorted)
+
+	n := len(sorted)
+	bounds := make([]float64, 0, count)
+	for i := 1; i <= count; i++ {
+		// NB: The bound is the last sample of the i-th partition, rounded
+		// up, as buckets include their upper bound.
+		idx := (i*n+count)/(count+1) - 1
+		if idx < 0 {
+			idx = 0
+		}
+		if b := sorted[idx]; len(bounds) == 0 || b > bounds[len(bounds)-1] {
+			bounds = append(bounds, b)
+		}
+	}
+	return bounds
+}
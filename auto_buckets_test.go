@@ -0,0 +1,166 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoBuckets(t *testing.T) {
+	now := time.Unix(1000, 0)
+	globalNow = func() time.Time { return now }
+	defer func() { globalNow = time.Now }()
+
+	s := NewTestScope("", nil)
+	h := s.Histogram("sizes", AutoBuckets(time.Minute, 3))
+	assert.Equal(t, h, s.Histogram("sizes", AutoBuckets(time.Minute, 3)))
+
+	for v := 1; v < 1000; v++ {
+		h.RecordValue(float64(v))
+	}
+	h.RecordDuration(time.Second)
+
+	// Nothing is visible during the warm-up.
+	assert.Empty(t, s.Snapshot().Histograms())
+
+	now = now.Add(time.Minute)
+	h.RecordValue(1000)
+
+	snap := s.Snapshot().Histograms()["sizes+"]
+	require.NotNil(t, snap)
+	assert.Equal(t, map[float64]int64{
+		250:             250,
+		500:             250,
+		750:             250,
+		math.MaxFloat64: 250,
+	}, snap.Values())
+
+	h.RecordValue(1)
+	assert.EqualValues(t, 251, s.Snapshot().Histograms()["sizes+"].Values()[250])
+}
+
+func TestAutoBucketsSkewed(t *testing.T) {
+	now := time.Unix(1000, 0)
+	globalNow = func() time.Time { return now }
+	defer func() { globalNow = time.Now }()
+
+	s := NewTestScope("", nil)
+	h := s.Histogram("latency", AutoDurationBuckets(time.Minute, 4))
+
+	// Mostly fast calls with a slow tail, the bounds follow the samples.
+	for i := 0; i < 900; i++ {
+		h.RecordDuration(time.Duration(i%10+1) * time.Millisecond)
+	}
+	for i := 0; i < 100; i++ {
+		h.RecordDuration(time.Duration(i+1) * time.Second)
+	}
+	h.RecordValue(1)
+
+	now = now.Add(time.Minute)
+	h.RecordDuration(time.Millisecond)
+
+	snap := s.Snapshot().Histograms()["latency+"]
+	require.NotNil(t, snap)
+	durations := snap.Durations()
+	assert.Len(t, durations, 5)
+	var total int64
+	for bound, count := range durations {
+		total += count
+		if bound != time.Duration(math.MaxInt64) {
+			assert.True(t, count >= 150 && count <= 300, "bucket %v has %d", bound, count)
+		}
+	}
+	assert.EqualValues(t, 1001, total)
+}
+
+func TestAutoBucketsRepeatedValues(t *testing.T) {
+	assert.Equal(t, []float64{5}, equalFrequencyBounds([]float64{5, 5, 5, 5}, 3, 1))
+	assert.Equal(t, []float64{1, 2}, equalFrequencyBounds([]float64{2, 1, 2, 1}, 2, 1))
+	assert.Equal(t, []float64{20}, equalFrequencyBounds([]float64{1, 2, 3}, 0, 10))
+}
+
+func TestAutoBucketsMaxSamples(t *testing.T) {
+	s := NewTestScope("", nil)
+	h := s.Histogram("sizes", AutoBuckets(time.Hour, 1))
+	for i := 0; i < autoBucketsMaxSamples; i++ {
+		h.RecordValue(float64(i % 2))
+	}
+
+	snap := s.Snapshot().Histograms()["sizes+"]
+	require.NotNil(t, snap)
+	assert.Equal(t, map[float64]int64{
+		0:               autoBucketsMaxSamples / 2,
+		math.MaxFloat64: autoBucketsMaxSamples / 2,
+	}, snap.Values())
+}
+
+func TestAutoBucketsDerivedOnReport(t *testing.T) {
+	now := time.Unix(1000, 0)
+	globalNow = func() time.Time { return now }
+	defer func() { globalNow = time.Now }()
+
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{Reporter: r}, 0)
+	s := root.(*scope)
+	s.SubScope("db").Histogram("rows", AutoBuckets(time.Minute, 1)).RecordValue(3)
+
+	// The report derives the buckets once the warm-up ended.
+	s.reportRegistry()
+	require.Empty(t, r.getHistograms())
+
+	now = now.Add(time.Minute)
+	r.hg.Add(1)
+	s.reportRegistry()
+	r.WaitAll()
+	assert.EqualValues(t, 1, r.getHistograms()["db.rows"].valueSamples[3])
+
+	assert.NoError(t, closer.Close())
+}
+
+func TestAutoBucketsConcurrent(t *testing.T) {
+	s := NewTestScope("", nil)
+	h := s.Histogram("sizes", AutoBuckets(time.Hour, 10))
+
+	// The warm-up ends on the maximum number of samples while recording.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := 0; v < 2000; v++ {
+				h.RecordValue(float64(v))
+			}
+		}()
+	}
+	wg.Wait()
+
+	var total int64
+	for _, count := range s.Snapshot().Histograms()["sizes+"].Values() {
+		total += count
+	}
+	assert.EqualValues(t, 16000, total)
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+)
+
+// BenchScope returns a Scope for benchmarking instrumented code. It has no
+// reporting goroutine and reports to a reporter that discards all values,
+// but only after serializing each of them the way a real reporter would,
+// so that benchmarks reflect realistic instrumentation overhead.
+//
+// Unlike NoopScope, whose reporter returns immediately, BenchScope resolves
+// the name and tags of every reported value. Any Reporter or CachedReporter
+// set on opts is replaced.
+func BenchScope(opts ScopeOptions) Scope {
+	opts.Reporter = benchStatsReporter{}
+	opts.CachedReporter = nil
+	return newRootScope(opts, 0)
+}
+
+// benchStatsReporter is a StatsReporter that serializes reported values into
+// pooled buffers before discarding them.
+type benchStatsReporter struct{}
+
+func (r benchStatsReporter) ReportCounter(name string, tags map[string]string, value int64) {
+	var scratch [32]byte
+	r.serialize(name, tags, strconv.AppendInt(scratch[:0], value, 10))
+}
+
+func (r benchStatsReporter) ReportGauge(name string, tags map[string]string, value float64) {
+	var scratch [32]byte
+	r.serialize(name, tags, strconv.AppendFloat(scratch[:0], value, 'f', -1, 64))
+}
+
+func (r benchStatsReporter) ReportTimer(name string, tags map[string]string, interval time.Duration) {
+	var scratch [32]byte
+	r.serialize(name, tags, strconv.AppendInt(scratch[:0], int64(interval), 10))
+}
+
+func (r benchStatsReporter) ReportHistogramValueSamples(
+	name string,
+	tags map[string]string,
+	buckets Buckets,
+	bucketLowerBound,
+	bucketUpperBound float64,
+	samples int64,
+) {
+	var scratch [96]byte
+	b := strconv.AppendFloat(scratch[:0], bucketLowerBound, 'f', -1, 64)
+	b = strconv.AppendFloat(append(b, '-'), bucketUpperBound, 'f', -1, 64)
+	b = strconv.AppendInt(append(b, ':'), samples, 10)
+	r.serialize(name, tags, b)
+}
+
+func (r benchStatsReporter) ReportHistogramDurationSamples(
+	name string,
+	tags map[string]string,
+	buckets Buckets,
+	bucketLowerBound,
+	bucketUpperBound time.Duration,
+	samples int64,
+) {
+	var scratch [96]byte
+	b := strconv.AppendInt(scratch[:0], int64(bucketLowerBound), 10)
+	b = strconv.AppendInt(append(b, '-'), int64(bucketUpperBound), 10)
+	b = strconv.AppendInt(append(b, ':'), samples, 10)
+	r.serialize(name, tags, b)
+}
+
+func (r benchStatsReporter) Capabilities() Capabilities {
+	return capabilitiesReportingTagging
+}
+
+func (r benchStatsReporter) Flush() {
+}
+
+func (r benchStatsReporter) serialize(name string, tags map[string]string, value []byte) {
+	buf := keyGenPool.bufferPool.Get().(*bytes.Buffer)
+	writeKeyForPrefixedStringMaps(buf, name, tags)
+	buf.WriteByte(keyNameSplitter)
+	buf.Write(value)
+	keyGenPool.releaseBuffer(buf)
+}
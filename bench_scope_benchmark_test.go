@@ -0,0 +1,89 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func BenchmarkBenchScope(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		fn   func(s Scope)
+	}{
+		{
+			name: "counter",
+			fn:   func(s Scope) { s.Counter("requests").Inc(1) },
+		},
+		{
+			name: "tagged-counter",
+			fn: func(s Scope) {
+				s.Tagged(map[string]string{"endpoint": "get"}).Counter("requests").Inc(1)
+			},
+		},
+		{
+			name: "timer",
+			fn:   func(s Scope) { s.Timer("latency").Record(time.Millisecond) },
+		},
+		{
+			name: "histogram",
+			fn: func(s Scope) {
+				s.Histogram("latency", DefaultBuckets).RecordDuration(time.Millisecond)
+			},
+		},
+	}
+
+	for _, bm := range benchmarks {
+		for _, tags := range []int{0, 8} {
+			opts := ScopeOptions{Prefix: "service", Tags: map[string]string{}}
+			for i := 0; i < tags; i++ {
+				opts.Tags[fmt.Sprintf("key%d", i)] = fmt.Sprintf("value%d", i)
+			}
+
+			b.Run(fmt.Sprintf("%s/tags=%d", bm.name, tags), func(b *testing.B) {
+				s := BenchScope(opts)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for n := 0; n < b.N; n++ {
+					bm.fn(s)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkBenchScopeReport(b *testing.B) {
+	s := BenchScope(ScopeOptions{Tags: map[string]string{"env": "bench"}}).(*scope)
+	c := s.Counter("requests")
+	g := s.Gauge("queue")
+	h := s.Histogram("latency", DefaultBuckets)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		c.Inc(1)
+		g.Update(float64(n))
+		h.RecordDuration(time.Millisecond)
+		s.reportLoopRun()
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"fmt"
+	"log"
+)
+
+// callbackPanics returns the counter with the given name on the root scope
+// tagged with the name of the callback, or nil if name is empty. The counter
+// never expires.
+func callbackPanics(root Scope, name, callback string) Counter {
+	if name == "" {
+		return nil
+	}
+	c := root.Tagged(map[string]string{CallbackTagKey: callback}).Counter(name)
+	pinCounter(c)
+	return c
+}
+
+// recoverCallback recovers a panic in the callback of the given kind and
+// name, logging it, counting it with panics if not nil and reporting it to
+// OnError. It must be deferred by the caller of the callback.
+func (r *scopeRegistry) recoverCallback(kind, name string, panics Counter) {
+	if p := recover(); p != nil {
+		log.Printf("tally: recovered panic in %s %s: %v", kind, name, p)
+		if panics != nil {
+			panics.Inc(1)
+		}
+		r.errors.report(CallbackPanicErrorPhase,
+			fmt.Errorf("%s %s panicked: %v", kind, name, p))
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// panickingGauge is a gauge alias that panics on every update.
+type panickingGauge struct{}
+
+func (panickingGauge) Update(float64) { panic("bug") }
+func (panickingGauge) Add(float64)    { panic("bug") }
+
+func TestRecoverCallbackPanicsOnError(t *testing.T) {
+	errs := make(chan error, errorQueueSize)
+	handled := 0
+	root, closer := NewRootScope(ScopeOptions{
+		Reporter:              NullStatsReporter,
+		RecoverCallbackPanics: true,
+		CallbackPanicsCounter: "tally.callback_panics",
+		OnError: func(err error) {
+			handled++
+			if handled == 1 {
+				panic("bug")
+			}
+			errs <- err
+		},
+	}, 0)
+
+	s := root.(*scope)
+	GaugeFunc(s, "bad", func() float64 { panic("bug") })
+	s.report(NullStatsReporter)
+	s.report(NullStatsReporter)
+
+	// The handler survives its own panic and handles the next error.
+	se := receiveScopeError(t, errs)
+	assert.Equal(t, CallbackPanicErrorPhase, se.Phase)
+	counters := s.Snapshot().Counters()
+	assert.EqualValues(t, 1, counters["tally.callback_panics+callback=OnError"].Value())
+	assert.EqualValues(t, 2, counters["tally.callback_panics+callback=bad"].Value())
+
+	assert.NoError(t, closer.Close())
+}
+
+func TestRecoverCallbackPanicsTagValidator(t *testing.T) {
+	errs := make(chan error, errorQueueSize)
+	root, closer := NewRootScope(ScopeOptions{
+		Reporter:              NullStatsReporter,
+		RecoverCallbackPanics: true,
+		CallbackPanicsCounter: "tally.callback_panics",
+		MetricTagValidators: map[string]func(map[string]string) error{
+			"requests": func(map[string]string) error { panic("bug") },
+		},
+		OnError: func(err error) { errs <- err },
+	}, 0)
+
+	// The metric is created as if its tags were valid.
+	var c Counter
+	require.NotPanics(t, func() { c = root.Counter("requests") })
+	c.Inc(1)
+
+	se := receiveScopeError(t, errs)
+	assert.Equal(t, "tally: callback panic: tag validator requests panicked: bug", se.Error())
+	counters := root.(*scope).Snapshot().Counters()
+	assert.EqualValues(t, 1, counters["requests+"].Value())
+	assert.EqualValues(t, 1, counters["tally.callback_panics+callback=requests"].Value())
+
+	assert.NoError(t, closer.Close())
+}
+
+func TestRecoverCallbackPanicsRatioAndInfo(t *testing.T) {
+	root, closer := NewRootScope(ScopeOptions{
+		Reporter:              NullStatsReporter,
+		RecoverCallbackPanics: true,
+		CallbackPanicsCounter: "tally.callback_panics",
+	}, 0)
+
+	s := root.(*scope)
+	RegisterRatio(s, "error_rate", "errors", "requests", RatioOptions{})
+	Info(s, "build_info", map[string]string{"version": "1.0"})
+	s.Gauge("error_rate").(*gauge).aliases.Store([]Gauge{panickingGauge{}})
+	s.Tagged(map[string]string{"version": "1.0"}).Gauge("build_info").(*gauge).
+		aliases.Store([]Gauge{panickingGauge{}})
+
+	// The report survives the panics.
+	require.NotPanics(t, func() { s.registry.Report(NullStatsReporter) })
+
+	counters := s.Snapshot().Counters()
+	assert.EqualValues(t, 1, counters["tally.callback_panics+callback=error_rate"].Value())
+	assert.EqualValues(t, 1, counters["tally.callback_panics+callback=build_info"].Value())
+
+	assert.NoError(t, closer.Close())
+}
+
+func TestRecoverCallbackPanicsDisabled(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{
+		MetricTagValidators: map[string]func(map[string]string) error{
+			"requests": func(map[string]string) error { panic(errors.New("bug")) },
+		},
+	})
+	assert.Panics(t, func() { s.Counter("requests") })
+}
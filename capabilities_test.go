@@ -0,0 +1,85 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// noHistogramsReporter is a reporter without histogram support.
+type noHistogramsReporter struct {
+	StatsReporter
+}
+
+func (r noHistogramsReporter) Capabilities() Capabilities {
+	return noHistogramsCapabilities{}
+}
+
+type noHistogramsCapabilities struct{}
+
+func (noHistogramsCapabilities) Reporting() bool  { return true }
+func (noHistogramsCapabilities) Tagging() bool    { return true }
+func (noHistogramsCapabilities) Histograms() bool { return false }
+
+func TestSupportsHistograms(t *testing.T) {
+	assert.True(t, SupportsHistograms(capabilitiesReportingTagging))
+	assert.True(t, SupportsHistograms(NullStatsReporter.Capabilities()))
+	assert.False(t, SupportsHistograms(noHistogramsCapabilities{}))
+}
+
+func TestSupportsHistogramsSubscopes(t *testing.T) {
+	r := noHistogramsReporter{NullStatsReporter}
+	root, closer := NewRootScope(ScopeOptions{Reporter: r}, 0)
+	defer closer.Close()
+
+	sub := root.SubScope("db").Tagged(map[string]string{"table": "users"})
+	assert.False(t, SupportsHistograms(root.Capabilities()))
+	assert.False(t, SupportsHistograms(sub.Capabilities()))
+
+	// Histograms are still recorded by default.
+	sub.Histogram("latency", ValueBuckets{1}).RecordValue(1)
+	assert.Len(t, root.(TestScope).Snapshot().Histograms(), 1)
+}
+
+func TestDropUnsupportedHistograms(t *testing.T) {
+	r := noHistogramsReporter{NullStatsReporter}
+	root, closer := NewRootScope(ScopeOptions{
+		Reporter:                  r,
+		DropUnsupportedHistograms: true,
+	}, 0)
+	defer closer.Close()
+
+	sub := root.SubScope("db")
+	h := sub.Histogram("latency", ValueBuckets{1})
+	assert.Equal(t, noopMetric{}, h)
+	h.RecordValue(1)
+	assert.Empty(t, root.(TestScope).Snapshot().Histograms())
+
+	// Reporters supporting histograms keep them.
+	s := NewTestScopeWithOptions(ScopeOptions{
+		Reporter:                  NullStatsReporter,
+		DropUnsupportedHistograms: true,
+	})
+	s.Histogram("latency", ValueBuckets{1}).RecordValue(1)
+	assert.Len(t, s.Snapshot().Histograms(), 1)
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+const (
+	// DefaultCardinalityUsedGaugeName is the default name of the gauge of
+	// the series used by a scope with a cardinality budget.
+	DefaultCardinalityUsedGaugeName = "tally.cardinality_used"
+	// DefaultCardinalityBudgetGaugeName is the default name of the gauge of
+	// the cardinality budget of a scope.
+	DefaultCardinalityBudgetGaugeName = "tally.cardinality_budget"
+)
+
+// CardinalityBudgetOptions is the cardinality budget of a scope.
+type CardinalityBudgetOptions struct {
+	// Budget is the number of series the scope is expected to use.
+	Budget int
+	// UsedGaugeName is the name of the gauge of the series used, defaults to
+	// DefaultCardinalityUsedGaugeName.
+	UsedGaugeName string
+	// BudgetGaugeName is the name of the gauge of the budget, defaults to
+	// DefaultCardinalityBudgetGaugeName.
+	BudgetGaugeName string
+}
+
+// SetCardinalityBudget makes the scope s report on each flush the number of
+// distinct series it uses and its budget as two gauges of s, e.g. to show on
+// a dashboard how close the scope of a team is to its budget. Every counter,
+// gauge, timer and histogram of s counts as one series, the two budget gauges
+// and the metrics of the scopes created from s are not counted. The budget is
+// only reported, metrics over it are still created. Setting the budget again
+// replaces it. On Scope implementations other than those created by
+// NewRootScope this is a no-op.
+func SetCardinalityBudget(s Scope, opts CardinalityBudgetOptions) {
+	if bs, ok := s.(cardinalityBudgetScope); ok {
+		bs.setCardinalityBudget(opts)
+	}
+}
+
+type cardinalityBudgetScope interface {
+	setCardinalityBudget(opts CardinalityBudgetOptions)
+}
+
+type cardinalityBudget struct {
+	budget float64
+	used   Gauge
+	gauge  Gauge
+}
+
+func (s *scope) setCardinalityBudget(opts CardinalityBudgetOptions) {
+	if opts.UsedGaugeName == "" {
+		opts.UsedGaugeName = DefaultCardinalityUsedGaugeName
+	}
+	if opts.BudgetGaugeName == "" {
+		opts.BudgetGaugeName = DefaultCardinalityBudgetGaugeName
+	}
+
+	used, usedOK := s.Gauge(opts.UsedGaugeName).(*gauge)
+	budget, budgetOK := s.Gauge(opts.BudgetGaugeName).(*gauge)
+	if !usedOK || !budgetOK {
+		// NB: A name rejected by SanitizeReject, already reported to OnError.
+		return
+	}
+	used.idle.pin()
+	budget.idle.pin()
+
+	b := &cardinalityBudget{
+		budget: float64(opts.Budget),
+		used:   used,
+		gauge:  budget,
+	}
+
+	s.gm.Lock()
+	s.cardinalityBudget = b
+	s.gm.Unlock()
+}
+
+// updateCardinalityBudget updates the cardinality budget gauges, it must be
+// called without any of the metric locks held.
+func (s *scope) updateCardinalityBudget() {
+	s.gm.RLock()
+	b := s.cardinalityBudget
+	// NB: Exclude the two budget gauges from the series used.
+	used := len(s.gauges) - 2
+	s.gm.RUnlock()
+	if b == nil {
+		return
+	}
+
+	s.cm.RLock()
+	used += len(s.counters)
+	s.cm.RUnlock()
+
+	s.tm.RLock()
+	used += len(s.timers)
+	s.tm.RUnlock()
+
+	s.hm.RLock()
+	used += len(s.histograms)
+	s.hm.RUnlock()
+
+	s.mm.RLock()
+	used += len(s.meters)
+	s.mm.RUnlock()
+
+	b.used.Update(float64(used))
+	b.gauge.Update(b.budget)
+}
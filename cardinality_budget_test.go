@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCardinalityBudget(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{Reporter: r}, 0)
+
+	s := root.Tagged(map[string]string{"team": "a"}).(*scope)
+	SetCardinalityBudget(s, CardinalityBudgetOptions{Budget: 10})
+
+	s.Counter("requests")
+	s.Tagged(map[string]string{"method": "GET"}).Counter("requests")
+	s.Counter("errors")
+	s.Gauge("queue")
+	s.Timer("latency")
+	s.Histogram("size", MustMakeLinearValueBuckets(0, 1, 2))
+	// Metrics of the parent scope are not counted.
+	root.Counter("requests")
+
+	r.gg.Add(2)
+	s.report(r)
+	r.WaitAll()
+	gauges := r.getGauges()
+	assert.Equal(t, float64(5), gauges[DefaultCardinalityUsedGaugeName].val)
+	assert.Equal(t, float64(10), gauges[DefaultCardinalityBudgetGaugeName].val)
+
+	// The gauges are reported on every flush.
+	s.Counter("retries")
+	r.gg.Add(2)
+	s.report(r)
+	r.WaitAll()
+	gauges = r.getGauges()
+	assert.Equal(t, float64(6), gauges[DefaultCardinalityUsedGaugeName].val)
+	assert.Equal(t, float64(10), gauges[DefaultCardinalityBudgetGaugeName].val)
+
+	// The final report on close updates the gauges again.
+	r.gg.Add(2)
+	assert.NoError(t, closer.Close())
+	r.WaitAll()
+}
+
+func TestCardinalityBudgetNames(t *testing.T) {
+	s := NewTestScope("", nil)
+	SetCardinalityBudget(s, CardinalityBudgetOptions{
+		Budget:          3,
+		UsedGaugeName:   "team.used",
+		BudgetGaugeName: "team.budget",
+	})
+	s.Counter("requests")
+	s.(*scope).updateCardinalityBudget()
+
+	gauges := s.Snapshot().Gauges()
+	assert.Equal(t, float64(1), gauges["team.used+"].Value())
+	assert.Equal(t, float64(3), gauges["team.budget+"].Value())
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import "time"
+
+// Clock is a source of time for a scope, see ScopeOptions.Clock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTicker returns a ticker that ticks every period d.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks at intervals like a time.Ticker.
+type Ticker interface {
+	// C returns the channel the ticks are delivered on.
+	C() <-chan time.Time
+
+	// Stop turns off the ticker, no more ticks are delivered once stopped.
+	Stop()
+}
+
+// wallClock is the default Clock, backed by the time package.
+type wallClock struct{}
+
+func (wallClock) Now() time.Time {
+	return globalNow()
+}
+
+func (wallClock) NewTicker(d time.Duration) Ticker {
+	return wallTicker{ticker: time.NewTicker(d)}
+}
+
+type wallTicker struct {
+	ticker *time.Ticker
+}
+
+func (t wallTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t wallTicker) Stop() {
+	t.ticker.Stop()
+}
+
+// clockNow returns the current time of c, or of the wall clock if c is nil.
+func clockNow(c Clock) time.Time {
+	if c == nil {
+		return globalNow()
+	}
+	return c.Now()
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommonTags(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{
+		Reporter:   r,
+		Tags:       map[string]string{"env": "prod"},
+		CommonTags: map[string]string{"host": "h1", "env": "common"},
+	}, 0)
+	defer closer.Close()
+
+	s := root.(*scope)
+	sub := s.Tagged(map[string]string{"region": "east"})
+	sub.Counter("requests").Inc(1)
+
+	r.cg.Add(1)
+	s.registry.Report(r)
+	r.WaitAll()
+
+	// The tags of the scope win over the common tags.
+	assert.Equal(t, map[string]string{
+		"env":    "prod",
+		"host":   "h1",
+		"region": "east",
+	}, r.getCounters()["requests"].tags)
+
+	r.tg.Add(1)
+	sub.Timer("latency").Record(time.Millisecond)
+	r.WaitAll()
+	assert.Equal(t, "h1", r.getTimers()["latency"].tags["host"])
+
+	// The common tags are not tags of the scopes.
+	assert.Equal(t, map[string]string{"env": "prod", "region": "east"}, sub.(*scope).tags)
+	assert.Contains(t, s.Snapshot().Counters(), "requests+env=prod,region=east")
+	assert.Equal(t, sub, s.Tagged(map[string]string{"region": "east"}))
+	assert.Len(t, s.registry.subscopes, 2)
+}
+
+func TestCommonTagsCachedReporter(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{
+		CachedReporter: r,
+		CommonTags:     map[string]string{"host": "h1"},
+	}, 0)
+
+	root.SubScope("db").Counter("queries").Inc(1)
+	root.Gauge("queue").Update(1)
+
+	r.cg.Add(1)
+	r.gg.Add(1)
+	assert.NoError(t, closer.Close())
+	r.WaitAll()
+
+	assert.Equal(t, map[string]string{"host": "h1"}, r.getCounters()["db.queries"].tags)
+	assert.Equal(t, map[string]string{"host": "h1"}, r.getGauges()["queue"].tags)
+}
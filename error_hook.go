@@ -0,0 +1,170 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"log"
+	"sync"
+)
+
+// errorQueueSize is the number of errors queued for ScopeOptions.OnError,
+// further errors are dropped until the handler catches up.
+const errorQueueSize = 64
+
+// ErrorPhase is the phase of a scope in which an error passed to
+// ScopeOptions.OnError occurred.
+type ErrorPhase int
+
+const (
+	// FlushErrorPhase is a failed periodic or final flush of a reporter
+	// implementing FlushErrorReporter or FlushStatsReporter.
+	FlushErrorPhase ErrorPhase = iota
+	// SanitizeErrorPhase is a metric or subscope rejected with
+	// SanitizeReject.
+	SanitizeErrorPhase
+	// CallbackPanicErrorPhase is a recovered panic in a GaugeFunc or in one
+	// of the callbacks protected by ScopeOptions.RecoverCallbackPanics,
+	// except OnError itself.
+	CallbackPanicErrorPhase
+	// TagKeyErrorPhase is a tag key dropped for ScopeOptions.AllowedTagKeys.
+	TagKeyErrorPhase
+)
+
+func (p ErrorPhase) String() string {
+	switch p {
+	case FlushErrorPhase:
+		return "flush"
+	case SanitizeErrorPhase:
+		return "sanitize"
+	case CallbackPanicErrorPhase:
+		return "callback panic"
+	case TagKeyErrorPhase:
+		return "tag key"
+	default:
+		return "unknown"
+	}
+}
+
+// ScopeError is the error passed to ScopeOptions.OnError, the error Err that
+// occurred in Phase.
+type ScopeError struct {
+	Phase ErrorPhase
+	Err   error
+}
+
+func (e *ScopeError) Error() string {
+	return "tally: " + e.Phase.String() + ": " + e.Err.Error()
+}
+
+// errorHook calls ScopeOptions.OnError from its own goroutine so that a slow
+// handler never stalls the scope.
+type errorHook struct {
+	fn    func(err error)
+	queue chan error
+	done  chan struct{}
+	wg    sync.WaitGroup
+
+	// recoverPanics and panics recover and count the panics of fn like
+	// those of the other callbacks with ScopeOptions.RecoverCallbackPanics,
+	// except that they are not passed to fn.
+	recoverPanics bool
+	panics        Counter
+}
+
+// newErrorHook returns the hook of fn, or nil if fn is nil. The errors
+// reported are queued until the hook is started.
+func newErrorHook(fn func(err error), recoverPanics bool) *errorHook {
+	if fn == nil {
+		return nil
+	}
+	return &errorHook{
+		fn:            fn,
+		queue:         make(chan error, errorQueueSize),
+		done:          make(chan struct{}),
+		recoverPanics: recoverPanics,
+	}
+}
+
+// start starts handling the queued errors, it does nothing for a nil hook.
+func (h *errorHook) start() {
+	if h == nil {
+		return
+	}
+	h.wg.Add(1)
+	go h.run()
+}
+
+func (h *errorHook) run() {
+	defer h.wg.Done()
+	for {
+		select {
+		case err := <-h.queue:
+			h.handle(err)
+		case <-h.done:
+			for {
+				select {
+				case err := <-h.queue:
+					h.handle(err)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (h *errorHook) handle(err error) {
+	if h.recoverPanics {
+		defer h.recoverPanic()
+	}
+	h.fn(err)
+}
+
+func (h *errorHook) recoverPanic() {
+	if p := recover(); p != nil {
+		log.Printf("tally: recovered panic in OnError: %v", p)
+		if h.panics != nil {
+			h.panics.Inc(1)
+		}
+	}
+}
+
+// report queues the error of the phase without blocking, dropping it if the
+// queue is full, and does nothing for a nil hook.
+func (h *errorHook) report(phase ErrorPhase, err error) {
+	if h == nil {
+		return
+	}
+	select {
+	case h.queue <- &ScopeError{Phase: phase, Err: err}:
+	default:
+	}
+}
+
+// close waits for the queued errors to be handled and stops the hook, errors
+// reported afterwards are dropped once the queue is full.
+func (h *errorHook) close() {
+	if h == nil {
+		return
+	}
+	close(h.done)
+	h.wg.Wait()
+}
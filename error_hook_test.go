@@ -0,0 +1,141 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receiveScopeError(t *testing.T, errs <-chan error) *ScopeError {
+	select {
+	case err := <-errs:
+		se, ok := err.(*ScopeError)
+		require.True(t, ok, "not a *ScopeError: %v", err)
+		return se
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for an error")
+		return nil
+	}
+}
+
+func TestOnErrorFlush(t *testing.T) {
+	errFlush := errors.New("push failed")
+	r := flushErrorReporter{testStatsReporter: newTestStatsReporter(), err: errFlush}
+	errs := make(chan error, errorQueueSize)
+	_, closer := NewRootScope(ScopeOptions{
+		Reporter: r,
+		OnError:  func(err error) { errs <- err },
+	}, 10*time.Millisecond)
+
+	se := receiveScopeError(t, errs)
+	assert.Equal(t, FlushErrorPhase, se.Phase)
+	assert.Equal(t, errFlush, se.Err)
+	assert.Equal(t, "tally: flush: push failed", se.Error())
+
+	assert.Equal(t, errFlush, closer.Close())
+}
+
+func TestOnErrorSlowHandler(t *testing.T) {
+	r := flushErrorReporter{
+		testStatsReporter: newTestStatsReporter(),
+		err:               errors.New("push failed"),
+	}
+	release := make(chan struct{})
+	var handled int32
+	_, closer := NewRootScope(ScopeOptions{
+		Reporter: r,
+		OnError: func(err error) {
+			<-release
+			atomic.AddInt32(&handled, 1)
+		},
+	}, time.Millisecond)
+
+	// The report loop keeps flushing while the handler is stuck.
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&r.flushes) < 10 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.True(t, atomic.LoadInt32(&r.flushes) >= 10)
+
+	close(release)
+	assert.Error(t, closer.Close())
+
+	// Close waits for the queued errors, the others were dropped.
+	n := atomic.LoadInt32(&handled)
+	assert.True(t, n > 0 && n <= errorQueueSize+1, "handled %d errors", n)
+}
+
+func TestOnErrorSanitizeReject(t *testing.T) {
+	errs := make(chan error, errorQueueSize)
+	s := NewTestScopeWithOptions(ScopeOptions{
+		SanitizeOptions: &SanitizeOptions{
+			NameCharacters:  ValidCharacters{Ranges: AlphanumericRange},
+			KeyCharacters:   ValidCharacters{Ranges: AlphanumericRange},
+			ValueCharacters: ValidCharacters{Ranges: AlphanumericRange},
+			OnInvalid:       SanitizeReject,
+		},
+		OnError: func(err error) { errs <- err },
+	})
+
+	s.Counter("a:b").Inc(1)
+	se := receiveScopeError(t, errs)
+	assert.Equal(t, SanitizeErrorPhase, se.Phase)
+	assert.Equal(t, `tally: sanitize: rejected "a:b" with invalid character ':'`, se.Error())
+
+	s.Tagged(map[string]string{"key": "va|lue"})
+	se = receiveScopeError(t, errs)
+	assert.Equal(t, SanitizeErrorPhase, se.Phase)
+	assert.Contains(t, se.Error(), `"va|lue"`)
+}
+
+func TestOnErrorCallbackPanic(t *testing.T) {
+	r := newTestStatsReporter()
+	errs := make(chan error, errorQueueSize)
+	root, closer := NewRootScope(ScopeOptions{
+		Reporter:              r,
+		RecoverCallbackPanics: true,
+		OnError:               func(err error) { errs <- err },
+	}, 0)
+
+	s := root.(*scope)
+	GaugeFunc(s, "bad", func() float64 { panic("bug") })
+	s.report(r)
+
+	se := receiveScopeError(t, errs)
+	assert.Equal(t, CallbackPanicErrorPhase, se.Phase)
+	assert.Equal(t, "tally: callback panic: gauge func bad panicked: bug", se.Error())
+
+	assert.NoError(t, closer.Close())
+}
+
+func TestErrorPhaseString(t *testing.T) {
+	assert.Equal(t, "flush", FlushErrorPhase.String())
+	assert.Equal(t, "sanitize", SanitizeErrorPhase.String())
+	assert.Equal(t, "callback panic", CallbackPanicErrorPhase.String())
+	assert.Equal(t, "tag key", TagKeyErrorPhase.String())
+	assert.Equal(t, "unknown", ErrorPhase(-1).String())
+}
@@ -0,0 +1,390 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"context"
+	"time"
+)
+
+// FanoutScope returns a Scope that forwards every metric to each of the given
+// scopes, e.g. to feed both a reporting scope and a TestScope from code
+// instrumented once. The metrics it returns emit to the metric of the same
+// name of each scope, and Tagged and SubScope return fan-out scopes of the
+// children of each scope. Its capabilities report if any of the scopes
+// reports, and tag if all of them tag.
+//
+// Package level helpers that create metrics or scopes or register callbacks,
+// such as CounterWithOptions, SubScopeWithSeparator and GaugeFunc, are
+// forwarded to each of the scopes.
+func FanoutScope(scopes ...Scope) Scope {
+	return fanoutScope(append([]Scope(nil), scopes...))
+}
+
+type fanoutScope []Scope
+
+func (s fanoutScope) Counter(name string) Counter {
+	counters := make(fanoutCounter, 0, len(s))
+	for _, scope := range s {
+		counters = append(counters, scope.Counter(name))
+	}
+	return counters
+}
+
+func (s fanoutScope) Gauge(name string) Gauge {
+	gauges := make(fanoutGauge, 0, len(s))
+	for _, scope := range s {
+		gauges = append(gauges, scope.Gauge(name))
+	}
+	return gauges
+}
+
+func (s fanoutScope) Timer(name string) Timer {
+	timers := make(fanoutTimer, 0, len(s))
+	for _, scope := range s {
+		timers = append(timers, scope.Timer(name))
+	}
+	return timers
+}
+
+func (s fanoutScope) Histogram(name string, buckets Buckets) Histogram {
+	histograms := make(fanoutHistogram, 0, len(s))
+	for _, scope := range s {
+		histograms = append(histograms, scope.Histogram(name, buckets))
+	}
+	return histograms
+}
+
+func (s fanoutScope) Tagged(tags map[string]string) Scope {
+	children := make(fanoutScope, 0, len(s))
+	for _, scope := range s {
+		children = append(children, scope.Tagged(tags))
+	}
+	return children
+}
+
+func (s fanoutScope) SubScope(name string) Scope {
+	children := make(fanoutScope, 0, len(s))
+	for _, scope := range s {
+		children = append(children, scope.SubScope(name))
+	}
+	return children
+}
+
+func (s fanoutScope) tryTagged(tags map[string]string) (Scope, error) {
+	children := make(fanoutScope, 0, len(s))
+	for _, scope := range s {
+		child, err := TryTagged(scope, tags)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	return children, nil
+}
+
+func (s fanoutScope) subScopeFromTags(tagKeys []string, tags map[string]string) Scope {
+	children := make(fanoutScope, 0, len(s))
+	for _, scope := range s {
+		children = append(children, SubScopeFromTags(scope, tagKeys, tags))
+	}
+	return children
+}
+
+func (s fanoutScope) subScopeWithSeparator(name, separator string) Scope {
+	children := make(fanoutScope, 0, len(s))
+	for _, scope := range s {
+		children = append(children, SubScopeWithSeparator(scope, name, separator))
+	}
+	return children
+}
+
+func (s fanoutScope) child(opts ChildOptions) Scope {
+	children := make(fanoutScope, 0, len(s))
+	for _, scope := range s {
+		children = append(children, Child(scope, opts))
+	}
+	return children
+}
+
+func (s fanoutScope) counterWithOptions(name string, opts CounterOptions) Counter {
+	counters := make(fanoutCounter, 0, len(s))
+	for _, scope := range s {
+		counters = append(counters, CounterWithOptions(scope, name, opts))
+	}
+	return counters
+}
+
+func (s fanoutScope) rateLimitedCounter(name string, minInterval time.Duration) Counter {
+	counters := make(fanoutCounter, 0, len(s))
+	for _, scope := range s {
+		counters = append(counters, RateLimitedCounter(scope, name, minInterval))
+	}
+	return counters
+}
+
+func (s fanoutScope) gaugeWithOptions(name string, opts GaugeOptions) Gauge {
+	gauges := make(fanoutGauge, 0, len(s))
+	for _, scope := range s {
+		gauges = append(gauges, GaugeWithOptions(scope, name, opts))
+	}
+	return gauges
+}
+
+func (s fanoutScope) trackingGauge(name string) Gauge {
+	gauges := make(fanoutGauge, 0, len(s))
+	for _, scope := range s {
+		gauges = append(gauges, TrackingGauge(scope, name))
+	}
+	return gauges
+}
+
+func (s fanoutScope) timerWithOptions(name string, opts TimerOptions) Timer {
+	timers := make(fanoutTimer, 0, len(s))
+	for _, scope := range s {
+		timers = append(timers, TimerWithOptions(scope, name, opts))
+	}
+	return timers
+}
+
+func (s fanoutScope) percentileTimer(name string, percentiles []float64) Timer {
+	timers := make(fanoutTimer, 0, len(s))
+	for _, scope := range s {
+		// NB: The percentiles were already validated by PercentileTimer.
+		t, _ := PercentileTimer(scope, name, percentiles)
+		timers = append(timers, t)
+	}
+	return timers
+}
+
+func (s fanoutScope) histogramWithOptions(
+	name string,
+	buckets Buckets,
+	opts HistogramOptions,
+) Histogram {
+	histograms := make(fanoutHistogram, 0, len(s))
+	for _, scope := range s {
+		// NB: The options were already validated by HistogramWithOptions.
+		h, _ := HistogramWithOptions(scope, name, buckets, opts)
+		histograms = append(histograms, h)
+	}
+	return histograms
+}
+
+func (s fanoutScope) meter(name string) Meter {
+	meters := make(fanoutMeter, 0, len(s))
+	for _, scope := range s {
+		meters = append(meters, ScopeMeter(scope, name))
+	}
+	return meters
+}
+
+// reportsMeters returns whether any of the scopes reports meters.
+func (s fanoutScope) reportsMeters() bool {
+	for _, scope := range s {
+		if ReportsMeters(scope) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s fanoutScope) emitEvent(title, text string, tags map[string]string) {
+	for _, scope := range s {
+		EmitEvent(scope, title, text, tags)
+	}
+}
+
+func (s fanoutScope) registerRatio(name, numerator, denominator string, opts RatioOptions) {
+	for _, scope := range s {
+		RegisterRatio(scope, name, numerator, denominator, opts)
+	}
+}
+
+func (s fanoutScope) registerInfo(name string, labels map[string]string) {
+	for _, scope := range s {
+		Info(scope, name, labels)
+	}
+}
+
+func (s fanoutScope) registerGaugeFunc(name string, fn func() float64) {
+	for _, scope := range s {
+		GaugeFunc(scope, name, fn)
+	}
+}
+
+func (s fanoutScope) setCardinalityBudget(opts CardinalityBudgetOptions) {
+	for _, scope := range s {
+		SetCardinalityBudget(scope, opts)
+	}
+}
+
+func (s fanoutScope) aliasMetric(canonical, alias string) {
+	for _, scope := range s {
+		AliasMetric(scope, canonical, alias)
+	}
+}
+
+func (s fanoutScope) removeMetricAlias(canonical, alias string) {
+	for _, scope := range s {
+		RemoveMetricAlias(scope, canonical, alias)
+	}
+}
+
+// recorderClock returns the clock of the first scope that has one.
+func (s fanoutScope) recorderClock() Clock {
+	for _, scope := range s {
+		if c := recorderClock(scope); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s fanoutScope) Capabilities() Capabilities {
+	c := &capabilities{tagging: len(s) > 0}
+	for _, scope := range s {
+		c.reporting = c.reporting || scope.Capabilities().Reporting()
+		c.tagging = c.tagging && scope.Capabilities().Tagging()
+	}
+	return c
+}
+
+type fanoutCounter []Counter
+
+func (c fanoutCounter) Inc(delta int64) {
+	for _, counter := range c {
+		counter.Inc(delta)
+	}
+}
+
+func (c fanoutCounter) Dec(delta int64) {
+	for _, counter := range c {
+		counter.Dec(delta)
+	}
+}
+
+func (c fanoutCounter) IncFloat(delta float64) {
+	for _, counter := range c {
+		counter.IncFloat(delta)
+	}
+}
+
+type fanoutGauge []Gauge
+
+func (g fanoutGauge) Update(value float64) {
+	for _, gauge := range g {
+		gauge.Update(value)
+	}
+}
+
+func (g fanoutGauge) Add(delta float64) {
+	for _, gauge := range g {
+		gauge.Add(delta)
+	}
+}
+
+type fanoutMeter []Meter
+
+func (m fanoutMeter) Record(value float64) {
+	for _, meter := range m {
+		meter.Record(value)
+	}
+}
+
+type fanoutTimer []Timer
+
+func (t fanoutTimer) Record(value time.Duration) {
+	for _, timer := range t {
+		timer.Record(value)
+	}
+}
+
+func (t fanoutTimer) Start() Stopwatch {
+	return NewStopwatch(stopwatchNow(t), t)
+}
+
+func (t fanoutTimer) RecordFunc(f func()) {
+	defer t.Start().Stop()
+	f()
+}
+
+func (t fanoutTimer) StartContext(ctx context.Context) Stopwatch {
+	return StartStopwatch(NewContextRecorder(ctx, t, ContextRecorderOptions{}))
+}
+
+func (t fanoutTimer) RecordStopwatch(stopwatchStart time.Time) {
+	t.Record(stopwatchNow(t).Sub(stopwatchStart))
+}
+
+func (t fanoutTimer) recordElapsed(stopwatchStart, now time.Time) {
+	t.Record(now.Sub(stopwatchStart))
+}
+
+// recorderClock returns the clock of the first timer that has one.
+func (t fanoutTimer) recorderClock() Clock {
+	return multiTimerRecorder(t).recorderClock()
+}
+
+type fanoutHistogram []Histogram
+
+func (h fanoutHistogram) RecordValue(value float64) {
+	for _, histogram := range h {
+		histogram.RecordValue(value)
+	}
+}
+
+func (h fanoutHistogram) RecordDuration(value time.Duration) {
+	for _, histogram := range h {
+		histogram.RecordDuration(value)
+	}
+}
+
+func (h fanoutHistogram) Start() Stopwatch {
+	return NewStopwatch(stopwatchNow(h), h)
+}
+
+func (h fanoutHistogram) RecordDurationFunc(f func()) {
+	defer h.Start().Stop()
+	f()
+}
+
+func (h fanoutHistogram) StartContext(ctx context.Context) Stopwatch {
+	return StartStopwatch(NewContextRecorder(ctx, h, ContextRecorderOptions{}))
+}
+
+func (h fanoutHistogram) RecordStopwatch(stopwatchStart time.Time) {
+	h.RecordDuration(stopwatchNow(h).Sub(stopwatchStart))
+}
+
+func (h fanoutHistogram) recordElapsed(stopwatchStart, now time.Time) {
+	h.RecordDuration(now.Sub(stopwatchStart))
+}
+
+// recorderClock returns the clock of the first histogram that has one.
+func (h fanoutHistogram) recorderClock() Clock {
+	for _, histogram := range h {
+		if c := recorderClock(histogram); c != nil {
+			return c
+		}
+	}
+	return nil
+}
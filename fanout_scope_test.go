@@ -0,0 +1,91 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFanoutScope(t *testing.T) {
+	now := time.Now()
+	defer withFixedNow(now)()
+
+	a, b := NewTestScope("", nil), NewTestScope("", nil)
+	s := FanoutScope(a, b).SubScope("rpc").Tagged(map[string]string{"method": "get"})
+
+	s.Counter("calls").Inc(2)
+	s.Gauge("inflight").Update(3)
+	s.Timer("latency").Record(time.Second)
+	NewStopwatch(now.Add(-time.Second), s.Timer("latency").(StopwatchRecorder)).Stop()
+	s.Histogram("size", ValueBuckets{10}).RecordValue(5)
+
+	for _, scope := range []TestScope{a, b} {
+		snap := scope.Snapshot()
+		assert.EqualValues(t, 2, snap.Counters()["rpc.calls+method=get"].Value())
+		assert.Equal(t, 3.0, snap.Gauges()["rpc.inflight+method=get"].Value())
+		assert.Equal(t, []time.Duration{time.Second, time.Second},
+			snap.Timers()["rpc.latency+method=get"].Values())
+		assert.Equal(t, map[float64]int64{10: 1, math.MaxFloat64: 0},
+			snap.Histograms()["rpc.size+method=get"].Values())
+	}
+}
+
+func TestFanoutScopeCapabilities(t *testing.T) {
+	reporting, _ := NewRootScope(ScopeOptions{Reporter: newTestStatsReporter()}, 0)
+	test := NewTestScope("", nil)
+
+	c := FanoutScope(reporting, test).Capabilities()
+	assert.True(t, c.Reporting())
+	assert.False(t, c.Tagging())
+
+	c = FanoutScope().Capabilities()
+	assert.False(t, c.Reporting())
+	assert.False(t, c.Tagging())
+}
+
+func TestFanoutScopeHelpers(t *testing.T) {
+	a, b := newRootScope(ScopeOptions{}, 0), newRootScope(ScopeOptions{}, 0)
+	defer a.Close()
+	defer b.Close()
+
+	s := SubScopeWithSeparator(FanoutScope(a, b), "rpc", "/")
+	CounterWithOptions(s, "calls", CounterOptions{}).Inc(2)
+	ScopeMeter(s, "load").Record(0.5)
+	GaugeFunc(s, "queue", func() float64 { return 4 })
+	tm, err := PercentileTimer(s, "latency", []float64{50})
+	assert.NoError(t, err)
+	tm.Record(time.Second)
+
+	for _, scope := range []*scope{a, b} {
+		snap := scope.Snapshot()
+		assert.EqualValues(t, 2, snap.Counters()["rpc/calls+"].Value())
+		assert.Equal(t, []float64{0.5}, snap.Meters()["rpc/load+"].Values())
+
+		scope.registry.Report(NullStatsReporter)
+		gauges := scope.Snapshot().Gauges()
+		assert.Equal(t, 4.0, gauges["rpc/queue+"].Value())
+		assert.Equal(t, 1.0, gauges["rpc/latency/p50+"].Value())
+	}
+}
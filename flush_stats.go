@@ -0,0 +1,88 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+// MetricTypeTagKey is the tag key of the metric type used by the
+// FlushedMetricsGauge.
+const MetricTypeTagKey = "metric_type"
+
+// FlushStats are the numbers of metrics of each type a reporter flushed.
+type FlushStats struct {
+	Counters   int
+	Gauges     int
+	Timers     int
+	Histograms int
+	Meters     int
+}
+
+// FlushStatsReporter is an optional interface implemented by reporters that
+// count the metrics they flush, scopes flush them with FlushWithStats instead
+// of Flush or FlushWithError and update the ScopeOptions.FlushedMetricsGauge
+// with the counts.
+type FlushStatsReporter interface {
+	// FlushWithStats flushes all reported values like Flush and returns the
+	// numbers of metrics of each type flushed and whether that failed.
+	FlushWithStats() (FlushStats, error)
+}
+
+// flushedMetrics are the gauges of ScopeOptions.FlushedMetricsGauge.
+type flushedMetrics struct {
+	counters, gauges, timers, histograms, meters Gauge
+}
+
+func newFlushedMetrics(s Scope, name string) *flushedMetrics {
+	gauge := func(t MetricType) Gauge {
+		return s.Tagged(map[string]string{MetricTypeTagKey: t.String()}).Gauge(name)
+	}
+	return &flushedMetrics{
+		counters:   gauge(CounterMetricType),
+		gauges:     gauge(GaugeMetricType),
+		timers:     gauge(TimerMetricType),
+		histograms: gauge(HistogramMetricType),
+		meters:     gauge(MeterMetricType),
+	}
+}
+
+func (f *flushedMetrics) update(stats FlushStats) {
+	if f == nil {
+		return
+	}
+	f.counters.Update(float64(stats.Counters))
+	f.gauges.Update(float64(stats.Gauges))
+	f.timers.Update(float64(stats.Timers))
+	f.histograms.Update(float64(stats.Histograms))
+	f.meters.Update(float64(stats.Meters))
+}
+
+// flushReporter flushes the reporter with the first of FlushWithStats,
+// FlushWithError and Flush it implements.
+func (s *scope) flushReporter(r BaseStatsReporter) error {
+	if fr, ok := r.(FlushStatsReporter); ok {
+		stats, err := fr.FlushWithStats()
+		s.flushedMetrics.update(stats)
+		return err
+	}
+	if fr, ok := r.(FlushErrorReporter); ok {
+		return fr.FlushWithError()
+	}
+	r.Flush()
+	return nil
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flushStatsReporter counts the distinct metrics of each type reported
+// since the last flush.
+type flushStatsReporter struct {
+	nullStatsReporter
+
+	sync.Mutex
+	counters, gauges, timers, histograms, meters map[string]struct{}
+	flushes                                      int
+	err                                          error
+}
+
+func newFlushStatsReporter() *flushStatsReporter {
+	r := &flushStatsReporter{}
+	r.reset()
+	return r
+}
+
+func (r *flushStatsReporter) reset() {
+	r.counters = make(map[string]struct{})
+	r.gauges = make(map[string]struct{})
+	r.timers = make(map[string]struct{})
+	r.histograms = make(map[string]struct{})
+	r.meters = make(map[string]struct{})
+}
+
+func (r *flushStatsReporter) add(m map[string]struct{}, name string, tags map[string]string) {
+	r.Lock()
+	m[KeyForPrefixedStringMap(name, tags)] = struct{}{}
+	r.Unlock()
+}
+
+func (r *flushStatsReporter) ReportCounter(name string, tags map[string]string, value int64) {
+	r.add(r.counters, name, tags)
+}
+
+func (r *flushStatsReporter) ReportGauge(name string, tags map[string]string, value float64) {
+	r.add(r.gauges, name, tags)
+}
+
+func (r *flushStatsReporter) ReportTimer(name string, tags map[string]string, interval time.Duration) {
+	r.add(r.timers, name, tags)
+}
+
+func (r *flushStatsReporter) ReportHistogramValueSamples(
+	name string,
+	tags map[string]string,
+	buckets Buckets,
+	bucketLowerBound,
+	bucketUpperBound float64,
+	samples int64,
+) {
+	r.add(r.histograms, name, tags)
+}
+
+func (r *flushStatsReporter) ReportMeter(name string, tags map[string]string, samples []float64) {
+	r.add(r.meters, name, tags)
+}
+
+func (r *flushStatsReporter) Flush() {
+	panic("Flush called instead of FlushWithStats")
+}
+
+func (r *flushStatsReporter) FlushWithStats() (FlushStats, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	stats := FlushStats{
+		Counters:   len(r.counters),
+		Gauges:     len(r.gauges),
+		Timers:     len(r.timers),
+		Histograms: len(r.histograms),
+		Meters:     len(r.meters),
+	}
+	r.reset()
+	r.flushes++
+	return stats, r.err
+}
+
+func TestFlushStatsReporter(t *testing.T) {
+	r := newFlushStatsReporter()
+	s := newRootScope(ScopeOptions{
+		Reporter:            r,
+		FlushedMetricsGauge: "flushed",
+	}, 0)
+
+	s.Counter("a").Inc(1)
+	s.Counter("b").Inc(1)
+	s.Gauge("g").Update(1)
+	s.Timer("t").Record(time.Second)
+	s.Histogram("h", ValueBuckets{1}).RecordValue(1)
+	ScopeMeter(s, "m").Record(1)
+	assert.NoError(t, s.reportRegistry())
+	assert.Equal(t, 1, r.flushes)
+
+	gauges := s.Snapshot().Gauges()
+	for typ, want := range map[string]float64{
+		"counter":   2,
+		"gauge":     1,
+		"timer":     1,
+		"histogram": 1,
+		"meter":     1,
+	} {
+		assert.Equal(t, want, gauges["flushed+metric_type="+typ].Value(), typ)
+	}
+
+	// The next flush counts the flushed metrics gauges themselves.
+	r.err = errors.New("flush failed")
+	s.Counter("a").Inc(1)
+	assert.EqualError(t, s.reportRegistry(), "flush failed")
+
+	gauges = s.Snapshot().Gauges()
+	assert.Equal(t, float64(1), gauges["flushed+metric_type=counter"].Value())
+	assert.Equal(t, float64(5), gauges["flushed+metric_type=gauge"].Value())
+	assert.Equal(t, float64(0), gauges["flushed+metric_type=histogram"].Value())
+	assert.Equal(t, float64(0), gauges["flushed+metric_type=meter"].Value())
+
+	r.err = nil
+	assert.NoError(t, s.Close())
+}
+
+func TestFlushStatsReporterWithoutGauge(t *testing.T) {
+	r := newFlushStatsReporter()
+	s := newRootScope(ScopeOptions{Reporter: r}, 0)
+
+	s.Counter("a").Inc(1)
+	assert.NoError(t, s.reportRegistry())
+	assert.Equal(t, 1, r.flushes)
+	assert.Empty(t, s.Snapshot().Gauges())
+	assert.NoError(t, s.Close())
+}
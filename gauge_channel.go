@@ -0,0 +1,96 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"io"
+	"sync"
+)
+
+// GaugeFromChannel starts a goroutine updating the gauge with the given name
+// of s with each value received from ch, e.g. for values pushed by another
+// goroutine such as configuration updates. The goroutine stops when ch is
+// closed, when the returned closer is closed, which waits for it to stop, or
+// when s or its root scope is closed. Values sent once it stopped are not
+// received, so senders should not block on ch. On Scope implementations other
+// than those created by NewRootScope the goroutine only stops with ch or the
+// returned closer.
+func GaugeFromChannel(s Scope, name string, ch <-chan float64) io.Closer {
+	g := &channelGauge{
+		gauge:   s.Gauge(name),
+		ch:      ch,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	// NB: Receiving from a nil channel blocks forever, so without the done
+	// channels of a scope the goroutine never stops by them.
+	if ss, ok := s.(gaugeFromChannelScope); ok {
+		g.scopeDone, g.rootDone = ss.gaugeChannelDone()
+	}
+
+	go g.run()
+	return g
+}
+
+type gaugeFromChannelScope interface {
+	gaugeChannelDone() (scopeDone, rootDone <-chan struct{})
+}
+
+func (s *scope) gaugeChannelDone() (scopeDone, rootDone <-chan struct{}) {
+	return s.done, s.registry.root.done
+}
+
+type channelGauge struct {
+	gauge     Gauge
+	ch        <-chan float64
+	scopeDone <-chan struct{}
+	rootDone  <-chan struct{}
+
+	stop      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+func (g *channelGauge) run() {
+	defer close(g.stopped)
+	for {
+		select {
+		case v, ok := <-g.ch:
+			if !ok {
+				return
+			}
+			g.gauge.Update(v)
+		case <-g.stop:
+			return
+		case <-g.scopeDone:
+			return
+		case <-g.rootDone:
+			return
+		}
+	}
+}
+
+// Close stops the goroutine and waits for it to stop.
+func (g *channelGauge) Close() error {
+	g.closeOnce.Do(func() { close(g.stop) })
+	<-g.stopped
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func waitForGauge(t *testing.T, s TestScope, id string, value float64) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if g, ok := s.Snapshot().Gauges()[id]; ok && g.Value() == value {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("gauge %s never reached %v", id, value)
+}
+
+func TestGaugeFromChannel(t *testing.T) {
+	s := NewTestScope("", nil)
+	ch := make(chan float64)
+	closer := GaugeFromChannel(s, "config.version", ch)
+
+	ch <- 1
+	ch <- 2
+	waitForGauge(t, s, "config.version+", 2)
+
+	require.NoError(t, closer.Close())
+	require.NoError(t, closer.Close())
+
+	select {
+	case ch <- 3:
+		t.Fatal("value received after close")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestGaugeFromChannelStopsWithChannel(t *testing.T) {
+	s := NewTestScope("", nil)
+	ch := make(chan float64, 1)
+	closer := GaugeFromChannel(s, "config.version", ch)
+
+	ch <- 1
+	close(ch)
+	waitForGauge(t, s, "config.version+", 1)
+
+	// Close returns once the goroutine stopped with the channel.
+	require.NoError(t, closer.Close())
+}
+
+func TestGaugeFromChannelStopsWithScope(t *testing.T) {
+	root, closer := NewRootScope(ScopeOptions{Reporter: NullStatsReporter}, 0)
+	ch := make(chan float64)
+	g := GaugeFromChannel(root.SubScope("config"), "version", ch)
+
+	require.NoError(t, closer.Close())
+	done := make(chan struct{})
+	go func() {
+		g.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("goroutine did not stop with the root scope")
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+// CallbackTagKey is the tag key of the callback name used by the
+// CallbackPanicsCounter.
+const CallbackTagKey = "callback"
+
+// GaugeFunc registers a gauge with the given name on a scope created by
+// NewRootScope that is updated with the value returned by fn on each report,
+// e.g. to report the size of a queue. fn is called from the report loop and
+// must be safe to call concurrently with the rest of the program. Panics in fn
+// are always recovered, regardless of ScopeOptions.RecoverCallbackPanics, so
+// that they never crash the report loop: they are logged, counted with the
+// CallbackPanicsCounter, passed to OnError and the gauge keeps its previous
+// value. Registering a gauge with the same name again replaces its fn. On
+// other Scope implementations nothing is registered.
+func GaugeFunc(s Scope, name string, fn func() float64) {
+	if r, ok := s.(gaugeFuncRegisterer); ok {
+		r.registerGaugeFunc(name, fn)
+	}
+}
+
+type gaugeFuncRegisterer interface {
+	registerGaugeFunc(name string, fn func() float64)
+}
+
+type gaugeFunc struct {
+	name   string
+	gauge  *gauge
+	fn     func() float64
+	panics Counter
+}
+
+func (s *scope) registerGaugeFunc(name string, fn func() float64) {
+	g, ok := s.Gauge(name).(*gauge)
+	if !ok {
+		// NB: A name rejected by SanitizeReject, already reported to OnError.
+		return
+	}
+	f := gaugeFunc{
+		name:  s.fullyQualifiedName(s.sanitizer.Name(name)),
+		gauge: g,
+		fn:    fn,
+	}
+	// NB: The counter is created up front as scopes cannot be created while
+	// the registry is reporting.
+	f.panics = callbackPanics(s.registry.root, s.callbackPanicsCounter, f.name)
+	f.gauge.idle.pin()
+
+	s.gm.Lock()
+	defer s.gm.Unlock()
+
+	for i := range s.gaugeFuncs {
+		if s.gaugeFuncs[i].name == f.name {
+			s.gaugeFuncs[i] = f
+			return
+		}
+	}
+	s.gaugeFuncs = append(s.gaugeFuncs, f)
+}
+
+// updateGaugeFuncs updates all gauges registered with GaugeFunc, must be
+// called with the gauges lock held.
+func (s *scope) updateGaugeFuncs() {
+	for _, f := range s.gaugeFuncs {
+		s.updateGaugeFunc(f)
+	}
+}
+
+func (s *scope) updateGaugeFunc(f gaugeFunc) {
+	defer s.registry.recoverCallback("gauge func", f.name, f.panics)
+	f.gauge.Update(f.fn())
+}
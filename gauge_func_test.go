@@ -0,0 +1,126 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGaugeFunc(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{Reporter: r}, 0)
+
+	s := root.(*scope)
+	value := 1.0
+	GaugeFunc(s, "queue", func() float64 { return value })
+
+	for _, v := range []float64{1, 2} {
+		value = v
+		r.gg.Add(1)
+		s.report(r)
+		r.WaitAll()
+		assert.Equal(t, v, r.getGauges()["queue"].val)
+	}
+
+	r.gg.Add(1)
+	assert.NoError(t, closer.Close())
+	r.WaitAll()
+}
+
+func TestGaugeFuncReplace(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{Reporter: r}, 0)
+
+	s := root.(*scope)
+	calls := 0
+	GaugeFunc(s, "queue", func() float64 {
+		calls++
+		return 1
+	})
+	GaugeFunc(s, "queue", func() float64 { return 2 })
+
+	for i := 0; i < 2; i++ {
+		r.gg.Add(1)
+		s.report(r)
+		r.WaitAll()
+		assert.Equal(t, float64(2), r.getGauges()["queue"].val)
+	}
+	assert.Equal(t, 0, calls)
+
+	r.gg.Add(1)
+	assert.NoError(t, closer.Close())
+	r.WaitAll()
+}
+
+func TestGaugeFuncRecoverPanics(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{
+		Reporter:              r,
+		RecoverCallbackPanics: true,
+		CallbackPanicsCounter: "tally.callback_panics",
+	}, 0)
+
+	s := root.(*scope)
+	GaugeFunc(s, "good", func() float64 { return 42 })
+	GaugeFunc(s, "bad", func() float64 { panic("bug") })
+
+	// The report survives the panic and still reports the other gauge.
+	r.gg.Add(1)
+	s.report(r)
+	r.WaitAll()
+	assert.Equal(t, float64(42), r.getGauges()["good"].val)
+	assert.NotContains(t, r.getGauges(), "bad")
+
+	counters := s.Snapshot().Counters()
+	assert.EqualValues(t, 1, counters["tally.callback_panics+callback=bad"].Value())
+
+	r.cg.Add(1)
+	r.gg.Add(1)
+	assert.NoError(t, closer.Close())
+	r.WaitAll()
+}
+
+func TestGaugeFuncRecoverPanicsByDefault(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{
+		Reporter:              r,
+		CallbackPanicsCounter: "tally.callback_panics",
+	}, 0)
+
+	s := root.(*scope)
+	GaugeFunc(s, "good", func() float64 { return 42 })
+	GaugeFunc(s, "bad", func() float64 { panic("bug") })
+
+	r.gg.Add(1)
+	s.report(r)
+	r.WaitAll()
+	assert.Equal(t, float64(42), r.getGauges()["good"].val)
+
+	counters := s.Snapshot().Counters()
+	assert.EqualValues(t, 1, counters["tally.callback_panics+callback=bad"].Value())
+
+	r.cg.Add(1)
+	r.gg.Add(1)
+	assert.NoError(t, closer.Close())
+	r.WaitAll()
+}
@@ -32,9 +32,13 @@ var (
 	// DefaultBuckets can be passed to specify to default buckets.
 	DefaultBuckets Buckets
 
-	errBucketsCountNeedsGreaterThanZero = errors.New("n needs to be > 0")
-	errBucketsStartNeedsGreaterThanZero = errors.New("start needs to be > 0")
-	errBucketsFactorNeedsGreaterThanOne = errors.New("factor needs to be > 1")
+	errBucketsCountNeedsGreaterThanZero     = errors.New("n needs to be > 0")
+	errBucketsStartNeedsGreaterThanZero     = errors.New("start needs to be > 0")
+	errBucketsFactorNeedsGreaterThanOne     = errors.New("factor needs to be > 1")
+	errBucketsSegmentNotIncreasing          = errors.New("segment buckets need to be increasing")
+	errBucketsWidthNeedsNonZero             = errors.New("width needs to be != 0")
+	errBucketsMagnitudeNeedsGreaterThanZero = errors.New("magnitude needs to be > 0")
+	errBucketsMixedTypes                    = errors.New("buckets need to be both value or both duration buckets")
 
 	_singleBucket = bucketPair{
 		lowerBoundDuration: time.Duration(math.MinInt64),
@@ -279,15 +283,22 @@ func (p bucketPair) UpperBoundDuration() time.Duration {
 	return p.upperBoundDuration
 }
 
-// LinearValueBuckets creates a set of linear value buckets.
+// LinearValueBuckets creates a set of linear value buckets. The start and
+// width may be negative, e.g. for buckets around zero, the buckets are
+// sorted so a negative width counts down from start. The width must not be
+// zero for more than one bucket.
 func LinearValueBuckets(start, width float64, n int) (ValueBuckets, error) {
 	if n <= 0 {
 		return nil, errBucketsCountNeedsGreaterThanZero
 	}
+	if width == 0 && n > 1 {
+		return nil, errBucketsWidthNeedsNonZero
+	}
 	buckets := make([]float64, n)
 	for i := range buckets {
 		buckets[i] = start + (float64(i) * width)
 	}
+	sort.Float64s(buckets)
 	return buckets, nil
 }
 
@@ -301,15 +312,20 @@ func MustMakeLinearValueBuckets(start, width float64, n int) ValueBuckets {
 	return buckets
 }
 
-// LinearDurationBuckets creates a set of linear duration buckets.
+// LinearDurationBuckets creates a set of linear duration buckets, with the
+// same rules for the start and width as LinearValueBuckets.
 func LinearDurationBuckets(start, width time.Duration, n int) (DurationBuckets, error) {
 	if n <= 0 {
 		return nil, errBucketsCountNeedsGreaterThanZero
 	}
+	if width == 0 && n > 1 {
+		return nil, errBucketsWidthNeedsNonZero
+	}
 	buckets := make([]time.Duration, n)
 	for i := range buckets {
 		buckets[i] = start + (time.Duration(i) * width)
 	}
+	sort.Sort(DurationBuckets(buckets))
 	return buckets, nil
 }
 
@@ -323,6 +339,38 @@ func MustMakeLinearDurationBuckets(start, width time.Duration, n int) DurationBu
 	return buckets
 }
 
+// SymmetricValueBuckets creates a set of linear value buckets symmetric around
+// zero, with n buckets on each side of zero up to the magnitude, e.g. a
+// magnitude of 100 and n of 2 creates the buckets -100, -50, 0, 50 and 100.
+func SymmetricValueBuckets(magnitude float64, n int) (ValueBuckets, error) {
+	if n <= 0 {
+		return nil, errBucketsCountNeedsGreaterThanZero
+	}
+	if !(magnitude > 0) {
+		return nil, errBucketsMagnitudeNeedsGreaterThanZero
+	}
+	buckets, err := LinearValueBuckets(-magnitude, magnitude/float64(n), 2*n+1)
+	if err != nil {
+		return nil, err
+	}
+	// NB: Avoid rounding errors around zero and at the positive magnitude.
+	for i := 0; i < n; i++ {
+		buckets[2*n-i] = -buckets[i]
+	}
+	buckets[n] = 0
+	return buckets, nil
+}
+
+// MustMakeSymmetricValueBuckets creates a set of value buckets symmetric
+// around zero or panics.
+func MustMakeSymmetricValueBuckets(magnitude float64, n int) ValueBuckets {
+	buckets, err := SymmetricValueBuckets(magnitude, n)
+	if err != nil {
+		panic(err)
+	}
+	return buckets
+}
+
 // ExponentialValueBuckets creates a set of exponential value buckets.
 func ExponentialValueBuckets(start, factor float64, n int) (ValueBuckets, error) {
 	if n <= 0 {
@@ -382,3 +430,149 @@ func MustMakeExponentialDurationBuckets(start time.Duration, factor float64, n i
 	}
 	return buckets
 }
+
+// MergeValueBuckets merges segments of value buckets into one set of sorted
+// value buckets without duplicates, e.g. linear segments that are dense near
+// SLO thresholds combined with a sparse exponential segment. Each segment
+// needs to be strictly increasing, segments may overlap each other.
+func MergeValueBuckets(segments ...ValueBuckets) (ValueBuckets, error) {
+	var merged []float64
+	for _, segment := range segments {
+		for i, v := range segment {
+			if math.IsNaN(v) || (i > 0 && v <= segment[i-1]) {
+				return nil, errBucketsSegmentNotIncreasing
+			}
+			merged = append(merged, v)
+		}
+	}
+	if len(merged) == 0 {
+		return nil, errBucketsCountNeedsGreaterThanZero
+	}
+	sort.Float64s(merged)
+
+	buckets := merged[:1]
+	for _, v := range merged[1:] {
+		if v != buckets[len(buckets)-1] {
+			buckets = append(buckets, v)
+		}
+	}
+	return buckets, nil
+}
+
+// MustMakeMergedValueBuckets merges segments of value buckets or panics.
+func MustMakeMergedValueBuckets(segments ...ValueBuckets) ValueBuckets {
+	buckets, err := MergeValueBuckets(segments...)
+	if err != nil {
+		panic(err)
+	}
+	return buckets
+}
+
+// MergeDurationBuckets merges segments of duration buckets like
+// MergeValueBuckets.
+func MergeDurationBuckets(segments ...DurationBuckets) (DurationBuckets, error) {
+	var merged []time.Duration
+	for _, segment := range segments {
+		for i, v := range segment {
+			if i > 0 && v <= segment[i-1] {
+				return nil, errBucketsSegmentNotIncreasing
+			}
+			merged = append(merged, v)
+		}
+	}
+	if len(merged) == 0 {
+		return nil, errBucketsCountNeedsGreaterThanZero
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i] < merged[j] })
+
+	buckets := merged[:1]
+	for _, v := range merged[1:] {
+		if v != buckets[len(buckets)-1] {
+			buckets = append(buckets, v)
+		}
+	}
+	return buckets, nil
+}
+
+// MustMakeMergedDurationBuckets merges segments of duration buckets or
+// panics.
+func MustMakeMergedDurationBuckets(segments ...DurationBuckets) DurationBuckets {
+	buckets, err := MergeDurationBuckets(segments...)
+	if err != nil {
+		panic(err)
+	}
+	return buckets
+}
+
+// MergeBuckets returns the sorted union without duplicates of two sets of
+// buckets in any order, e.g. a shared base set and a per service extension,
+// as value buckets if both are value buckets and as duration buckets if both
+// are duration buckets. Merging value buckets with duration buckets returns
+// an error, while a nil or empty set merges as no buckets. Unlike
+// MergeValueBuckets the sets need not be sorted.
+func MergeBuckets(a, b Buckets) (Buckets, error) {
+	// NB: The type of an empty set does not matter.
+	_, aDurations := a.(DurationBuckets)
+	aDurations = aDurations && bucketsLen(a) > 0
+	_, bDurations := b.(DurationBuckets)
+	bDurations = bDurations && bucketsLen(b) > 0
+	switch {
+	case bucketsLen(a)+bucketsLen(b) == 0:
+		return nil, errBucketsCountNeedsGreaterThanZero
+	case bucketsLen(a) > 0 && bucketsLen(b) > 0 && aDurations != bDurations:
+		return nil, errBucketsMixedTypes
+	}
+
+	if aDurations || bDurations {
+		var merged DurationBuckets
+		if a != nil {
+			merged = append(merged, a.AsDurations()...)
+		}
+		if b != nil {
+			merged = append(merged, b.AsDurations()...)
+		}
+		sort.Sort(merged)
+
+		buckets := merged[:1]
+		for _, v := range merged[1:] {
+			if v != buckets[len(buckets)-1] {
+				buckets = append(buckets, v)
+			}
+		}
+		return buckets, nil
+	}
+
+	var merged ValueBuckets
+	if a != nil {
+		merged = append(merged, a.AsValues()...)
+	}
+	if b != nil {
+		merged = append(merged, b.AsValues()...)
+	}
+	sort.Sort(merged)
+
+	buckets := merged[:1]
+	for _, v := range merged[1:] {
+		if v != buckets[len(buckets)-1] {
+			buckets = append(buckets, v)
+		}
+	}
+	return buckets, nil
+}
+
+// MustMakeMergedBuckets merges two sets of buckets like MergeBuckets or
+// panics.
+func MustMakeMergedBuckets(a, b Buckets) Buckets {
+	buckets, err := MergeBuckets(a, b)
+	if err != nil {
+		panic(err)
+	}
+	return buckets
+}
+
+func bucketsLen(b Buckets) int {
+	if b == nil {
+		return 0
+	}
+	return b.Len()
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import "time"
+
+// RecordValues records the values into a value histogram like calling
+// h.RecordValue for each of them, but adds up the samples of each bucket and
+// updates each bucket once, and takes the rate limit of
+// ScopeOptions.SeriesRateLimit once, e.g. to import thousands of
+// pre-aggregated samples at once. Values over the rate limit are dropped
+// from the end of values. On Histogram implementations other than those
+// created by NewRootScope RecordValue is called for each value.
+func RecordValues(h Histogram, values []float64) {
+	if bh, ok := h.(batchHistogram); ok {
+		bh.recordValues(values)
+		return
+	}
+	for _, value := range values {
+		h.RecordValue(value)
+	}
+}
+
+// RecordDurations records the durations into a duration histogram like
+// RecordValues.
+func RecordDurations(h Histogram, values []time.Duration) {
+	if bh, ok := h.(batchHistogram); ok {
+		bh.recordDurations(values)
+		return
+	}
+	for _, value := range values {
+		h.RecordDuration(value)
+	}
+}
+
+type batchHistogram interface {
+	recordValues(values []float64)
+	recordDurations(values []time.Duration)
+}
+
+// allowed returns the first n values within the rate limit of the histogram.
+func (h *histogram) allowed(n int) int {
+	if h.limiter == nil {
+		return n
+	}
+	return h.limiter.allowN(n)
+}
+
+func (h *histogram) recordValues(values []float64) {
+	if h.htype != valueHistogramType || len(values) == 0 {
+		return
+	}
+	values = values[:h.allowed(len(values))]
+	if len(values) == 0 {
+		return
+	}
+	h.idle.touch()
+
+	counts := make([]int64, len(h.samples))
+	for _, value := range values {
+		if h.scale != 0 {
+			value *= h.scale
+		}
+		h.countValueOutOfRange(value)
+		if h.clamp != nil {
+			value = h.clamp.value(value)
+		}
+
+		counts[h.valueBucket(value)]++
+
+		if h.retained != nil {
+			h.retained.Update(value)
+		}
+		h.recordValueAliases(value)
+	}
+	h.addSamples(counts)
+}
+
+func (h *histogram) recordDurations(values []time.Duration) {
+	if h.htype != durationHistogramType || len(values) == 0 {
+		return
+	}
+	values = values[:h.allowed(len(values))]
+	if len(values) == 0 {
+		return
+	}
+	h.idle.touch()
+
+	counts := make([]int64, len(h.samples))
+	for _, value := range values {
+		h.countDurationOutOfRange(value)
+		if h.clamp != nil {
+			value = h.clamp.duration(value)
+		}
+
+		counts[h.durationBucket(value)]++
+
+		if h.retained != nil {
+			h.retained.Update(float64(value) / float64(time.Second))
+		}
+		h.recordDurationAliases(value)
+	}
+	h.addSamples(counts)
+}
+
+func (h *histogram) addSamples(counts []int64) {
+	for i, count := range counts {
+		if count != 0 {
+			h.samples[i].counter.Inc(count)
+		}
+	}
+}
+
+func (h *autoHistogram) recordValues(values []float64) {
+	if derived, ok := h.derived.Load().(Histogram); ok {
+		RecordValues(derived, values)
+		return
+	}
+	for _, value := range values {
+		h.RecordValue(value)
+	}
+}
+
+func (h *autoHistogram) recordDurations(values []time.Duration) {
+	if derived, ok := h.derived.Load().(Histogram); ok {
+		RecordDurations(derived, values)
+		return
+	}
+	for _, value := range values {
+		h.RecordDuration(value)
+	}
+}
+
+func (h fanoutHistogram) recordValues(values []float64) {
+	for _, histogram := range h {
+		RecordValues(histogram, values)
+	}
+}
+
+func (h fanoutHistogram) recordDurations(values []time.Duration) {
+	for _, histogram := range h {
+		RecordDurations(histogram, values)
+	}
+}
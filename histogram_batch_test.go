@@ -0,0 +1,89 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordValuesMatchesRecordValue(t *testing.T) {
+	values := []float64{-1, 0, 0.5, 1, 3, 3, 7, 100}
+	durations := []time.Duration{0, time.Millisecond, time.Second, time.Second, time.Hour}
+	valueBuckets := ValueBuckets{0, 1, 5, 10}
+	durationBuckets := DurationBuckets{time.Millisecond, time.Second, time.Minute}
+
+	batch := NewTestScope("", nil)
+	RecordValues(batch.Histogram("values", valueBuckets), values)
+	RecordDurations(batch.Histogram("durations", durationBuckets), durations)
+
+	single := NewTestScope("", nil)
+	for _, v := range values {
+		single.Histogram("values", valueBuckets).RecordValue(v)
+	}
+	for _, d := range durations {
+		single.Histogram("durations", durationBuckets).RecordDuration(d)
+	}
+
+	want, got := single.Snapshot().Histograms(), batch.Snapshot().Histograms()
+	require.Len(t, got, 2)
+	for id, h := range want {
+		assert.Equal(t, h.Values(), got[id].Values(), id)
+		assert.Equal(t, h.Durations(), got[id].Durations(), id)
+		assert.Equal(t, h.Underflow(), got[id].Underflow(), id)
+		assert.Equal(t, h.Overflow(), got[id].Overflow(), id)
+	}
+
+	// Values of the other type are ignored like RecordValue.
+	RecordValues(batch.Histogram("durations", durationBuckets), values)
+	assert.Equal(t, want["durations+"].Durations(),
+		batch.Snapshot().Histograms()["durations+"].Durations())
+}
+
+func TestRecordValuesRateLimit(t *testing.T) {
+	s := newRootScope(ScopeOptions{
+		SeriesRateLimit:               0.001,
+		SeriesRateLimitBurst:          3,
+		SeriesRateLimitDroppedCounter: "dropped",
+	}, 0)
+	defer s.Close()
+
+	RecordValues(s.Histogram("h", ValueBuckets{1, 10}), []float64{1, 1, 5, 5, 5})
+
+	snap := s.Snapshot()
+	assert.Equal(t, map[float64]int64{1: 2, 10: 1, math.MaxFloat64: 0},
+		snap.Histograms()["h+"].Values())
+	assert.EqualValues(t, 2, snap.Counters()["dropped+"].Value())
+}
+
+func TestRecordValuesFallback(t *testing.T) {
+	s := NewTestScope("", nil)
+	h := s.Histogram("h", ValueBuckets{1})
+	RecordValues(fanoutHistogram{h, NewNoopScope().Histogram("noop", nil)}, []float64{0, 2})
+	RecordDurations(NewNoopScope().Histogram("noop", nil), []time.Duration{time.Second})
+
+	assert.Equal(t, map[float64]int64{1: 1, math.MaxFloat64: 1},
+		s.Snapshot().Histograms()["h+"].Values())
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"sort"
+	"time"
+)
+
+// BucketBoundary is the bucket a histogram records a value equal to the
+// boundary between two buckets into.
+type BucketBoundary int
+
+const (
+	// UpperInclusiveBoundary records a value into the bucket it is the upper
+	// bound of, matching the Prometheus "le" semantics, i.e. a bucket holds
+	// the values in (lower, upper]. This is the default.
+	UpperInclusiveBoundary BucketBoundary = iota
+	// LowerInclusiveBoundary records a value into the bucket it is the lower
+	// bound of, i.e. a bucket holds the values in [lower, upper). The
+	// maximum value, the upper bound of the last bucket, is still recorded
+	// into the last bucket.
+	LowerInclusiveBoundary
+)
+
+// valueBucket returns the index of the bucket to record value into.
+func (h *histogram) valueBucket(value float64) int {
+	// Find the highest inclusive of the bucket upper bound
+	// and emit directly to it. Since we use BucketPairs to derive
+	// buckets there will always be an inclusive bucket as
+	// we always have a math.MaxFloat64 bucket.
+	if h.boundary == LowerInclusiveBoundary {
+		return h.lastBucket(sort.Search(len(h.buckets), func(i int) bool {
+			return h.buckets[i].valueUpperBound > value
+		}))
+	}
+	return sort.Search(len(h.buckets), func(i int) bool {
+		return h.buckets[i].valueUpperBound >= value
+	})
+}
+
+// durationBucket returns the index of the bucket to record value into.
+func (h *histogram) durationBucket(value time.Duration) int {
+	// Same as valueBucket, there is always a math.MaxInt64 bucket.
+	if h.boundary == LowerInclusiveBoundary {
+		return h.lastBucket(sort.Search(len(h.buckets), func(i int) bool {
+			return h.buckets[i].durationUpperBound > value
+		}))
+	}
+	return sort.Search(len(h.buckets), func(i int) bool {
+		return h.buckets[i].durationUpperBound >= value
+	})
+}
+
+// lastBucket caps idx to the last bucket, for the maximum value which is not
+// below any upper bound.
+func (h *histogram) lastBucket(idx int) int {
+	if idx == len(h.buckets) {
+		return idx - 1
+	}
+	return idx
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramBucketBoundaryValues(t *testing.T) {
+	tests := []struct {
+		boundary BucketBoundary
+		want     map[float64]int64
+	}{
+		{
+			boundary: UpperInclusiveBoundary,
+			want:     map[float64]int64{1: 1, 2: 1, math.MaxFloat64: 1},
+		},
+		{
+			boundary: LowerInclusiveBoundary,
+			want:     map[float64]int64{1: 0, 2: 1, math.MaxFloat64: 2},
+		},
+	}
+	for _, tt := range tests {
+		s := NewTestScope("", nil)
+		h, err := HistogramWithOptions(s, "h", ValueBuckets{1, 2},
+			HistogramOptions{BucketBoundary: tt.boundary})
+		require.NoError(t, err)
+
+		h.RecordValue(1)
+		h.RecordValue(2)
+		h.RecordValue(math.MaxFloat64)
+
+		assert.Equal(t, tt.want, s.Snapshot().Histograms()["h+"].Values(), "boundary %v", tt.boundary)
+	}
+}
+
+func TestHistogramBucketBoundaryDurations(t *testing.T) {
+	tests := []struct {
+		boundary BucketBoundary
+		want     map[time.Duration]int64
+	}{
+		{
+			boundary: UpperInclusiveBoundary,
+			want: map[time.Duration]int64{
+				time.Second:                  1,
+				2 * time.Second:              1,
+				time.Duration(math.MaxInt64): 1,
+			},
+		},
+		{
+			boundary: LowerInclusiveBoundary,
+			want: map[time.Duration]int64{
+				time.Second:                  0,
+				2 * time.Second:              1,
+				time.Duration(math.MaxInt64): 2,
+			},
+		},
+	}
+	for _, tt := range tests {
+		s := NewTestScope("", nil)
+		h, err := HistogramWithOptions(s, "h", DurationBuckets{time.Second, 2 * time.Second},
+			HistogramOptions{BucketBoundary: tt.boundary})
+		require.NoError(t, err)
+
+		h.RecordDuration(time.Second)
+		h.RecordDuration(2 * time.Second)
+		h.RecordDuration(time.Duration(math.MaxInt64))
+
+		assert.Equal(t, tt.want, s.Snapshot().Histograms()["h+"].Durations(), "boundary %v", tt.boundary)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultClampedCounterSuffix is the default name suffix of the counter of
+// clamped observations of a histogram.
+const DefaultClampedCounterSuffix = "clamped"
+
+// ClampOptions is a set of options to clamp the values recorded by a
+// histogram.
+type ClampOptions struct {
+	// Min and Max are the range each recorded value is clamped to before
+	// bucketing, in seconds for duration histograms. A value outside the
+	// range is recorded as the nearest bound, so it still counts in the
+	// distribution and reservoir samples but its magnitude is lost.
+	Min float64
+	Max float64
+
+	// CounterNameSuffix is the name suffix of the counter, in the scope of
+	// the histogram, incremented for each clamped observation. If you do not
+	// set this value it will be set to DefaultClampedCounterSuffix.
+	CounterNameSuffix string
+}
+
+func (o ClampOptions) validate() error {
+	if !(o.Min <= o.Max) {
+		return fmt.Errorf("clamp min %v must not be greater than max %v", o.Min, o.Max)
+	}
+	return nil
+}
+
+type histogramClamp struct {
+	min         float64
+	max         float64
+	minDuration time.Duration
+	maxDuration time.Duration
+	clamped     Counter
+}
+
+func newHistogramClamp(opts ClampOptions, clamped Counter) *histogramClamp {
+	return &histogramClamp{
+		min:         opts.Min,
+		max:         opts.Max,
+		minDuration: time.Duration(opts.Min * float64(time.Second)),
+		maxDuration: time.Duration(opts.Max * float64(time.Second)),
+		clamped:     clamped,
+	}
+}
+
+func (c *histogramClamp) value(v float64) float64 {
+	switch {
+	case v < c.min:
+		c.clamped.Inc(1)
+		return c.min
+	case v > c.max:
+		c.clamped.Inc(1)
+		return c.max
+	default:
+		return v
+	}
+}
+
+func (c *histogramClamp) duration(d time.Duration) time.Duration {
+	switch {
+	case d < c.minDuration:
+		c.clamped.Inc(1)
+		return c.minDuration
+	case d > c.maxDuration:
+		c.clamped.Inc(1)
+		return c.maxDuration
+	default:
+		return d
+	}
+}
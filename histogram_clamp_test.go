@@ -0,0 +1,72 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramClampValues(t *testing.T) {
+	s := NewTestScope("", nil)
+	h, err := HistogramWithOptions(s, "size", ValueBuckets{10, 100},
+		HistogramOptions{Clamp: &ClampOptions{Min: 1, Max: 50}})
+	require.NoError(t, err)
+
+	h.RecordValue(0)
+	h.RecordValue(20)
+	h.RecordValue(1e12)
+
+	snap := s.Snapshot()
+	assert.Equal(t, map[float64]int64{10: 1, 100: 2, math.MaxFloat64: 0},
+		snap.Histograms()["size+"].Values())
+	assert.EqualValues(t, 2, snap.Counters()["size.clamped+"].Value())
+}
+
+func TestHistogramClampDurations(t *testing.T) {
+	s := NewTestScope("", nil)
+	h, err := HistogramWithOptions(s, "latency",
+		DurationBuckets{time.Second, 10 * time.Second},
+		HistogramOptions{Clamp: &ClampOptions{Max: 5, CounterNameSuffix: "outliers"}})
+	require.NoError(t, err)
+
+	h.RecordDuration(time.Hour)
+	h.RecordDuration(time.Millisecond)
+
+	snap := s.Snapshot()
+	assert.Equal(t, map[time.Duration]int64{
+		time.Second:                  1,
+		10 * time.Second:             1,
+		time.Duration(math.MaxInt64): 0,
+	}, snap.Histograms()["latency+"].Durations())
+	assert.EqualValues(t, 1, snap.Counters()["latency.outliers+"].Value())
+}
+
+func TestHistogramClampValidation(t *testing.T) {
+	s := NewTestScope("", nil)
+	_, err := HistogramWithOptions(s, "h", ValueBuckets{1},
+		HistogramOptions{Clamp: &ClampOptions{Min: 2, Max: 1}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errBucketsEmpty = errors.New("buckets need at least one bound")
+
+// ParseValueBuckets parses value buckets from a comma separated list of
+// numbers such as "1,5,10,50", e.g. loaded from configuration. Whitespace
+// around the numbers is ignored and the buckets are sorted. It fails for an
+// empty list, an element that is not a number and duplicate numbers.
+func ParseValueBuckets(s string) (ValueBuckets, error) {
+	elems, err := splitBuckets(s)
+	if err != nil {
+		return nil, err
+	}
+	buckets := make(ValueBuckets, 0, len(elems))
+	for _, elem := range elems {
+		v, err := strconv.ParseFloat(elem, 64)
+		if err != nil || math.IsNaN(v) {
+			return nil, fmt.Errorf("invalid value bucket %q", elem)
+		}
+		buckets = append(buckets, v)
+	}
+	sort.Float64s(buckets)
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] == buckets[i-1] {
+			return nil, fmt.Errorf("duplicate value bucket %v", buckets[i])
+		}
+	}
+	return buckets, nil
+}
+
+// ParseDurationBuckets parses duration buckets from a comma separated list
+// of durations in the format of time.ParseDuration such as
+// "10ms,50ms,100ms,1s", like ParseValueBuckets.
+func ParseDurationBuckets(s string) (DurationBuckets, error) {
+	elems, err := splitBuckets(s)
+	if err != nil {
+		return nil, err
+	}
+	buckets := make(DurationBuckets, 0, len(elems))
+	for _, elem := range elems {
+		d, err := time.ParseDuration(elem)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration bucket %q", elem)
+		}
+		buckets = append(buckets, d)
+	}
+	sort.Sort(buckets)
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] == buckets[i-1] {
+			return nil, fmt.Errorf("duplicate duration bucket %v", buckets[i])
+		}
+	}
+	return buckets, nil
+}
+
+func splitBuckets(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, errBucketsEmpty
+	}
+	elems := strings.Split(s, ",")
+	for i, elem := range elems {
+		elems[i] = strings.TrimSpace(elem)
+		if elems[i] == "" {
+			return nil, fmt.Errorf("empty bucket at position %d of %q", i, s)
+		}
+	}
+	return elems, nil
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseValueBuckets(t *testing.T) {
+	buckets, err := ParseValueBuckets("1,5,10,50")
+	require.NoError(t, err)
+	assert.Equal(t, ValueBuckets{1, 5, 10, 50}, buckets)
+
+	buckets, err = ParseValueBuckets(" 10 , -2.5,\t1e3 ")
+	require.NoError(t, err)
+	assert.Equal(t, ValueBuckets{-2.5, 10, 1000}, buckets)
+
+	// The buckets are usable directly in a histogram.
+	s := NewTestScope("", nil)
+	s.Histogram("sizes", buckets).RecordValue(5)
+	assert.EqualValues(t, 1, s.Snapshot().Histograms()["sizes+"].Values()[10])
+}
+
+func TestParseDurationBuckets(t *testing.T) {
+	buckets, err := ParseDurationBuckets("10ms,50ms,100ms,1s")
+	require.NoError(t, err)
+	assert.Equal(t, DurationBuckets{
+		10 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond, time.Second,
+	}, buckets)
+
+	buckets, err = ParseDurationBuckets(" 1m , 500us ,2s")
+	require.NoError(t, err)
+	assert.Equal(t, DurationBuckets{500 * time.Microsecond, 2 * time.Second, time.Minute}, buckets)
+}
+
+func TestParseBucketsErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"", "buckets need at least one bound"},
+		{"  ", "buckets need at least one bound"},
+		{"1,,2", `empty bucket at position 1 of "1,,2"`},
+		{"1,2,", `empty bucket at position 2 of "1,2,"`},
+		{"1,x", `invalid value bucket "x"`},
+		{"1,NaN", `invalid value bucket "NaN"`},
+		{"5,1,5", "duplicate value bucket 5"},
+	}
+	for _, tt := range tests {
+		_, err := ParseValueBuckets(tt.input)
+		assert.EqualError(t, err, tt.err, tt.input)
+	}
+
+	_, err := ParseDurationBuckets("10ms,10")
+	assert.EqualError(t, err, `invalid duration bucket "10"`)
+	_, err = ParseDurationBuckets("1s,1000ms")
+	assert.EqualError(t, err, "duplicate duration bucket 1s")
+	_, err = ParseDurationBuckets("")
+	assert.EqualError(t, err, "buckets need at least one bound")
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type histogramPercentiles struct {
+	percentiles []float64
+	suffixes    []string
+	cached      []CachedGauge
+}
+
+func validatePercentiles(percentiles []float64) error {
+	for _, p := range percentiles {
+		if !(p > 0 && p <= 100) {
+			return fmt.Errorf("percentile %v must be in the range (0, 100]", p)
+		}
+	}
+	return nil
+}
+
+func newHistogramPercentiles(
+	name string,
+	separator string,
+	tags map[string]string,
+	cachedReporter CachedStatsReporter,
+	percentiles []float64,
+) *histogramPercentiles {
+	hp := &histogramPercentiles{
+		percentiles: append([]float64(nil), percentiles...),
+		suffixes:    make([]string, 0, len(percentiles)),
+	}
+	for _, p := range percentiles {
+		suffix := percentileSuffix(separator, p)
+		hp.suffixes = append(hp.suffixes, suffix)
+		if cachedReporter != nil {
+			hp.cached = append(hp.cached, cachedReporter.AllocateGauge(name+suffix, tags))
+		}
+	}
+	return hp
+}
+
+// percentileSuffix returns the name suffix of the gauge of a percentile
+// joined by the separator, e.g. ".p99_9" for 99.9 and the default separator.
+func percentileSuffix(separator string, p float64) string {
+	return separator + "p" + strings.Replace(strconv.FormatFloat(p, 'f', -1, 64), ".", "_", -1)
+}
+
+func (hp *histogramPercentiles) report(
+	name string,
+	tags map[string]string,
+	h *histogram,
+	counts []int64,
+	r StatsReporter,
+) {
+	total := sumCounts(counts)
+	if total == 0 {
+		return
+	}
+	for i, p := range hp.percentiles {
+		r.ReportGauge(name+hp.suffixes[i], tags, h.percentile(counts, total, p))
+	}
+}
+
+func (hp *histogramPercentiles) cachedReport(h *histogram, counts []int64) {
+	total := sumCounts(counts)
+	if total == 0 {
+		return
+	}
+	for i, p := range hp.percentiles {
+		hp.cached[i].ReportGauge(h.percentile(counts, total, p))
+	}
+}
+
+func sumCounts(counts []int64) int64 {
+	var total int64
+	for _, c := range counts {
+		total += c
+	}
+	return total
+}
+
+// percentile estimates the percentile of the given bucket counts as the upper
+// bound of the bucket it falls into, or the lower bound for the last bucket
+// that has no finite upper bound.
+func (h *histogram) percentile(counts []int64, total int64, p float64) float64 {
+	rank := int64(math.Ceil(p / 100 * float64(total)))
+
+	var (
+		cumulative int64
+		idx        int
+	)
+	for idx = range counts {
+		cumulative += counts[idx]
+		if cumulative >= rank {
+			break
+		}
+	}
+
+	switch h.htype {
+	case durationHistogramType:
+		d := h.buckets[idx].durationUpperBound
+		if d == time.Duration(math.MaxInt64) {
+			d = durationLowerBound(h.buckets, idx)
+		}
+		return float64(d) / float64(time.Second)
+	default:
+		v := h.buckets[idx].valueUpperBound
+		if v == math.MaxFloat64 {
+			v = valueLowerBound(h.buckets, idx)
+		}
+		return v
+	}
+}
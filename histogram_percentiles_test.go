@@ -0,0 +1,112 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramWithOptionsPercentiles(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{Reporter: r}, 0)
+	defer closer.Close()
+
+	s := root.(*scope)
+	latency, err := HistogramWithOptions(s, "latency",
+		DurationBuckets{10 * time.Millisecond, 100 * time.Millisecond, time.Second},
+		HistogramOptions{Percentiles: []float64{99.9}})
+	require.NoError(t, err)
+	size, err := HistogramWithOptions(s, "size",
+		ValueBuckets{1, 10, 100},
+		HistogramOptions{Percentiles: []float64{50, 90}})
+	require.NoError(t, err)
+
+	for i := 0; i < 9; i++ {
+		latency.RecordDuration(5 * time.Millisecond)
+		size.RecordValue(5)
+	}
+	latency.RecordDuration(500 * time.Millisecond)
+	size.RecordValue(50)
+
+	r.hg.Add(4)
+	r.gg.Add(3)
+	s.report(r)
+	r.WaitAll()
+
+	gauges := r.getGauges()
+	assert.Len(t, gauges, 3)
+	assert.Equal(t, 1.0, gauges["latency.p99_9"].val)
+	assert.Equal(t, 10.0, gauges["size.p50"].val)
+	assert.Equal(t, 10.0, gauges["size.p90"].val)
+}
+
+func TestHistogramWithOptionsPercentilesSeparator(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{Separator: "_", Reporter: r}, 0)
+	defer closer.Close()
+
+	s := root.(*scope)
+	h, err := HistogramWithOptions(s, "size", ValueBuckets{1, 10},
+		HistogramOptions{Percentiles: []float64{99.9}})
+	require.NoError(t, err)
+	h.RecordValue(5)
+
+	r.hg.Add(1)
+	r.gg.Add(1)
+	s.report(r)
+	r.WaitAll()
+
+	assert.Equal(t, 10.0, r.getGauges()["size_p99_9"].val)
+}
+
+func TestHistogramWithOptionsPercentilesCachedReport(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{CachedReporter: r}, 0)
+	defer closer.Close()
+
+	s := root.(*scope)
+	h, err := HistogramWithOptions(s, "size", ValueBuckets{1, 10},
+		HistogramOptions{Percentiles: []float64{50}})
+	require.NoError(t, err)
+
+	// Values above the highest bucket are estimated by its lower bound.
+	h.RecordValue(50)
+
+	r.hg.Add(1)
+	r.gg.Add(1)
+	s.cachedReport()
+	r.WaitAll()
+
+	assert.Equal(t, 10.0, r.getGauges()["size.p50"].val)
+}
+
+func TestHistogramWithOptionsInvalidPercentiles(t *testing.T) {
+	s := NewTestScope("", nil)
+	for _, p := range []float64{0, -1, 100.1} {
+		_, err := HistogramWithOptions(s, "size", nil,
+			HistogramOptions{Percentiles: []float64{p}})
+		assert.Error(t, err, "percentile %v", p)
+	}
+}
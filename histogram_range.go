@@ -0,0 +1,58 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import "time"
+
+// countValueOutOfRange counts value as an underflow if it is below the
+// lowest finite bucket bound or as an overflow if it is above the highest,
+// for histograms with finite bucket bounds.
+func (h *histogram) countValueOutOfRange(value float64) {
+	n := len(h.buckets)
+	if n < 2 {
+		return
+	}
+	if value < h.buckets[0].valueUpperBound {
+		h.underflow.Inc(1)
+	} else if value > h.buckets[n-2].valueUpperBound {
+		h.overflow.Inc(1)
+	}
+}
+
+// countDurationOutOfRange is countValueOutOfRange for a durationHistogram.
+func (h *histogram) countDurationOutOfRange(value time.Duration) {
+	n := len(h.buckets)
+	if n < 2 {
+		return
+	}
+	if value < h.buckets[0].durationUpperBound {
+		h.underflow.Inc(1)
+	} else if value > h.buckets[n-2].durationUpperBound {
+		h.overflow.Inc(1)
+	}
+}
+
+// resetOutOfRange starts counting the underflows and overflows of the next
+// report.
+func (h *histogram) resetOutOfRange() {
+	h.underflow.value()
+	h.overflow.value()
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramUnderflowOverflow(t *testing.T) {
+	s := NewTestScope("", nil)
+	h := s.Histogram("sizes", ValueBuckets{1, 10, 100})
+	for _, v := range []float64{-5, 0.5, 1, 50, 100, 101, 1e6} {
+		h.RecordValue(v)
+	}
+
+	snap := s.Snapshot().Histograms()["sizes+"]
+	assert.EqualValues(t, 2, snap.Underflow())
+	assert.EqualValues(t, 2, snap.Overflow())
+	// The samples are still counted in the edge buckets.
+	assert.EqualValues(t, 3, snap.Values()[1])
+	assert.EqualValues(t, 2, snap.Values()[math.MaxFloat64])
+}
+
+func TestHistogramDurationUnderflowOverflow(t *testing.T) {
+	s := NewTestScope("", nil)
+	h := s.Histogram("latency", DurationBuckets{time.Millisecond, time.Second})
+	h.RecordDuration(time.Microsecond)
+	h.RecordDuration(time.Millisecond)
+	h.RecordDuration(time.Second)
+	h.RecordDuration(time.Minute)
+	h.RecordDuration(time.Hour)
+
+	snap := s.Snapshot().Histograms()["latency+"]
+	assert.EqualValues(t, 1, snap.Underflow())
+	assert.EqualValues(t, 2, snap.Overflow())
+}
+
+func TestHistogramUnderflowOverflowReset(t *testing.T) {
+	s := NewTestScope("", nil).(*scope)
+	h := s.Histogram("sizes", ValueBuckets{1, 10})
+	h.RecordValue(0)
+	h.RecordValue(20)
+
+	// The counts are of the samples since the last report.
+	s.report(NullStatsReporter)
+	snap := s.Snapshot().Histograms()["sizes+"]
+	assert.EqualValues(t, 0, snap.Underflow())
+	assert.EqualValues(t, 0, snap.Overflow())
+
+	h.RecordValue(20)
+	snap = s.Snapshot().Histograms()["sizes+"]
+	assert.EqualValues(t, 0, snap.Underflow())
+	assert.EqualValues(t, 1, snap.Overflow())
+}
+
+func TestHistogramUnderflowOverflowBeforeClamp(t *testing.T) {
+	s := NewTestScope("", nil)
+	h, err := HistogramWithOptions(s, "sizes", ValueBuckets{1, 10},
+		HistogramOptions{Clamp: &ClampOptions{Min: 1, Max: 10}})
+	require.NoError(t, err)
+	h.RecordValue(0)
+	h.RecordValue(50)
+
+	snap := s.Snapshot().Histograms()["sizes+"]
+	assert.EqualValues(t, 1, snap.Underflow())
+	assert.EqualValues(t, 1, snap.Overflow())
+	assert.EqualValues(t, 0, snap.Values()[math.MaxFloat64])
+}
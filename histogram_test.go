@@ -114,6 +114,58 @@ func TestMustMakeLinearDurationBucketsPanicsOnBadCount(t *testing.T) {
 	})
 }
 
+func TestLinearValueBucketsNegative(t *testing.T) {
+	result, err := LinearValueBuckets(-100, 50, 5)
+	require.NoError(t, err)
+	assert.Equal(t, ValueBuckets{-100, -50, 0, 50, 100}, result)
+
+	// A negative width counts down from start.
+	result, err = LinearValueBuckets(100, -50, 5)
+	require.NoError(t, err)
+	assert.Equal(t, ValueBuckets{-100, -50, 0, 50, 100}, result)
+
+	result, err = LinearValueBuckets(-3, -1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, ValueBuckets{-5, -4, -3}, result)
+}
+
+func TestLinearDurationBucketsNegative(t *testing.T) {
+	result, err := LinearDurationBuckets(time.Second, -time.Second, 3)
+	require.NoError(t, err)
+	assert.Equal(t, DurationBuckets{-time.Second, 0, time.Second}, result)
+}
+
+func TestLinearBucketsZeroWidth(t *testing.T) {
+	_, err := LinearValueBuckets(1, 0, 2)
+	assert.Equal(t, errBucketsWidthNeedsNonZero, err)
+	_, err = LinearDurationBuckets(time.Second, 0, 2)
+	assert.Equal(t, errBucketsWidthNeedsNonZero, err)
+
+	// The width of a single bucket does not matter.
+	result, err := LinearValueBuckets(1, 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, ValueBuckets{1}, result)
+}
+
+func TestMustMakeSymmetricValueBuckets(t *testing.T) {
+	assert.Equal(t, ValueBuckets{-100, -50, 0, 50, 100}, MustMakeSymmetricValueBuckets(100, 2))
+	assert.Equal(t, ValueBuckets{-1, 0, 1}, MustMakeSymmetricValueBuckets(1, 1))
+
+	buckets := MustMakeSymmetricValueBuckets(1, 3)
+	require.Len(t, buckets, 7)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, -buckets[i], buckets[6-i])
+	}
+	assert.Equal(t, float64(0), buckets[3])
+
+	assert.Panics(t, func() {
+		MustMakeSymmetricValueBuckets(100, 0)
+	})
+	assert.Panics(t, func() {
+		MustMakeSymmetricValueBuckets(0, 2)
+	})
+}
+
 func TestMustMakeExponentialValueBuckets(t *testing.T) {
 	assert.NotPanics(t, func() {
 		assert.Equal(t, ValueBuckets{
@@ -166,6 +218,50 @@ func TestMustMakeExponentialDurationBucketsPanicsOnBadFactor(t *testing.T) {
 	})
 }
 
+func TestMergeDurationBucketsAroundThresholds(t *testing.T) {
+	// Dense near the 200ms and 1s SLO thresholds, sparse in between.
+	buckets, err := MergeDurationBuckets(
+		MustMakeLinearDurationBuckets(180*time.Millisecond, 10*time.Millisecond, 5),
+		MustMakeLinearDurationBuckets(900*time.Millisecond, 50*time.Millisecond, 5),
+		MustMakeExponentialDurationBuckets(100*time.Millisecond, 2, 4),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, DurationBuckets{
+		100 * time.Millisecond,
+		180 * time.Millisecond,
+		190 * time.Millisecond,
+		200 * time.Millisecond,
+		210 * time.Millisecond,
+		220 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		900 * time.Millisecond,
+		950 * time.Millisecond,
+		1000 * time.Millisecond,
+		1050 * time.Millisecond,
+		1100 * time.Millisecond,
+	}, buckets)
+}
+
+func TestMergeValueBuckets(t *testing.T) {
+	assert.Equal(t, ValueBuckets{1, 2, 3, 5, 10},
+		MustMakeMergedValueBuckets(ValueBuckets{1, 2, 3}, ValueBuckets{2, 5, 10}))
+}
+
+func TestMergeBucketsInvalid(t *testing.T) {
+	_, err := MergeValueBuckets(ValueBuckets{1, 3}, ValueBuckets{5, 4})
+	assert.Error(t, err)
+	_, err = MergeValueBuckets(ValueBuckets{1, math.NaN()})
+	assert.Error(t, err)
+	_, err = MergeValueBuckets()
+	assert.Error(t, err)
+	_, err = MergeDurationBuckets(DurationBuckets{time.Second, time.Second})
+	assert.Error(t, err)
+	assert.Panics(t, func() {
+		MustMakeMergedDurationBuckets()
+	})
+}
+
 func TestBucketPairsNoRaceWhenSorted(t *testing.T) {
 	buckets := DurationBuckets{}
 	for i := 0; i < 99; i++ {
@@ -213,3 +309,45 @@ func BenchmarkBucketsEqual(b *testing.B) {
 		bench(b, buckets, buckets)
 	})
 }
+
+func TestMergeBuckets(t *testing.T) {
+	merged, err := MergeBuckets(ValueBuckets{5, 1, 10}, ValueBuckets{10, 2, 1})
+	require.NoError(t, err)
+	assert.Equal(t, ValueBuckets{1, 2, 5, 10}, merged)
+
+	merged, err = MergeBuckets(
+		DurationBuckets{time.Second, time.Millisecond},
+		DurationBuckets{time.Minute, time.Second},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, DurationBuckets{time.Millisecond, time.Second, time.Minute}, merged)
+
+	// Nil and empty sets merge as no buckets, whatever their type.
+	merged, err = MergeBuckets(nil, ValueBuckets{2, 1})
+	require.NoError(t, err)
+	assert.Equal(t, ValueBuckets{1, 2}, merged)
+	merged, err = MergeBuckets(DurationBuckets{}, ValueBuckets{1})
+	require.NoError(t, err)
+	assert.Equal(t, ValueBuckets{1}, merged)
+
+	_, err = MergeBuckets(ValueBuckets{1}, DurationBuckets{time.Second})
+	assert.Equal(t, errBucketsMixedTypes, err)
+	_, err = MergeBuckets(nil, ValueBuckets{})
+	assert.Equal(t, errBucketsCountNeedsGreaterThanZero, err)
+	assert.Panics(t, func() {
+		MustMakeMergedBuckets(ValueBuckets{1}, DurationBuckets{time.Second})
+	})
+}
+
+func TestMergeBucketsHistogram(t *testing.T) {
+	s := NewTestScope("", nil)
+	buckets := MustMakeMergedBuckets(ValueBuckets{10, 20}, ValueBuckets{15, 10})
+	h := s.Histogram("size", buckets)
+	h.RecordValue(12)
+	h.RecordValue(20)
+
+	snap := s.Snapshot().Histograms()["size+"]
+	assert.Equal(t, map[float64]int64{
+		10: 0, 15: 1, 20: 1, math.MaxFloat64: 0,
+	}, snap.Values())
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import "time"
+
+// DefaultSampledHistogramSuffix is the default name suffix of the sibling
+// histogram of the observations of sampled traces.
+const DefaultSampledHistogramSuffix = "sampled"
+
+// RecordValueTraced records a value to the histogram and, if the value was
+// observed in a sampled trace, to its sibling histogram created with
+// HistogramOptions.TraceSampled. Histograms without a sibling only record
+// the value.
+func RecordValueTraced(h Histogram, value float64, sampled bool) {
+	h.RecordValue(value)
+	if sampled {
+		if s, ok := sampledHistogram(h); ok {
+			s.RecordValue(value)
+		}
+	}
+}
+
+// RecordDurationTraced is RecordValueTraced for duration histograms.
+func RecordDurationTraced(h Histogram, value time.Duration, sampled bool) {
+	h.RecordDuration(value)
+	if sampled {
+		if s, ok := sampledHistogram(h); ok {
+			s.RecordDuration(value)
+		}
+	}
+}
+
+func sampledHistogram(h Histogram) (Histogram, bool) {
+	th, ok := h.(*histogram)
+	if !ok {
+		return nil, false
+	}
+	s, ok := th.sampled.Load().(Histogram)
+	return s, ok
+}
+
+func (s *scope) createSampledHistogram(
+	name string,
+	b Buckets,
+	opts HistogramOptions,
+	h *histogram,
+) {
+	suffix := opts.SampledNameSuffix
+	if suffix == "" {
+		suffix = DefaultSampledHistogramSuffix
+	}
+	opts.TraceSampled = false
+	sampled := s.histogramWithOptions(name+s.separator+suffix, b, opts)
+	pinHistogram(sampled)
+	h.sampled.Store(sampled)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordValueTraced(t *testing.T) {
+	s := NewTestScope("", nil)
+	buckets := MustMakeLinearValueBuckets(0, 10, 3)
+	h, err := HistogramWithOptions(s, "latency", buckets, HistogramOptions{
+		TraceSampled: true,
+	})
+	require.NoError(t, err)
+
+	RecordValueTraced(h, 5, true)
+	RecordValueTraced(h, 15, false)
+	RecordValueTraced(h, 15, true)
+	RecordValueTraced(h, 25, false)
+
+	histograms := s.Snapshot().Histograms()
+	require.Contains(t, histograms, "latency+")
+	require.Contains(t, histograms, "latency.sampled+")
+	assert.Equal(t, map[float64]int64{0: 0, 10: 1, 20: 2, math.MaxFloat64: 1},
+		histograms["latency+"].Values())
+	assert.Equal(t, map[float64]int64{0: 0, 10: 1, 20: 1, math.MaxFloat64: 0},
+		histograms["latency.sampled+"].Values())
+}
+
+func TestRecordDurationTracedSuffix(t *testing.T) {
+	s := NewTestScope("", nil)
+	buckets := MustMakeLinearDurationBuckets(0, time.Second, 2)
+	h, err := HistogramWithOptions(s, "latency", buckets, HistogramOptions{
+		TraceSampled:      true,
+		SampledNameSuffix: "traced",
+	})
+	require.NoError(t, err)
+
+	RecordDurationTraced(h, 500*time.Millisecond, true)
+	RecordDurationTraced(h, 500*time.Millisecond, false)
+
+	histograms := s.Snapshot().Histograms()
+	require.Contains(t, histograms, "latency.traced+")
+	assert.Equal(t, int64(2), histograms["latency+"].Durations()[time.Second])
+	assert.Equal(t, int64(1), histograms["latency.traced+"].Durations()[time.Second])
+}
+
+func TestRecordValueTracedWithoutSibling(t *testing.T) {
+	s := NewTestScope("", nil)
+	h := s.Histogram("latency", MustMakeLinearValueBuckets(0, 10, 2))
+
+	RecordValueTraced(h, 5, true)
+
+	histograms := s.Snapshot().Histograms()
+	assert.Len(t, histograms, 1)
+	assert.Equal(t, int64(1), histograms["latency+"].Values()[10])
+}
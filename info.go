@@ -0,0 +1,94 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+// Info registers an info metric on the scope, a gauge with the given name and
+// labels as tags whose value is always 1 and that is reported every interval,
+// e.g. kube_pod_info. Info metrics carry no value of their own and are used
+// to attach their labels to other series by joining on the shared tags in
+// queries.
+//
+// Calling Info again with the same name and labels has no effect, while
+// calling it with different labels creates an additional series rather than
+// replacing the previous one. On Scope implementations other than those
+// created by NewRootScope the gauge is only updated once.
+func Info(s Scope, name string, labels map[string]string) {
+	if r, ok := s.(infoRegisterer); ok {
+		r.registerInfo(name, labels)
+		return
+	}
+	s.Tagged(labels).Gauge(name).Update(1)
+}
+
+type infoRegisterer interface {
+	registerInfo(name string, labels map[string]string)
+}
+
+type info struct {
+	name   string
+	gauge  *gauge
+	panics Counter
+}
+
+func (s *scope) registerInfo(name string, labels map[string]string) {
+	sub, ok := s.Tagged(labels).(*scope)
+	if !ok {
+		// NB: Labels rejected by SanitizeReject, already reported to OnError.
+		return
+	}
+	g, ok := sub.Gauge(name).(*gauge)
+	if !ok {
+		return
+	}
+	g.idle.pin()
+
+	i := info{name: sub.fullyQualifiedName(sub.sanitizer.Name(name)), gauge: g}
+	// NB: The counter is created before taking the gauges lock, which must
+	// not be held while creating counters.
+	if sub.recoverCallbackPanics {
+		i.panics = callbackPanics(sub.registry.root, sub.callbackPanicsCounter, i.name)
+	}
+
+	sub.gm.Lock()
+	defer sub.gm.Unlock()
+
+	for _, existing := range sub.infos {
+		if existing.gauge == g {
+			return
+		}
+	}
+	sub.infos = append(sub.infos, i)
+}
+
+// updateInfos updates all info gauges to 1 so they are reported every
+// interval, must be called with the gauges lock held.
+func (s *scope) updateInfos() {
+	for _, i := range s.infos {
+		s.updateInfo(i)
+	}
+}
+
+func (s *scope) updateInfo(i info) {
+	if s.recoverCallbackPanics {
+		defer s.registry.recoverCallback("info", i.name, i.panics)
+	}
+	i.gauge.Update(1)
+}
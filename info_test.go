@@ -0,0 +1,53 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfo(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{Reporter: r}, 0)
+
+	s := root.(*scope)
+	Info(s, "pod_info", map[string]string{"pod": "a", "node": "n1"})
+	Info(s, "pod_info", map[string]string{"pod": "a", "node": "n1"})
+	Info(s, "pod_info", map[string]string{"pod": "b", "node": "n2"})
+
+	// Both series are reported with a value of 1 on every report.
+	for i := 0; i < 2; i++ {
+		r.gg.Add(2)
+		s.reportRegistry()
+		r.WaitAll()
+	}
+
+	gauges := s.Snapshot().Gauges()
+	assert.Len(t, gauges, 2)
+	assert.Equal(t, float64(1), gauges["pod_info+node=n1,pod=a"].Value())
+	assert.Equal(t, float64(1), gauges["pod_info+node=n2,pod=b"].Value())
+
+	r.gg.Add(2)
+	assert.NoError(t, closer.Close())
+	r.WaitAll()
+}
@@ -62,6 +62,20 @@ func KeyForPrefixedStringMap(
 //
 // If a key occurs in multiple maps, keys on the right take precedence.
 func keyForPrefixedStringMaps(prefix string, maps ...map[string]string) string {
+	buf := keyGenPool.bufferPool.Get().(*bytes.Buffer)
+	writeKeyForPrefixedStringMaps(buf, prefix, maps...)
+	key := buf.String()
+	keyGenPool.releaseBuffer(buf)
+	return key
+}
+
+// writeKeyForPrefixedStringMaps writes the key generated by
+// keyForPrefixedStringMaps to buf without allocating the key string.
+func writeKeyForPrefixedStringMaps(
+	buf *bytes.Buffer,
+	prefix string,
+	maps ...map[string]string,
+) {
 	keys := keyGenPool.stringsPool.Get().([]string)
 	for _, m := range maps {
 		for k := range m {
@@ -70,8 +84,6 @@ func keyForPrefixedStringMaps(prefix string, maps ...map[string]string) string {
 	}
 	sort.Strings(keys)
 
-	buf := keyGenPool.bufferPool.Get().(*bytes.Buffer)
-
 	if prefix != nilString {
 		buf.WriteString(prefix)
 		buf.WriteByte(prefixSplitter)
@@ -101,9 +113,7 @@ func keyForPrefixedStringMaps(prefix string, maps ...map[string]string) string {
 		}
 	}
 
-	key := buf.String()
-	keyGenPool.release(buf, keys)
-	return key
+	keyGenPool.releaseStrings(keys)
 }
 
 func newKeyGenerationPool(size, blen, slen int) *keyGenerationPool {
@@ -123,10 +133,12 @@ func newKeyGenerationPool(size, blen, slen int) *keyGenerationPool {
 	}
 }
 
-func (s *keyGenerationPool) release(b *bytes.Buffer, strs []string) {
+func (s *keyGenerationPool) releaseBuffer(b *bytes.Buffer) {
 	b.Reset()
 	s.bufferPool.Put(b)
+}
 
+func (s *keyGenerationPool) releaseStrings(strs []string) {
 	for i := range strs {
 		strs[i] = nilString
 	}
@@ -49,6 +49,15 @@ type Configuration struct {
 	// HistogramBucketTagPrecision is precision to use when formatting the metric tag
 	// with the histogram bucket bound values.
 	HistogramBucketTagPrecision uint `yaml:"histogramBucketTagPrecision"`
+
+	// QueueDepthGaugeName is the name of the gauge to emit the queue depth
+	// of the client with on each flush, not emitted if empty.
+	QueueDepthGaugeName string `yaml:"queueDepthGaugeName"`
+
+	// QueueDepthHighWaterMarkGaugeName is the name of the gauge to emit the
+	// highest queue depth of the client since the last flush with, not
+	// emitted if empty.
+	QueueDepthHighWaterMarkGaugeName string `yaml:"queueDepthHighWaterMarkGaugeName"`
 }
 
 // NewReporter creates a new M3 reporter from this configuration.
@@ -66,5 +75,8 @@ func (c Configuration) NewReporter() (Reporter, error) {
 		MaxPacketSizeBytes:          c.PacketSize,
 		IncludeHost:                 c.IncludeHost,
 		HistogramBucketTagPrecision: c.HistogramBucketTagPrecision,
+
+		QueueDepthGaugeName:              c.QueueDepthGaugeName,
+		QueueDepthHighWaterMarkGaugeName: c.QueueDepthHighWaterMarkGaugeName,
 	})
 }
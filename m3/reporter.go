@@ -132,6 +132,10 @@ type reporter struct {
 	numMetricsCounter     tally.CachedCount
 	numWriteErrors        atomic.Int64
 	numWriteErrorsCounter tally.CachedCount
+
+	queueDepthGauge              tally.CachedGauge
+	queueDepthHighWaterMark      atomic.Int64
+	queueDepthHighWaterMarkGauge tally.CachedGauge
 }
 
 // Options is a set of options for the M3 reporter.
@@ -147,10 +151,40 @@ type Options struct {
 	HistogramBucketIDName       string
 	HistogramBucketName         string
 	HistogramBucketTagPrecision uint
+
+	// QueueDepthGaugeName if set enables emitting the queue depth of the
+	// reporter on each flush as a gauge with this name, e.g.
+	// "tally.reporter.queue_depth".
+	QueueDepthGaugeName string
+	// QueueDepthHighWaterMarkGaugeName if set enables emitting the highest
+	// queue depth of the reporter since the last flush on each flush as a
+	// gauge with this name, e.g. "tally.reporter.queue_depth_high_water_mark".
+	QueueDepthHighWaterMarkGaugeName string
 }
 
 // NewReporter creates a new M3 reporter.
 func NewReporter(opts Options) (Reporter, error) {
+	r, err := newReporter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.process()
+	}()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.timeLoop()
+	}()
+
+	return r, nil
+}
+
+func newReporter(opts Options) (*reporter, error) {
 	if opts.MaxQueueSize <= 0 {
 		opts.MaxQueueSize = DefaultMaxQueueSize
 	}
@@ -292,18 +326,14 @@ func NewReporter(opts Options) (Reporter, error) {
 	r.numBatchesCounter = r.AllocateCounter("tally.internal.num-batches", internalTags)
 	r.numMetricsCounter = r.AllocateCounter("tally.internal.num-metrics", internalTags)
 	r.numWriteErrorsCounter = r.AllocateCounter("tally.internal.num-write-errors", internalTags)
-
-	r.wg.Add(1)
-	go func() {
-		defer r.wg.Done()
-		r.process()
-	}()
-
-	r.wg.Add(1)
-	go func() {
-		defer r.wg.Done()
-		r.timeLoop()
-	}()
+	if opts.QueueDepthGaugeName != "" {
+		r.queueDepthGauge = r.AllocateGauge(opts.QueueDepthGaugeName, internalTags)
+	}
+	if opts.QueueDepthHighWaterMarkGaugeName != "" {
+		r.queueDepthHighWaterMarkGauge = r.AllocateGauge(
+			opts.QueueDepthHighWaterMarkGaugeName, internalTags,
+		)
+	}
 
 	return r, nil
 }
@@ -520,21 +550,39 @@ func (r *reporter) reportCopyMetric(
 
 	select {
 	case r.metCh <- sm:
+		if r.queueDepthHighWaterMarkGauge != nil {
+			r.updateQueueDepthHighWaterMark(len(r.metCh))
+		}
 	case <-r.donech:
 	}
 }
 
 // Flush sends an empty sizedMetric to signal a flush.
 func (r *reporter) Flush() {
+	r.flushWithWriteErrors()
+}
+
+// FlushWithError implements tally.FlushErrorReporter. Batches are emitted
+// asynchronously, so the error is that of the batches which failed to be
+// emitted since the previous flush rather than those of this flush.
+func (r *reporter) FlushWithError() error {
+	if writeErrors := r.flushWithWriteErrors(); writeErrors > 0 {
+		return fmt.Errorf("failed to emit %d metric batches since the last flush", writeErrors)
+	}
+	return nil
+}
+
+func (r *reporter) flushWithWriteErrors() int64 {
 	r.pending.Inc()
 	defer r.pending.Dec()
 
 	if r.done.Load() {
-		return
+		return 0
 	}
 
-	r.reportInternalMetrics()
+	writeErrors := r.reportInternalMetrics()
 	r.metCh <- sizedMetric{}
+	return writeErrors
 }
 
 // Close waits for metrics to be flushed before closing the backend.
@@ -555,6 +603,30 @@ func (r *reporter) Close() (err error) {
 	return nil
 }
 
+// QueueDepth implements tally.QueueDepthReporter.
+func (r *reporter) QueueDepth() int {
+	return len(r.metCh)
+}
+
+func (r *reporter) updateQueueDepthHighWaterMark(depth int) {
+	for {
+		hwm := r.queueDepthHighWaterMark.Load()
+		if int64(depth) <= hwm || r.queueDepthHighWaterMark.CAS(hwm, int64(depth)) {
+			return
+		}
+	}
+}
+
+// resetQueueDepthHighWaterMark resets the high water mark to the current
+// depth for the next flush and returns the high water mark since the last.
+func (r *reporter) resetQueueDepthHighWaterMark(depth int) int64 {
+	hwm := r.queueDepthHighWaterMark.Swap(int64(depth))
+	if int64(depth) > hwm {
+		return int64(depth)
+	}
+	return hwm
+}
+
 func (r *reporter) Capabilities() tally.Capabilities {
 	return r
 }
@@ -667,8 +739,12 @@ func (r *reporter) convertTags(tags map[string]string) []m3thrift.MetricTag {
 	return mtags
 }
 
-func (r *reporter) reportInternalMetrics() {
+// reportInternalMetrics reports the internal metrics since the last call and
+// returns the number of write errors since then.
+func (r *reporter) reportInternalMetrics() int64 {
 	var (
+		depth       = r.QueueDepth()
+		hwm         = r.resetQueueDepthHighWaterMark(depth)
 		batches     = r.numBatches.Swap(0)
 		metrics     = r.numMetrics.Swap(0)
 		writeErrors = r.numWriteErrors.Swap(0)
@@ -690,6 +766,14 @@ func (r *reporter) reportInternalMetrics() {
 	r.numBatchesCounter.ReportCount(batches)
 	r.numMetricsCounter.ReportCount(metrics)
 	r.numWriteErrorsCounter.ReportCount(writeErrors)
+
+	if r.queueDepthGauge != nil {
+		r.queueDepthGauge.ReportGauge(float64(depth))
+	}
+	if r.queueDepthHighWaterMarkGauge != nil {
+		r.queueDepthHighWaterMarkGauge.ReportGauge(float64(hwm))
+	}
+	return writeErrors
 }
 
 func (r *reporter) timeLoop() {
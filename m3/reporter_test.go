@@ -556,6 +556,69 @@ func TestReporterResetTagsAfterReturnToPool(t *testing.T) {
 	require.Equal(t, 0, len(filtered[1].GetTags()))
 }
 
+func TestReporterQueueDepth(t *testing.T) {
+	var wg sync.WaitGroup
+	server := newFakeM3Server(t, &wg, false, Compact)
+	go server.Serve()
+	defer server.Close()
+
+	// Not starting the reporter so that nothing drains its queue.
+	r, err := newReporter(Options{
+		HostPorts:                        []string{server.Addr},
+		Service:                          "test-service",
+		CommonTags:                       defaultCommonTags,
+		MaxQueueSize:                     queueSize,
+		MaxPacketSizeBytes:               maxPacketSize,
+		QueueDepthGaugeName:              "tally.reporter.queue_depth",
+		QueueDepthHighWaterMarkGaugeName: "tally.reporter.queue_depth_hwm",
+	})
+	require.NoError(t, err)
+
+	c := r.AllocateCounter("my-counter", nil)
+	for i := 0; i < 5; i++ {
+		c.ReportCount(1)
+	}
+	require.Equal(t, 5, r.QueueDepth())
+
+	for i := 0; i < 3; i++ {
+		<-r.metCh
+	}
+	r.reportInternalMetrics()
+
+	gauges := make(map[string]float64)
+	for len(r.metCh) > 0 {
+		m := (<-r.metCh).m
+		if m.Value.MetricType == m3thrift.MetricType_GAUGE {
+			gauges[m.Name] = m.Value.Gauge
+		}
+	}
+	assert.Equal(t, map[string]float64{
+		"tally.reporter.queue_depth":     2,
+		"tally.reporter.queue_depth_hwm": 5,
+	}, gauges)
+}
+
+func TestReporterFlushWithError(t *testing.T) {
+	// Not starting the reporter so that nothing drains its queue.
+	r, err := newReporter(Options{
+		HostPorts:          []string{"127.0.0.1:9052"},
+		Service:            "test-service",
+		CommonTags:         defaultCommonTags,
+		MaxQueueSize:       queueSize,
+		MaxPacketSizeBytes: maxPacketSize,
+	})
+	require.NoError(t, err)
+
+	var _ tally.FlushErrorReporter = r
+	assert.NoError(t, r.FlushWithError())
+
+	r.numWriteErrors.Add(2)
+	err = r.FlushWithError()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to emit 2 metric batches")
+	assert.NoError(t, r.FlushWithError())
+}
+
 func TestReporterHasReportingAndTaggingCapability(t *testing.T) {
 	r, err := NewReporter(Options{
 		HostPorts:  []string{"127.0.0.1:9052"},
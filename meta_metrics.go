@@ -0,0 +1,134 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+// MetaMetric is one of the metrics the scope emits about itself.
+type MetaMetric int
+
+// The meta metrics of a scope, each enabled individually by the ScopeOptions
+// field of the same name or for all by MetaMetricsOptions.
+const (
+	NameLengthExceededMetaMetric MetaMetric = iota
+	SeriesRateLimitDroppedMetaMetric
+	TimerSnapshotDroppedMetaMetric
+	NameOverflowMetaMetric
+	TimeToFirstReportMetaMetric
+	ReportLoopHeartbeatMetaMetric
+	ProcessStartTimeMetaMetric
+	ReportPhaseOffsetMetaMetric
+	CallbackPanicsMetaMetric
+	TagValidationFailedMetaMetric
+	FlushedMetricsMetaMetric
+)
+
+// DefaultMetaMetricsPrefix is the default prefix of the names of the meta
+// metrics enabled by MetaMetricsOptions.
+const DefaultMetaMetricsPrefix = "tally"
+
+var metaMetricNames = map[MetaMetric]string{
+	NameLengthExceededMetaMetric:     "name_length_exceeded",
+	SeriesRateLimitDroppedMetaMetric: "series_rate_limit_dropped",
+	TimerSnapshotDroppedMetaMetric:   "timer_snapshot_dropped",
+	NameOverflowMetaMetric:           "name_overflows",
+	TimeToFirstReportMetaMetric:      "time_to_first_report_seconds",
+	ReportLoopHeartbeatMetaMetric:    "report_loop_heartbeat",
+	ProcessStartTimeMetaMetric:       "process_start_time_seconds",
+	ReportPhaseOffsetMetaMetric:      "report_phase_offset_ms",
+	CallbackPanicsMetaMetric:         "callback_panics",
+	TagValidationFailedMetaMetric:    "tag_validation_failed",
+	FlushedMetricsMetaMetric:         "flushed_metrics",
+}
+
+// MetaMetricsMode controls the meta metrics of a scope as a whole.
+type MetaMetricsMode int
+
+const (
+	// MetaMetricsDefault emits only the meta metrics named by their
+	// individual ScopeOptions fields, this is the default.
+	MetaMetricsDefault MetaMetricsMode = iota
+	// MetaMetricsEnabled emits the meta metrics in MetaMetricsOptions.Metrics,
+	// or all of them if it is empty, in addition to those named by their
+	// individual ScopeOptions fields.
+	MetaMetricsEnabled
+	// MetaMetricsDisabled emits no meta metrics, including those named by
+	// their individual ScopeOptions fields, e.g. to turn them all off in
+	// steady state without changing the rest of the options.
+	MetaMetricsDisabled
+)
+
+// MetaMetricsOptions is a set of options controlling all the meta metrics of
+// a scope, the metrics about tally itself. Meta metrics of reporters are
+// configured by the reporters.
+type MetaMetricsOptions struct {
+	Mode MetaMetricsMode
+
+	// Metrics are the meta metrics emitted with MetaMetricsEnabled. A meta
+	// metric not named by its individual ScopeOptions field is named with
+	// Prefix, the separator and its default name, e.g.
+	// "tally.report_loop_heartbeat".
+	Metrics []MetaMetric
+
+	// Prefix of the default names of meta metrics. If you do not set this
+	// value it will be set to DefaultMetaMetricsPrefix.
+	Prefix string
+}
+
+// applyMetaMetrics returns opts with the names of the meta metrics set or
+// cleared according to opts.MetaMetrics.
+func applyMetaMetrics(opts ScopeOptions) ScopeOptions {
+	names := map[MetaMetric]*string{
+		NameLengthExceededMetaMetric:     &opts.NameLengthExceededCounter,
+		SeriesRateLimitDroppedMetaMetric: &opts.SeriesRateLimitDroppedCounter,
+		TimerSnapshotDroppedMetaMetric:   &opts.TimerSnapshotDroppedCounter,
+		NameOverflowMetaMetric:           &opts.NameOverflowCounter,
+		TimeToFirstReportMetaMetric:      &opts.TimeToFirstReportGauge,
+		ReportLoopHeartbeatMetaMetric:    &opts.ReportLoopHeartbeatCounter,
+		ProcessStartTimeMetaMetric:       &opts.ProcessStartTimeGauge,
+		ReportPhaseOffsetMetaMetric:      &opts.ReportPhaseOffsetGauge,
+		CallbackPanicsMetaMetric:         &opts.CallbackPanicsCounter,
+		TagValidationFailedMetaMetric:    &opts.TagValidationFailedCounter,
+		FlushedMetricsMetaMetric:         &opts.FlushedMetricsGauge,
+	}
+
+	switch mo := opts.MetaMetrics; mo.Mode {
+	case MetaMetricsEnabled:
+		prefix := mo.Prefix
+		if prefix == "" {
+			prefix = DefaultMetaMetricsPrefix
+		}
+		metrics := mo.Metrics
+		if len(metrics) == 0 {
+			for m := range names {
+				metrics = append(metrics, m)
+			}
+		}
+		for _, m := range metrics {
+			if name, ok := names[m]; ok && *name == "" {
+				*name = prefix + opts.Separator + metaMetricNames[m]
+			}
+		}
+	case MetaMetricsDisabled:
+		for _, name := range names {
+			*name = ""
+		}
+	}
+	return opts
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetaMetricsEnabled(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{
+		ReportLoopHeartbeatCounter: "custom.heartbeat",
+		MetaMetrics: MetaMetricsOptions{
+			Mode:    MetaMetricsEnabled,
+			Metrics: []MetaMetric{ReportLoopHeartbeatMetaMetric, NameLengthExceededMetaMetric},
+			Prefix:  "meta",
+		},
+	})
+
+	counters := s.Snapshot().Counters()
+	assert.Len(t, counters, 2)
+	// An individually named meta metric keeps its name.
+	assert.Contains(t, counters, "custom.heartbeat+")
+	assert.Contains(t, counters, "meta.name_length_exceeded+")
+}
+
+func TestMetaMetricsEnabledAll(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{
+		MetaMetrics: MetaMetricsOptions{Mode: MetaMetricsEnabled},
+	})
+
+	snap := s.Snapshot()
+	for _, id := range []string{
+		"tally.name_length_exceeded+",
+		"tally.series_rate_limit_dropped+",
+		"tally.timer_snapshot_dropped+",
+		"tally.name_overflows+",
+		"tally.report_loop_heartbeat+",
+	} {
+		assert.Contains(t, snap.Counters(), id)
+	}
+	for _, id := range []string{
+		"tally.time_to_first_report_seconds+",
+		"tally.process_start_time_seconds+",
+		"tally.flushed_metrics+metric_type=counter",
+	} {
+		assert.Contains(t, snap.Gauges(), id)
+	}
+}
+
+func TestMetaMetricsDisabled(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{
+		NameLengthExceededCounter:  "tally.name_length_exceeded",
+		ReportLoopHeartbeatCounter: "tally.report_loop_heartbeat",
+		ProcessStartTimeGauge:      "tally.process_start_time_seconds",
+		MetaMetrics:                MetaMetricsOptions{Mode: MetaMetricsDisabled},
+	})
+
+	assert.Equal(t, 0, s.Snapshot().Counts().Total)
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+// The allocate methods allocate metrics on the cached reporter of the scope,
+// passing the metadata of the metric when the reporter supports it.
+
+func (s *scope) allocateCounter(name string, md Metadata) CachedCount {
+	if r, ok := s.cachedReporter.(MetadataCachedStatsReporter); ok && md != (Metadata{}) {
+		return r.AllocateCounterWithMetadata(name, s.reportTags, md)
+	}
+	return s.cachedReporter.AllocateCounter(name, s.reportTags)
+}
+
+func (s *scope) allocateGauge(name string, md Metadata) CachedGauge {
+	if r, ok := s.cachedReporter.(MetadataCachedStatsReporter); ok && md != (Metadata{}) {
+		return r.AllocateGaugeWithMetadata(name, s.reportTags, md)
+	}
+	return s.cachedReporter.AllocateGauge(name, s.reportTags)
+}
+
+func (s *scope) allocateTimer(name string, md Metadata) CachedTimer {
+	if r, ok := s.cachedReporter.(MetadataCachedStatsReporter); ok && md != (Metadata{}) {
+		return r.AllocateTimerWithMetadata(name, s.reportTags, md)
+	}
+	return s.cachedReporter.AllocateTimer(name, s.reportTags)
+}
+
+func (s *scope) allocateHistogram(name string, b Buckets, md Metadata) CachedHistogram {
+	if r, ok := s.cachedReporter.(MetadataCachedStatsReporter); ok && md != (Metadata{}) {
+		return r.AllocateHistogramWithMetadata(name, s.reportTags, b, md)
+	}
+	return s.cachedReporter.AllocateHistogram(name, s.reportTags, b)
+}
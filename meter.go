@@ -0,0 +1,189 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import "sync"
+
+// ScopeMeter returns the Meter object corresponding to the name on a scope
+// created by NewRootScope, creating it if it does not yet exist. The values
+// recorded by a meter are buffered and reported in the order they were
+// recorded on each report, through MeterReporter or CachedMeterReporter if
+// the reporter of the scope implements it and discarded otherwise, see
+// ReportsMeters. On other Scope implementations it returns a meter that
+// discards all values.
+func ScopeMeter(s Scope, name string) Meter {
+	if ms, ok := s.(meterScope); ok {
+		return ms.meter(name)
+	}
+	return noopMeter{}
+}
+
+// ReportsMeters returns whether the values of the meters of the scope s are
+// reported, i.e. whether it was created by NewRootScope with a reporter that
+// implements MeterReporter or a cached reporter that implements
+// CachedMeterReporter.
+func ReportsMeters(s Scope) bool {
+	if ms, ok := s.(meterScope); ok {
+		return ms.reportsMeters()
+	}
+	return false
+}
+
+type meterScope interface {
+	meter(name string) Meter
+	reportsMeters() bool
+}
+
+func (s *scope) meter(name string) Meter {
+	if s.sanitizeReject.rejectName(name) {
+		return noopMeter{}
+	}
+	name = s.sanitizer.Name(name)
+	name, exceeded := s.limitName(name)
+	s.mm.RLock()
+	m, ok := s.meters[name]
+	s.mm.RUnlock()
+	if ok {
+		return m
+	}
+	if !s.allowName(name) {
+		return s.registry.root.meter(NameOverflowMetric)
+	}
+
+	s.mm.Lock()
+	defer s.mm.Unlock()
+
+	if m, ok := s.meters[name]; ok {
+		return m
+	}
+	s.countNameLengthExceeded(exceeded)
+	s.validateTags(name)
+
+	var cachedMeter CachedMeter
+	if r, ok := s.cachedReporter.(CachedMeterReporter); ok {
+		cachedMeter = r.AllocateMeter(s.fullyQualifiedName(name), s.reportTags)
+	}
+
+	m = &meter{
+		name:        s.fullyQualifiedName(name),
+		tags:        s.reportTags,
+		cachedMeter: cachedMeter,
+	}
+	m.limiter = s.seriesLimiter()
+	m.idle.start(s.registry.idle)
+	s.meters[name] = m
+	s.metersSlice = append(s.metersSlice, m)
+
+	return m
+}
+
+func (s *scope) reportsMeters() bool {
+	if _, ok := s.reporter.(MeterReporter); ok {
+		return true
+	}
+	_, ok := s.cachedReporter.(CachedMeterReporter)
+	return ok
+}
+
+type meter struct {
+	idle        idleTracker
+	name        string
+	tags        map[string]string
+	cachedMeter CachedMeter
+	limiter     *seriesLimiter
+
+	sync.Mutex
+	unreported []float64
+}
+
+func (m *meter) Record(value float64) {
+	if m.limiter != nil && !m.limiter.Allow() {
+		return
+	}
+	m.idle.touch()
+	m.Lock()
+	m.unreported = append(m.unreported, value)
+	m.Unlock()
+}
+
+// take removes and returns the unreported values.
+func (m *meter) take() []float64 {
+	m.Lock()
+	values := m.unreported
+	m.unreported = nil
+	m.Unlock()
+	return values
+}
+
+func (m *meter) report(r MeterReporter) {
+	values := m.take()
+	if len(values) == 0 || r == nil {
+		return
+	}
+	r.ReportMeter(m.name, m.tags, values)
+}
+
+func (m *meter) cachedReport() {
+	values := m.take()
+	if len(values) == 0 || m.cachedMeter == nil {
+		return
+	}
+	m.cachedMeter.ReportMeter(values)
+}
+
+func (m *meter) snapshot() []float64 {
+	m.Lock()
+	defer m.Unlock()
+
+	snap := make([]float64, len(m.unreported))
+	copy(snap, m.unreported)
+	return snap
+}
+
+func (m *meter) hasUnreported() bool {
+	m.Lock()
+	defer m.Unlock()
+
+	return len(m.unreported) > 0
+}
+
+type meterSnapshot struct {
+	name   string
+	tags   map[string]string
+	values []float64
+}
+
+func (s *meterSnapshot) Name() string {
+	return s.name
+}
+
+func (s *meterSnapshot) Tags() map[string]string {
+	return s.tags
+}
+
+func (s *meterSnapshot) Values() []float64 {
+	return s.values
+}
+
+type noopMeter struct{}
+
+func (noopMeter) Record(value float64) {
+}
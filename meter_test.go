@@ -0,0 +1,150 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type meterTestReporter struct {
+	*testStatsReporter
+
+	sync.Mutex
+	meters map[string][]float64
+}
+
+func newMeterTestReporter() *meterTestReporter {
+	return &meterTestReporter{
+		testStatsReporter: newTestStatsReporter(),
+		meters:            make(map[string][]float64),
+	}
+}
+
+func (r *meterTestReporter) ReportMeter(name string, tags map[string]string, samples []float64) {
+	r.Lock()
+	defer r.Unlock()
+	r.meters[KeyForPrefixedStringMap(name, tags)] = append(
+		r.meters[KeyForPrefixedStringMap(name, tags)], samples...)
+}
+
+func (r *meterTestReporter) AllocateMeter(name string, tags map[string]string) CachedMeter {
+	return cachedTestMeter{r: r, name: name, tags: tags}
+}
+
+func (r *meterTestReporter) getMeters() map[string][]float64 {
+	r.Lock()
+	defer r.Unlock()
+	meters := make(map[string][]float64, len(r.meters))
+	for k, v := range r.meters {
+		meters[k] = v
+	}
+	return meters
+}
+
+type cachedTestMeter struct {
+	r    *meterTestReporter
+	name string
+	tags map[string]string
+}
+
+func (m cachedTestMeter) ReportMeter(samples []float64) {
+	m.r.ReportMeter(m.name, m.tags, samples)
+}
+
+func TestMeterSnapshot(t *testing.T) {
+	s := NewTestScope("svc", nil)
+	m := ScopeMeter(s.Tagged(map[string]string{"a": "b"}), "sizes")
+	values := []float64{3, 1, 4, 1, 5, 9, 2, 6}
+	for _, v := range values {
+		m.Record(v)
+	}
+	assert.True(t, m == ScopeMeter(s.Tagged(map[string]string{"a": "b"}), "sizes"))
+
+	meters := s.Snapshot().Meters()
+	assert.Len(t, meters, 1)
+	snap := meters["svc.sizes+a=b"]
+	assert.Equal(t, "svc.sizes", snap.Name())
+	assert.Equal(t, map[string]string{"a": "b"}, snap.Tags())
+	assert.Equal(t, values, snap.Values())
+	assert.Equal(t, 1, s.Snapshot().Counts().Meters)
+
+	ScopeMeter(s, "empty")
+	assert.Len(t, s.Snapshot().Meters(), 2)
+	assert.Len(t, s.Snapshot().Compact().Meters(), 1)
+}
+
+func TestMeterReport(t *testing.T) {
+	r := newMeterTestReporter()
+	root, closer := NewRootScope(ScopeOptions{Reporter: r}, 0)
+	s := root.(*scope)
+	assert.True(t, ReportsMeters(s))
+
+	m := ScopeMeter(s, "sizes")
+	m.Record(2)
+	m.Record(1)
+	s.reportRegistry()
+	assert.Equal(t, map[string][]float64{"sizes+": {2, 1}}, r.getMeters())
+	assert.Empty(t, s.Snapshot().Meters()["sizes+"].Values())
+
+	// Values are only reported once.
+	m.Record(3)
+	assert.NoError(t, closer.Close())
+	assert.Equal(t, map[string][]float64{"sizes+": {2, 1, 3}}, r.getMeters())
+}
+
+func TestMeterCachedReport(t *testing.T) {
+	r := newMeterTestReporter()
+	root, closer := NewRootScope(ScopeOptions{CachedReporter: r}, 0)
+	defer closer.Close()
+	s := root.(*scope)
+	assert.True(t, ReportsMeters(s))
+
+	ScopeMeter(s.SubScope("db"), "rows").Record(7)
+	s.reportRegistry()
+	assert.Equal(t, map[string][]float64{"db.rows+": {7}}, r.getMeters())
+}
+
+func TestMeterUnsupportedReporter(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{Reporter: r}, 0)
+	defer closer.Close()
+	s := root.(*scope)
+	assert.False(t, ReportsMeters(s))
+
+	m := ScopeMeter(s, "sizes")
+	m.Record(1)
+	s.reportRegistry()
+	// The values are discarded rather than buffered indefinitely.
+	assert.Empty(t, s.Snapshot().Meters()["sizes+"].Values())
+}
+
+func TestMeterOtherScopes(t *testing.T) {
+	s := NewNoopScope()
+	ScopeMeter(s, "sizes").Record(1)
+	assert.False(t, ReportsMeters(s))
+
+	ts := NewTestScope("", nil)
+	ScopeMeter(NewSourceTaggingScope(ts), "sizes").Record(1)
+	assert.Len(t, ts.Snapshot().Meters(), 1)
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// idleClock is the clock of ScopeOptions.MetricIdleTTL, it only advances on
+// each report so that updating a metric does not read the time.
+type idleClock struct {
+	now int64
+	ttl time.Duration
+}
+
+func newIdleClock(now time.Time, ttl time.Duration) *idleClock {
+	return &idleClock{now: now.UnixNano(), ttl: ttl}
+}
+
+func (c *idleClock) advance(now time.Time) {
+	atomic.StoreInt64(&c.now, now.UnixNano())
+}
+
+// cutoff returns the time before which metrics last updated are idle.
+func (c *idleClock) cutoff() int64 {
+	return atomic.LoadInt64(&c.now) - int64(c.ttl)
+}
+
+// idleTracker tracks when a metric was last updated, it must be the first
+// field of the metric so that updated is 64-bit aligned.
+type idleTracker struct {
+	updated int64
+	pinned  int32
+	// clock is nil if the metric never expires.
+	clock *idleClock
+}
+
+func (t *idleTracker) start(clock *idleClock) {
+	if clock != nil {
+		t.clock = clock
+		t.updated = atomic.LoadInt64(&clock.now)
+	}
+}
+
+func (t *idleTracker) touch() {
+	if t.clock != nil {
+		atomic.StoreInt64(&t.updated, atomic.LoadInt64(&t.clock.now))
+	}
+}
+
+// pin keeps metrics that the library holds on to from expiring, as updates
+// to an expired metric are lost.
+func (t *idleTracker) pin() {
+	atomic.StoreInt32(&t.pinned, 1)
+}
+
+func (t *idleTracker) expired(cutoff int64) bool {
+	return t.clock != nil &&
+		atomic.LoadInt32(&t.pinned) == 0 &&
+		atomic.LoadInt64(&t.updated) < cutoff
+}
+
+// pinCounter pins a counter created by the library if it expires.
+func pinCounter(c Counter) {
+	if c, ok := c.(*counter); ok {
+		c.idle.pin()
+	}
+}
+
+// pinHistogram pins a histogram created by the library if it expires.
+func pinHistogram(h Histogram) {
+	if h, ok := h.(*histogram); ok {
+		h.idle.pin()
+	}
+}
+
+func (c *counter) hasUnreported() bool {
+	return atomic.LoadInt64(&c.curr) != atomic.LoadInt64(&c.prev) ||
+		atomic.LoadUint64(&c.currFloat) != atomic.LoadUint64(&c.prevFloat)
+}
+
+func (g *gauge) hasUnreported() bool {
+	return atomic.LoadUint64(&g.updated) == 1
+}
+
+func (t *timer) hasUnreported() bool {
+	t.unreported.RLock()
+	defer t.unreported.RUnlock()
+
+	return len(t.unreported.values) > 0
+}
+
+func (h *histogram) hasUnreported() bool {
+	for i := range h.samples {
+		if h.samples[i].counter.hasUnreported() {
+			return true
+		}
+	}
+	return false
+}
+
+// evictIdleMetrics removes the metrics of the registry not updated since the
+// cutoff that have nothing left to report.
+func (r *scopeRegistry) evictIdleMetrics(cutoff int64) {
+	r.ForEachScope(func(s *scope) {
+		s.evictIdleMetrics(cutoff)
+	})
+}
+
+func (s *scope) evictIdleMetrics(cutoff int64) {
+	s.cm.Lock()
+	// NB: The sets of evicted metrics are only allocated once one is.
+	var evictedCounters map[*counter]struct{}
+	for name, c := range s.counters {
+		if c.idle.expired(cutoff) && !c.hasUnreported() {
+			delete(s.counters, name)
+			if evictedCounters == nil {
+				evictedCounters = make(map[*counter]struct{})
+			}
+			evictedCounters[c] = struct{}{}
+		}
+	}
+	if len(evictedCounters) > 0 {
+		counters := s.countersSlice[:0]
+		for _, c := range s.countersSlice {
+			if _, ok := evictedCounters[c]; !ok {
+				counters = append(counters, c)
+			}
+		}
+		s.countersSlice = counters
+	}
+	s.cm.Unlock()
+
+	s.gm.Lock()
+	var evictedGauges map[*gauge]struct{}
+	for name, g := range s.gauges {
+		if g.idle.expired(cutoff) && !g.hasUnreported() {
+			delete(s.gauges, name)
+			if evictedGauges == nil {
+				evictedGauges = make(map[*gauge]struct{})
+			}
+			evictedGauges[g] = struct{}{}
+		}
+	}
+	if len(evictedGauges) > 0 {
+		gauges := s.gaugesSlice[:0]
+		for _, g := range s.gaugesSlice {
+			if _, ok := evictedGauges[g]; !ok {
+				gauges = append(gauges, g)
+			}
+		}
+		s.gaugesSlice = gauges
+	}
+	s.gm.Unlock()
+
+	s.tm.Lock()
+	var evictedTimers map[*timer]struct{}
+	for name, t := range s.timers {
+		if t.idle.expired(cutoff) && !t.hasUnreported() {
+			delete(s.timers, name)
+			if evictedTimers == nil {
+				evictedTimers = make(map[*timer]struct{})
+			}
+			evictedTimers[t] = struct{}{}
+		}
+	}
+	if len(evictedTimers) > 0 {
+		timers := s.decayingTimers[:0]
+		for _, t := range s.decayingTimers {
+			if _, ok := evictedTimers[t]; !ok {
+				timers = append(timers, t)
+			}
+		}
+		s.decayingTimers = timers
+	}
+	s.tm.Unlock()
+
+	s.hm.Lock()
+	var evictedHistograms map[*histogram]struct{}
+	for name, h := range s.histograms {
+		if h.idle.expired(cutoff) && !h.hasUnreported() {
+			delete(s.histograms, name)
+			if evictedHistograms == nil {
+				evictedHistograms = make(map[*histogram]struct{})
+			}
+			evictedHistograms[h] = struct{}{}
+		}
+	}
+	if len(evictedHistograms) > 0 {
+		histograms := s.histogramsSlice[:0]
+		for _, h := range s.histogramsSlice {
+			if _, ok := evictedHistograms[h]; !ok {
+				histograms = append(histograms, h)
+			}
+		}
+		s.histogramsSlice = histograms
+		s.registry.numHistograms.Sub(int64(len(evictedHistograms)))
+	}
+	s.hm.Unlock()
+
+	s.mm.Lock()
+	var evictedMeters map[*meter]struct{}
+	for name, m := range s.meters {
+		if m.idle.expired(cutoff) && !m.hasUnreported() {
+			delete(s.meters, name)
+			if evictedMeters == nil {
+				evictedMeters = make(map[*meter]struct{})
+			}
+			evictedMeters[m] = struct{}{}
+		}
+	}
+	if len(evictedMeters) > 0 {
+		meters := s.metersSlice[:0]
+		for _, m := range s.metersSlice {
+			if _, ok := evictedMeters[m]; !ok {
+				meters = append(meters, m)
+			}
+		}
+		s.metersSlice = meters
+	}
+	s.mm.Unlock()
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricIdleTTL(t *testing.T) {
+	now := time.Unix(1000, 0)
+	globalNow = func() time.Time { return now }
+	defer func() { globalNow = time.Now }()
+
+	root, closer := NewRootScope(ScopeOptions{
+		Reporter:      NullStatsReporter,
+		MetricIdleTTL: time.Minute,
+	}, 0)
+	defer closer.Close()
+
+	s := root.(*scope)
+	sub := s.Tagged(map[string]string{"customer": "a"}).(*scope)
+	sub.Counter("requests").Inc(1)
+	sub.Gauge("queue").Update(1)
+	sub.Timer("latency").Record(time.Second)
+	sub.Histogram("size", ValueBuckets{1}).RecordValue(1)
+	s.Counter("active").Inc(1)
+
+	now = now.Add(30 * time.Second)
+	s.reportRegistry()
+	assert.Equal(t, 5, s.Snapshot().Counts().Total)
+
+	s.Counter("active").Inc(1)
+	now = now.Add(45 * time.Second)
+	s.reportRegistry()
+
+	snap := s.Snapshot()
+	assert.Equal(t, 1, snap.Counts().Total)
+	assert.Contains(t, snap.Counters(), "active+")
+	assert.Empty(t, sub.counters)
+	assert.Empty(t, sub.countersSlice)
+	assert.Empty(t, sub.gauges)
+	assert.Empty(t, sub.gaugesSlice)
+	assert.Empty(t, sub.timers)
+	assert.Empty(t, sub.histograms)
+	assert.Empty(t, sub.histogramsSlice)
+	assert.Equal(t, int64(0), s.registry.numHistograms.Load())
+
+	// An expired metric is created again when it is looked up.
+	sub.Counter("requests").Inc(2)
+	counters := s.Snapshot().Counters()
+	require.Contains(t, counters, "requests+customer=a")
+	assert.Equal(t, int64(2), counters["requests+customer=a"].Value())
+}
+
+func TestMetricIdleTTLKeepsUnreported(t *testing.T) {
+	now := time.Unix(1000, 0)
+	globalNow = func() time.Time { return now }
+	defer func() { globalNow = time.Now }()
+
+	root, closer := NewRootScope(ScopeOptions{
+		Reporter:      NullStatsReporter,
+		MetricIdleTTL: time.Minute,
+	}, 0)
+	defer closer.Close()
+
+	s := root.(*scope)
+	s.Counter("requests").Inc(1)
+	s.Gauge("queue").Update(1)
+	RegisterRatio(s, "error_rate", "errors", "calls", RatioOptions{})
+
+	now = now.Add(2 * time.Minute)
+	s.registry.idle.advance(now)
+	s.evictIdleMetrics(s.registry.idle.cutoff())
+	counters := s.Snapshot().Counters()
+	assert.Contains(t, counters, "requests+")
+	assert.Contains(t, s.Snapshot().Gauges(), "queue+")
+
+	// Once reported the idle metrics expire, but not those of the ratio.
+	s.reportRegistry()
+	snap := s.Snapshot()
+	assert.NotContains(t, snap.Counters(), "requests+")
+	assert.NotContains(t, snap.Gauges(), "queue+")
+	assert.Contains(t, snap.Counters(), "errors+")
+	assert.Contains(t, snap.Counters(), "calls+")
+	assert.Contains(t, snap.Gauges(), "error_rate+")
+}
+
+func TestMetricIdleTTLKeepsCumulativeCounters(t *testing.T) {
+	now := time.Unix(1000, 0)
+	globalNow = func() time.Time { return now }
+	defer func() { globalNow = time.Now }()
+
+	root, closer := NewRootScope(ScopeOptions{
+		Reporter:      NullStatsReporter,
+		MetricIdleTTL: time.Minute,
+	}, 0)
+	defer closer.Close()
+
+	s := root.(*scope)
+	CounterWithOptions(s, "total", CounterOptions{Cumulative: true}).Inc(3)
+	s.Counter("requests").Inc(1)
+	s.reportRegistry()
+
+	now = now.Add(2 * time.Minute)
+	s.registry.idle.advance(now)
+	s.reportRegistry()
+	counters := s.Snapshot().Counters()
+	assert.NotContains(t, counters, "requests+")
+	assert.Equal(t, int64(3), counters["total+"].Value())
+}
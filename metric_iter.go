@@ -0,0 +1,132 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+// MetricType is the type of a metric visited by ForEachMetric.
+type MetricType int
+
+const (
+	// CounterMetricType is the type of counters.
+	CounterMetricType MetricType = iota + 1
+	// GaugeMetricType is the type of gauges.
+	GaugeMetricType
+	// TimerMetricType is the type of timers.
+	TimerMetricType
+	// HistogramMetricType is the type of histograms.
+	HistogramMetricType
+	// MeterMetricType is the type of meters.
+	MeterMetricType
+)
+
+// String returns the name of the metric type.
+func (t MetricType) String() string {
+	switch t {
+	case CounterMetricType:
+		return "counter"
+	case GaugeMetricType:
+		return "gauge"
+	case TimerMetricType:
+		return "timer"
+	case HistogramMetricType:
+		return "histogram"
+	case MeterMetricType:
+		return "meter"
+	}
+	return "unknown"
+}
+
+// MetricMetadata describes a metric registered on a scope.
+type MetricMetadata struct {
+	// Name is the fully qualified name of the metric.
+	Name string
+	// Tags are the tags of the metric, a copy that may be modified.
+	Tags map[string]string
+	// Type is the type of the metric.
+	Type MetricType
+}
+
+// ForEachMetric calls fn with the metadata of every metric currently
+// registered on the scope s and its descendants, the scopes created from it
+// with SubScope and Tagged, in no particular order. It does not report or
+// reset any values and is safe to call concurrently with the creation of
+// metrics; fn is called once the metadata has been collected, so it may
+// itself use the scopes. On Scope implementations other than those created by
+// NewRootScope fn is never called.
+func ForEachMetric(s Scope, fn func(m MetricMetadata)) {
+	if fs, ok := s.(forEachMetricScope); ok {
+		for _, m := range fs.metricMetadata() {
+			fn(m)
+		}
+	}
+}
+
+type forEachMetricScope interface {
+	metricMetadata() []MetricMetadata
+}
+
+func (s *scope) metricMetadata() []MetricMetadata {
+	var metrics []MetricMetadata
+	s.registry.ForEachScope(func(ss *scope) {
+		if !s.inSubtree(ss) {
+			return
+		}
+
+		add := func(key string, t MetricType) {
+			// NB: tags are immutable, copy them so fn cannot mutate the scope.
+			tags := make(map[string]string, len(ss.tags))
+			for k, v := range ss.tags {
+				tags[k] = v
+			}
+			metrics = append(metrics, MetricMetadata{
+				Name: ss.fullyQualifiedName(key),
+				Tags: tags,
+				Type: t,
+			})
+		}
+
+		ss.cm.RLock()
+		for key := range ss.counters {
+			add(key, CounterMetricType)
+		}
+		ss.cm.RUnlock()
+		ss.gm.RLock()
+		for key := range ss.gauges {
+			add(key, GaugeMetricType)
+		}
+		ss.gm.RUnlock()
+		ss.tm.RLock()
+		for key := range ss.timers {
+			add(key, TimerMetricType)
+		}
+		ss.tm.RUnlock()
+		ss.hm.RLock()
+		for key := range ss.histograms {
+			add(key, HistogramMetricType)
+		}
+		ss.hm.RUnlock()
+		ss.mm.RLock()
+		for key := range ss.meters {
+			add(key, MeterMetricType)
+		}
+		ss.mm.RUnlock()
+	})
+	return metrics
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForEachMetric(t *testing.T) {
+	s := NewTestScope("", nil)
+	svc := s.SubScope("svc")
+	svc.Counter("requests").Inc(1)
+
+	db := svc.SubScope("db").Tagged(map[string]string{"table": "users"})
+	db.Gauge("rows").Update(3)
+	db.Timer("latency").Record(time.Millisecond)
+	db.SubScope("pool").Tagged(map[string]string{"tier": "primary"}).
+		Histogram("wait", DefaultBuckets).RecordValue(1)
+
+	s.SubScope("other").Counter("ignored").Inc(1)
+
+	var seen []string
+	ForEachMetric(svc, func(m MetricMetadata) {
+		seen = append(seen, m.Type.String()+" "+KeyForPrefixedStringMap(m.Name, m.Tags))
+	})
+	sort.Strings(seen)
+	assert.Equal(t, []string{
+		"counter svc.requests+",
+		"gauge svc.db.rows+table=users",
+		"histogram svc.db.pool.wait+table=users,tier=primary",
+		"timer svc.db.latency+table=users",
+	}, seen)
+}
+
+func TestForEachMetricTagsCopied(t *testing.T) {
+	s := NewTestScope("", nil)
+	tagged := s.Tagged(map[string]string{"a": "b"})
+	tagged.Counter("c").Inc(1)
+
+	ForEachMetric(tagged, func(m MetricMetadata) {
+		m.Tags["a"] = "mutated"
+	})
+	ForEachMetric(tagged, func(m MetricMetadata) {
+		assert.Equal(t, "b", m.Tags["a"])
+	})
+}
+
+func TestForEachMetricCreatesFromVisitor(t *testing.T) {
+	s := NewTestScope("", nil)
+	s.Counter("c").Inc(1)
+
+	ForEachMetric(s, func(m MetricMetadata) {
+		s.Gauge("created_while_visiting").Update(1)
+	})
+	assert.Contains(t, s.Snapshot().Gauges(), "created_while_visiting+")
+}
+
+func TestForEachMetricConcurrentCreation(t *testing.T) {
+	s := NewTestScope("", nil)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			s.Tagged(map[string]string{"i": string(rune('a' + i%26))}).
+				Counter("c").Inc(1)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		ForEachMetric(s, func(MetricMetadata) {})
+	}
+	wg.Wait()
+}
+
+func TestForEachMetricSourceTaggingScope(t *testing.T) {
+	s := NewTestScope("", nil)
+	db := NewSourceTaggingScope(s).SubScope("db")
+	db.Counter("queries").Inc(1)
+	s.SubScope("http").Counter("requests").Inc(1)
+
+	var names []string
+	ForEachMetric(db, func(m MetricMetadata) {
+		names = append(names, m.Name)
+	})
+	assert.Equal(t, []string{"db.queries"}, names)
+}
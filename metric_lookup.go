@@ -0,0 +1,134 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+// LookupCounter returns the counter with the given name of a scope created by
+// NewRootScope and true if it was already created, or nil and false
+// otherwise, without creating it, e.g. to assert that application code
+// registered a metric. On other Scope implementations it always returns nil
+// and false.
+func LookupCounter(s Scope, name string) (Counter, bool) {
+	if ls, ok := s.(lookupScope); ok {
+		return ls.lookupCounter(name)
+	}
+	return nil, false
+}
+
+// LookupGauge is LookupCounter for gauges.
+func LookupGauge(s Scope, name string) (Gauge, bool) {
+	if ls, ok := s.(lookupScope); ok {
+		return ls.lookupGauge(name)
+	}
+	return nil, false
+}
+
+// LookupTimer is LookupCounter for timers.
+func LookupTimer(s Scope, name string) (Timer, bool) {
+	if ls, ok := s.(lookupScope); ok {
+		return ls.lookupTimer(name)
+	}
+	return nil, false
+}
+
+// LookupHistogram is LookupCounter for histograms.
+func LookupHistogram(s Scope, name string) (Histogram, bool) {
+	if ls, ok := s.(lookupScope); ok {
+		return ls.lookupHistogram(name)
+	}
+	return nil, false
+}
+
+type lookupScope interface {
+	lookupCounter(name string) (Counter, bool)
+	lookupGauge(name string) (Gauge, bool)
+	lookupTimer(name string) (Timer, bool)
+	lookupHistogram(name string) (Histogram, bool)
+}
+
+// lookupName returns the name a metric with the given name is created with,
+// or false if no metric can be created with it.
+func (s *scope) lookupName(name string) (string, bool) {
+	truncated, exceeded := s.truncateName(s.sanitizer.Name(name))
+	if exceeded && (s.nameLengthMode == PanicNameLengthMode || len(truncated) == 0) {
+		return "", false
+	}
+	return truncated, true
+}
+
+func (s *scope) lookupCounter(name string) (Counter, bool) {
+	name, ok := s.lookupName(name)
+	if !ok {
+		return nil, false
+	}
+
+	s.cm.RLock()
+	defer s.cm.RUnlock()
+
+	if c, ok := s.counters[name]; ok {
+		return c, true
+	}
+	return nil, false
+}
+
+func (s *scope) lookupGauge(name string) (Gauge, bool) {
+	name, ok := s.lookupName(name)
+	if !ok {
+		return nil, false
+	}
+
+	s.gm.RLock()
+	defer s.gm.RUnlock()
+
+	if g, ok := s.gauges[name]; ok {
+		return g, true
+	}
+	return nil, false
+}
+
+func (s *scope) lookupTimer(name string) (Timer, bool) {
+	name, ok := s.lookupName(name)
+	if !ok {
+		return nil, false
+	}
+
+	s.tm.RLock()
+	defer s.tm.RUnlock()
+
+	if t, ok := s.timers[name]; ok {
+		return t, true
+	}
+	return nil, false
+}
+
+func (s *scope) lookupHistogram(name string) (Histogram, bool) {
+	name, ok := s.lookupName(name)
+	if !ok {
+		return nil, false
+	}
+
+	s.hm.RLock()
+	defer s.hm.RUnlock()
+
+	if h, ok := s.histograms[name]; ok {
+		return h, true
+	}
+	return nil, false
+}
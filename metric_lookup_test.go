@@ -0,0 +1,93 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupMetrics(t *testing.T) {
+	s := NewTestScope("svc", nil)
+
+	c, ok := LookupCounter(s, "c")
+	assert.False(t, ok)
+	assert.Nil(t, c)
+	g, ok := LookupGauge(s, "g")
+	assert.False(t, ok)
+	assert.Nil(t, g)
+	tm, ok := LookupTimer(s, "t")
+	assert.False(t, ok)
+	assert.Nil(t, tm)
+	h, ok := LookupHistogram(s, "h")
+	assert.False(t, ok)
+	assert.Nil(t, h)
+	// Lookups do not create the metrics.
+	assert.Equal(t, 0, s.Snapshot().Counts().Total)
+
+	counter := s.Counter("c")
+	gauge := s.Gauge("g")
+	timer := s.Timer("t")
+	histogram := s.Histogram("h", DefaultBuckets)
+
+	c, ok = LookupCounter(s, "c")
+	assert.True(t, ok)
+	assert.True(t, counter == c)
+	g, ok = LookupGauge(s, "g")
+	assert.True(t, ok)
+	assert.True(t, gauge == g)
+	tm, ok = LookupTimer(s, "t")
+	assert.True(t, ok)
+	assert.True(t, timer == tm)
+	h, ok = LookupHistogram(s, "h")
+	assert.True(t, ok)
+	assert.True(t, histogram == h)
+
+	// Metrics are looked up on the scope they were created on only.
+	_, ok = LookupCounter(s.SubScope("sub"), "c")
+	assert.False(t, ok)
+	_, ok = LookupCounter(s.Tagged(map[string]string{"a": "b"}), "c")
+	assert.False(t, ok)
+}
+
+func TestLookupMetricsNameLength(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{MaxNameLength: 4})
+	counter := s.Counter("counter")
+
+	c, ok := LookupCounter(s, "counter")
+	assert.True(t, ok)
+	assert.True(t, counter == c)
+
+	panicking := NewTestScopeWithOptions(ScopeOptions{
+		MaxNameLength:  4,
+		NameLengthMode: PanicNameLengthMode,
+	})
+	_, ok = LookupCounter(panicking, "counter")
+	assert.False(t, ok)
+}
+
+func TestLookupMetricsOtherScopes(t *testing.T) {
+	s := NewNoopScope()
+	s.Counter("c")
+	_, ok := LookupCounter(s, "c")
+	assert.False(t, ok)
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import "sync"
+
+// NameOverflowMetric is the name of the metrics on the root scope returned
+// in place of metrics with new names once ScopeOptions.MaxMetricNames is
+// reached.
+const NameOverflowMetric = "tally.name_overflow"
+
+// metricNames tracks the distinct fully qualified metric names of a registry
+// to enforce ScopeOptions.MaxMetricNames.
+type metricNames struct {
+	sync.Mutex
+	max      int
+	names    map[string]struct{}
+	overflow string
+	counter  Counter
+}
+
+func (r *scopeRegistry) limitNames(max int) {
+	if max <= 0 {
+		return
+	}
+	root := r.root
+	r.names.max = max
+	r.names.names = make(map[string]struct{}, max)
+	r.names.overflow = root.fullyQualifiedName(root.sanitizer.Name(NameOverflowMetric))
+}
+
+// allowName returns whether a metric with the given sanitized name can be
+// created on the scope without exceeding the maximum number of names.
+func (s *scope) allowName(name string) bool {
+	names := &s.registry.names
+	if names.max <= 0 {
+		return true
+	}
+
+	name = s.fullyQualifiedName(name)
+	if name == names.overflow {
+		return true
+	}
+
+	names.Lock()
+	_, ok := names.names[name]
+	if !ok && len(names.names) < names.max {
+		names.names[name] = struct{}{}
+		ok = true
+	}
+	names.Unlock()
+
+	if !ok && names.counter != nil {
+		names.counter.Inc(1)
+	}
+	return ok
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxMetricNames(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{
+		Prefix:              "service",
+		MaxMetricNames:      3,
+		NameOverflowCounter: "overflows",
+	})
+	sub := s.SubScope("sub")
+
+	s.Counter("a").Inc(1)
+	s.Gauge("a").Update(1)
+	sub.Counter("b").Inc(1)
+	s.Timer("c")
+
+	// Over the limit new names use the shared overflow metrics.
+	for i := 0; i < 2; i++ {
+		sub.Counter(fmt.Sprintf("user-%d", i)).Inc(1)
+	}
+	sub.Gauge("user").Update(5)
+
+	// Existing names keep working.
+	s.Counter("a").Inc(1)
+	sub.Counter("b").Inc(1)
+
+	snap := s.Snapshot()
+	counters := snap.Counters()
+	assert.EqualValues(t, 2, counters["service.a+"].Value())
+	assert.EqualValues(t, 2, counters["service.sub.b+"].Value())
+	assert.EqualValues(t, 2, counters["service.tally.name_overflow+"].Value())
+	assert.EqualValues(t, 3, counters["service.overflows+"].Value())
+	assert.NotContains(t, counters, "service.sub.user-0+")
+	assert.EqualValues(t, 5, snap.Gauges()["service.tally.name_overflow+"].Value())
+	assert.Equal(t, 7, snap.Counts().Total)
+}
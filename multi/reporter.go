@@ -21,6 +21,8 @@
 package multi
 
 import (
+	"math"
+	"strings"
 	"time"
 
 	"github.com/uber-go/tally"
@@ -111,6 +113,48 @@ func (r *multi) Flush() {
 	r.multiBaseReporters.Flush()
 }
 
+// FlushWithError flushes all reporters, including the remaining ones when
+// one fails, and returns the errors of those implementing
+// tally.FlushErrorReporter joined together.
+func (r *multi) FlushWithError() error {
+	return r.multiBaseReporters.FlushWithError()
+}
+
+func (r *multi) Event(title, text string, tags map[string]string) {
+	r.multiBaseReporters.Event(title, text, tags)
+}
+
+// ReportCounterFloat implements tally.FloatCounterReporter, reporters that
+// do not implement it get the value rounded, so unlike reporting to them
+// directly the fractions are not carried over to the next reports.
+func (r *multi) ReportCounterFloat(
+	name string,
+	tags map[string]string,
+	value float64,
+) {
+	for _, r := range r.reporters {
+		if fr, ok := r.(tally.FloatCounterReporter); ok {
+			fr.ReportCounterFloat(name, tags, value)
+		} else {
+			r.ReportCounter(name, tags, int64(math.Round(value)))
+		}
+	}
+}
+
+// ReportMeter implements tally.MeterReporter, the values are discarded for
+// reporters that do not implement it.
+func (r *multi) ReportMeter(
+	name string,
+	tags map[string]string,
+	samples []float64,
+) {
+	for _, r := range r.reporters {
+		if mr, ok := r.(tally.MeterReporter); ok {
+			mr.ReportMeter(name, tags, samples)
+		}
+	}
+}
+
 type multiCached struct {
 	multiBaseReporters multiBaseReporters
 	reporters          []tally.CachedStatsReporter
@@ -175,6 +219,94 @@ func (r *multiCached) AllocateHistogram(
 	return multiMetric{histograms: metrics}
 }
 
+// AllocateCounterWithMetadata implements tally.MetadataCachedStatsReporter,
+// reporters that do not implement it allocate the counter without metadata.
+func (r *multiCached) AllocateCounterWithMetadata(
+	name string,
+	tags map[string]string,
+	md tally.Metadata,
+) tally.CachedCount {
+	metrics := make([]tally.CachedCount, 0, len(r.reporters))
+	for _, r := range r.reporters {
+		if mr, ok := r.(tally.MetadataCachedStatsReporter); ok {
+			metrics = append(metrics, mr.AllocateCounterWithMetadata(name, tags, md))
+		} else {
+			metrics = append(metrics, r.AllocateCounter(name, tags))
+		}
+	}
+	return multiMetric{counters: metrics}
+}
+
+// AllocateGaugeWithMetadata implements tally.MetadataCachedStatsReporter
+// like AllocateCounterWithMetadata.
+func (r *multiCached) AllocateGaugeWithMetadata(
+	name string,
+	tags map[string]string,
+	md tally.Metadata,
+) tally.CachedGauge {
+	metrics := make([]tally.CachedGauge, 0, len(r.reporters))
+	for _, r := range r.reporters {
+		if mr, ok := r.(tally.MetadataCachedStatsReporter); ok {
+			metrics = append(metrics, mr.AllocateGaugeWithMetadata(name, tags, md))
+		} else {
+			metrics = append(metrics, r.AllocateGauge(name, tags))
+		}
+	}
+	return multiMetric{gauges: metrics}
+}
+
+// AllocateTimerWithMetadata implements tally.MetadataCachedStatsReporter
+// like AllocateCounterWithMetadata.
+func (r *multiCached) AllocateTimerWithMetadata(
+	name string,
+	tags map[string]string,
+	md tally.Metadata,
+) tally.CachedTimer {
+	metrics := make([]tally.CachedTimer, 0, len(r.reporters))
+	for _, r := range r.reporters {
+		if mr, ok := r.(tally.MetadataCachedStatsReporter); ok {
+			metrics = append(metrics, mr.AllocateTimerWithMetadata(name, tags, md))
+		} else {
+			metrics = append(metrics, r.AllocateTimer(name, tags))
+		}
+	}
+	return multiMetric{timers: metrics}
+}
+
+// AllocateHistogramWithMetadata implements tally.MetadataCachedStatsReporter
+// like AllocateCounterWithMetadata.
+func (r *multiCached) AllocateHistogramWithMetadata(
+	name string,
+	tags map[string]string,
+	buckets tally.Buckets,
+	md tally.Metadata,
+) tally.CachedHistogram {
+	metrics := make([]tally.CachedHistogram, 0, len(r.reporters))
+	for _, r := range r.reporters {
+		if mr, ok := r.(tally.MetadataCachedStatsReporter); ok {
+			metrics = append(metrics, mr.AllocateHistogramWithMetadata(name, tags, buckets, md))
+		} else {
+			metrics = append(metrics, r.AllocateHistogram(name, tags, buckets))
+		}
+	}
+	return multiMetric{histograms: metrics}
+}
+
+// AllocateMeter implements tally.CachedMeterReporter, the values are
+// discarded for reporters that do not implement it.
+func (r *multiCached) AllocateMeter(
+	name string,
+	tags map[string]string,
+) tally.CachedMeter {
+	var metrics []tally.CachedMeter
+	for _, r := range r.reporters {
+		if mr, ok := r.(tally.CachedMeterReporter); ok {
+			metrics = append(metrics, mr.AllocateMeter(name, tags))
+		}
+	}
+	return multiMetric{meters: metrics}
+}
+
 func (r *multiCached) Capabilities() tally.Capabilities {
 	return r.multiBaseReporters.Capabilities()
 }
@@ -183,11 +315,37 @@ func (r *multiCached) Flush() {
 	r.multiBaseReporters.Flush()
 }
 
+// FlushWithError flushes all reporters like the FlushWithError method of
+// a reporter created by NewMultiReporter.
+func (r *multiCached) FlushWithError() error {
+	return r.multiBaseReporters.FlushWithError()
+}
+
+func (r *multiCached) Event(title, text string, tags map[string]string) {
+	r.multiBaseReporters.Event(title, text, tags)
+}
+
+// Reporters returns the reporters wrapped by a reporter created by
+// NewMultiReporter or NewMultiCachedReporter, in the order they were passed
+// to it, e.g. to check which backends are attached and their capabilities.
+// It returns false for any other reporter.
+func Reporters(r tally.BaseStatsReporter) ([]tally.BaseStatsReporter, bool) {
+	switch r := r.(type) {
+	case *multi:
+		return r.multiBaseReporters.copy(), true
+	case *multiCached:
+		return r.multiBaseReporters.copy(), true
+	default:
+		return nil, false
+	}
+}
+
 type multiMetric struct {
 	counters   []tally.CachedCount
 	gauges     []tally.CachedGauge
 	timers     []tally.CachedTimer
 	histograms []tally.CachedHistogram
+	meters     []tally.CachedMeter
 }
 
 func (m multiMetric) ReportCount(value int64) {
@@ -196,6 +354,25 @@ func (m multiMetric) ReportCount(value int64) {
 	}
 }
 
+// ReportCountFloat implements tally.CachedFloatCount, counters that do not
+// implement it get the value rounded like the ReportCounterFloat method of a
+// reporter created by NewMultiReporter.
+func (m multiMetric) ReportCountFloat(value float64) {
+	for _, m := range m.counters {
+		if fm, ok := m.(tally.CachedFloatCount); ok {
+			fm.ReportCountFloat(value)
+		} else {
+			m.ReportCount(int64(math.Round(value)))
+		}
+	}
+}
+
+func (m multiMetric) ReportMeter(samples []float64) {
+	for _, m := range m.meters {
+		m.ReportMeter(samples)
+	}
+}
+
 func (m multiMetric) ReportGauge(value float64) {
 	for _, m := range m.gauges {
 		m.ReportGauge(value)
@@ -243,20 +420,66 @@ func (m multiHistogramBucket) ReportSamples(value int64) {
 type multiBaseReporters []tally.BaseStatsReporter
 
 func (r multiBaseReporters) Capabilities() tally.Capabilities {
-	c := &capabilities{reporting: true, tagging: true}
+	c := &capabilities{reporting: true, tagging: true, histograms: true}
 	for _, r := range r {
 		c.reporting = c.reporting && r.Capabilities().Reporting()
 		c.tagging = c.tagging && r.Capabilities().Tagging()
+		c.histograms = c.histograms && tally.SupportsHistograms(r.Capabilities())
 	}
 	return c
 }
 
+func (r multiBaseReporters) copy() []tally.BaseStatsReporter {
+	return append([]tally.BaseStatsReporter(nil), r...)
+}
+
 func (r multiBaseReporters) Flush() {
 	for _, r := range r {
 		r.Flush()
 	}
 }
 
+func (r multiBaseReporters) FlushWithError() error {
+	var errs flushErrors
+	for _, r := range r {
+		fr, ok := r.(tally.FlushErrorReporter)
+		if !ok {
+			r.Flush()
+			continue
+		}
+		if err := fr.FlushWithError(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return errs
+	}
+}
+
+// flushErrors is the error returned when several reporters failed to flush.
+type flushErrors []error
+
+func (e flushErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (r multiBaseReporters) Event(title, text string, tags map[string]string) {
+	for _, r := range r {
+		if e, ok := r.(tally.EventReporter); ok {
+			e.Event(title, text, tags)
+		}
+	}
+}
+
 type capabilities struct {
 	reporting  bool
 	tagging    bool
@@ -270,3 +493,7 @@ func (c *capabilities) Reporting() bool {
 func (c *capabilities) Tagging() bool {
 	return c.tagging
 }
+
+func (c *capabilities) Histograms() bool {
+	return c.histograms
+}
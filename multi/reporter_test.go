@@ -21,6 +21,7 @@
 package multi
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -180,10 +181,17 @@ type capturingStatsReporter struct {
 	timers                   []capturedTimer
 	histogramValueSamples    []capturedHistogramValueSamples
 	histogramDurationSamples []capturedHistogramDurationSamples
+	events                   []capturedEvent
 	capabilities             int
 	flush                    int
 }
 
+type capturedEvent struct {
+	title string
+	text  string
+	tags  map[string]string
+}
+
 type capturedCount struct {
 	name  string
 	tags  map[string]string
@@ -218,6 +226,291 @@ type capturedHistogramDurationSamples struct {
 	samples          int64
 }
 
+func TestMultiReporterEvent(t *testing.T) {
+	a, b := newCapturingStatsReporter(), newCapturingStatsReporter()
+	tags := map[string]string{"foo": "bar"}
+
+	NewMultiReporter(a, tally.NullStatsReporter).(tally.EventReporter).
+		Event("deploy", "v1", tags)
+	NewMultiCachedReporter(b).(tally.EventReporter).
+		Event("deploy", "v2", tags)
+
+	assert.Equal(t, []capturedEvent{{"deploy", "v1", tags}}, a.events)
+	assert.Equal(t, []capturedEvent{{"deploy", "v2", tags}}, b.events)
+}
+
+func TestReporters(t *testing.T) {
+	a, b := newCapturingStatsReporter(), newCapturingStatsReporter()
+	tagless := tagless{a}
+
+	r := NewMultiReporter(a, tagless)
+	reporters, ok := Reporters(r)
+	require.True(t, ok)
+	assert.Equal(t, []tally.BaseStatsReporter{a, tagless}, reporters)
+	assert.True(t, reporters[0].Capabilities().Tagging())
+	assert.False(t, reporters[1].Capabilities().Tagging())
+	assert.True(t, r.Capabilities().Reporting())
+	assert.False(t, r.Capabilities().Tagging())
+
+	// The returned slice is a copy.
+	reporters[0] = nil
+	reporters, _ = Reporters(r)
+	assert.Equal(t, a, reporters[0])
+
+	cached := NewMultiCachedReporter(a, b)
+	reporters, ok = Reporters(cached)
+	require.True(t, ok)
+	assert.Equal(t, []tally.BaseStatsReporter{a, b}, reporters)
+	assert.True(t, cached.Capabilities().Tagging())
+
+	_, ok = Reporters(a)
+	assert.False(t, ok)
+}
+
+func TestMultiReporterHistogramCapabilities(t *testing.T) {
+	a := newCapturingStatsReporter()
+	assert.True(t, tally.SupportsHistograms(NewMultiReporter(a).Capabilities()))
+	assert.True(t, tally.SupportsHistograms(NewMultiCachedReporter(a).Capabilities()))
+
+	r := NewMultiReporter(a, noHistograms{a})
+	assert.False(t, tally.SupportsHistograms(r.Capabilities()))
+	assert.True(t, r.Capabilities().Tagging())
+}
+
+// noHistograms is a reporter without histogram support.
+type noHistograms struct {
+	*capturingStatsReporter
+}
+
+func (r noHistograms) Capabilities() tally.Capabilities {
+	return r
+}
+
+func (r noHistograms) Histograms() bool {
+	return false
+}
+
+func TestMultiReporterFlushWithError(t *testing.T) {
+	a, b, c :=
+		newCapturingStatsReporter(),
+		newCapturingStatsReporter(),
+		newCapturingStatsReporter()
+	errA, errC := errors.New("a failed"), errors.New("c failed")
+
+	r := NewMultiReporter(a, b)
+	fr, ok := r.(tally.FlushErrorReporter)
+	require.True(t, ok)
+	assert.NoError(t, fr.FlushWithError())
+	assert.Equal(t, 1, a.flush)
+	assert.Equal(t, 1, b.flush)
+
+	r = NewMultiReporter(flushFailing{a, errA}, b)
+	assert.Equal(t, errA, r.(tally.FlushErrorReporter).FlushWithError())
+	assert.Equal(t, 2, a.flush)
+	assert.Equal(t, 2, b.flush)
+
+	r = NewMultiReporter(flushFailing{a, errA}, b, flushFailing{c, errC})
+	err := r.(tally.FlushErrorReporter).FlushWithError()
+	require.Error(t, err)
+	assert.Equal(t, "a failed; c failed", err.Error())
+	assert.Equal(t, 3, a.flush)
+	assert.Equal(t, 3, b.flush)
+	assert.Equal(t, 1, c.flush)
+
+	// Closing a root scope surfaces the error of the final flush.
+	_, closer := tally.NewRootScope(tally.ScopeOptions{
+		Reporter: NewMultiReporter(b, flushFailing{c, errC}),
+	}, time.Hour)
+	assert.Equal(t, errC, closer.Close())
+}
+
+func TestMultiCachedReporterScope(t *testing.T) {
+	a, b := newCapturingStatsReporter(), newCapturingStatsReporter()
+	errB := errors.New("b failed")
+
+	r := NewMultiCachedReporter(a, flushFailing{b, errB})
+	_, ok := r.(tally.FlushErrorReporter)
+	require.True(t, ok)
+
+	s, closer := tally.NewRootScope(tally.ScopeOptions{CachedReporter: r}, time.Hour)
+	s.Counter("foo").Inc(3)
+	s.Histogram("bar", tally.ValueBuckets{1, 10}).RecordValue(5)
+	assert.Equal(t, errB, closer.Close())
+
+	// A single report of each cached handle reaches every backend, the
+	// buckets of the histogram through the handles each backend allocated.
+	for _, r := range []*capturingStatsReporter{a, b} {
+		assert.Equal(t, []capturedCount{{"foo", map[string]string{}, 3}}, r.counts)
+		require.Equal(t, 1, len(r.histogramValueSamples))
+		assert.Equal(t, capturedHistogramValueSamples{
+			"bar", map[string]string{}, 1, 10, 1,
+		},
+			r.histogramValueSamples[0])
+		assert.Equal(t, 1, r.flush)
+	}
+}
+
+func TestMultiReporterOptionalInterfaces(t *testing.T) {
+	ext, plain := newExtendedReporter(), newCapturingStatsReporter()
+	r := NewMultiReporter(ext, plain)
+
+	r.(tally.FloatCounterReporter).ReportCounterFloat("foo", nil, 1.5)
+	assert.Equal(t, []float64{1.5}, ext.floats)
+	assert.Equal(t, []capturedCount{{"foo", nil, 2}}, plain.counts)
+
+	r.(tally.MeterReporter).ReportMeter("bar", nil, []float64{1, 2})
+	assert.Equal(t, [][]float64{{1, 2}}, ext.meters)
+}
+
+func TestMultiCachedReporterOptionalInterfaces(t *testing.T) {
+	ext, plain := newExtendedReporter(), newCapturingStatsReporter()
+	r := NewMultiCachedReporter(ext, plain)
+
+	md := tally.Metadata{Help: "the foo"}
+	mr := r.(tally.MetadataCachedStatsReporter)
+	mr.AllocateCounterWithMetadata("foo", nil, md).(tally.CachedFloatCount).
+		ReportCountFloat(2.5)
+	mr.AllocateGaugeWithMetadata("baz", nil, md).ReportGauge(1)
+	mr.AllocateTimerWithMetadata("qux", nil, md).ReportTimer(time.Second)
+	mr.AllocateHistogramWithMetadata("bzz", nil, tally.ValueBuckets{1}, md).
+		ValueBucket(0, 1).ReportSamples(1)
+	assert.Equal(t, []string{"the foo", "the foo", "the foo", "the foo"}, ext.helps)
+	assert.Equal(t, []float64{2.5}, ext.floats)
+	assert.Equal(t, []capturedCount{{"foo", nil, 3}}, plain.counts)
+	assert.Equal(t, 1, len(plain.gauges))
+	assert.Equal(t, 1, len(plain.timers))
+	assert.Equal(t, 1, len(plain.histogramValueSamples))
+
+	r.(tally.CachedMeterReporter).AllocateMeter("bar", nil).
+		ReportMeter([]float64{1, 2})
+	assert.Equal(t, [][]float64{{1, 2}}, ext.meters)
+}
+
+// extendedReporter is a reporter implementing the optional interfaces of
+// float counters, meters and metadata.
+type extendedReporter struct {
+	*capturingStatsReporter
+	floats []float64
+	meters [][]float64
+	helps  []string
+}
+
+func newExtendedReporter() *extendedReporter {
+	return &extendedReporter{capturingStatsReporter: newCapturingStatsReporter()}
+}
+
+func (r *extendedReporter) ReportCounterFloat(
+	name string,
+	tags map[string]string,
+	value float64,
+) {
+	r.floats = append(r.floats, value)
+}
+
+func (r *extendedReporter) ReportMeter(
+	name string,
+	tags map[string]string,
+	samples []float64,
+) {
+	r.meters = append(r.meters, samples)
+}
+
+func (r *extendedReporter) AllocateMeter(
+	name string,
+	tags map[string]string,
+) tally.CachedMeter {
+	return cachedMeter{fn: func(samples []float64) {
+		r.meters = append(r.meters, samples)
+	}}
+}
+
+func (r *extendedReporter) AllocateCounterWithMetadata(
+	name string,
+	tags map[string]string,
+	md tally.Metadata,
+) tally.CachedCount {
+	r.helps = append(r.helps, md.Help)
+	return cachedFloatCount{fn: func(value float64) {
+		r.floats = append(r.floats, value)
+	}}
+}
+
+func (r *extendedReporter) AllocateGaugeWithMetadata(
+	name string,
+	tags map[string]string,
+	md tally.Metadata,
+) tally.CachedGauge {
+	r.helps = append(r.helps, md.Help)
+	return r.AllocateGauge(name, tags)
+}
+
+func (r *extendedReporter) AllocateTimerWithMetadata(
+	name string,
+	tags map[string]string,
+	md tally.Metadata,
+) tally.CachedTimer {
+	r.helps = append(r.helps, md.Help)
+	return r.AllocateTimer(name, tags)
+}
+
+func (r *extendedReporter) AllocateHistogramWithMetadata(
+	name string,
+	tags map[string]string,
+	buckets tally.Buckets,
+	md tally.Metadata,
+) tally.CachedHistogram {
+	r.helps = append(r.helps, md.Help)
+	return r.AllocateHistogram(name, tags, buckets)
+}
+
+type cachedFloatCount struct {
+	fn func(value float64)
+}
+
+func (c cachedFloatCount) ReportCount(value int64) {
+	c.fn(float64(value))
+}
+
+func (c cachedFloatCount) ReportCountFloat(value float64) {
+	c.fn(value)
+}
+
+type cachedMeter struct {
+	fn func(samples []float64)
+}
+
+func (m cachedMeter) ReportMeter(samples []float64) {
+	m.fn(samples)
+}
+
+// flushFailing is a reporter whose flushes fail with err.
+type flushFailing struct {
+	*capturingStatsReporter
+	err error
+}
+
+func (r flushFailing) FlushWithError() error {
+	r.Flush()
+	return r.err
+}
+
+// tagless is a reporter without tagging support.
+type tagless struct {
+	*capturingStatsReporter
+}
+
+func (r tagless) Capabilities() tally.Capabilities {
+	return r
+}
+
+func (r tagless) Reporting() bool {
+	return true
+}
+
+func (r tagless) Tagging() bool {
+	return false
+}
+
 func newCapturingStatsReporter() *capturingStatsReporter {
 	return &capturingStatsReporter{}
 }
@@ -337,6 +630,10 @@ func (r *capturingStatsReporter) Flush() {
 	r.flush++
 }
 
+func (r *capturingStatsReporter) Event(title, text string, tags map[string]string) {
+	r.events = append(r.events, capturedEvent{title, text, tags})
+}
+
 type cachedCount struct {
 	fn func(value int64)
 }
@@ -0,0 +1,104 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// NameLengthMode describes how a scope handles metric names that exceed
+// its configured MaxNameLength.
+type NameLengthMode int
+
+const (
+	// TruncateNameLengthMode truncates names that are too long, keeping the
+	// longest prefix of the name that fits within the limit.
+	TruncateNameLengthMode NameLengthMode = iota
+
+	// PanicNameLengthMode panics at metric creation if a name is too long.
+	PanicNameLengthMode
+)
+
+// limitName applies the scope's name length limit to a sanitized metric
+// name, returning the name to use and whether it exceeded the limit. The
+// limit applies to the fully qualified name, so only the part of the name
+// following the scope prefix is ever truncated. If nothing of the name would
+// remain after truncation, limitName panics rather than letting distinct
+// metrics collapse into the same series.
+func (s *scope) limitName(name string) (string, bool) {
+	truncated, exceeded := s.truncateName(name)
+	if !exceeded {
+		return name, false
+	}
+	if s.nameLengthMode == PanicNameLengthMode || len(truncated) == 0 {
+		// NB: A name that panics is never created, so every call is a
+		// creation attempt and is always counted.
+		s.countNameLengthExceeded(true)
+		panic(fmt.Sprintf(
+			"metric name %q exceeds max name length %d",
+			s.fullyQualifiedName(name), s.maxNameLength,
+		))
+	}
+
+	return truncated, true
+}
+
+// truncateName returns a sanitized metric name truncated to the scope's name
+// length limit and whether it exceeded the limit.
+func (s *scope) truncateName(name string) (string, bool) {
+	if s.maxNameLength <= 0 {
+		return name, false
+	}
+
+	fullLen := len(name)
+	if len(s.prefix) > 0 {
+		fullLen += len(s.prefix) + len(s.separator)
+	}
+	if fullLen <= s.maxNameLength {
+		return name, false
+	}
+
+	return truncateName(name, len(name)-(fullLen-s.maxNameLength)), true
+}
+
+// countNameLengthExceeded increments the name length exceeded meta counter
+// if enabled and the name of a newly created metric exceeded the limit.
+func (s *scope) countNameLengthExceeded(exceeded bool) {
+	if exceeded && s.nameLengthExceeded != nil {
+		s.nameLengthExceeded.Inc(1)
+	}
+}
+
+// truncateName truncates a name to at most n bytes without splitting a
+// multibyte character.
+func truncateName(name string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if n >= len(name) {
+		return name
+	}
+	for n > 0 && !utf8.RuneStart(name[n]) {
+		n--
+	}
+	return name[:n]
+}
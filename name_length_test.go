@@ -0,0 +1,135 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxNameLengthTruncate(t *testing.T) {
+	root, closer := NewRootScope(ScopeOptions{
+		Prefix:                    "foo",
+		MaxNameLength:             10,
+		NameLengthMode:            TruncateNameLengthMode,
+		NameLengthExceededCounter: "name-length-exceeded",
+	}, 0)
+	defer closer.Close()
+
+	s := root.(*scope)
+	s.Counter("below").Inc(1)
+	s.Counter("at1234").Inc(1)
+	s.Counter("above12345").Inc(1)
+	s.Counter("above12345").Inc(1)
+
+	counters := s.Snapshot().Counters()
+	assert.EqualValues(t, 1, counters["foo.below+"].Value())
+	assert.EqualValues(t, 1, counters["foo.at1234+"].Value())
+	assert.EqualValues(t, 2, counters["foo.above1+"].Value())
+	// Only the creation of the over long counter is counted, not lookups.
+	assert.EqualValues(t, 1, counters["foo.name-length-exceeded+"].Value())
+}
+
+func TestMaxNameLengthTruncatePrefixTooLong(t *testing.T) {
+	root, closer := NewRootScope(ScopeOptions{
+		Prefix:        "averylongprefix",
+		MaxNameLength: 10,
+	}, 0)
+	defer closer.Close()
+
+	// Nothing of the name survives truncation, so rather than distinct
+	// metrics merging into the same series creation panics.
+	assert.Panics(t, func() {
+		root.Counter("a")
+	})
+	assert.Panics(t, func() {
+		root.Counter("b")
+	})
+}
+
+func TestMaxNameLengthTruncateSubScope(t *testing.T) {
+	root, closer := NewRootScope(ScopeOptions{
+		Prefix:        "foo",
+		MaxNameLength: 10,
+	}, 0)
+	defer closer.Close()
+
+	root.SubScope("bar").Gauge("bazqux").Update(1)
+
+	gauges := root.(*scope).Snapshot().Gauges()
+	assert.EqualValues(t, 1, gauges["foo.bar.ba+"].Value())
+}
+
+func TestMaxNameLengthTruncateMultibyte(t *testing.T) {
+	root, closer := NewRootScope(ScopeOptions{MaxNameLength: 6}, 0)
+	defer closer.Close()
+
+	// "ab" followed by two 3 byte characters, the limit falls within the
+	// second multibyte character which must be dropped entirely.
+	root.Counter("ab日本").Inc(1)
+
+	counters := root.(*scope).Snapshot().Counters()
+	assert.EqualValues(t, 1, counters["ab日+"].Value())
+}
+
+func TestMaxNameLengthPanic(t *testing.T) {
+	root, closer := NewRootScope(ScopeOptions{
+		Prefix:                    "foo",
+		MaxNameLength:             10,
+		NameLengthMode:            PanicNameLengthMode,
+		NameLengthExceededCounter: "name-length-exceeded",
+	}, 0)
+	defer closer.Close()
+
+	assert.NotPanics(t, func() {
+		root.Counter("below")
+		root.Timer("at1234")
+	})
+	assert.Panics(t, func() {
+		root.Histogram("above12345", DefaultBuckets)
+	})
+
+	counters := root.(*scope).Snapshot().Counters()
+	assert.EqualValues(t, 1, counters["foo.name-length-exceeded+"].Value())
+}
+
+func TestTruncateName(t *testing.T) {
+	tests := []struct {
+		name     string
+		n        int
+		expected string
+	}{
+		{name: "abc", n: 5, expected: "abc"},
+		{name: "abc", n: 3, expected: "abc"},
+		{name: "abc", n: 2, expected: "ab"},
+		{name: "abc", n: 0, expected: ""},
+		{name: "abc", n: -1, expected: ""},
+		{name: "é", n: 1, expected: ""},
+		{name: "aé", n: 2, expected: "a"},
+		{name: "aé", n: 3, expected: "aé"},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, truncateName(tt.name, tt.n), "name=%q n=%d", tt.name, tt.n)
+	}
+}
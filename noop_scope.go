@@ -0,0 +1,185 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"context"
+	"time"
+)
+
+// NewNoopScope returns a Scope that discards every metric without any cost:
+// it has no registry and no reporting goroutine, its Counter, Gauge, Timer
+// and Histogram return shared no-op metrics and its Tagged and SubScope
+// return the scope itself, so none of its methods allocate. Its capabilities
+// report neither reporting nor tagging.
+//
+// Unlike NoopScope, which is a root scope reporting to NullStatsReporter and
+// still creates and tracks its metrics and subscopes, it is meant as a sink
+// for code that is instrumented but whose metrics are never needed.
+func NewNoopScope() Scope {
+	return theNoopScope
+}
+
+var theNoopScope = &noopScope{}
+
+type noopScope struct{}
+
+func (s *noopScope) Counter(name string) Counter {
+	return noopMetric{}
+}
+
+func (s *noopScope) Gauge(name string) Gauge {
+	return noopMetric{}
+}
+
+func (s *noopScope) Timer(name string) Timer {
+	return noopMetric{}
+}
+
+func (s *noopScope) Histogram(name string, buckets Buckets) Histogram {
+	return noopMetric{}
+}
+
+func (s *noopScope) Tagged(tags map[string]string) Scope {
+	return s
+}
+
+func (s *noopScope) SubScope(name string) Scope {
+	return s
+}
+
+func (s *noopScope) Capabilities() Capabilities {
+	return capabilitiesNone
+}
+
+// NB: The scope implements the interfaces of the package level helpers so
+// that they return the shared no-op metrics and the scope itself rather
+// than falling back to the Scope methods.
+
+func (s *noopScope) counterWithOptions(name string, opts CounterOptions) Counter {
+	return noopMetric{}
+}
+
+func (s *noopScope) rateLimitedCounter(name string, minInterval time.Duration) Counter {
+	return noopMetric{}
+}
+
+func (s *noopScope) gaugeWithOptions(name string, opts GaugeOptions) Gauge {
+	return noopMetric{}
+}
+
+func (s *noopScope) trackingGauge(name string) Gauge {
+	return noopMetric{}
+}
+
+func (s *noopScope) timerWithOptions(name string, opts TimerOptions) Timer {
+	return noopMetric{}
+}
+
+func (s *noopScope) percentileTimer(name string, percentiles []float64) Timer {
+	return noopMetric{}
+}
+
+func (s *noopScope) histogramWithOptions(
+	name string,
+	buckets Buckets,
+	opts HistogramOptions,
+) Histogram {
+	return noopMetric{}
+}
+
+func (s *noopScope) meter(name string) Meter {
+	return noopMeter{}
+}
+
+func (s *noopScope) reportsMeters() bool {
+	return false
+}
+
+func (s *noopScope) transaction(counterName, timerName string) Transaction {
+	return noopMetric{}
+}
+
+func (s *noopScope) tryTagged(tags map[string]string) (Scope, error) {
+	return s, nil
+}
+
+func (s *noopScope) subScopeFromTags(tagKeys []string, tags map[string]string) Scope {
+	return s
+}
+
+func (s *noopScope) subScopeWithSeparator(name, separator string) Scope {
+	return s
+}
+
+func (s *noopScope) child(opts ChildOptions) Scope {
+	return s
+}
+
+// noopMetric is the Counter, Gauge, Timer, Histogram and Transaction of
+// noopScope, and the recorder of their stopwatches.
+type noopMetric struct{}
+
+func (m noopMetric) Inc(delta int64) {
+}
+
+func (m noopMetric) Dec(delta int64) {
+}
+
+func (m noopMetric) IncFloat(delta float64) {
+}
+
+func (m noopMetric) Update(value float64) {
+}
+
+func (m noopMetric) Add(delta float64) {
+}
+
+func (m noopMetric) Record(value time.Duration) {
+}
+
+func (m noopMetric) RecordValue(value float64) {
+}
+
+func (m noopMetric) Observe(d time.Duration) {
+}
+
+func (m noopMetric) RecordDuration(value time.Duration) {
+}
+
+func (m noopMetric) RecordFunc(f func()) {
+	f()
+}
+
+func (m noopMetric) RecordDurationFunc(f func()) {
+	f()
+}
+
+func (m noopMetric) Start() Stopwatch {
+	return NewStopwatch(time.Time{}, m)
+}
+
+func (m noopMetric) StartContext(ctx context.Context) Stopwatch {
+	return NewStopwatch(time.Time{}, m)
+}
+
+func (m noopMetric) RecordStopwatch(stopwatchStart time.Time) {
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoopScope(t *testing.T) {
+	s := NewNoopScope()
+	tags := map[string]string{"a": "b"}
+
+	assert.True(t, s == s.Tagged(tags))
+	assert.True(t, s.Tagged(tags) == s.Tagged(tags))
+	assert.True(t, s == s.SubScope("sub").Tagged(tags))
+	assert.True(t, s == NewNoopScope())
+	assert.False(t, s.Capabilities().Reporting())
+	assert.False(t, s.Capabilities().Tagging())
+
+	called := 0
+	s.Timer("t").RecordFunc(func() { called++ })
+	s.Histogram("h", DefaultBuckets).RecordDurationFunc(func() { called++ })
+	assert.Equal(t, 2, called)
+}
+
+func TestNoopScopeZeroAllocs(t *testing.T) {
+	s := NewNoopScope()
+	tags := map[string]string{"a": "b"}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		sub := s.Tagged(tags).SubScope("sub")
+		sub.Counter("c").Inc(1)
+		sub.Gauge("g").Update(1)
+		sub.Timer("t").Record(time.Millisecond)
+		sub.Timer("t").Start().Stop()
+		sub.Timer("t").StartContext(context.Background()).Stop()
+		sub.Histogram("h", DefaultBuckets).RecordValue(1)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+func TestNoopScopeHelpers(t *testing.T) {
+	s := NewNoopScope()
+	tags := map[string]string{"a": "b"}
+	tagKeys := []string{"a"}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		sub := SubScopeWithSeparator(s, "sub", "/")
+		sub = SubScopeFromTags(sub, tagKeys, tags)
+		sub = Child(sub, ChildOptions{Prefix: "child", Tags: tags})
+		sub, _ = TryTagged(sub, tags)
+		CounterWithOptions(sub, "c", CounterOptions{}).Inc(1)
+		RateLimitedCounter(sub, "c", time.Second).Inc(1)
+		GaugeWithOptions(sub, "g", GaugeOptions{}).Update(1)
+		TrackingGauge(sub, "g").Update(1)
+		TimerWithOptions(sub, "t", TimerOptions{}).Record(time.Millisecond)
+		ScopeMeter(sub, "m").Record(1)
+		NewTransaction(sub, "c", "t").Observe(time.Millisecond)
+	})
+	assert.Equal(t, float64(0), allocs)
+
+	assert.True(t, s == SubScopeWithSeparator(s, "sub", "/"))
+	assert.True(t, s == Child(s, ChildOptions{Prefix: "child"}))
+	assert.False(t, ReportsMeters(s))
+	timer, err := PercentileTimer(s, "t", []float64{50})
+	assert.NoError(t, err)
+	assert.Equal(t, noopMetric{}, timer)
+	h, err := HistogramWithOptions(s, "h", DefaultBuckets, HistogramOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, noopMetric{}, h)
+}
@@ -0,0 +1,257 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package prometheus
+
+import (
+	"math"
+	"strconv"
+	"sync"
+
+	prom "github.com/m3db/prometheus_client_golang/prometheus"
+	dto "github.com/m3db/prometheus_client_model/go"
+	"github.com/uber-go/tally"
+)
+
+// ImportOptions is a set of options for a reporter importing metrics from a
+// Prometheus gatherer.
+type ImportOptions struct {
+	// Gatherer is the Prometheus gatherer to import metrics from.
+	Gatherer prom.Gatherer
+
+	// OnGatherError defines a method to call when gathering the metrics
+	// fails, the metrics gathered successfully are still imported. Use nil
+	// to ignore errors.
+	OnGatherError func(err error)
+}
+
+// NewImportingReporter returns a tally.StatsReporter that forwards to the
+// given reporter and on each flush also imports the metric families of the
+// Prometheus gatherer into it before flushing it, e.g. to unify metrics of
+// dependencies using Prometheus client_golang with tally's reporting.
+// Labels are mapped to tags and families are translated as follows:
+//
+//   - Counters are reported as tally counters with the delta since the last
+//     flush, truncated to an integer with the fraction carried over to the
+//     next flushes.
+//   - Gauges and untyped metrics are reported as tally gauges.
+//   - Histograms are reported as tally value histograms with the delta of
+//     each bucket since the last flush.
+//   - Summaries report each quantile as a gauge tagged with "quantile", and
+//     their sample count as a counter with the "_count" name suffix.
+//
+// The sums of histograms and summaries, timestamps and exemplars are not
+// imported. Native histograms are not supported by the client this package
+// uses and are not imported.
+func NewImportingReporter(r tally.StatsReporter, opts ImportOptions) tally.StatsReporter {
+	return &importingReporter{
+		StatsReporter: r,
+		opts:          opts,
+		counters:      make(map[string]importedCounter),
+		histograms:    make(map[string][]uint64),
+	}
+}
+
+type importingReporter struct {
+	tally.StatsReporter
+	opts ImportOptions
+
+	sync.Mutex
+	counters   map[string]importedCounter
+	histograms map[string][]uint64
+}
+
+// importedCounter is the last value of an imported counter and the part of
+// it reported so far, the rest being the fraction not reported yet.
+type importedCounter struct {
+	value    float64
+	reported float64
+}
+
+func (r *importingReporter) Flush() {
+	r.importFamilies()
+	r.StatsReporter.Flush()
+}
+
+// FlushWithError implements tally.FlushErrorReporter, returning the error of
+// the underlying reporter if it implements it. Errors gathering the metrics
+// are still passed to OnGatherError.
+func (r *importingReporter) FlushWithError() error {
+	r.importFamilies()
+	if fr, ok := r.StatsReporter.(tally.FlushErrorReporter); ok {
+		return fr.FlushWithError()
+	}
+	r.StatsReporter.Flush()
+	return nil
+}
+
+// ReportCounterFloat implements tally.FloatCounterReporter, the value is
+// rounded if the underlying reporter does not implement it.
+func (r *importingReporter) ReportCounterFloat(
+	name string,
+	tags map[string]string,
+	value float64,
+) {
+	if fr, ok := r.StatsReporter.(tally.FloatCounterReporter); ok {
+		fr.ReportCounterFloat(name, tags, value)
+	} else {
+		r.ReportCounter(name, tags, int64(math.Round(value)))
+	}
+}
+
+// ReportMeter implements tally.MeterReporter, the values are discarded if
+// the underlying reporter does not implement it.
+func (r *importingReporter) ReportMeter(
+	name string,
+	tags map[string]string,
+	samples []float64,
+) {
+	if mr, ok := r.StatsReporter.(tally.MeterReporter); ok {
+		mr.ReportMeter(name, tags, samples)
+	}
+}
+
+// Event implements tally.EventReporter, forwarding events to the underlying
+// reporter if it supports them.
+func (r *importingReporter) Event(title, text string, tags map[string]string) {
+	if er, ok := r.StatsReporter.(tally.EventReporter); ok {
+		er.Event(title, text, tags)
+	}
+}
+
+func (r *importingReporter) importFamilies() {
+	families, err := r.opts.Gatherer.Gather()
+	if err != nil && r.opts.OnGatherError != nil {
+		r.opts.OnGatherError(err)
+	}
+
+	r.Lock()
+	for _, family := range families {
+		r.importFamily(family)
+	}
+	r.Unlock()
+}
+
+func (r *importingReporter) importFamily(family *dto.MetricFamily) {
+	name := family.GetName()
+	for _, m := range family.GetMetric() {
+		tags := make(map[string]string, len(m.GetLabel()))
+		for _, l := range m.GetLabel() {
+			tags[l.GetName()] = l.GetValue()
+		}
+
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			r.importCounter(name, tags, m.GetCounter().GetValue())
+		case dto.MetricType_GAUGE:
+			r.ReportGauge(name, tags, m.GetGauge().GetValue())
+		case dto.MetricType_UNTYPED:
+			r.ReportGauge(name, tags, m.GetUntyped().GetValue())
+		case dto.MetricType_HISTOGRAM:
+			r.importHistogram(name, tags, m.GetHistogram())
+		case dto.MetricType_SUMMARY:
+			r.importSummary(name, tags, m.GetSummary())
+		}
+	}
+}
+
+// importCounter reports the delta of a cumulative counter since the last
+// flush, a decrease means the counter was reset and its value is the delta.
+// The fraction of the delta is carried over to the next flushes.
+func (r *importingReporter) importCounter(
+	name string,
+	tags map[string]string,
+	value float64,
+) {
+	key := tally.KeyForPrefixedStringMap(name, tags)
+	var reported float64
+	if prev, ok := r.counters[key]; ok && value >= prev.value {
+		reported = prev.reported
+	}
+	delta := int64(value - reported)
+	r.counters[key] = importedCounter{value: value, reported: reported + float64(delta)}
+
+	if delta != 0 {
+		r.ReportCounter(name, tags, delta)
+	}
+}
+
+func (r *importingReporter) importHistogram(
+	name string,
+	tags map[string]string,
+	h *dto.Histogram,
+) {
+	var (
+		buckets = h.GetBucket()
+		bounds  = make(tally.ValueBuckets, 0, len(buckets))
+		counts  = make([]uint64, 0, len(buckets)+1)
+		prev    uint64
+	)
+	for _, b := range buckets {
+		if math.IsInf(b.GetUpperBound(), 1) {
+			continue
+		}
+		bounds = append(bounds, b.GetUpperBound())
+		counts = append(counts, b.GetCumulativeCount()-prev)
+		prev = b.GetCumulativeCount()
+	}
+	// The samples above the highest bucket.
+	counts = append(counts, h.GetSampleCount()-prev)
+
+	key := tally.KeyForPrefixedStringMap(name, tags)
+	last, ok := r.histograms[key]
+	if ok && len(last) != len(counts) {
+		ok = false
+	}
+	r.histograms[key] = counts
+
+	lower := -math.MaxFloat64
+	for i, count := range counts {
+		upper := math.MaxFloat64
+		if i < len(bounds) {
+			upper = bounds[i]
+		}
+
+		samples := count
+		if ok && count >= last[i] {
+			samples = count - last[i]
+		}
+		if samples > 0 {
+			r.ReportHistogramValueSamples(name, tags, bounds, lower, upper, int64(samples))
+		}
+		lower = upper
+	}
+}
+
+func (r *importingReporter) importSummary(
+	name string,
+	tags map[string]string,
+	s *dto.Summary,
+) {
+	for _, q := range s.GetQuantile() {
+		qtags := make(map[string]string, len(tags)+1)
+		for k, v := range tags {
+			qtags[k] = v
+		}
+		qtags["quantile"] = strconv.FormatFloat(q.GetQuantile(), 'f', -1, 64)
+		r.ReportGauge(name, qtags, q.GetValue())
+	}
+	r.importCounter(name+"_count", tags, float64(s.GetSampleCount()))
+}
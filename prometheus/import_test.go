@@ -0,0 +1,225 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package prometheus
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	prom "github.com/m3db/prometheus_client_golang/prometheus"
+	"github.com/uber-go/tally"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type capturingStatsReporter struct {
+	tally.StatsReporter
+	counters   map[string]int64
+	gauges     map[string]float64
+	histograms map[string]map[float64]int64
+	flushes    int
+}
+
+func newCapturingStatsReporter() *capturingStatsReporter {
+	r := &capturingStatsReporter{StatsReporter: tally.NullStatsReporter}
+	r.reset()
+	return r
+}
+
+func (r *capturingStatsReporter) reset() {
+	r.counters = make(map[string]int64)
+	r.gauges = make(map[string]float64)
+	r.histograms = make(map[string]map[float64]int64)
+}
+
+func (r *capturingStatsReporter) ReportCounter(
+	name string,
+	tags map[string]string,
+	value int64,
+) {
+	r.counters[tally.KeyForPrefixedStringMap(name, tags)] += value
+}
+
+func (r *capturingStatsReporter) ReportGauge(
+	name string,
+	tags map[string]string,
+	value float64,
+) {
+	r.gauges[tally.KeyForPrefixedStringMap(name, tags)] = value
+}
+
+func (r *capturingStatsReporter) ReportHistogramValueSamples(
+	name string,
+	tags map[string]string,
+	buckets tally.Buckets,
+	bucketLowerBound,
+	bucketUpperBound float64,
+	samples int64,
+) {
+	key := tally.KeyForPrefixedStringMap(name, tags)
+	if r.histograms[key] == nil {
+		r.histograms[key] = make(map[float64]int64)
+	}
+	r.histograms[key][bucketUpperBound] += samples
+}
+
+func (r *capturingStatsReporter) Flush() {
+	r.flushes++
+}
+
+func TestImportingReporter(t *testing.T) {
+	registry := prom.NewRegistry()
+	counter := prom.NewCounterVec(prom.CounterOpts{
+		Name: "requests",
+		Help: "requests",
+	}, []string{"method"})
+	gauge := prom.NewGauge(prom.GaugeOpts{Name: "inflight", Help: "inflight"})
+	histogram := prom.NewHistogram(prom.HistogramOpts{
+		Name:    "size",
+		Help:    "size",
+		Buckets: []float64{1, 10},
+	})
+	summary := prom.NewSummary(prom.SummaryOpts{
+		Name:       "latency",
+		Help:       "latency",
+		Objectives: map[float64]float64{0.5: 0.01},
+	})
+	registry.MustRegister(counter, gauge, histogram, summary)
+
+	capture := newCapturingStatsReporter()
+	r := NewImportingReporter(capture, ImportOptions{Gatherer: registry})
+
+	counter.WithLabelValues("GET").Add(3)
+	gauge.Set(2)
+	histogram.Observe(0.5)
+	histogram.Observe(5)
+	histogram.Observe(50)
+	summary.Observe(4)
+
+	r.Flush()
+	assert.Equal(t, 1, capture.flushes)
+	assert.Equal(t, map[string]int64{
+		"requests+method=GET": 3,
+		"latency_count+":      1,
+	}, capture.counters)
+	assert.Equal(t, map[string]float64{
+		"inflight+":            2,
+		"latency+quantile=0.5": 4,
+	}, capture.gauges)
+	assert.Equal(t, map[string]map[float64]int64{
+		"size+": {1: 1, 10: 1, math.MaxFloat64: 1},
+	}, capture.histograms)
+
+	// Only the deltas since the last flush are reported for counters and
+	// histograms.
+	capture.reset()
+	counter.WithLabelValues("GET").Add(1)
+	histogram.Observe(5)
+
+	r.Flush()
+	require.Equal(t, 2, capture.flushes)
+	assert.Equal(t, map[string]int64{"requests+method=GET": 1}, capture.counters)
+	assert.Equal(t, map[string]map[float64]int64{"size+": {10: 1}}, capture.histograms)
+}
+
+func TestImportingReporterFractionalCounter(t *testing.T) {
+	registry := prom.NewRegistry()
+	counter := prom.NewCounter(prom.CounterOpts{Name: "bytes", Help: "bytes"})
+	registry.MustRegister(counter)
+
+	capture := newCapturingStatsReporter()
+	r := NewImportingReporter(capture, ImportOptions{Gatherer: registry})
+
+	// The fractions add up across flushes rather than being truncated away.
+	var reported []int64
+	for i := 0; i < 5; i++ {
+		capture.reset()
+		counter.Add(0.4)
+		r.Flush()
+		reported = append(reported, capture.counters["bytes+"])
+	}
+	assert.Equal(t, []int64{0, 0, 1, 0, 1}, reported)
+}
+
+func TestImportingReporterOptionalInterfaces(t *testing.T) {
+	registry := prom.NewRegistry()
+	registry.MustRegister(prom.NewGauge(prom.GaugeOpts{Name: "inflight", Help: "inflight"}))
+
+	capture := newCapturingStatsReporter()
+	r := NewImportingReporter(capture, ImportOptions{Gatherer: registry})
+	r.(tally.FloatCounterReporter).ReportCounterFloat("requests", nil, 1.5)
+	r.(tally.MeterReporter).ReportMeter("sizes", nil, []float64{1})
+	r.(tally.EventReporter).Event("deploy", "v1", nil)
+	assert.Equal(t, map[string]int64{"requests+": 2}, capture.counters)
+	assert.NoError(t, r.(tally.FlushErrorReporter).FlushWithError())
+	assert.Equal(t, 1, capture.flushes)
+	assert.Equal(t, map[string]float64{"inflight+": 0}, capture.gauges)
+
+	ext := &extendedStatsReporter{
+		capturingStatsReporter: newCapturingStatsReporter(),
+		err:                    errors.New("flush failed"),
+	}
+	r = NewImportingReporter(ext, ImportOptions{Gatherer: registry})
+	r.(tally.FloatCounterReporter).ReportCounterFloat("requests", nil, 1.5)
+	r.(tally.MeterReporter).ReportMeter("sizes", nil, []float64{1})
+	r.(tally.EventReporter).Event("deploy", "v1", nil)
+	assert.Equal(t, []float64{1.5}, ext.floats)
+	assert.Equal(t, [][]float64{{1}}, ext.meters)
+	assert.Equal(t, []string{"deploy"}, ext.events)
+	assert.Equal(t, ext.err, r.(tally.FlushErrorReporter).FlushWithError())
+	assert.Equal(t, map[string]float64{"inflight+": 0}, ext.gauges)
+}
+
+// extendedStatsReporter is a reporter implementing the optional interfaces
+// of float counters, meters, events and flush errors.
+type extendedStatsReporter struct {
+	*capturingStatsReporter
+	floats []float64
+	meters [][]float64
+	events []string
+	err    error
+}
+
+func (r *extendedStatsReporter) ReportCounterFloat(
+	name string,
+	tags map[string]string,
+	value float64,
+) {
+	r.floats = append(r.floats, value)
+}
+
+func (r *extendedStatsReporter) ReportMeter(
+	name string,
+	tags map[string]string,
+	samples []float64,
+) {
+	r.meters = append(r.meters, samples)
+}
+
+func (r *extendedStatsReporter) Event(title, text string, tags map[string]string) {
+	r.events = append(r.events, title)
+}
+
+func (r *extendedStatsReporter) FlushWithError() error {
+	return r.err
+}
@@ -360,8 +360,17 @@ func (r *reporter) counterVec(
 
 // AllocateCounter implements tally.CachedStatsReporter.
 func (r *reporter) AllocateCounter(name string, tags map[string]string) tally.CachedCount {
+	return r.AllocateCounterWithMetadata(name, tags, tally.Metadata{})
+}
+
+// AllocateCounterWithMetadata implements tally.MetadataCachedStatsReporter.
+func (r *reporter) AllocateCounterWithMetadata(
+	name string,
+	tags map[string]string,
+	md tally.Metadata,
+) tally.CachedCount {
 	tagKeys := keysFromMap(tags)
-	counterVec, err := r.counterVec(name, tagKeys, name+" counter")
+	counterVec, err := r.counterVec(name, tagKeys, help(md, name+" counter"))
 	if err != nil {
 		r.onRegisterError(err)
 		return noopMetric{}
@@ -409,8 +418,17 @@ func (r *reporter) gaugeVec(
 
 // AllocateGauge implements tally.CachedStatsReporter.
 func (r *reporter) AllocateGauge(name string, tags map[string]string) tally.CachedGauge {
+	return r.AllocateGaugeWithMetadata(name, tags, tally.Metadata{})
+}
+
+// AllocateGaugeWithMetadata implements tally.MetadataCachedStatsReporter.
+func (r *reporter) AllocateGaugeWithMetadata(
+	name string,
+	tags map[string]string,
+	md tally.Metadata,
+) tally.CachedGauge {
 	tagKeys := keysFromMap(tags)
-	gaugeVec, err := r.gaugeVec(name, tagKeys, name+" gauge")
+	gaugeVec, err := r.gaugeVec(name, tagKeys, help(md, name+" gauge"))
 	if err != nil {
 		r.onRegisterError(err)
 		return noopMetric{}
@@ -524,6 +542,15 @@ func (r *reporter) histogramVec(
 
 // AllocateTimer implements tally.CachedStatsReporter.
 func (r *reporter) AllocateTimer(name string, tags map[string]string) tally.CachedTimer {
+	return r.AllocateTimerWithMetadata(name, tags, tally.Metadata{})
+}
+
+// AllocateTimerWithMetadata implements tally.MetadataCachedStatsReporter.
+func (r *reporter) AllocateTimerWithMetadata(
+	name string,
+	tags map[string]string,
+	md tally.Metadata,
+) tally.CachedTimer {
 	var (
 		timer tally.CachedTimer
 		err   error
@@ -533,7 +560,7 @@ func (r *reporter) AllocateTimer(name string, tags map[string]string) tally.Cach
 	switch timerType {
 	case HistogramTimerType:
 		var histogramVec *prom.HistogramVec
-		histogramVec, err = r.histogramVec(name, tagKeys, name+" histogram", buckets)
+		histogramVec, err = r.histogramVec(name, tagKeys, help(md, name+" histogram"), buckets)
 		if err == nil {
 			t := &cachedMetric{histogram: histogramVec.With(tags)}
 			t.reportTimer = t.reportTimerHistogram
@@ -541,7 +568,7 @@ func (r *reporter) AllocateTimer(name string, tags map[string]string) tally.Cach
 		}
 	case SummaryTimerType:
 		var summaryVec *prom.SummaryVec
-		summaryVec, err = r.summaryVec(name, tagKeys, name+" summary", objectives)
+		summaryVec, err = r.summaryVec(name, tagKeys, help(md, name+" summary"), objectives)
 		if err == nil {
 			t := &cachedMetric{summary: summaryVec.With(tags)}
 			t.reportTimer = t.reportTimerSummary
@@ -557,13 +584,25 @@ func (r *reporter) AllocateTimer(name string, tags map[string]string) tally.Cach
 	return timer
 }
 
+// AllocateHistogram implements tally.CachedStatsReporter.
 func (r *reporter) AllocateHistogram(
 	name string,
 	tags map[string]string,
 	buckets tally.Buckets,
+) tally.CachedHistogram {
+	return r.AllocateHistogramWithMetadata(name, tags, buckets, tally.Metadata{})
+}
+
+// AllocateHistogramWithMetadata implements tally.MetadataCachedStatsReporter.
+func (r *reporter) AllocateHistogramWithMetadata(
+	name string,
+	tags map[string]string,
+	buckets tally.Buckets,
+	md tally.Metadata,
 ) tally.CachedHistogram {
 	tagKeys := keysFromMap(tags)
-	histogramVec, err := r.histogramVec(name, tagKeys, name+" histogram", buckets.AsValues())
+	histogramVec, err := r.histogramVec(name, tagKeys,
+		help(md, name+" histogram"), buckets.AsValues())
 	if err != nil {
 		r.onRegisterError(err)
 		return noopMetric{}
@@ -586,6 +625,14 @@ func (r *reporter) Tagging() bool {
 // Flush does nothing for prometheus
 func (r *reporter) Flush() {}
 
+// help returns the help text of the metadata, or the default if it has none.
+func help(md tally.Metadata, defaultHelp string) string {
+	if md.Help != "" {
+		return md.Help
+	}
+	return defaultHelp
+}
+
 var metricIDKeyValue = "1"
 
 // NOTE: this generates a canonical MetricID for a given name+label keys,
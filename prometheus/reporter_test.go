@@ -22,6 +22,8 @@ package prometheus
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -417,6 +419,36 @@ func TestAlreadyRegisteredCounter(t *testing.T) {
 	}
 }
 
+func TestHelpMetadata(t *testing.T) {
+	registry := prom.NewRegistry()
+	r := NewReporter(Options{Registerer: registry})
+	scope, closer := tally.NewRootScope(tally.ScopeOptions{
+		CachedReporter: r,
+		Separator:      DefaultSeparator,
+	}, 0)
+
+	tally.CounterWithOptions(scope, "requests", tally.CounterOptions{
+		Help: "Number of requests served.",
+	}).Inc(1)
+	tally.GaugeWithOptions(scope, "inflight", tally.GaugeOptions{
+		Help: "Number of requests in flight.",
+	}).Update(2)
+	scope.Counter("errors").Inc(1)
+
+	// Closing the scope flushes the metrics to the reporter.
+	require.NoError(t, closer.Close())
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	r.HTTPHandler().ServeHTTP(rec, req)
+	body := rec.Body.String()
+
+	assert.Contains(t, body, "# HELP requests Number of requests served.\n")
+	assert.Contains(t, body, "# HELP inflight Number of requests in flight.\n")
+	assert.Contains(t, body, "# HELP errors errors counter\n")
+	assert.Contains(t, body, "errors 1\n")
+}
+
 func gather(t *testing.T, r prom.Gatherer) []*dto.MetricFamily {
 	metrics, err := r.Gather()
 	require.NoError(t, err)
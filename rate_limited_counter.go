@@ -0,0 +1,135 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimitedCounter returns a counter with the given name on a scope created
+// by NewRootScope that buffers its increments and adds them to the counter of
+// the scope with that name at most once per minInterval, e.g. to coalesce
+// the increments of an extremely hot counter. An increment adds the buffered
+// increments when at least minInterval elapsed since they were last added,
+// and each report adds them before the counters are reported, including the
+// final report on Close, so no increment is lost. Buffered increments are
+// not visible in snapshots until they are added. Calling it again with the
+// same name returns the same counter with the first minInterval. On other
+// Scope implementations it returns s.Counter(name).
+func RateLimitedCounter(s Scope, name string, minInterval time.Duration) Counter {
+	if r, ok := s.(rateLimitedCounterScope); ok {
+		return r.rateLimitedCounter(name, minInterval)
+	}
+	return s.Counter(name)
+}
+
+type rateLimitedCounterScope interface {
+	rateLimitedCounter(name string, minInterval time.Duration) Counter
+}
+
+type rateLimitedCounter struct {
+	pending      int64
+	pendingFloat uint64 // float64 bits
+	lastAdded    int64  // unix nanos
+
+	name        string
+	counter     Counter
+	minInterval int64
+	clock       Clock
+}
+
+func (s *scope) rateLimitedCounter(name string, minInterval time.Duration) Counter {
+	c := s.Counter(name)
+	if _, ok := c.(*counter); !ok {
+		return c
+	}
+	pinCounter(c)
+
+	s.cm.Lock()
+	defer s.cm.Unlock()
+
+	for _, r := range s.rateLimitedCounters {
+		if r.name == name {
+			return r
+		}
+	}
+	r := &rateLimitedCounter{
+		name:        name,
+		counter:     c,
+		minInterval: int64(minInterval),
+		clock:       s.clock,
+		lastAdded:   s.clock.Now().UnixNano(),
+	}
+	s.rateLimitedCounters = append(s.rateLimitedCounters, r)
+	return r
+}
+
+// addRateLimitedCounters adds the buffered increments of all counters
+// returned by RateLimitedCounter, must be called with the counters lock held.
+func (s *scope) addRateLimitedCounters() {
+	for _, r := range s.rateLimitedCounters {
+		r.add()
+	}
+}
+
+func (r *rateLimitedCounter) Inc(delta int64) {
+	atomic.AddInt64(&r.pending, delta)
+	r.maybeAdd()
+}
+
+func (r *rateLimitedCounter) Dec(delta int64) {
+	r.Inc(-delta)
+}
+
+func (r *rateLimitedCounter) IncFloat(delta float64) {
+	for {
+		old := atomic.LoadUint64(&r.pendingFloat)
+		updated := math.Float64bits(math.Float64frombits(old) + delta)
+		if atomic.CompareAndSwapUint64(&r.pendingFloat, old, updated) {
+			break
+		}
+	}
+	r.maybeAdd()
+}
+
+// maybeAdd adds the buffered increments if minInterval elapsed since they
+// were last added.
+func (r *rateLimitedCounter) maybeAdd() {
+	now := r.clock.Now().UnixNano()
+	last := atomic.LoadInt64(&r.lastAdded)
+	if now-last < r.minInterval {
+		return
+	}
+	if atomic.CompareAndSwapInt64(&r.lastAdded, last, now) {
+		r.add()
+	}
+}
+
+func (r *rateLimitedCounter) add() {
+	if delta := atomic.SwapInt64(&r.pending, 0); delta != 0 {
+		r.counter.Inc(delta)
+	}
+	if bits := atomic.SwapUint64(&r.pendingFloat, 0); bits != 0 {
+		r.counter.IncFloat(math.Float64frombits(bits))
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitedCounter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	globalNow = func() time.Time { return now }
+	defer func() { globalNow = time.Now }()
+
+	s := NewTestScope("", nil)
+	c := RateLimitedCounter(s, "hits", time.Second)
+	for i := 0; i < 1000; i++ {
+		c.Inc(1)
+	}
+	c.IncFloat(0.5)
+
+	// The increments are buffered until the interval elapsed.
+	counters := s.Snapshot().Counters()
+	assert.EqualValues(t, 0, counters["hits+"].Value())
+
+	now = now.Add(time.Second)
+	c.Inc(1)
+	counters = s.Snapshot().Counters()
+	assert.EqualValues(t, 1002, counters["hits+"].Value())
+	assert.Equal(t, 1001.5, counters["hits+"].ValueFloat())
+
+	c.Dec(2)
+	assert.EqualValues(t, 1002, s.Snapshot().Counters()["hits+"].Value())
+
+	assert.Equal(t, c, RateLimitedCounter(s, "hits", time.Minute))
+}
+
+func TestRateLimitedCounterReport(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{Reporter: r}, 0)
+
+	c := RateLimitedCounter(root.SubScope("sub"), "hits", time.Hour)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.Inc(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The final report on Close adds the buffered increments.
+	r.cg.Add(1)
+	assert.NoError(t, closer.Close())
+	r.WaitAll()
+	assert.EqualValues(t, 10000, r.getCounters()["sub.hits"].val)
+}
+
+func TestRateLimitedCounterOtherScopes(t *testing.T) {
+	s := NewNoopScope()
+	assert.Equal(t, s.Counter("hits"), RateLimitedCounter(s, "hits", time.Second))
+
+	ts := NewTestScope("", nil)
+	c := RateLimitedCounter(NewSourceTaggingScope(ts), "hits", 0)
+	c.Inc(1)
+	counters := ts.Snapshot().Counters()
+	assert.Len(t, counters, 1)
+	for _, counter := range counters {
+		assert.EqualValues(t, 1, counter.Value())
+		assert.Contains(t, counter.Tags()[SourceTagKey], "rate_limited_counter_test.go")
+	}
+}
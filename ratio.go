@@ -0,0 +1,110 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import "sync/atomic"
+
+// RatioOptions is a set of options for a ratio registered with RegisterRatio.
+type RatioOptions struct {
+	// SkipZeroDenominator leaves the ratio unchanged for intervals in which
+	// the denominator did not change, rather than updating it to zero. Note
+	// that without it the ratio is updated, and so re-emitted, as zero on
+	// every idle interval.
+	SkipZeroDenominator bool
+}
+
+// RegisterRatio registers a gauge with the given name on a scope created by
+// NewRootScope that is updated on each report with the ratio of the deltas of
+// the numerator and denominator counters of the scope over the interval, e.g.
+// an error rate from error and request counters.
+//
+// The ratio is computed after the counters of the scope are reported, so it
+// always reflects the same interval as the reported counter values.
+func RegisterRatio(s Scope, name, numerator, denominator string, opts RatioOptions) {
+	if r, ok := s.(ratioRegisterer); ok {
+		r.registerRatio(name, numerator, denominator, opts)
+	}
+}
+
+type ratioRegisterer interface {
+	registerRatio(name, numerator, denominator string, opts RatioOptions)
+}
+
+type ratio struct {
+	name        string
+	gauge       *gauge
+	numerator   *counter
+	denominator *counter
+	opts        RatioOptions
+	panics      Counter
+}
+
+func (s *scope) registerRatio(name, numerator, denominator string, opts RatioOptions) {
+	g, gaugeOK := s.Gauge(name).(*gauge)
+	n, numeratorOK := s.Counter(numerator).(*counter)
+	d, denominatorOK := s.Counter(denominator).(*counter)
+	if !gaugeOK || !numeratorOK || !denominatorOK {
+		// NB: A name rejected by SanitizeReject, already reported to OnError.
+		return
+	}
+	r := ratio{
+		name:        s.fullyQualifiedName(s.sanitizer.Name(name)),
+		gauge:       g,
+		numerator:   n,
+		denominator: d,
+		opts:        opts,
+	}
+	r.gauge.idle.pin()
+	r.numerator.idle.pin()
+	r.denominator.idle.pin()
+	if s.recoverCallbackPanics {
+		r.panics = callbackPanics(s.registry.root, s.callbackPanicsCounter, r.name)
+	}
+
+	s.gm.Lock()
+	s.ratios = append(s.ratios, r)
+	s.gm.Unlock()
+}
+
+// updateRatios updates all ratio gauges from the deltas of their counters in
+// the last report, must be called with the gauges lock held.
+func (s *scope) updateRatios() {
+	for _, r := range s.ratios {
+		s.updateRatio(r)
+	}
+}
+
+func (s *scope) updateRatio(r ratio) {
+	if s.recoverCallbackPanics {
+		defer s.registry.recoverCallback("ratio", r.name, r.panics)
+	}
+
+	denominator := atomic.LoadInt64(&r.denominator.reported)
+	if denominator == 0 {
+		if !r.opts.SkipZeroDenominator {
+			r.gauge.Update(0)
+		}
+		return
+	}
+
+	numerator := atomic.LoadInt64(&r.numerator.reported)
+	r.gauge.Update(float64(numerator) / float64(denominator))
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRatio(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{Reporter: r}, 0)
+
+	s := root.(*scope)
+	RegisterRatio(s, "error-rate", "errors", "requests", RatioOptions{})
+	RegisterRatio(s, "error-rate-skip", "errors", "requests", RatioOptions{
+		SkipZeroDenominator: true,
+	})
+
+	s.Counter("errors").Inc(1)
+	s.Counter("requests").Inc(4)
+
+	r.cg.Add(2)
+	r.gg.Add(2)
+	s.report(r)
+	r.WaitAll()
+
+	gauges := r.getGauges()
+	assert.Equal(t, 0.25, gauges["error-rate"].val)
+	assert.Equal(t, 0.25, gauges["error-rate-skip"].val)
+
+	// No requests in the interval, only the ratio without skipping is
+	// updated to zero.
+	r.gg.Add(1)
+	s.report(r)
+	r.WaitAll()
+
+	gauges = r.getGauges()
+	assert.Equal(t, float64(0), gauges["error-rate"].val)
+	assert.Equal(t, 0.25, gauges["error-rate-skip"].val)
+
+	// The flush on close is another idle interval that re-emits the ratio
+	// without skipping.
+	r.gg.Add(1)
+	assert.NoError(t, closer.Close())
+	r.WaitAll()
+}
+
+func TestRatioCachedReport(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{CachedReporter: r}, 0)
+
+	s := root.(*scope)
+	RegisterRatio(s.SubScope("rpc"), "error-rate", "errors", "requests", RatioOptions{})
+
+	sub := s.SubScope("rpc")
+	sub.Counter("errors").Inc(3)
+	sub.Counter("requests").Inc(6)
+
+	r.cg.Add(2)
+	r.gg.Add(1)
+	s.reportRegistry()
+	r.WaitAll()
+
+	assert.Equal(t, 0.5, r.getGauges()["rpc.error-rate"].val)
+
+	r.gg.Add(1)
+	assert.NoError(t, closer.Close())
+	r.WaitAll()
+
+	assert.Equal(t, float64(0), r.getGauges()["rpc.error-rate"].val)
+}
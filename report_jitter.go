@@ -0,0 +1,90 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math/rand"
+	"time"
+)
+
+// reportJitter draws the random offsets of ScopeOptions.ReportingIntervalJitter,
+// it is only used by the report loop so needs no locking.
+type reportJitter struct {
+	max time.Duration
+	rnd *rand.Rand
+}
+
+// newReportJitter returns the jitter capped at the interval, or nil if there
+// is no jitter.
+func newReportJitter(interval, jitter time.Duration, seed int64) *reportJitter {
+	if jitter <= 0 {
+		return nil
+	}
+	if jitter > interval {
+		jitter = interval
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &reportJitter{max: jitter, rnd: rand.New(rand.NewSource(seed))}
+}
+
+// offset returns a random offset in [0, max), or zero for a nil jitter.
+func (j *reportJitter) offset() time.Duration {
+	if j == nil {
+		return 0
+	}
+	return time.Duration(j.rnd.Int63n(int64(j.max)))
+}
+
+// jitteredReportLoop is used by the root scope for periodic reporting with
+// the reports delayed by the jitter from each multiple of the interval since
+// the loop started.
+func (s *scope) jitteredReportLoop(interval time.Duration, jitter *reportJitter) {
+	now := globalNow()
+	next := now.Add(interval)
+	timer := time.NewTimer(next.Sub(now) + jitter.offset())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			s.reportLoopRun()
+			now = globalNow()
+			next = nextJitteredReport(next, now, interval)
+			timer.Reset(next.Sub(now) + jitter.offset())
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// nextJitteredReport returns the multiple of the interval to report after,
+// before jitter, once the report scheduled after prev ran at now. Like a
+// ticker, intervals that have already passed when a report runs late are
+// skipped.
+func nextJitteredReport(prev, now time.Time, interval time.Duration) time.Time {
+	next := prev.Add(interval)
+	if next.Before(now) {
+		next = next.Add(now.Sub(next).Truncate(interval) + interval)
+	}
+	return next
+}
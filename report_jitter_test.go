@@ -0,0 +1,121 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportJitterSchedule(t *testing.T) {
+	const (
+		interval = 10 * time.Second
+		max      = 3 * time.Second
+	)
+	// Drive the schedule of the report loop with a fake clock that reads
+	// exactly when each report is due.
+	schedule := func(seed int64) []time.Time {
+		jitter := newReportJitter(interval, max, seed)
+		now := time.Date(2021, 3, 14, 11, 0, 0, 0, time.UTC)
+		next := now.Add(interval)
+		var flushes []time.Time
+		for i := 0; i < 20; i++ {
+			now = next.Add(jitter.offset())
+			flushes = append(flushes, now)
+			next = nextJitteredReport(next, now, interval)
+		}
+		return flushes
+	}
+
+	flushes := schedule(1)
+	start := time.Date(2021, 3, 14, 11, 0, 0, 0, time.UTC)
+	gaps := make(map[time.Duration]struct{})
+	for i, flushed := range flushes {
+		base := start.Add(time.Duration(i+1) * interval)
+		assert.False(t, flushed.Before(base), "flush %d at %v", i, flushed)
+		assert.True(t, flushed.Before(base.Add(max)), "flush %d at %v", i, flushed)
+		if i > 0 {
+			gap := flushed.Sub(flushes[i-1])
+			assert.True(t, gap > interval-max && gap < interval+max, "gap %v", gap)
+			gaps[gap] = struct{}{}
+		}
+	}
+	assert.True(t, len(gaps) > 1, "gaps do not vary")
+
+	// The same seed gives the same schedule.
+	assert.Equal(t, flushes, schedule(1))
+	assert.NotEqual(t, flushes, schedule(2))
+}
+
+func TestReportJitterBounds(t *testing.T) {
+	assert.Nil(t, newReportJitter(time.Second, 0, 1))
+	assert.Equal(t, time.Duration(0), (*reportJitter)(nil).offset())
+
+	// Jitter larger than the interval is capped at the interval.
+	jitter := newReportJitter(time.Second, time.Minute, 1)
+	assert.Equal(t, time.Second, jitter.max)
+	for i := 0; i < 100; i++ {
+		offset := jitter.offset()
+		assert.True(t, offset >= 0 && offset < time.Second, "offset %v", offset)
+	}
+}
+
+func TestNextJitteredReportSkipsMissedIntervals(t *testing.T) {
+	prev := time.Date(2021, 3, 14, 11, 0, 0, 0, time.UTC)
+	assert.Equal(t, prev.Add(time.Second),
+		nextJitteredReport(prev, prev.Add(500*time.Millisecond), time.Second))
+	assert.Equal(t, prev.Add(4*time.Second),
+		nextJitteredReport(prev, prev.Add(3500*time.Millisecond), time.Second))
+}
+
+func TestJitteredReportLoop(t *testing.T) {
+	const (
+		interval = 20 * time.Millisecond
+		jitter   = 10 * time.Millisecond
+	)
+	r := &flushTimeReporter{
+		testStatsReporter: newTestStatsReporter(),
+		flushes:           make(chan time.Time, 100),
+	}
+	_, closer := NewRootScope(ScopeOptions{
+		Reporter:                    r,
+		ReportingIntervalJitter:     jitter,
+		ReportingIntervalJitterSeed: 1,
+	}, interval)
+	defer closer.Close()
+
+	var flushes []time.Time
+	for len(flushes) < 3 {
+		select {
+		case flushed := <-r.flushes:
+			flushes = append(flushes, flushed)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timed out waiting for jittered flush")
+		}
+	}
+	for i := 1; i < len(flushes); i++ {
+		gap := flushes[i].Sub(flushes[i-1])
+		assert.True(t, gap > interval-jitter, "gap %v", gap)
+	}
+}
@@ -77,6 +77,98 @@ type StatsReporter interface {
 	)
 }
 
+// EventReporter is an optional interface implemented by reporters that support
+// point-in-time events, such as deploy markers or incident annotations.
+// Events bypass periodic aggregation and are handed to the reporter as soon
+// as they are emitted, the reporter may send them immediately or on its next
+// flush.
+type EventReporter interface {
+	// Event reports an event with a title, descriptive text and tags.
+	Event(title, text string, tags map[string]string)
+}
+
+// QueueDepthReporter is an optional interface implemented by reporters that
+// buffer metrics in a queue before they are emitted, a growing queue depth
+// gives early warning that the backend is not keeping up.
+type QueueDepthReporter interface {
+	// QueueDepth returns the number of metrics currently queued.
+	QueueDepth() int
+}
+
+// FlushErrorReporter is an optional interface implemented by reporters
+// whose flushes can fail, scopes flush them with FlushWithError instead of
+// Flush so that the error of the final flush is returned when the root scope
+// is closed. The errors of the periodic flushes and of the final one are
+// passed to ScopeOptions.OnError if set, and are otherwise ignored.
+type FlushErrorReporter interface {
+	// FlushWithError flushes all reported values like Flush and returns
+	// whether that failed.
+	FlushWithError() error
+}
+
+// FloatCounterReporter is an optional interface implemented by reporters
+// that support counters with fractional values, scopes report the counters
+// incremented with Counter.IncFloat through it. Other reporters get the
+// values rounded through ReportCounter.
+type FloatCounterReporter interface {
+	// ReportCounterFloat reports a counter value with a fraction.
+	ReportCounterFloat(name string, tags map[string]string, value float64)
+}
+
+// MeterReporter is an optional interface implemented by reporters that
+// support meters, scopes report the values recorded by each meter through it.
+// The values of meters are discarded when reporting to other reporters.
+type MeterReporter interface {
+	// ReportMeter reports the values recorded by a meter since the last
+	// report in the order they were recorded.
+	ReportMeter(name string, tags map[string]string, samples []float64)
+}
+
+// Metadata is descriptive metadata of a metric set when it is created.
+type Metadata struct {
+	// Help is a description of the metric, e.g. for the HELP line of
+	// exposition formats.
+	Help string
+}
+
+// MetadataCachedStatsReporter is an optional interface implemented by cached
+// reporters that use the Metadata of metrics, scopes allocate metrics created
+// with metadata through it. Other reporters ignore metadata.
+type MetadataCachedStatsReporter interface {
+	// AllocateCounterWithMetadata pre allocates a counter data structure
+	// with name, tags & metadata.
+	AllocateCounterWithMetadata(
+		name string,
+		tags map[string]string,
+		md Metadata,
+	) CachedCount
+
+	// AllocateGaugeWithMetadata pre allocates a gauge data structure with
+	// name, tags & metadata.
+	AllocateGaugeWithMetadata(
+		name string,
+		tags map[string]string,
+		md Metadata,
+	) CachedGauge
+
+	// AllocateTimerWithMetadata pre allocates a timer data structure with
+	// name, tags & metadata.
+	AllocateTimerWithMetadata(
+		name string,
+		tags map[string]string,
+		md Metadata,
+	) CachedTimer
+
+	// AllocateHistogramWithMetadata pre allocates a histogram data structure
+	// with name, tags, buckets & metadata.
+	AllocateHistogramWithMetadata(
+		name string,
+		tags map[string]string,
+		buckets Buckets,
+		md Metadata,
+	) CachedHistogram
+}
+
 // CachedStatsReporter is a backend for Scopes that pre allocates all
 // counter, gauges, timers & histograms. This is harder to implement but more performant.
 type CachedStatsReporter interface {
@@ -109,11 +201,32 @@ type CachedStatsReporter interface {
 	) CachedHistogram
 }
 
+// CachedMeterReporter is an optional interface implemented by cached
+// reporters that support meters, like MeterReporter.
+type CachedMeterReporter interface {
+	// AllocateMeter pre allocates a meter data structure with name & tags.
+	AllocateMeter(
+		name string,
+		tags map[string]string,
+	) CachedMeter
+}
+
 // CachedCount interface for reporting an individual counter
 type CachedCount interface {
 	ReportCount(value int64)
 }
 
+// CachedFloatCount is an optional interface implemented by a CachedCount
+// that supports fractional values, like FloatCounterReporter.
+type CachedFloatCount interface {
+	ReportCountFloat(value float64)
+}
+
+// CachedMeter interface for reporting an individual meter
+type CachedMeter interface {
+	ReportMeter(samples []float64)
+}
+
 // CachedGauge interface for reporting an individual gauge
 type CachedGauge interface {
 	ReportGauge(value float64)
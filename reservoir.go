@@ -0,0 +1,214 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// reservoir is a fixed size uniform random sample of a stream of values,
+// maintained using reservoir sampling. The values are held by the caller,
+// which also guards the reservoir with its own lock.
+type reservoir struct {
+	size  int // zero retains all values
+	count int64
+	rand  *sampleRand
+}
+
+func newReservoir(size int, rnd *sampleRand) reservoir {
+	return reservoir{size: size, rand: rnd}
+}
+
+// index counts a value of the stream and returns where to store it among the
+// n retained values: n to append it, or once the reservoir is full the index
+// of the value it replaces with probability size/count, or -1 to drop it.
+func (r *reservoir) index(n int) int {
+	r.count++
+	if r.size <= 0 || n < r.size {
+		return n
+	}
+	if i := r.rand.int63n(r.count); i < int64(r.size) {
+		return int(i)
+	}
+	return -1
+}
+
+// appendDuration adds a value to the stream of the retained values.
+func (r *reservoir) appendDuration(values []time.Duration, v time.Duration) []time.Duration {
+	switch i := r.index(len(values)); {
+	case i == len(values):
+		values = append(values, v)
+	case i >= 0:
+		values[i] = v
+	}
+	return values
+}
+
+// appendValue adds a value to the stream of the retained values.
+func (r *reservoir) appendValue(values []float64, v float64) []float64 {
+	switch i := r.index(len(values)); {
+	case i == len(values):
+		values = append(values, v)
+	case i >= 0:
+		values[i] = v
+	}
+	return values
+}
+
+// reset restarts the stream, the caller discards the retained values.
+func (r *reservoir) reset() {
+	r.count = 0
+}
+
+// valueReservoir is a reservoir of values safe for concurrent use.
+type valueReservoir struct {
+	sync.Mutex
+	reservoir
+	values []float64
+}
+
+func newValueReservoir(size int, rnd *sampleRand) *valueReservoir {
+	return &valueReservoir{
+		reservoir: newReservoir(size, rnd),
+		values:    make([]float64, 0, size),
+	}
+}
+
+// Update adds a value to the stream, retaining it with probability
+// size/count once the reservoir is full.
+func (r *valueReservoir) Update(v float64) {
+	r.Lock()
+	r.values = r.appendValue(r.values, v)
+	r.Unlock()
+}
+
+// Values returns a copy of the retained values.
+func (r *valueReservoir) Values() []float64 {
+	r.Lock()
+	values := make([]float64, len(r.values))
+	copy(values, r.values)
+	r.Unlock()
+	return values
+}
+
+// Reset discards all retained values.
+func (r *valueReservoir) Reset() {
+	r.Lock()
+	r.reset()
+	r.values = r.values[:0]
+	r.Unlock()
+}
+
+// DefaultDecayingReservoirAlpha is the default decay factor per second of
+// decaying reservoirs, which weighs values recorded 5 minutes apart by a
+// factor of about 90.
+const DefaultDecayingReservoirAlpha = 0.015
+
+// decayingReservoir is a fixed size random sample of a stream of durations
+// biased toward recent values, maintained using forward decay priority
+// sampling. Each value is weighted by exp(alpha * t) for the seconds t on its
+// clock since the reservoir was last reset and the values with the highest
+// weighted random priorities are retained.
+type decayingReservoir struct {
+	sync.Mutex
+	size     int
+	alpha    float64
+	clock    Clock
+	landmark time.Time
+	samples  prioritizedDurations
+}
+
+func newDecayingReservoir(size int, alpha float64, clock Clock) *decayingReservoir {
+	if alpha <= 0 {
+		alpha = DefaultDecayingReservoirAlpha
+	}
+	return &decayingReservoir{
+		size:     size,
+		alpha:    alpha,
+		clock:    clock,
+		landmark: clockNow(clock),
+		samples:  make(prioritizedDurations, 0, size),
+	}
+}
+
+// Update adds a value to the stream.
+func (r *decayingReservoir) Update(v time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+
+	t := clockNow(r.clock).Sub(r.landmark).Seconds()
+	// NB: 1 - Float64 is in (0, 1] so the priority is always finite.
+	priority := math.Exp(r.alpha*t) / (1 - rand.Float64())
+
+	s := prioritizedDuration{priority: priority, value: v}
+	if len(r.samples) < r.size {
+		heap.Push(&r.samples, s)
+	} else if priority > r.samples[0].priority {
+		r.samples[0] = s
+		heap.Fix(&r.samples, 0)
+	}
+}
+
+// Values returns a copy of the retained values.
+func (r *decayingReservoir) Values() []time.Duration {
+	r.Lock()
+	values := make([]time.Duration, len(r.samples))
+	for i := range r.samples {
+		values[i] = r.samples[i].value
+	}
+	r.Unlock()
+	return values
+}
+
+// Reset discards all retained values and restarts the decay from now.
+func (r *decayingReservoir) Reset() {
+	r.Lock()
+	r.landmark = clockNow(r.clock)
+	r.samples = r.samples[:0]
+	r.Unlock()
+}
+
+type prioritizedDuration struct {
+	priority float64
+	value    time.Duration
+}
+
+// prioritizedDurations is a min heap of durations by priority.
+type prioritizedDurations []prioritizedDuration
+
+func (p prioritizedDurations) Len() int           { return len(p) }
+func (p prioritizedDurations) Less(i, j int) bool { return p[i].priority < p[j].priority }
+func (p prioritizedDurations) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+func (p *prioritizedDurations) Push(x interface{}) {
+	*p = append(*p, x.(prioritizedDuration))
+}
+
+func (p *prioritizedDurations) Pop() interface{} {
+	old := *p
+	x := old[len(old)-1]
+	*p = old[:len(old)-1]
+	return x
+}
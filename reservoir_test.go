@@ -0,0 +1,98 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservoir(t *testing.T) {
+	r := newValueReservoir(10, nil)
+	for i := 0; i < 5; i++ {
+		r.Update(float64(i))
+	}
+	assert.Equal(t, []float64{0, 1, 2, 3, 4}, r.Values())
+
+	for i := 5; i < 1000; i++ {
+		r.Update(float64(i))
+	}
+	values := r.Values()
+	assert.Equal(t, 10, len(values))
+	for _, v := range values {
+		assert.True(t, v >= 0 && v < 1000)
+	}
+
+	r.Reset()
+	assert.Equal(t, 0, len(r.Values()))
+}
+
+func TestDecayingReservoirFavorsRecentValues(t *testing.T) {
+	var (
+		now   = globalNow
+		start = time.Now()
+	)
+	globalNow = func() time.Time { return start }
+	defer func() { globalNow = now }()
+
+	s := NewTestScope("", nil)
+	timer := TimerWithOptions(s, "latency", TimerOptions{
+		DecayingReservoirSize:  100,
+		DecayingReservoirAlpha: 0.1,
+	})
+
+	// Latency shifts from 10ms to 100ms a minute into the interval.
+	for i := 0; i < 1000; i++ {
+		timer.Record(10 * time.Millisecond)
+	}
+	globalNow = func() time.Time { return start.Add(time.Minute) }
+	for i := 0; i < 1000; i++ {
+		timer.Record(100 * time.Millisecond)
+	}
+
+	samples := s.Snapshot().Timers()["latency+"].Samples()
+	assert.Len(t, samples, 100)
+
+	var recent int
+	for _, v := range samples {
+		if v == 100*time.Millisecond {
+			recent++
+		}
+	}
+	// A uniform sample would retain about half of the newer values, the
+	// decay weighs them exp(6) times more than the older ones.
+	assert.True(t, recent > 95, "only %d of the samples are recent", recent)
+}
+
+func TestDecayingReservoirReset(t *testing.T) {
+	r := newDecayingReservoir(10, 0, nil)
+	assert.Equal(t, DefaultDecayingReservoirAlpha, r.alpha)
+
+	for i := 0; i < 100; i++ {
+		r.Update(time.Duration(i))
+	}
+	assert.Len(t, r.Values(), 10)
+
+	r.Reset()
+	assert.Len(t, r.Values(), 0)
+}
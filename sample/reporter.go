@@ -0,0 +1,234 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package sample provides a reporter that forwards a sampled subset of
+// series to another reporter.
+package sample
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/uber-go/tally"
+)
+
+// Options is a set of options for a sampled reporter.
+type Options struct {
+	// Fraction of series to forward in the range [0, 1].
+	Fraction float64
+
+	// SkippedCounterName if set is the name of a counter reported to the
+	// underlying reporter on each flush with the number of distinct series
+	// that were skipped since the last flush.
+	SkippedCounterName string
+}
+
+type sampledReporter struct {
+	reporter  tally.StatsReporter
+	threshold uint32
+	opts      Options
+
+	mu      sync.Mutex
+	skipped map[string]struct{}
+}
+
+// NewSampledReporter creates a new tally.StatsReporter that forwards only a
+// sampled fraction of series to the given reporter, all other series are
+// skipped. Series are sampled deterministically by their name and tags, so
+// the same series are consistently forwarded or skipped on every flush.
+//
+// Unlike sampling at emit time the scope still aggregates every series
+// locally, so the value of each forwarded series is exact. However a sum or
+// count across series on the backend only covers the forwarded series and
+// underestimates the total roughly by the sampled fraction. Scaling it by
+// 1/Fraction estimates the total, which is unbiased over the choice of series
+// but can have high variance when there are few series or their values are
+// skewed, e.g. a single hot series that happens to be skipped.
+func NewSampledReporter(r tally.StatsReporter, opts Options) tally.StatsReporter {
+	return &sampledReporter{
+		reporter:  r,
+		threshold: threshold(opts.Fraction),
+		opts:      opts,
+		skipped:   make(map[string]struct{}),
+	}
+}
+
+func threshold(fraction float64) uint32 {
+	switch {
+	case fraction <= 0:
+		return 0
+	case fraction >= 1:
+		return math.MaxUint32
+	default:
+		return uint32(fraction * math.MaxUint32)
+	}
+}
+
+// sampled returns whether the series with the given name and tags is
+// forwarded, recording it as skipped otherwise.
+func (r *sampledReporter) sampled(name string, tags map[string]string) bool {
+	if r.threshold == math.MaxUint32 {
+		return true
+	}
+
+	key := tally.KeyForPrefixedStringMap(name, tags)
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	if h.Sum32() < r.threshold {
+		return true
+	}
+
+	if r.opts.SkippedCounterName != "" {
+		r.mu.Lock()
+		r.skipped[key] = struct{}{}
+		r.mu.Unlock()
+	}
+	return false
+}
+
+func (r *sampledReporter) ReportCounter(
+	name string,
+	tags map[string]string,
+	value int64,
+) {
+	if r.sampled(name, tags) {
+		r.reporter.ReportCounter(name, tags, value)
+	}
+}
+
+// ReportCounterFloat implements tally.FloatCounterReporter, the value is
+// rounded if the underlying reporter does not implement it.
+func (r *sampledReporter) ReportCounterFloat(
+	name string,
+	tags map[string]string,
+	value float64,
+) {
+	if !r.sampled(name, tags) {
+		return
+	}
+	if fr, ok := r.reporter.(tally.FloatCounterReporter); ok {
+		fr.ReportCounterFloat(name, tags, value)
+	} else {
+		r.reporter.ReportCounter(name, tags, int64(math.Round(value)))
+	}
+}
+
+func (r *sampledReporter) ReportGauge(
+	name string,
+	tags map[string]string,
+	value float64,
+) {
+	if r.sampled(name, tags) {
+		r.reporter.ReportGauge(name, tags, value)
+	}
+}
+
+func (r *sampledReporter) ReportTimer(
+	name string,
+	tags map[string]string,
+	interval time.Duration,
+) {
+	if r.sampled(name, tags) {
+		r.reporter.ReportTimer(name, tags, interval)
+	}
+}
+
+func (r *sampledReporter) ReportHistogramValueSamples(
+	name string,
+	tags map[string]string,
+	buckets tally.Buckets,
+	bucketLowerBound,
+	bucketUpperBound float64,
+	samples int64,
+) {
+	if r.sampled(name, tags) {
+		r.reporter.ReportHistogramValueSamples(name, tags, buckets,
+			bucketLowerBound, bucketUpperBound, samples)
+	}
+}
+
+func (r *sampledReporter) ReportHistogramDurationSamples(
+	name string,
+	tags map[string]string,
+	buckets tally.Buckets,
+	bucketLowerBound,
+	bucketUpperBound time.Duration,
+	samples int64,
+) {
+	if r.sampled(name, tags) {
+		r.reporter.ReportHistogramDurationSamples(name, tags, buckets,
+			bucketLowerBound, bucketUpperBound, samples)
+	}
+}
+
+// ReportMeter implements tally.MeterReporter, the values are discarded if
+// the underlying reporter does not implement it.
+func (r *sampledReporter) ReportMeter(
+	name string,
+	tags map[string]string,
+	samples []float64,
+) {
+	if mr, ok := r.reporter.(tally.MeterReporter); ok && r.sampled(name, tags) {
+		mr.ReportMeter(name, tags, samples)
+	}
+}
+
+func (r *sampledReporter) Capabilities() tally.Capabilities {
+	return r.reporter.Capabilities()
+}
+
+func (r *sampledReporter) Flush() {
+	r.reportSkipped()
+	r.reporter.Flush()
+}
+
+// FlushWithError implements tally.FlushErrorReporter, returning the error of
+// the underlying reporter if it implements it.
+func (r *sampledReporter) FlushWithError() error {
+	r.reportSkipped()
+	if fr, ok := r.reporter.(tally.FlushErrorReporter); ok {
+		return fr.FlushWithError()
+	}
+	r.reporter.Flush()
+	return nil
+}
+
+func (r *sampledReporter) reportSkipped() {
+	if r.opts.SkippedCounterName == "" {
+		return
+	}
+
+	r.mu.Lock()
+	skipped := len(r.skipped)
+	r.skipped = make(map[string]struct{}, skipped)
+	r.mu.Unlock()
+
+	r.reporter.ReportCounter(r.opts.SkippedCounterName, nil, int64(skipped))
+}
+
+// Event forwards events to the underlying reporter if it supports them,
+// events are never sampled.
+func (r *sampledReporter) Event(title, text string, tags map[string]string) {
+	if er, ok := r.reporter.(tally.EventReporter); ok {
+		er.Event(title, text, tags)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sample
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/uber-go/tally"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingStatsReporter struct {
+	tally.StatsReporter
+	counts map[string]int64
+}
+
+func newCountingStatsReporter() *countingStatsReporter {
+	return &countingStatsReporter{
+		StatsReporter: tally.NullStatsReporter,
+		counts:        make(map[string]int64),
+	}
+}
+
+func (r *countingStatsReporter) ReportCounter(
+	name string,
+	tags map[string]string,
+	value int64,
+) {
+	r.counts[tally.KeyForPrefixedStringMap(name, tags)] = value
+}
+
+func TestSampledReporter(t *testing.T) {
+	const numSeries = 1000
+
+	capture := newCountingStatsReporter()
+	r := NewSampledReporter(capture, Options{
+		Fraction:           0.25,
+		SkippedCounterName: "skipped",
+	})
+
+	var forwarded map[string]int64
+	for flush := 0; flush < 2; flush++ {
+		capture.counts = make(map[string]int64)
+		for i := 0; i < numSeries; i++ {
+			r.ReportCounter("requests", map[string]string{"id": fmt.Sprint(i)}, 1)
+		}
+		r.Flush()
+
+		skipped := capture.counts["skipped+"]
+		delete(capture.counts, "skipped+")
+		assert.EqualValues(t, numSeries-len(capture.counts), skipped)
+		assert.InDelta(t, 0.25, float64(len(capture.counts))/numSeries, 0.05)
+
+		// The same series are forwarded on every flush.
+		if forwarded != nil {
+			assert.Equal(t, forwarded, capture.counts)
+		}
+		forwarded = capture.counts
+	}
+}
+
+func TestSampledReporterFractionBounds(t *testing.T) {
+	for _, fraction := range []float64{0, 1} {
+		capture := newCountingStatsReporter()
+		r := NewSampledReporter(capture, Options{Fraction: fraction})
+		for i := 0; i < 100; i++ {
+			r.ReportCounter("requests", map[string]string{"id": fmt.Sprint(i)}, 1)
+		}
+		assert.Len(t, capture.counts, int(fraction*100), "fraction %v", fraction)
+	}
+}
+
+func TestSampledReporterOptionalInterfaces(t *testing.T) {
+	capture := newCountingStatsReporter()
+	r := NewSampledReporter(capture, Options{Fraction: 1})
+	r.(tally.FloatCounterReporter).ReportCounterFloat("requests", nil, 1.5)
+	r.(tally.MeterReporter).ReportMeter("sizes", nil, []float64{1})
+	assert.Equal(t, map[string]int64{"requests+": 2}, capture.counts)
+	assert.NoError(t, r.(tally.FlushErrorReporter).FlushWithError())
+
+	ext := &extendedStatsReporter{
+		countingStatsReporter: newCountingStatsReporter(),
+		err:                   errors.New("flush failed"),
+	}
+	r = NewSampledReporter(ext, Options{Fraction: 1})
+	r.(tally.FloatCounterReporter).ReportCounterFloat("requests", nil, 1.5)
+	r.(tally.MeterReporter).ReportMeter("sizes", nil, []float64{1})
+	assert.Equal(t, []float64{1.5}, ext.floats)
+	assert.Equal(t, [][]float64{{1}}, ext.meters)
+	assert.Equal(t, ext.err, r.(tally.FlushErrorReporter).FlushWithError())
+
+	// Neither are the values of skipped series forwarded.
+	ext.floats, ext.meters = nil, nil
+	r = NewSampledReporter(ext, Options{Fraction: 0})
+	r.(tally.FloatCounterReporter).ReportCounterFloat("requests", nil, 1.5)
+	r.(tally.MeterReporter).ReportMeter("sizes", nil, []float64{1})
+	assert.Empty(t, ext.floats)
+	assert.Empty(t, ext.meters)
+}
+
+// extendedStatsReporter is a reporter implementing the optional interfaces
+// of float counters, meters and flush errors.
+type extendedStatsReporter struct {
+	*countingStatsReporter
+	floats []float64
+	meters [][]float64
+	err    error
+}
+
+func (r *extendedStatsReporter) ReportCounterFloat(
+	name string,
+	tags map[string]string,
+	value float64,
+) {
+	r.floats = append(r.floats, value)
+}
+
+func (r *extendedStatsReporter) ReportMeter(
+	name string,
+	tags map[string]string,
+	samples []float64,
+) {
+	r.meters = append(r.meters, samples)
+}
+
+func (r *extendedStatsReporter) FlushWithError() error {
+	return r.err
+}
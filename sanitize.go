@@ -22,6 +22,8 @@ package tally
 
 import (
 	"bytes"
+	"fmt"
+	"sort"
 )
 
 var (
@@ -65,12 +67,40 @@ type ValidCharacters struct {
 	Characters []rune
 }
 
+// SanitizeMode is how scopes handle names, tag keys and tag values with
+// invalid characters.
+type SanitizeMode int
+
+const (
+	// SanitizeReplace replaces invalid characters with the replacement
+	// character.
+	SanitizeReplace SanitizeMode = iota
+	// SanitizeReject does not create metrics with invalid characters in
+	// their name or tags.
+	SanitizeReject
+)
+
 // SanitizeOptions are the set of configurable options for sanitisation.
 type SanitizeOptions struct {
 	NameCharacters       ValidCharacters
 	KeyCharacters        ValidCharacters
 	ValueCharacters      ValidCharacters
 	ReplacementCharacter rune
+
+	// OnInvalid is how a scope handles invalid characters, they are
+	// replaced by default. With SanitizeReject, creating a metric whose name
+	// has an invalid character returns a metric that does nothing, and
+	// creating a subscope with a name or tags with an invalid character
+	// returns a scope whose metrics do nothing, such as NewNoopScope, so the
+	// mangled series is never emitted. The prefix and tags of the root scope
+	// itself are still replaced. Sanitizers created with NewSanitizer always
+	// replace.
+	OnInvalid SanitizeMode
+
+	// OnRejected if set is called with the rejected name, tag key or tag
+	// value and its first invalid character each time a metric or subscope
+	// is rejected with SanitizeReject, e.g. to log it.
+	OnRejected func(value string, invalid rune)
 }
 
 // Sanitizer sanitizes the provided input based on the function executed.
@@ -124,24 +154,37 @@ func (s sanitizer) Value(v string) string {
 	return s.valueFn(v)
 }
 
+// valid returns whether ch is a valid character.
+func (c *ValidCharacters) valid(ch rune) bool {
+	for i := 0; i < len(c.Ranges); i++ {
+		if ch >= c.Ranges[i][0] && ch <= c.Ranges[i][1] {
+			return true
+		}
+	}
+	for i := 0; i < len(c.Characters); i++ {
+		if c.Characters[i] == ch {
+			return true
+		}
+	}
+	return false
+}
+
+// firstInvalid returns the first invalid character of value, if any.
+func (c *ValidCharacters) firstInvalid(value string) (rune, bool) {
+	for _, ch := range value {
+		if !c.valid(ch) {
+			return ch, true
+		}
+	}
+	return 0, false
+}
+
 func (c *ValidCharacters) sanitizeFn(repChar rune) SanitizeFn {
 	return func(value string) string {
 		var buf *bytes.Buffer
 		for idx, ch := range value {
 			// first check if the provided character is valid
-			validCurr := false
-			for i := 0; !validCurr && i < len(c.Ranges); i++ {
-				if ch >= c.Ranges[i][0] && ch <= c.Ranges[i][1] {
-					validCurr = true
-					break
-				}
-			}
-			for i := 0; !validCurr && i < len(c.Characters); i++ {
-				if c.Characters[i] == ch {
-					validCurr = true
-					break
-				}
-			}
+			validCurr := c.valid(ch)
 
 			// if it's valid, we can optimise allocations by avoiding copying
 			if validCurr {
@@ -174,3 +217,68 @@ func (c *ValidCharacters) sanitizeFn(repChar rune) SanitizeFn {
 		return buf.String()
 	}
 }
+
+// sanitizeRejecter rejects names and tags with invalid characters for
+// SanitizeReject.
+type sanitizeRejecter struct {
+	opts   SanitizeOptions
+	errors *errorHook
+}
+
+// newSanitizeRejecter returns the rejecter of the options, or nil if invalid
+// characters are replaced.
+func newSanitizeRejecter(opts *SanitizeOptions, errHook *errorHook) *sanitizeRejecter {
+	if opts == nil || opts.OnInvalid != SanitizeReject {
+		return nil
+	}
+	return &sanitizeRejecter{opts: *opts, errors: errHook}
+}
+
+// rejectName returns whether the name has an invalid character, always false
+// for a nil rejecter.
+func (r *sanitizeRejecter) rejectName(name string) bool {
+	if r == nil {
+		return false
+	}
+	return r.reject(&r.opts.NameCharacters, name)
+}
+
+// rejectTags returns whether a tag key or value has an invalid character,
+// reporting the first in key order, always false for a nil rejecter.
+func (r *sanitizeRejecter) rejectTags(tags map[string]string) bool {
+	if r == nil {
+		return false
+	}
+
+	var invalid []string
+	for k, v := range tags {
+		if _, ok := r.opts.KeyCharacters.firstInvalid(k); ok {
+			invalid = append(invalid, k)
+		} else if _, ok := r.opts.ValueCharacters.firstInvalid(v); ok {
+			invalid = append(invalid, k)
+		}
+	}
+	if len(invalid) == 0 {
+		return false
+	}
+
+	sort.Strings(invalid)
+	k := invalid[0]
+	if !r.reject(&r.opts.KeyCharacters, k) {
+		r.reject(&r.opts.ValueCharacters, tags[k])
+	}
+	return true
+}
+
+func (r *sanitizeRejecter) reject(c *ValidCharacters, value string) bool {
+	ch, ok := c.firstInvalid(value)
+	if !ok {
+		return false
+	}
+	if r.opts.OnRejected != nil {
+		r.opts.OnRejected(value, ch)
+	}
+	r.errors.report(SanitizeErrorPhase,
+		fmt.Errorf("rejected %q with invalid character %q", value, ch))
+	return true
+}
@@ -23,6 +23,7 @@ package tally
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -58,3 +59,103 @@ func TestSanitizeTestCases(t *testing.T) {
 		require.Equal(t, tc.output, fn(tc.input))
 	}
 }
+
+type rejection struct {
+	value   string
+	invalid rune
+}
+
+func newSanitizeTestScope(mode SanitizeMode, rejected *[]rejection) TestScope {
+	valid := ValidCharacters{
+		Ranges:     AlphanumericRange,
+		Characters: UnderscoreDashDotCharacters,
+	}
+	return NewTestScopeWithOptions(ScopeOptions{
+		SanitizeOptions: &SanitizeOptions{
+			NameCharacters:       valid,
+			KeyCharacters:        valid,
+			ValueCharacters:      valid,
+			ReplacementCharacter: DefaultReplacementCharacter,
+			OnInvalid:            mode,
+			OnRejected: func(value string, invalid rune) {
+				*rejected = append(*rejected, rejection{value, invalid})
+			},
+		},
+	})
+}
+
+func TestSanitizeReplaceMode(t *testing.T) {
+	var rejected []rejection
+	s := newSanitizeTestScope(SanitizeReplace, &rejected)
+
+	s.Counter("a:b").Inc(1)
+	s.Tagged(map[string]string{"k|ey": "va:lue"}).Gauge("g").Update(1)
+
+	snap := s.Snapshot()
+	assert.Contains(t, snap.Counters(), "a_b+")
+	assert.Contains(t, snap.Gauges(), "g+k_ey=va_lue")
+	assert.Empty(t, rejected)
+}
+
+func TestSanitizeRejectMode(t *testing.T) {
+	var rejected []rejection
+	s := newSanitizeTestScope(SanitizeReject, &rejected)
+
+	s.Counter("a:b").Inc(1)
+	s.Gauge("g|1").Update(1)
+	s.Timer("t:1").Record(1)
+	s.Histogram("h:1", DefaultBuckets).RecordValue(1)
+	s.Tagged(map[string]string{"k|ey": "value"}).Counter("c").Inc(1)
+	s.Tagged(map[string]string{"key": "va:lue"}).Counter("c").Inc(1)
+	s.SubScope("sub:scope").Counter("c").Inc(1)
+
+	// Valid names and tags are unaffected.
+	s.Tagged(map[string]string{"key": "value"}).Counter("valid").Inc(1)
+
+	snap := s.Snapshot()
+	assert.Equal(t, []string{"valid+key=value"}, snapshotKeys(snap.Counters()))
+	assert.Equal(t, 1, snap.Counts().Total)
+	assert.Equal(t, []rejection{
+		{"a:b", ':'},
+		{"g|1", '|'},
+		{"t:1", ':'},
+		{"h:1", ':'},
+		{"k|ey", '|'},
+		{"va:lue", ':'},
+		{"sub:scope", ':'},
+	}, rejected)
+}
+
+func TestSanitizeRejectModeFirstTagInKeyOrder(t *testing.T) {
+	var rejected []rejection
+	s := newSanitizeTestScope(SanitizeReject, &rejected)
+
+	_, err := TryTagged(s, map[string]string{"b": "x|y", "a": "x:y"})
+	require.NoError(t, err)
+	assert.Equal(t, []rejection{{"x:y", ':'}}, rejected)
+}
+
+func TestSanitizeRejectModeHelpers(t *testing.T) {
+	var rejected []rejection
+	s := newSanitizeTestScope(SanitizeReject, &rejected)
+
+	RegisterRatio(s, "ratio:bad", "num", "den", RatioOptions{})
+	RegisterRatio(s, "ratio", "num:bad", "den", RatioOptions{})
+	GaugeFunc(s, "func:bad", func() float64 { return 1 })
+	Info(s, "info:bad", map[string]string{"key": "value"})
+	Info(s, "info", map[string]string{"key": "va:lue"})
+	SetCardinalityBudget(s, CardinalityBudgetOptions{Budget: 10, UsedGaugeName: "used:bad"})
+	s.(*scope).report(NullStatsReporter)
+
+	assert.Equal(t, []rejection{
+		{"ratio:bad", ':'},
+		{"num:bad", ':'},
+		{"func:bad", ':'},
+		{"info:bad", ':'},
+		{"va:lue", ':'},
+		{"used:bad", ':'},
+	}, rejected)
+	for _, g := range s.Snapshot().Gauges() {
+		assert.NotContains(t, g.Name(), "bad")
+	}
+}
@@ -21,6 +21,7 @@
 package tally
 
 import (
+	"fmt"
 	"io"
 	"sync"
 	"time"
@@ -63,18 +64,54 @@ type scope struct {
 	separator      string
 	prefix         string
 	tags           map[string]string
+	reportTags     map[string]string // tags with the common tags, for reporters
 	reporter       StatsReporter
 	cachedReporter CachedStatsReporter
 	baseReporter   BaseStatsReporter
 	defaultBuckets Buckets
 	sanitizer      Sanitizer
+	sanitizeReject *sanitizeRejecter
+
+	maxNameLength      int
+	nameLengthMode     NameLengthMode
+	nameLengthExceeded Counter
+
+	histogramReservoirSize int
+
+	seriesRateLimit        float64
+	seriesRateLimitBurst   int
+	seriesRateLimitDropped Counter
+
+	timerSnapshotLimit         int
+	timerSnapshotDropRemainder bool
+	timerSnapshotDropped       Counter
+
+	tagKeyCase            TagCase
+	tagValueCase          TagCase
+	tagKeyCollisionPolicy TagKeyCollisionPolicy
+
+	recoverCallbackPanics bool
+	callbackPanicsCounter string
+
+	created           time.Time
+	timeToFirstReport Gauge
+	firstReported     atomic.Bool
+	heartbeat         Counter
+	flushedMetrics    *flushedMetrics
+	processStartTime  Gauge
+	reportInterval    time.Duration
+	phaseOffset       Gauge
 
 	registry *scopeRegistry
+	clock    Clock
 
 	cm sync.RWMutex
 	gm sync.RWMutex
 	tm sync.RWMutex
 	hm sync.RWMutex
+	mm sync.RWMutex
+
+	aliases metricAliases
 
 	counters        map[string]*counter
 	countersSlice   []*counter
@@ -83,6 +120,19 @@ type scope struct {
 	histograms      map[string]*histogram
 	histogramsSlice []*histogram
 	timers          map[string]*timer
+	meters          map[string]*meter
+	metersSlice     []*meter
+	ratios          []ratio
+	infos           []info
+	gaugeFuncs      []gaugeFunc
+	trackingGauges  []*trackingGauge
+	decayingTimers  []*timer
+
+	rateLimitedCounters []*rateLimitedCounter
+	autoHistograms      map[string]*autoHistogram
+	timerPercentiles    []*timerPercentiles
+
+	cardinalityBudget *cardinalityBudget
 	// nb: deliberately skipping timersSlice as we report timers immediately,
 	// no buffering is involved.
 
@@ -100,13 +150,312 @@ type ScopeOptions struct {
 	Reporter        StatsReporter
 	CachedReporter  CachedStatsReporter
 	Separator       string
-	DefaultBuckets  Buckets
 	SanitizeOptions *SanitizeOptions
+
+	// DefaultBuckets are the buckets of the histograms created with nil
+	// buckets, such as tally.DefaultBuckets, by the root scope and all the
+	// scopes created from it, e.g. to use different buckets per environment
+	// without recompiling. Buckets passed explicitly to Histogram, including
+	// empty ones, always take precedence over DefaultBuckets, and both are
+	// replaced by CoarseHistogramBuckets once HistogramCoarseningThreshold is
+	// reached. Defaults to a set of duration buckets from 0 to 5 seconds if
+	// not set or empty.
+	DefaultBuckets Buckets
+
+	// MaxNameLength is the maximum length in bytes of a fully qualified
+	// metric name, zero means no limit. Names exceeding the limit are
+	// handled according to NameLengthMode, except that creation always
+	// panics if the scope prefix leaves no room for any of the name.
+	MaxNameLength  int
+	NameLengthMode NameLengthMode
+
+	// NameLengthExceededCounter if set is the name of a counter on the root
+	// scope that is incremented each time a metric whose name exceeds
+	// MaxNameLength is created.
+	NameLengthExceededCounter string
+
+	// HistogramCoarseningThreshold is the number of histograms that can be
+	// created across the root scope and all its subscopes before newly
+	// created histograms use CoarseHistogramBuckets instead of the buckets
+	// they were created with. Existing histograms keep their buckets. Both
+	// must be set to enable coarsening.
+	//
+	// CoarseHistogramBuckets are used by both value and duration histograms,
+	// converted with AsValues and AsDurations respectively, so values map to
+	// durations in seconds and vice versa. For example DurationBuckets{
+	// time.Second} gives value histograms a single bucket at 1.
+	HistogramCoarseningThreshold int
+	CoarseHistogramBuckets       Buckets
+
+	// AlignReportsToClock aligns reports to wall clock multiples of the
+	// reporting interval, e.g. :00, :10, :20 for a 10s interval, rather than
+	// reporting relative to when the scope was created. The first report
+	// occurs at the first boundary after creation and covers the partial
+	// interval before it. Boundaries are multiples of the interval in UTC
+	// wall clock time so DST changes have no effect. Each report targets the
+	// boundary one interval after the previous one, so small clock
+	// adjustments never cause back to back reports, while clock steps of
+	// more than an interval are followed from the next report onwards.
+	AlignReportsToClock bool
+
+	// ReportingIntervalJitter if greater than zero delays each report by a
+	// uniformly random offset in [0, jitter) drawn anew for every report,
+	// so that instances started together with the same interval do not
+	// flush at the same time. The offsets are added to the schedule of the
+	// reports rather than accumulated, so reports still occur once per
+	// interval on average and, with AlignReportsToClock, start from the
+	// boundaries. The jitter is capped at the interval so that reports never
+	// reorder or skip an interval. Zero reports without jitter.
+	ReportingIntervalJitter time.Duration
+
+	// Clock is the source of time of the scope and its subscopes, used for
+	// the stopwatches of their timers and histograms, the ticker of the
+	// report loop and the time of reports, e.g. to control time in tests.
+	// Reports with AlignReportsToClock or ReportingIntervalJitter are still
+	// scheduled on the wall clock. Defaults to the wall clock.
+	Clock Clock
+
+	// ReportingIntervalJitterSeed if not zero seeds the random offsets of
+	// ReportingIntervalJitter, e.g. for deterministic tests. The offsets are
+	// seeded from the time the scope is created otherwise.
+	ReportingIntervalJitterSeed int64
+
+	// HistogramReservoirSize if greater than zero retains a uniform random
+	// sample of up to this many raw values recorded by each histogram since
+	// the last report, available from HistogramSnapshot.Samples, sampled
+	// like MaxTimerSamplesPerInterval. This is off by default and intended
+	// for debugging bucket selection.
+	HistogramReservoirSize int
+
+	// SeriesRateLimit if greater than zero limits the emissions to each
+	// series, i.e. each metric name and set of tags, to this many per second
+	// with bursts of up to SeriesRateLimitBurst. Emissions over the limit are
+	// dropped. Each series holds its own token bucket of a few dozen bytes
+	// which is freed along with the series, so memory is bounded by the
+	// number of series.
+	SeriesRateLimit      float64
+	SeriesRateLimitBurst int
+
+	// SeriesRateLimitDroppedCounter if set is the name of a counter on the
+	// root scope that is incremented for each emission dropped by the
+	// SeriesRateLimit.
+	SeriesRateLimitDroppedCounter string
+
+	// TimeToFirstReportGauge if set is the name of a gauge on the root scope
+	// that is updated once, on the first report, with the seconds elapsed
+	// since the scope was created, e.g. "tally.time_to_first_report_seconds".
+	TimeToFirstReportGauge string
+
+	// ReportLoopHeartbeatCounter if set is the name of a counter on the root
+	// scope that is incremented after every flush of the reporter, e.g.
+	// "tally.report_loop_heartbeat", so monitoring can alert when it stops
+	// increasing because the report loop is stalled or stopped, as opposed to
+	// no metrics being emitted.
+	ReportLoopHeartbeatCounter string
+
+	// FlushedMetricsGauge if set is the name of a gauge on the root scope
+	// that is updated after every flush of a reporter implementing
+	// FlushStatsReporter with the number of metrics of each type flushed,
+	// tagged with the type using the MetricTypeTagKey tag, e.g.
+	// "tally.flushed_metrics", to monitor the metrics pipeline itself.
+	FlushedMetricsGauge string
+
+	// ProcessStartTimeGauge if set is the name of a gauge on the root scope
+	// that is updated on every report with the Unix timestamp in seconds of
+	// when the scope was created, e.g. "tally.process_start_time_seconds"
+	// after the Prometheus convention, so uptime can be computed at query
+	// time.
+	ProcessStartTimeGauge string
+
+	// ReportPhaseOffsetGauge if set is the name of a gauge on the root scope
+	// that is updated on every report of the reporting interval with the
+	// milliseconds from the nearest wall clock multiple of the interval to
+	// when the report started, negative when it started early, e.g.
+	// "tally.report_phase_offset_ms". Instances report with different
+	// offsets unless AlignReportsToClock is set, so the gauge shows the skew
+	// between instances and the residual skew of aligned reports.
+	ReportPhaseOffsetGauge string
+
+	// MaxMetricNames if greater than zero is the maximum number of distinct
+	// fully qualified metric names that can be created across the root scope
+	// and all its subscopes, metrics of different kinds with the same name
+	// count once. Once reached, metrics with new names are not created and
+	// the shared metric of the same kind named NameOverflowMetric on the
+	// root scope is returned instead, while metrics with existing names keep
+	// working. This protects against unbounded names, e.g. names built from
+	// user input.
+	MaxMetricNames int
+
+	// NameOverflowCounter if set is the name of a counter on the root scope
+	// that is incremented each time a metric with a name over MaxMetricNames
+	// is requested.
+	NameOverflowCounter string
+
+	// MaxTagCardinality if greater than zero is the maximum number of
+	// distinct values of each tag key that can be used to create scopes with
+	// Tagged across the root scope and all its subscopes. Once a key has
+	// reached it, scopes tagged with a new value of that key get the value
+	// TagOverflowValue instead, so they share the same overflow scope, while
+	// values seen before keep their own scopes. This protects against
+	// unbounded tag values, e.g. values taken from user input. Values are
+	// counted after sanitization.
+	MaxTagCardinality int
+
+	// TagOverflowValue is the tag value replacing values over
+	// MaxTagCardinality, defaults to DefaultTagOverflowValue.
+	TagOverflowValue string
+
+	// AllowedTagKeys if not empty are the only tag keys that scopes created
+	// with Tagged and the other ways of creating tagged subscopes can add,
+	// across the root scope and all its subscopes. Other keys are dropped,
+	// so that the metrics are still emitted without them rather than lost,
+	// and reported to OnError with TagKeyErrorPhase. Keys are compared after
+	// sanitization and TagKeyCase, and the root scope Tags and CommonTags
+	// are not checked.
+	AllowedTagKeys []string
+
+	// TimerSnapshotLimit if greater than zero bounds the values copied from
+	// each timer by Snapshot, for timers on scopes without a reporter which
+	// buffer every recorded value. Snapshot then drains at most this many of
+	// the oldest buffered values of each timer rather than copying all of
+	// them and leaving them buffered. The remaining values are carried over
+	// to the next snapshot, or dropped if TimerSnapshotDropRemainder is set.
+	//
+	// This bounds the memory copied per snapshot but percentiles computed
+	// from a snapshot only cover the values drained into it. When carrying
+	// over, a snapshot of a hot timer covers the earliest values and lags
+	// behind the values recorded since. When dropping, values recorded after
+	// the limit within an interval are lost and never observed.
+	TimerSnapshotLimit         int
+	TimerSnapshotDropRemainder bool
+
+	// TimerSnapshotDroppedCounter if set is the name of a counter on the
+	// root scope that is incremented for each timer value dropped by
+	// TimerSnapshotDropRemainder.
+	TimerSnapshotDroppedCounter string
+
+	// MaxTimerSamplesPerInterval if greater than zero bounds the values each
+	// timer buffers per interval, the values of timers on scopes without a
+	// reporter returned by Snapshot and the values the percentiles of
+	// PercentileTimer are computed from. Once a timer has buffered this many
+	// values in an interval, each further value replaces a random buffered
+	// value with a probability such that they remain a uniform random sample
+	// of all the values of the interval, so percentiles stay representative
+	// while memory stays bounded. The interval of the percentiles ends on each
+	// report, that of the values of Snapshot when a snapshot with
+	// TimerSnapshotLimit removes them. Once sampling, the values taken by
+	// TimerSnapshotLimit are a random subset rather than the oldest ones.
+	MaxTimerSamplesPerInterval int
+
+	// TimerSampleSeed if not zero seeds the random source of the sampling of
+	// MaxTimerSamplesPerInterval and HistogramReservoirSize, e.g. for
+	// reproducible tests, rather than using the global source of math/rand.
+	TimerSampleSeed int64
+
+	// TagKeyCase normalizes the casing of tag keys of the scope and all its
+	// subscopes before they identify a series, so e.g. "Region" and "region"
+	// are the same tag. When several keys of the same tags normalize to the
+	// same key, they are resolved by TagKeyCollisionPolicy. A key of tags
+	// passed to Tagged replaces a parent key that normalizes to the same
+	// key, like any other key.
+	TagKeyCase TagCase
+
+	// TagValueCase normalizes the casing of tag values like TagKeyCase.
+	TagValueCase TagCase
+
+	// TagKeyCollisionPolicy resolves keys of the same tags that collide after
+	// normalization by TagKeyCase or the sanitizer, FirstTagKeyWins by
+	// default.
+	TagKeyCollisionPolicy TagKeyCollisionPolicy
+
+	// RecoverCallbackPanics recovers panics in the user supplied callbacks
+	// the scope calls rather than letting them crash the report loop or the
+	// code creating metrics. The protected callbacks are the OnError handler,
+	// the MetricTagValidators, for which a panic leaves the tags valid, and
+	// the updates of the gauges of RegisterRatio and Info. The panics are
+	// logged, counted with the CallbackPanicsCounter and passed to OnError,
+	// except those of OnError itself. The functions registered with GaugeFunc
+	// are always protected.
+	RecoverCallbackPanics bool
+
+	// CallbackPanicsCounter if set is the name of a counter on the root scope
+	// that is incremented for each recovered panic of a callback, tagged with
+	// the CallbackTagKey tag: the fully qualified name of the gauge or of the
+	// metric of the tag validator, or "OnError" for the OnError handler, e.g.
+	// "tally.callback_panics". The counters of the functions registered with
+	// GaugeFunc are created regardless of RecoverCallbackPanics.
+	CallbackPanicsCounter string
+
+	// MetricTagValidators validate the tags of metrics by their fully
+	// qualified name, e.g. to require an HTTP timer to have "method" and
+	// "status" tags. The validator of a name is called with the tags of the
+	// scope each time a metric of any kind with that name is created in the
+	// root scope or a subscope, not on every emission, so a missing tag
+	// fails where the metric is first created. A validator returning an
+	// error fails the metric according to TagValidationMode.
+	MetricTagValidators map[string]func(tags map[string]string) error
+	TagValidationMode   TagValidationMode
+
+	// TagValidationFailedCounter if set is the name of a counter on the root
+	// scope that is incremented for each metric created with invalid tags
+	// with LenientTagValidation.
+	TagValidationFailedCounter string
+
+	// MetricIdleTTL if greater than zero removes the metrics, of any kind,
+	// not updated for at least the TTL from the root scope and its subscopes
+	// after each report, so that metrics keyed by high cardinality values
+	// such as customers are released once idle. A metric is never removed
+	// while it has values left to report, and is created again the next
+	// time it is looked up by name. Updates to a removed metric through a
+	// Counter, Gauge, Timer or Histogram held on to are lost, so metrics
+	// should be looked up by name on each use rather than held on to with a
+	// TTL. The TTL is checked on each report, so it is rounded up to the
+	// report interval. The meta metrics above, the metrics the library
+	// holds on to, such as those of RegisterRatio and GaugeFunc, and the
+	// counters with CounterOptions.Cumulative, whose running total would
+	// restart, never expire.
+	MetricIdleTTL time.Duration
+
+	// OnError if set is called with a *ScopeError for each error the scope
+	// would otherwise only log or drop: the failed flushes of the report
+	// loop and of Close for a reporter implementing FlushErrorReporter or
+	// FlushStatsReporter, the metrics and subscopes rejected with
+	// SanitizeReject, the tag keys dropped for AllowedTagKeys and the
+	// recovered panics of the callbacks, see RecoverCallbackPanics. It is
+	// called from a goroutine of its own so that a slow handler does not
+	// stall the scope, the errors occurring while it is behind by more than
+	// 64 errors are dropped. Closing the root scope waits for the queued
+	// errors to be handled.
+	OnError func(err error)
+
+	// CommonTags are added to the tags of every metric and event passed to
+	// the reporter, e.g. host and pid tags, without being tags of the scopes:
+	// they are not part of the keys of subscopes, snapshots and the metrics
+	// of ForEachMetric, and are not subject to MaxTagCardinality and
+	// MetricTagValidators. A tag of a scope with the same key as a common tag
+	// wins. The tags passed to the reporter are merged once per subscope.
+	CommonTags map[string]string
+
+	// DropUnsupportedHistograms makes the root scope and its subscopes
+	// return histograms that do nothing, like NewNoopScope, if the
+	// capabilities of the reporter do not support histograms according to
+	// SupportsHistograms, rather than histograms whose values the reporter
+	// silently discards. Histograms are still recorded by default.
+	DropUnsupportedHistograms bool
+
+	// MetaMetrics controls all the meta metrics above at once, e.g. to
+	// enable them under default names while debugging.
+	MetaMetrics MetaMetricsOptions
 }
 
 // NewRootScope creates a new root Scope with a set of options and
 // a reporting interval.
 // Must provide either a StatsReporter or a CachedStatsReporter.
+// Closing the returned io.Closer stops the report loop, waiting for a report
+// in progress, then reports and flushes the metrics a final time and closes
+// the reporter if it is an io.Closer. It returns the error of the final
+// flush if the reporter implements FlushErrorReporter or FlushStatsReporter,
+// or else the error of closing the reporter. Closing it again does nothing and returns nil.
 func NewRootScope(opts ScopeOptions, interval time.Duration) (Scope, io.Closer) {
 	s := newRootScope(opts, interval)
 	return s, s
@@ -122,6 +471,15 @@ func NewTestScope(
 	return newRootScope(ScopeOptions{Prefix: prefix, Tags: tags}, 0)
 }
 
+// NewTestScopeWithOptions creates a new Scope with the given options that
+// adds the ability to take snapshots of metrics emitted to it, e.g. to mirror
+// the prefix, separator and default tags of a production scope so snapshot
+// keys match the emitted series names. The scope does not report on an
+// interval.
+func NewTestScopeWithOptions(opts ScopeOptions) TestScope {
+	return newRootScope(opts, 0)
+}
+
 func newRootScope(opts ScopeOptions, interval time.Duration) *scope {
 	sanitizer := NewNoOpSanitizer()
 	if o := opts.SanitizeOptions; o != nil {
@@ -134,6 +492,7 @@ func newRootScope(opts ScopeOptions, interval time.Duration) *scope {
 	if opts.Separator == "" {
 		opts.Separator = DefaultSeparator
 	}
+	opts = applyMetaMetrics(opts)
 
 	var baseReporter BaseStatsReporter
 	if opts.Reporter != nil {
@@ -145,11 +504,17 @@ func newRootScope(opts ScopeOptions, interval time.Duration) *scope {
 	if opts.DefaultBuckets == nil || opts.DefaultBuckets.Len() < 1 {
 		opts.DefaultBuckets = defaultScopeBuckets
 	}
+	if opts.Clock == nil {
+		opts.Clock = wallClock{}
+	}
+
+	errHook := newErrorHook(opts.OnError, opts.RecoverCallbackPanics)
 
 	s := &scope{
 		baseReporter:    baseReporter,
 		bucketCache:     newBucketCache(),
 		cachedReporter:  opts.CachedReporter,
+		clock:           opts.Clock,
 		counters:        make(map[string]*counter),
 		countersSlice:   make([]*counter, 0, _defaultInitialSliceSize),
 		defaultBuckets:  opts.DefaultBuckets,
@@ -161,9 +526,20 @@ func newRootScope(opts ScopeOptions, interval time.Duration) *scope {
 		prefix:          sanitizer.Name(opts.Prefix),
 		reporter:        opts.Reporter,
 		sanitizer:       sanitizer,
+		sanitizeReject:  newSanitizeRejecter(opts.SanitizeOptions, errHook),
 		separator:       sanitizer.Name(opts.Separator),
 		timers:          make(map[string]*timer),
+		meters:          make(map[string]*meter),
 		root:            true,
+		created:         opts.Clock.Now(),
+
+		histogramReservoirSize: opts.HistogramReservoirSize,
+		tagKeyCase:             opts.TagKeyCase,
+		tagKeyCollisionPolicy:  opts.TagKeyCollisionPolicy,
+		tagValueCase:           opts.TagValueCase,
+
+		recoverCallbackPanics: opts.RecoverCallbackPanics,
+		callbackPanicsCounter: opts.CallbackPanicsCounter,
 	}
 
 	// NB(r): Take a copy of the tags on creation
@@ -172,12 +548,107 @@ func newRootScope(opts ScopeOptions, interval time.Duration) *scope {
 
 	// Register the root scope
 	s.registry = newScopeRegistry(s)
+	s.registry.errors = errHook
+	if len(opts.CommonTags) > 0 {
+		s.registry.commonTags = s.copyAndSanitizeMap(opts.CommonTags)
+	}
+	s.reportTags = withCommonTags(s.registry.commonTags, s.tags)
+	s.registry.dropHistograms = opts.DropUnsupportedHistograms &&
+		baseReporter != nil && !SupportsHistograms(baseReporter.Capabilities())
+	if b := opts.CoarseHistogramBuckets; b != nil && opts.HistogramCoarseningThreshold > 0 {
+		s.registry.coarseningThreshold = int64(opts.HistogramCoarseningThreshold)
+		s.registry.coarseValueBuckets = ValueBuckets(b.AsValues())
+		s.registry.coarseDurationBuckets = DurationBuckets(b.AsDurations())
+	}
+
+	// NB: Meta counters are created before limits are enabled so that they
+	// are never subject to the limits themselves.
+	if opts.NameLengthExceededCounter != "" {
+		s.nameLengthExceeded = s.Counter(opts.NameLengthExceededCounter)
+	}
+	if opts.SeriesRateLimitDroppedCounter != "" {
+		s.seriesRateLimitDropped = s.Counter(opts.SeriesRateLimitDroppedCounter)
+	}
+	if opts.TimerSnapshotDroppedCounter != "" {
+		s.timerSnapshotDropped = s.Counter(opts.TimerSnapshotDroppedCounter)
+	}
+	if opts.NameOverflowCounter != "" {
+		s.registry.names.counter = s.Counter(opts.NameOverflowCounter)
+	}
+	if opts.TagValidationFailedCounter != "" {
+		s.registry.tagValidators.failed = s.Counter(opts.TagValidationFailedCounter)
+	}
+	if opts.TimeToFirstReportGauge != "" {
+		s.timeToFirstReport = s.Gauge(opts.TimeToFirstReportGauge)
+	}
+	if opts.ReportLoopHeartbeatCounter != "" {
+		s.heartbeat = s.Counter(opts.ReportLoopHeartbeatCounter)
+	}
+	if opts.FlushedMetricsGauge != "" {
+		s.flushedMetrics = newFlushedMetrics(s, opts.FlushedMetricsGauge)
+	}
+	if opts.ProcessStartTimeGauge != "" {
+		s.processStartTime = s.Gauge(opts.ProcessStartTimeGauge)
+	}
+	if opts.ReportPhaseOffsetGauge != "" && interval > 0 {
+		s.phaseOffset = s.Gauge(opts.ReportPhaseOffsetGauge)
+	}
+	if opts.RecoverCallbackPanics && opts.CallbackPanicsCounter != "" {
+		if errHook != nil {
+			errHook.panics = callbackPanics(s, opts.CallbackPanicsCounter, "OnError")
+		}
+		s.registry.tagValidators.panics = make(map[string]Counter, len(opts.MetricTagValidators))
+		for name := range opts.MetricTagValidators {
+			s.registry.tagValidators.panics[name] = callbackPanics(s, opts.CallbackPanicsCounter, name)
+		}
+	}
+	errHook.start()
+	// NB: The meta metrics above are created before so that they never
+	// expire.
+	if opts.MetricIdleTTL > 0 {
+		s.registry.idle = newIdleClock(s.clock.Now(), opts.MetricIdleTTL)
+	}
+	s.reportInterval = interval
+	s.maxNameLength = opts.MaxNameLength
+	s.nameLengthMode = opts.NameLengthMode
+	s.seriesRateLimit = opts.SeriesRateLimit
+	s.seriesRateLimitBurst = opts.SeriesRateLimitBurst
+	s.timerSnapshotLimit = opts.TimerSnapshotLimit
+	s.registry.limitNames(opts.MaxMetricNames)
+	s.registry.limitTagCardinality(opts.MaxTagCardinality, opts.TagOverflowValue)
+	s.registry.allowTagKeys(opts.AllowedTagKeys)
+	s.registry.maxTimerSamples = opts.MaxTimerSamplesPerInterval
+	s.registry.timerSampleRand = newSampleRand(opts.TimerSampleSeed)
+	// NB: Copy the validators so that they cannot be modified after set.
+	if len(opts.MetricTagValidators) > 0 {
+		s.registry.tagValidators.validators = make(map[string]func(map[string]string) error)
+		for name, validate := range opts.MetricTagValidators {
+			s.registry.tagValidators.validators[name] = validate
+		}
+	}
+	s.registry.tagValidators.mode = opts.TagValidationMode
+	s.timerSnapshotDropRemainder = opts.TimerSnapshotDropRemainder
 
 	if interval > 0 {
+		jitter := newReportJitter(interval, opts.ReportingIntervalJitter,
+			opts.ReportingIntervalJitterSeed)
+		// NB: The ticker is created before the loop starts so that ticks
+		// of a Clock advanced right after the scope is created are not lost.
+		var ticker Ticker
+		if !opts.AlignReportsToClock && jitter == nil {
+			ticker = s.clock.NewTicker(interval)
+		}
 		s.wg.Add(1)
 		go func() {
 			defer s.wg.Done()
-			s.reportLoop(interval)
+			switch {
+			case opts.AlignReportsToClock:
+				s.alignedReportLoop(interval, jitter)
+			case jitter != nil:
+				s.jitteredReportLoop(interval, jitter)
+			default:
+				s.reportLoop(ticker)
+			}
 		}()
 	}
 
@@ -186,64 +657,149 @@ func newRootScope(opts ScopeOptions, interval time.Duration) *scope {
 
 // report dumps all aggregated stats into the reporter. Should be called automatically by the root scope periodically.
 func (s *scope) report(r StatsReporter) {
+	s.updateCardinalityBudget()
+
 	s.cm.RLock()
+	s.addRateLimitedCounters()
 	for name, counter := range s.counters {
-		counter.report(s.fullyQualifiedName(name), s.tags, r)
+		counter.report(s.fullyQualifiedName(name), s.reportTags, r)
 	}
 	s.cm.RUnlock()
 
 	s.gm.RLock()
+	s.updateRatios()
+	s.updateInfos()
+	s.updateGaugeFuncs()
+	s.updateTrackingGauges()
+	s.updateTimerPercentiles()
 	for name, gauge := range s.gauges {
-		gauge.report(s.fullyQualifiedName(name), s.tags, r)
+		gauge.report(s.fullyQualifiedName(name), s.reportTags, r)
 	}
 	s.gm.RUnlock()
 
-	// we do nothing for timers here because timers report directly to ths StatsReporter without buffering
+	// we only reset the decaying reservoirs of timers here because timers report directly to ths StatsReporter without buffering
+	s.tm.RLock()
+	s.resetDecayingTimers()
+	s.tm.RUnlock()
 
 	s.hm.RLock()
 	for name, histogram := range s.histograms {
-		histogram.report(s.fullyQualifiedName(name), s.tags, r)
+		histogram.report(s.fullyQualifiedName(name), s.reportTags, r)
 	}
 	s.hm.RUnlock()
+
+	s.mm.RLock()
+	mr, _ := r.(MeterReporter)
+	for _, m := range s.metersSlice {
+		m.report(mr)
+	}
+	s.mm.RUnlock()
 }
 
 func (s *scope) cachedReport() {
+	s.updateCardinalityBudget()
+
 	s.cm.RLock()
+	s.addRateLimitedCounters()
 	for _, counter := range s.countersSlice {
 		counter.cachedReport()
 	}
 	s.cm.RUnlock()
 
 	s.gm.RLock()
+	s.updateRatios()
+	s.updateInfos()
+	s.updateGaugeFuncs()
+	s.updateTrackingGauges()
+	s.updateTimerPercentiles()
 	for _, gauge := range s.gaugesSlice {
 		gauge.cachedReport()
 	}
 	s.gm.RUnlock()
 
-	// we do nothing for timers here because timers report directly to ths StatsReporter without buffering
+	// we only reset the decaying reservoirs of timers here because timers report directly to ths StatsReporter without buffering
+	s.tm.RLock()
+	s.resetDecayingTimers()
+	s.tm.RUnlock()
 
 	s.hm.RLock()
 	for _, histogram := range s.histogramsSlice {
 		histogram.cachedReport()
 	}
 	s.hm.RUnlock()
+
+	s.mm.RLock()
+	for _, m := range s.metersSlice {
+		m.cachedReport()
+	}
+	s.mm.RUnlock()
 }
 
 // reportLoop is used by the root scope for periodic reporting
-func (s *scope) reportLoop(interval time.Duration) {
-	ticker := time.NewTicker(interval)
+func (s *scope) reportLoop(ticker Ticker) {
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
+			s.reportLoopRun()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// alignedReportLoop is used by the root scope for periodic reporting aligned
+// to wall clock multiples of the interval, delayed by the jitter if not nil.
+func (s *scope) alignedReportLoop(interval time.Duration, jitter *reportJitter) {
+	now := globalNow()
+	next := firstAlignedReport(now, interval)
+	timer := time.NewTimer(next.Sub(now) + jitter.offset())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
 			s.reportLoopRun()
+			now = globalNow()
+			next = nextAlignedReport(next, now, interval)
+			timer.Reset(next.Sub(now) + jitter.offset())
 		case <-s.done:
 			return
 		}
 	}
 }
 
+// firstAlignedReport returns the first wall clock multiple of interval after
+// now.
+func firstAlignedReport(now time.Time, interval time.Duration) time.Time {
+	// NB: Truncate strips the monotonic clock reading, so differences with
+	// the result are computed using the wall clock.
+	return now.Truncate(interval).Add(interval)
+}
+
+// nextAlignedReport returns the boundary to report at after the report
+// targeting prev ran at now. The timer fires on the monotonic clock, so the
+// wall clock may read slightly before or after prev; the next boundary is
+// always one interval after prev unless the wall clock has moved by more
+// than an interval from it, in which case it is derived from now again.
+func nextAlignedReport(prev, now time.Time, interval time.Duration) time.Time {
+	if drift := now.Sub(prev); drift > interval || drift < -interval {
+		return firstAlignedReport(now, interval)
+	}
+	return prev.Add(interval)
+}
+
+// reportPhaseOffset returns the offset of now from the nearest wall clock
+// multiple of the interval, in the range [-interval/2, interval/2).
+func reportPhaseOffset(now time.Time, interval time.Duration) time.Duration {
+	offset := now.Sub(now.Truncate(interval))
+	if offset >= interval/2 {
+		offset -= interval
+	}
+	return offset
+}
+
 func (s *scope) reportLoopRun() {
 	if s.closed.Load() {
 		return
@@ -252,21 +808,89 @@ func (s *scope) reportLoopRun() {
 	s.reportRegistry()
 }
 
-func (s *scope) reportRegistry() {
+// reportRegistry reports the registry and returns the error of the flush.
+func (s *scope) reportRegistry() error {
+	if s.timeToFirstReport != nil && s.firstReported.CAS(false, true) {
+		s.timeToFirstReport.Update(s.clock.Now().Sub(s.created).Seconds())
+	}
+	if s.processStartTime != nil {
+		s.processStartTime.Update(float64(s.created.UnixNano()) / float64(time.Second))
+	}
+	if s.phaseOffset != nil {
+		offset := reportPhaseOffset(s.clock.Now(), s.reportInterval)
+		s.phaseOffset.Update(float64(offset) / float64(time.Millisecond))
+	}
+	if s.registry.idle != nil {
+		s.registry.idle.advance(s.clock.Now())
+	}
+	s.registry.deriveAutoHistograms(s.clock.Now())
+
+	if s.registry.transactions.Load() {
+		s.registry.txBarrier.Lock()
+		defer s.registry.txBarrier.Unlock()
+	}
+
+	var err error
 	if s.reporter != nil {
 		s.registry.Report(s.reporter)
-		s.reporter.Flush()
+		err = s.flushReporter(s.reporter)
 	} else if s.cachedReporter != nil {
 		s.registry.CachedReport()
-		s.cachedReporter.Flush()
+		err = s.flushReporter(s.cachedReporter)
+	} else {
+		return nil
+	}
+	if err != nil {
+		s.registry.errors.report(FlushErrorPhase, err)
+	}
+
+	// NB: The increment is reported by the next flush, so a flush that
+	// never completes stops the heartbeat.
+	if s.heartbeat != nil {
+		s.heartbeat.Inc(1)
 	}
+	if s.registry.idle != nil {
+		s.registry.evictIdleMetrics(s.registry.idle.cutoff())
+	}
+	return err
 }
 
 func (s *scope) Counter(name string) Counter {
+	return s.counterWithOptions(name, CounterOptions{})
+}
+
+// CounterWithOptions returns the Counter object corresponding to the name on
+// a scope created by NewRootScope, creating it with the given options if it
+// does not yet exist. Options are ignored if the counter already exists, and
+// on other Scope implementations which just return s.Counter(name).
+func CounterWithOptions(s Scope, name string, opts CounterOptions) Counter {
+	if c, ok := s.(counterWithOptionsScope); ok {
+		return c.counterWithOptions(name, opts)
+	}
+	return s.Counter(name)
+}
+
+type counterWithOptionsScope interface {
+	counterWithOptions(name string, opts CounterOptions) Counter
+}
+
+func (s *scope) counterWithOptions(name string, opts CounterOptions) Counter {
+	if s.sanitizeReject.rejectName(name) {
+		return noopMetric{}
+	}
 	name = s.sanitizer.Name(name)
+	name, exceeded := s.limitName(name)
 	if c, ok := s.counter(name); ok {
 		return c
 	}
+	if !s.allowName(name) {
+		return s.registry.root.Counter(NameOverflowMetric)
+	}
+
+	// NB: Deferred before the lock so the aliases are linked once it is
+	// released, since linking creates the alias counters.
+	var c *counter
+	defer func() { s.linkCounterAliases(name, c) }()
 
 	s.cm.Lock()
 	defer s.cm.Unlock()
@@ -274,16 +898,25 @@ func (s *scope) Counter(name string) Counter {
 	if c, ok := s.counters[name]; ok {
 		return c
 	}
+	s.countNameLengthExceeded(exceeded)
+	s.validateTags(name)
 
 	var cachedCounter CachedCount
 	if s.cachedReporter != nil {
-		cachedCounter = s.cachedReporter.AllocateCounter(
+		cachedCounter = s.allocateCounter(
 			s.fullyQualifiedName(name),
-			s.tags,
+			Metadata{Help: opts.Help},
 		)
 	}
 
-	c := newCounter(cachedCounter)
+	c = newCounter(cachedCounter)
+	c.cumulative = opts.Cumulative
+	c.limiter = s.seriesLimiter()
+	c.idle.start(s.registry.idle)
+	if c.cumulative {
+		// NB: Evicting the counter would restart its running total.
+		c.idle.pin()
+	}
 	s.counters[name] = c
 	s.countersSlice = append(s.countersSlice, c)
 
@@ -299,10 +932,41 @@ func (s *scope) counter(sanitizedName string) (Counter, bool) {
 }
 
 func (s *scope) Gauge(name string) Gauge {
+	return s.gaugeWithOptions(name, GaugeOptions{})
+}
+
+// GaugeWithOptions returns the Gauge object corresponding to the name on a
+// scope created by NewRootScope, creating it with the given options if it
+// does not yet exist. Options are ignored if the gauge already exists, and on
+// other Scope implementations which just return s.Gauge(name).
+func GaugeWithOptions(s Scope, name string, opts GaugeOptions) Gauge {
+	if g, ok := s.(gaugeWithOptionsScope); ok {
+		return g.gaugeWithOptions(name, opts)
+	}
+	return s.Gauge(name)
+}
+
+type gaugeWithOptionsScope interface {
+	gaugeWithOptions(name string, opts GaugeOptions) Gauge
+}
+
+func (s *scope) gaugeWithOptions(name string, opts GaugeOptions) Gauge {
+	if s.sanitizeReject.rejectName(name) {
+		return noopMetric{}
+	}
 	name = s.sanitizer.Name(name)
+	name, exceeded := s.limitName(name)
 	if g, ok := s.gauge(name); ok {
 		return g
 	}
+	if !s.allowName(name) {
+		return s.registry.root.Gauge(NameOverflowMetric)
+	}
+
+	// NB: Deferred before the lock so the aliases are linked once it is
+	// released, since linking creates the alias gauges.
+	var g *gauge
+	defer func() { s.linkGaugeAliases(name, g) }()
 
 	s.gm.Lock()
 	defer s.gm.Unlock()
@@ -310,15 +974,19 @@ func (s *scope) Gauge(name string) Gauge {
 	if g, ok := s.gauges[name]; ok {
 		return g
 	}
+	s.countNameLengthExceeded(exceeded)
+	s.validateTags(name)
 
 	var cachedGauge CachedGauge
 	if s.cachedReporter != nil {
-		cachedGauge = s.cachedReporter.AllocateGauge(
-			s.fullyQualifiedName(name), s.tags,
+		cachedGauge = s.allocateGauge(
+			s.fullyQualifiedName(name), Metadata{Help: opts.Help},
 		)
 	}
 
-	g := newGauge(cachedGauge)
+	g = newGauge(cachedGauge)
+	g.limiter = s.seriesLimiter()
+	g.idle.start(s.registry.idle)
 	s.gauges[name] = g
 	s.gaugesSlice = append(s.gaugesSlice, g)
 
@@ -334,10 +1002,61 @@ func (s *scope) gauge(name string) (Gauge, bool) {
 }
 
 func (s *scope) Timer(name string) Timer {
+	return s.timerWithOptions(name, TimerOptions{})
+}
+
+// TimerWithOptions returns the Timer object corresponding to the name on a
+// scope created by NewRootScope, creating it with the given options if it
+// does not yet exist. Options are ignored if the timer already exists, and on
+// other Scope implementations which just return s.Timer(name).
+func TimerWithOptions(s Scope, name string, opts TimerOptions) Timer {
+	if t, ok := s.(timerWithOptionsScope); ok {
+		return t.timerWithOptions(name, opts)
+	}
+	return s.Timer(name)
+}
+
+type timerWithOptionsScope interface {
+	timerWithOptions(name string, opts TimerOptions) Timer
+}
+
+func (s *scope) timerWithOptions(name string, opts TimerOptions) Timer {
+	return s.timerWithPercentiles(name, opts, nil)
+}
+
+// timerWithPercentiles is timerWithOptions also emitting the percentiles of
+// PercentileTimer, validated by PercentileTimer.
+func (s *scope) timerWithPercentiles(name string, opts TimerOptions, percentiles []float64) Timer {
+	if s.sanitizeReject.rejectName(name) {
+		return noopMetric{}
+	}
 	name = s.sanitizer.Name(name)
+	name, exceeded := s.limitName(name)
 	if t, ok := s.timer(name); ok {
 		return t
 	}
+	if !s.allowName(name) {
+		return s.registry.root.Timer(NameOverflowMetric)
+	}
+
+	// NB: Deferred before the lock so the aliases are linked once it is
+	// released, since linking creates the alias timers.
+	var t *timer
+	defer func() { s.linkTimerAliases(name, t) }()
+	// NB: Likewise the mean gauge is registered once the lock is released,
+	// as registering takes the gauges lock.
+	meanSuffix := opts.MeanNameSuffix
+	if meanSuffix == "" {
+		meanSuffix = DefaultTimerMeanNameSuffix
+	}
+	defer func() {
+		if t != nil && t.mean != nil {
+			s.registerGaugeFunc(name+s.separator+meanSuffix, t.mean.value)
+		}
+		if t != nil && t.percentiles != nil {
+			s.registerTimerPercentiles(name, t)
+		}
+	}()
 
 	s.tm.Lock()
 	defer s.tm.Unlock()
@@ -345,22 +1064,59 @@ func (s *scope) Timer(name string) Timer {
 	if t, ok := s.timers[name]; ok {
 		return t
 	}
+	s.countNameLengthExceeded(exceeded)
+	s.validateTags(name)
 
 	var cachedTimer CachedTimer
 	if s.cachedReporter != nil {
-		cachedTimer = s.cachedReporter.AllocateTimer(
-			s.fullyQualifiedName(name), s.tags,
+		cachedTimer = s.allocateTimer(
+			s.fullyQualifiedName(name), Metadata{Help: opts.Help},
 		)
 	}
 
-	t := newTimer(
-		s.fullyQualifiedName(name), s.tags, s.reporter, cachedTimer,
+	t = newTimer(
+		s.fullyQualifiedName(name), s.reportTags, s.reporter, cachedTimer,
 	)
+	t.clock = s.clock
+	if opts.DecayingReservoirSize > 0 {
+		t.retained = newDecayingReservoir(
+			opts.DecayingReservoirSize, opts.DecayingReservoirAlpha, s.clock,
+		)
+		s.decayingTimers = append(s.decayingTimers, t)
+	}
+	if b := opts.HistogramBuckets; b != nil {
+		suffix := opts.HistogramNameSuffix
+		if suffix == "" {
+			suffix = DefaultTimerHistogramNameSuffix
+		}
+		t.histogram = s.Histogram(
+			name+s.separator+suffix, DurationBuckets(b.AsDurations()),
+		)
+	}
+	if opts.MeanNanoseconds {
+		t.mean = &timerMean{}
+	}
+	t.unreported.sample = newReservoir(s.registry.maxTimerSamples, s.registry.timerSampleRand)
+	t.percentiles = newTimerPercentiles(percentiles,
+		s.registry.maxTimerSamples, s.registry.timerSampleRand)
+	t.snapshotLimit = s.timerSnapshotLimit
+	t.snapshotDropRemainder = s.timerSnapshotDropRemainder
+	t.snapshotDropped = s.timerSnapshotDropped
+	t.limiter = s.seriesLimiter()
+	t.idle.start(s.registry.idle)
 	s.timers[name] = t
 
 	return t
 }
 
+// resetDecayingTimers resets the decaying reservoirs of timers for the next
+// interval, must be called with the timers lock held.
+func (s *scope) resetDecayingTimers() {
+	for _, t := range s.decayingTimers {
+		t.retained.Reset()
+	}
+}
+
 func (s *scope) timer(sanitizedName string) (Timer, bool) {
 	s.tm.RLock()
 	defer s.tm.RUnlock()
@@ -370,10 +1126,61 @@ func (s *scope) timer(sanitizedName string) (Timer, bool) {
 }
 
 func (s *scope) Histogram(name string, b Buckets) Histogram {
+	return s.histogramWithOptions(name, b, HistogramOptions{})
+}
+
+// HistogramWithOptions returns the Histogram object corresponding to the name
+// on a scope created by NewRootScope, creating it with the given options if it
+// does not yet exist. Options are ignored if the histogram already exists, and
+// on other Scope implementations which just return s.Histogram(name, b).
+func HistogramWithOptions(
+	s Scope,
+	name string,
+	b Buckets,
+	opts HistogramOptions,
+) (Histogram, error) {
+	if err := validatePercentiles(opts.Percentiles); err != nil {
+		return nil, err
+	}
+	if opts.Clamp != nil {
+		if err := opts.Clamp.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := unitScale(opts.InputUnit, opts.StorageUnit); err != nil {
+		return nil, err
+	}
+	if _, ok := b.(DurationBuckets); ok && opts.InputUnit != NoUnit {
+		return nil, errUnitDurationHistogram
+	}
+	if ab, ok := b.(autoBuckets); ok && ab.durations && opts.InputUnit != NoUnit {
+		return nil, errUnitDurationHistogram
+	}
+	if h, ok := s.(histogramWithOptionsScope); ok {
+		return h.histogramWithOptions(name, b, opts), nil
+	}
+	return s.Histogram(name, b), nil
+}
+
+type histogramWithOptionsScope interface {
+	histogramWithOptions(name string, b Buckets, opts HistogramOptions) Histogram
+}
+
+func (s *scope) histogramWithOptions(name string, b Buckets, opts HistogramOptions) Histogram {
+	if s.registry.dropHistograms || s.sanitizeReject.rejectName(name) {
+		return noopMetric{}
+	}
 	name = s.sanitizer.Name(name)
+	name, exceeded := s.limitName(name)
+	if ab, ok := b.(autoBuckets); ok {
+		return s.autoHistogram(name, ab, opts)
+	}
 	if h, ok := s.histogram(name); ok {
 		return h
 	}
+	if !s.allowName(name) {
+		return s.registry.root.Histogram(NameOverflowMetric, b)
+	}
 
 	if b == nil {
 		b = s.defaultBuckets
@@ -384,28 +1191,71 @@ func (s *scope) Histogram(name string, b Buckets) Histogram {
 		htype = durationHistogramType
 	}
 
+	// NB: Deferred before the lock so the aliases are linked once it is
+	// released, since linking creates the alias histograms.
+	var h *histogram
+	defer func() { s.linkHistogramAliases(name, h) }()
+	// NB: Likewise the sampled histogram is created once the lock is
+	// released.
+	sampledBuckets := b
+	defer func() {
+		if h != nil && opts.TraceSampled {
+			s.createSampledHistogram(name, sampledBuckets, opts, h)
+		}
+	}()
+
 	s.hm.Lock()
 	defer s.hm.Unlock()
 
 	if h, ok := s.histograms[name]; ok {
 		return h
 	}
+	s.countNameLengthExceeded(exceeded)
+	s.validateTags(name)
+
+	if coarse, ok := s.registry.coarseHistogramBuckets(htype); ok {
+		b = coarse
+	}
 
 	var cachedHistogram CachedHistogram
 	if s.cachedReporter != nil {
-		cachedHistogram = s.cachedReporter.AllocateHistogram(
-			s.fullyQualifiedName(name), s.tags, b,
+		cachedHistogram = s.allocateHistogram(
+			s.fullyQualifiedName(name), b, Metadata{Help: opts.Help},
 		)
 	}
 
-	h := newHistogram(
+	h = newHistogram(
 		htype,
 		s.fullyQualifiedName(name),
-		s.tags,
+		s.reportTags,
 		s.reporter,
 		s.bucketCache.Get(htype, b),
 		cachedHistogram,
 	)
+	h.clock = s.clock
+	if s.histogramReservoirSize > 0 {
+		h.retained = newValueReservoir(s.histogramReservoirSize, s.registry.timerSampleRand)
+	}
+	// NB: The units were validated by HistogramWithOptions.
+	h.scale, _ = unitScale(opts.InputUnit, opts.StorageUnit)
+	if len(opts.Percentiles) > 0 {
+		h.percentiles = newHistogramPercentiles(
+			s.fullyQualifiedName(name), s.separator, s.reportTags, s.cachedReporter,
+			opts.Percentiles,
+		)
+	}
+	if c := opts.Clamp; c != nil {
+		suffix := c.CounterNameSuffix
+		if suffix == "" {
+			suffix = DefaultClampedCounterSuffix
+		}
+		clamped := s.Counter(name + s.separator + suffix)
+		pinCounter(clamped)
+		h.clamp = newHistogramClamp(*c, clamped)
+	}
+	h.boundary = opts.BucketBoundary
+	h.limiter = s.seriesLimiter()
+	h.idle.start(s.registry.idle)
 	s.histograms[name] = h
 	s.histogramsSlice = append(s.histogramsSlice, h)
 
@@ -421,19 +1271,166 @@ func (s *scope) histogram(sanitizedName string) (Histogram, bool) {
 }
 
 func (s *scope) Tagged(tags map[string]string) Scope {
+	if s.sanitizeReject.rejectTags(tags) {
+		return NewNoopScope()
+	}
 	tags = s.copyAndSanitizeMap(tags)
 	return s.subscope(s.prefix, tags)
 }
 
 func (s *scope) SubScope(prefix string) Scope {
+	if s.sanitizeReject.rejectName(prefix) {
+		return NewNoopScope()
+	}
 	prefix = s.sanitizer.Name(prefix)
 	return s.subscope(s.fullyQualifiedName(prefix), nil)
 }
 
+// SubScopeFromTags returns a new child scope of s with the given tags and
+// current tags, appending to the name prefix the values of the given tag keys
+// in order, for backends without tag support. Values are looked up in the
+// current tags merged with the given tags, and keys that are missing from
+// both or have an empty value are not appended to the prefix. On Scope
+// implementations other than those created by NewRootScope only the given
+// tags are consulted.
+func SubScopeFromTags(s Scope, tagKeys []string, tags map[string]string) Scope {
+	if ss, ok := s.(subScopeFromTagsScope); ok {
+		return ss.subScopeFromTags(tagKeys, tags)
+	}
+
+	child := s.Tagged(tags)
+	for _, k := range tagKeys {
+		if v := tags[k]; len(v) > 0 {
+			child = child.SubScope(v)
+		}
+	}
+	return child
+}
+
+type subScopeFromTagsScope interface {
+	subScopeFromTags(tagKeys []string, tags map[string]string) Scope
+}
+
+func (s *scope) subScopeFromTags(tagKeys []string, tags map[string]string) Scope {
+	if s.sanitizeReject.rejectTags(tags) {
+		return NewNoopScope()
+	}
+	tags = s.copyAndSanitizeMap(tags)
+	prefix := s.prefix
+	for _, k := range tagKeys {
+		k = s.sanitizer.Key(k)
+		v, ok := tags[k]
+		if !ok {
+			v = s.tags[k]
+		}
+		v = s.sanitizer.Name(v)
+		if len(v) == 0 {
+			continue
+		}
+		if len(prefix) == 0 {
+			prefix = v
+		} else {
+			prefix = prefix + s.separator + v
+		}
+	}
+	return s.subscope(prefix, tags)
+}
+
+// ChildOptions is the name prefix and tags of a child scope created with
+// Child.
+type ChildOptions struct {
+	Prefix string
+	Tags   map[string]string
+}
+
+// Child returns a new child scope of s with the given tags merged with the
+// current tags and the given prefix appended to the name prefix, the same
+// scope as s.Tagged(opts.Tags).SubScope(opts.Prefix) but created in one pass
+// without the intermediate tagged scope, e.g. for hot paths. An empty prefix
+// only adds the tags, like s.Tagged(opts.Tags). On Scope implementations
+// other than those created by NewRootScope the scopes are chained.
+func Child(s Scope, opts ChildOptions) Scope {
+	if cs, ok := s.(childScope); ok {
+		return cs.child(opts)
+	}
+
+	child := s.Tagged(opts.Tags)
+	if len(opts.Prefix) > 0 {
+		child = child.SubScope(opts.Prefix)
+	}
+	return child
+}
+
+type childScope interface {
+	child(opts ChildOptions) Scope
+}
+
+func (s *scope) child(opts ChildOptions) Scope {
+	if s.sanitizeReject.rejectTags(opts.Tags) || s.sanitizeReject.rejectName(opts.Prefix) {
+		return NewNoopScope()
+	}
+	tags := s.copyAndSanitizeMap(opts.Tags)
+	prefix := s.prefix
+	if len(opts.Prefix) > 0 {
+		prefix = s.fullyQualifiedName(s.sanitizer.Name(opts.Prefix))
+	}
+	return s.subscope(prefix, tags)
+}
+
+// SubScopeWithSeparator returns a new child scope of s with the given name
+// appended to the name prefix, like s.SubScope(name), that joins the names
+// of its metrics and of its own subscopes with the given separator rather
+// than the separator of s, e.g. "a.b/c" for the metric "c" of
+// SubScopeWithSeparator(a, "b", "/") where a joins with ".". The subscopes
+// of the child inherit the separator. On Scope implementations other than
+// those created by NewRootScope the separator is ignored.
+func SubScopeWithSeparator(s Scope, name, separator string) Scope {
+	if ss, ok := s.(subScopeWithSeparatorScope); ok {
+		return ss.subScopeWithSeparator(name, separator)
+	}
+	return s.SubScope(name)
+}
+
+type subScopeWithSeparatorScope interface {
+	subScopeWithSeparator(name, separator string) Scope
+}
+
+func (s *scope) subScopeWithSeparator(name, separator string) Scope {
+	if s.sanitizeReject.rejectName(name) || s.sanitizeReject.rejectName(separator) {
+		return NewNoopScope()
+	}
+	name = s.sanitizer.Name(name)
+	separator = s.sanitizer.Name(separator)
+	return s.registry.SubscopeWithSeparator(s, s.fullyQualifiedName(name), separator, nil)
+}
+
 func (s *scope) subscope(prefix string, tags map[string]string) Scope {
+	tags = s.dropDisallowedTagKeys(tags)
+	tags = s.limitTagCardinality(tags)
 	return s.registry.Subscope(s, prefix, tags)
 }
 
+// EmitEvent emits an event to the reporter of a scope created by
+// NewRootScope, tagged with the scope's tags merged with the given tags.
+// Events are ignored if the reporter does not implement EventReporter.
+func EmitEvent(s Scope, title, text string, tags map[string]string) {
+	if e, ok := s.(eventEmitter); ok {
+		e.emitEvent(title, text, tags)
+	}
+}
+
+type eventEmitter interface {
+	emitEvent(title, text string, tags map[string]string)
+}
+
+func (s *scope) emitEvent(title, text string, tags map[string]string) {
+	r, ok := s.baseReporter.(EventReporter)
+	if !ok {
+		return
+	}
+	r.Event(title, text, mergeRightTags(s.reportTags, s.copyAndSanitizeMap(tags)))
+}
+
 func (s *scope) Capabilities() Capabilities {
 	if s.baseReporter == nil {
 		return capabilitiesNone
@@ -442,9 +1439,25 @@ func (s *scope) Capabilities() Capabilities {
 }
 
 func (s *scope) Snapshot() Snapshot {
+	return s.snapshot(nil, true)
+}
+
+// snapshot returns a snapshot of the scopes of the registry for which include
+// returns true, or all of them if include is nil. The counter deltas are only
+// consumed if advance is true.
+func (s *scope) snapshot(include func(ss *scope) bool, advance bool) Snapshot {
 	snap := newSnapshot()
 
+	if s.registry.transactions.Load() {
+		s.registry.txBarrier.Lock()
+		defer s.registry.txBarrier.Unlock()
+	}
+
 	s.registry.ForEachScope(func(ss *scope) {
+		if include != nil && !include(ss) {
+			return
+		}
+
 		// NB(r): tags are immutable, no lock required to read.
 		tags := make(map[string]string, len(s.tags))
 		for k, v := range ss.tags {
@@ -455,10 +1468,13 @@ func (s *scope) Snapshot() Snapshot {
 		for key, c := range ss.counters {
 			name := ss.fullyQualifiedName(key)
 			id := KeyForPrefixedStringMap(name, tags)
+			total := c.snapshotTotal()
 			snap.counters[id] = &counterSnapshot{
 				name:  name,
 				tags:  tags,
-				value: c.snapshot(),
+				value: total,
+				float: c.snapshotTotalFloat(),
+				delta: c.snapshotDelta(total, advance),
 			}
 		}
 		ss.cm.RUnlock()
@@ -467,9 +1483,10 @@ func (s *scope) Snapshot() Snapshot {
 			name := ss.fullyQualifiedName(key)
 			id := KeyForPrefixedStringMap(name, tags)
 			snap.gauges[id] = &gaugeSnapshot{
-				name:  name,
-				tags:  tags,
-				value: g.snapshot(),
+				name:    name,
+				tags:    tags,
+				value:   g.snapshot(),
+				updated: g.snapshotUpdated(),
 			}
 		}
 		ss.gm.RUnlock()
@@ -478,9 +1495,10 @@ func (s *scope) Snapshot() Snapshot {
 			name := ss.fullyQualifiedName(key)
 			id := KeyForPrefixedStringMap(name, tags)
 			snap.timers[id] = &timerSnapshot{
-				name:   name,
-				tags:   tags,
-				values: t.snapshot(),
+				name:    name,
+				tags:    tags,
+				values:  t.snapshot(),
+				samples: t.snapshotSamples(),
 			}
 		}
 		ss.tm.RUnlock()
@@ -493,9 +1511,24 @@ func (s *scope) Snapshot() Snapshot {
 				tags:      tags,
 				values:    h.snapshotValues(),
 				durations: h.snapshotDurations(),
+				samples:   h.snapshotSamples(),
+				buckets:   h.snapshotBuckets(),
+				underflow: h.snapshotUnderflow(),
+				overflow:  h.snapshotOverflow(),
 			}
 		}
 		ss.hm.RUnlock()
+		ss.mm.RLock()
+		for key, m := range ss.meters {
+			name := ss.fullyQualifiedName(key)
+			id := KeyForPrefixedStringMap(name, tags)
+			snap.meters[id] = &meterSnapshot{
+				name:   name,
+				tags:   tags,
+				values: m.snapshot(),
+			}
+		}
+		ss.mm.RUnlock()
 	})
 
 	return snap
@@ -511,10 +1544,18 @@ func (s *scope) Close() error {
 	close(s.done)
 
 	if s.root {
-		s.reportRegistry()
+		// NB: Wait for the report loop so that the final report is the last.
+		s.wg.Wait()
+		flushErr := s.reportRegistry()
+		var closeErr error
 		if closer, ok := s.baseReporter.(io.Closer); ok {
-			return closer.Close()
+			closeErr = closer.Close()
+		}
+		s.registry.errors.close()
+		if flushErr != nil {
+			return flushErr
 		}
+		return closeErr
 	}
 
 	return nil
@@ -525,25 +1566,42 @@ func (s *scope) clearMetrics() {
 	s.gm.Lock()
 	s.tm.Lock()
 	s.hm.Lock()
+	s.mm.Lock()
 	defer s.cm.Unlock()
 	defer s.gm.Unlock()
 	defer s.tm.Unlock()
 	defer s.hm.Unlock()
+	defer s.mm.Unlock()
+
+	for k := range s.meters {
+		delete(s.meters, k)
+	}
+	s.metersSlice = nil
 
 	for k := range s.counters {
 		delete(s.counters, k)
 	}
 	s.countersSlice = nil
+	s.rateLimitedCounters = nil
+	s.autoHistograms = nil
 
 	for k := range s.gauges {
 		delete(s.gauges, k)
 	}
 	s.gaugesSlice = nil
+	s.ratios = nil
+	s.infos = nil
+	s.gaugeFuncs = nil
+	s.trackingGauges = nil
+	s.timerPercentiles = nil
+	s.decayingTimers = nil
+	s.cardinalityBudget = nil
 
 	for k := range s.timers {
 		delete(s.timers, k)
 	}
 
+	s.registry.numHistograms.Sub(int64(len(s.histograms)))
 	for k := range s.histograms {
 		delete(s.histograms, k)
 	}
@@ -566,13 +1624,56 @@ func (s *scope) fullyQualifiedName(name string) string {
 }
 
 func (s *scope) copyAndSanitizeMap(tags map[string]string) map[string]string {
+	result, err := s.sanitizeTags(tags)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+func (s *scope) sanitizeTags(tags map[string]string) (map[string]string, error) {
 	result := make(map[string]string, len(tags))
+
+	// NB: Track the original key of each normalized key that differs from
+	// it so that collisions resolve by the sort order of the original keys
+	// rather than the map iteration order.
+	var origins map[string]string
+
 	for k, v := range tags {
-		k = s.sanitizer.Key(k)
-		v = s.sanitizer.Value(v)
-		result[k] = v
+		key := s.tagKeyCase.apply(s.sanitizer.Key(k))
+		if _, ok := result[key]; ok {
+			origin, ok := origins[key]
+			if !ok {
+				origin = key
+			}
+			switch s.tagKeyCollisionPolicy {
+			case ErrorOnTagKeyCollision:
+				first, second := origin, k
+				if second < first {
+					first, second = second, first
+				}
+				return nil, fmt.Errorf("tag keys %q and %q both normalize to %q", first, second, key)
+			case LastTagKeyWins:
+				if k < origin {
+					continue
+				}
+			default:
+				if origin < k {
+					continue
+				}
+			}
+		}
+		if key != k {
+			if origins == nil {
+				origins = make(map[string]string)
+			}
+			origins[key] = k
+		} else {
+			delete(origins, key)
+		}
+		result[key] = s.tagValueCase.apply(s.sanitizer.Value(v))
 	}
-	return result
+	return result, nil
 }
 
 // TestScope is a metrics collector that has no reporting, ensuring that
@@ -587,7 +1688,7 @@ type TestScope interface {
 
 // Snapshot is a snapshot of values since last report execution
 type Snapshot interface {
-	// Counters returns a snapshot of all counter summations since last report execution
+	// Counters returns a snapshot of all counter totals
 	Counters() map[string]CounterSnapshot
 
 	// Gauges returns a snapshot of gauge last values since last report execution
@@ -598,6 +1699,28 @@ type Snapshot interface {
 
 	// Histograms returns a snapshot of histogram samples since last report execution
 	Histograms() map[string]HistogramSnapshot
+
+	// Meters returns a snapshot of meter values since last report execution
+	Meters() map[string]MeterSnapshot
+
+	// Counts returns the number of series of each kind in the snapshot
+	Counts() SnapshotCounts
+
+	// Compact returns a copy of the snapshot without the empty series, i.e.
+	// counters with a zero value, gauges not updated since last report
+	// execution, timers and meters without values and histograms with only
+	// zero bucket counts
+	Compact() Snapshot
+}
+
+// SnapshotCounts is the number of series of each kind in a snapshot
+type SnapshotCounts struct {
+	Counters   int
+	Gauges     int
+	Timers     int
+	Histograms int
+	Meters     int
+	Total      int
 }
 
 // CounterSnapshot is a snapshot of a counter
@@ -608,8 +1731,18 @@ type CounterSnapshot interface {
 	// Tags returns the tags
 	Tags() map[string]string
 
-	// Value returns the value
+	// Value returns the total of the counter since it was created,
+	// regardless of reports in between.
 	Value() int64
+
+	// ValueFloat returns the value including the fractions of the
+	// increments of Counter.IncFloat, which Value rounds.
+	ValueFloat() float64
+
+	// Delta returns the change of the value since the previous
+	// TestScope.Snapshot, or since the counter was created for the first one.
+	// SubtreeSnapshot reports the same change without consuming it.
+	Delta() int64
 }
 
 // GaugeSnapshot is a snapshot of a gauge
@@ -634,6 +1767,15 @@ type TimerSnapshot interface {
 
 	// Values returns the values
 	Values() []time.Duration
+
+	// Samples returns the sample of values retained by a timer created with
+	// TimerOptions.DecayingReservoirSize since the last report, or nil.
+	Samples() []time.Duration
+
+	// Quantile returns the q quantile of the values, linearly interpolated
+	// between the two closest values, or 0 if there are none. q is clamped
+	// to the range [0, 1].
+	Quantile(q float64) time.Duration
 }
 
 // HistogramSnapshot is a snapshot of a histogram
@@ -649,6 +1791,71 @@ type HistogramSnapshot interface {
 
 	// Durations returns the sample values by upper bound for a durationHistogram
 	Durations() map[time.Duration]int64
+
+	// Samples returns the raw values retained since last report execution
+	// when enabled with ScopeOptions.HistogramReservoirSize, durations are
+	// returned in seconds.
+	Samples() []float64
+
+	// Buckets returns the bucket pairs of the histogram sorted by bound,
+	// ending with the overflow bucket whose upper bound is math.MaxFloat64
+	// for a valueHistogram and math.MaxInt64 for a durationHistogram. The
+	// upper bounds are the keys of Values or Durations.
+	Buckets() []BucketPair
+
+	// Underflow returns the number of samples below the lowest finite
+	// bucket bound, which are counted in the first bucket. Values are
+	// compared before they are clamped by HistogramOptions.
+	Underflow() int64
+
+	// Overflow returns the number of samples above the highest finite
+	// bucket bound, which are counted in the overflow bucket. Values are
+	// compared before they are clamped by HistogramOptions.
+	Overflow() int64
+
+	// Quantile estimates the q quantile of the samples by linear
+	// interpolation within the bucket it falls into, in seconds for a
+	// durationHistogram, or returns 0 if there are no samples. q is clamped
+	// to the range [0, 1]. A quantile in the first bucket or the overflow
+	// bucket, which have no finite lower and upper bound respectively, is
+	// their finite bound.
+	Quantile(q float64) float64
+
+	// CumulativeValues returns for a valueHistogram the number of samples
+	// by upper bound like Values, but counting the samples of every bucket
+	// up to and including the upper bound, so that the counts increase with
+	// the upper bound as in "le" buckets. The overflow bucket is keyed by
+	// math.Inf(1) rather than math.MaxFloat64 and holds the total number of
+	// samples. It returns nil for a durationHistogram.
+	CumulativeValues() map[float64]int64
+
+	// CumulativeDurations is CumulativeValues for a durationHistogram. The
+	// overflow bucket, which holds the total number of samples, keeps its
+	// upper bound of math.MaxInt64 as durations have no infinity. It returns
+	// nil for a valueHistogram.
+	CumulativeDurations() map[time.Duration]int64
+}
+
+// MeterSnapshot is a snapshot of a meter
+type MeterSnapshot interface {
+	// Name returns the name
+	Name() string
+
+	// Tags returns the tags
+	Tags() map[string]string
+
+	// Values returns the values recorded since last report execution in the
+	// order they were recorded
+	Values() []float64
+}
+
+// withCommonTags returns the tags of a scope merged with the common tags for
+// reporters, the tags themselves if there are no common tags.
+func withCommonTags(commonTags, tags map[string]string) map[string]string {
+	if len(commonTags) == 0 {
+		return tags
+	}
+	return mergeRightTags(commonTags, tags)
 }
 
 // mergeRightTags merges 2 sets of tags with the tags from tagsRight overriding values from tagsLeft
@@ -678,6 +1885,7 @@ type snapshot struct {
 	gauges     map[string]GaugeSnapshot
 	timers     map[string]TimerSnapshot
 	histograms map[string]HistogramSnapshot
+	meters     map[string]MeterSnapshot
 }
 
 func newSnapshot() *snapshot {
@@ -686,6 +1894,7 @@ func newSnapshot() *snapshot {
 		gauges:     make(map[string]GaugeSnapshot),
 		timers:     make(map[string]TimerSnapshot),
 		histograms: make(map[string]HistogramSnapshot),
+		meters:     make(map[string]MeterSnapshot),
 	}
 }
 
@@ -705,10 +1914,72 @@ func (s *snapshot) Histograms() map[string]HistogramSnapshot {
 	return s.histograms
 }
 
+func (s *snapshot) Meters() map[string]MeterSnapshot {
+	return s.meters
+}
+
+func (s *snapshot) Counts() SnapshotCounts {
+	c := SnapshotCounts{
+		Counters:   len(s.counters),
+		Gauges:     len(s.gauges),
+		Timers:     len(s.timers),
+		Histograms: len(s.histograms),
+		Meters:     len(s.meters),
+	}
+	c.Total = c.Counters + c.Gauges + c.Timers + c.Histograms + c.Meters
+	return c
+}
+
+func (s *snapshot) Compact() Snapshot {
+	compact := newSnapshot()
+	for id, c := range s.counters {
+		if c.Value() != 0 || c.ValueFloat() != 0 {
+			compact.counters[id] = c
+		}
+	}
+	for id, g := range s.gauges {
+		if gaugeSnapshotUpdated(g) {
+			compact.gauges[id] = g
+		}
+	}
+	for id, t := range s.timers {
+		if len(t.Values()) > 0 {
+			compact.timers[id] = t
+		}
+	}
+	for id, h := range s.histograms {
+		if !emptyHistogramSnapshot(h) {
+			compact.histograms[id] = h
+		}
+	}
+	for id, m := range s.meters {
+		if len(m.Values()) > 0 {
+			compact.meters[id] = m
+		}
+	}
+	return compact
+}
+
+func emptyHistogramSnapshot(h HistogramSnapshot) bool {
+	for _, count := range h.Values() {
+		if count != 0 {
+			return false
+		}
+	}
+	for _, count := range h.Durations() {
+		if count != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 type counterSnapshot struct {
 	name  string
 	tags  map[string]string
 	value int64
+	float float64
+	delta int64
 }
 
 func (s *counterSnapshot) Name() string {
@@ -723,10 +1994,19 @@ func (s *counterSnapshot) Value() int64 {
 	return s.value
 }
 
+func (s *counterSnapshot) ValueFloat() float64 {
+	return s.float
+}
+
+func (s *counterSnapshot) Delta() int64 {
+	return s.delta
+}
+
 type gaugeSnapshot struct {
-	name  string
-	tags  map[string]string
-	value float64
+	name    string
+	tags    map[string]string
+	value   float64
+	updated bool
 }
 
 func (s *gaugeSnapshot) Name() string {
@@ -742,9 +2022,10 @@ func (s *gaugeSnapshot) Value() float64 {
 }
 
 type timerSnapshot struct {
-	name   string
-	tags   map[string]string
-	values []time.Duration
+	name    string
+	tags    map[string]string
+	values  []time.Duration
+	samples []time.Duration
 }
 
 func (s *timerSnapshot) Name() string {
@@ -759,11 +2040,19 @@ func (s *timerSnapshot) Values() []time.Duration {
 	return s.values
 }
 
+func (s *timerSnapshot) Samples() []time.Duration {
+	return s.samples
+}
+
 type histogramSnapshot struct {
 	name      string
 	tags      map[string]string
 	values    map[float64]int64
 	durations map[time.Duration]int64
+	samples   []float64
+	buckets   []BucketPair
+	underflow int64
+	overflow  int64
 }
 
 func (s *histogramSnapshot) Name() string {
@@ -781,3 +2070,19 @@ func (s *histogramSnapshot) Values() map[float64]int64 {
 func (s *histogramSnapshot) Durations() map[time.Duration]int64 {
 	return s.durations
 }
+
+func (s *histogramSnapshot) Samples() []float64 {
+	return s.samples
+}
+
+func (s *histogramSnapshot) Buckets() []BucketPair {
+	return s.buckets
+}
+
+func (s *histogramSnapshot) Underflow() int64 {
+	return s.underflow
+}
+
+func (s *histogramSnapshot) Overflow() int64 {
+	return s.overflow
+}
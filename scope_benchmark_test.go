@@ -112,6 +112,43 @@ func BenchmarkScopeTaggedCachedSubscopes(b *testing.B) {
 	}
 }
 
+func BenchmarkScopeTaggedSubScopeCached(b *testing.B) {
+	root, _ := NewRootScope(ScopeOptions{
+		Prefix:   "funkytown",
+		Reporter: NullStatsReporter,
+		Tags: map[string]string{
+			"style": "funky",
+		},
+	}, 0)
+	tags := map[string]string{"foo": "bar", "baz": "qux"}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		root.Tagged(tags).SubScope("x").Counter("y")
+	}
+}
+
+func BenchmarkScopeChildCached(b *testing.B) {
+	root, _ := NewRootScope(ScopeOptions{
+		Prefix:   "funkytown",
+		Reporter: NullStatsReporter,
+		Tags: map[string]string{
+			"style": "funky",
+		},
+	}, 0)
+	opts := ChildOptions{
+		Prefix: "x",
+		Tags:   map[string]string{"foo": "bar", "baz": "qux"},
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		Child(root, opts).Counter("y")
+	}
+}
+
 func BenchmarkScopeTaggedNoCachedSubscopes(b *testing.B) {
 	root, _ := NewRootScope(ScopeOptions{
 		Prefix:   "funkytown",
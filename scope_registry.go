@@ -22,6 +22,8 @@ package tally
 
 import (
 	"sync"
+
+	"go.uber.org/atomic"
 )
 
 var scopeRegistryKey = keyForPrefixedStringMaps
@@ -30,6 +32,44 @@ type scopeRegistry struct {
 	mu        sync.RWMutex
 	root      *scope
 	subscopes map[string]*scope
+
+	numHistograms         atomic.Int64
+	coarseningThreshold   int64
+	coarseValueBuckets    ValueBuckets
+	coarseDurationBuckets DurationBuckets
+
+	names         metricNames
+	tagValues     tagCardinality
+	tagValidators tagValidators
+
+	// allowedTagKeys are the ScopeOptions.AllowedTagKeys, nil if all keys
+	// are allowed.
+	allowedTagKeys map[string]struct{}
+
+	// maxTimerSamples and timerSampleRand are the sampling of
+	// ScopeOptions.MaxTimerSamplesPerInterval.
+	maxTimerSamples int
+	timerSampleRand *sampleRand
+
+	// idle is the clock of MetricIdleTTL, nil if metrics never expire.
+	idle *idleClock
+
+	// errors is the hook of ScopeOptions.OnError, nil if not set.
+	errors *errorHook
+
+	// commonTags are the ScopeOptions.CommonTags, nil if not set.
+	commonTags map[string]string
+
+	// dropHistograms is whether histograms do nothing for
+	// ScopeOptions.DropUnsupportedHistograms.
+	dropHistograms bool
+
+	// autoHistograms are the histograms with AutoBuckets in warm-up.
+	autoMu         sync.Mutex
+	autoHistograms []*autoHistogram
+
+	transactions atomic.Bool
+	txBarrier    sync.RWMutex
 }
 
 func newScopeRegistry(root *scope) *scopeRegistry {
@@ -82,11 +122,28 @@ func (r *scopeRegistry) ForEachScope(f func(*scope)) {
 }
 
 func (r *scopeRegistry) Subscope(parent *scope, prefix string, tags map[string]string) *scope {
+	return r.SubscopeWithSeparator(parent, prefix, parent.separator, tags)
+}
+
+// SubscopeWithSeparator is Subscope for a subscope that joins names with the
+// given separator rather than the one of its parent.
+func (r *scopeRegistry) SubscopeWithSeparator(
+	parent *scope,
+	prefix string,
+	separator string,
+	tags map[string]string,
+) *scope {
 	if r.root.closed.Load() || parent.closed.Load() {
 		return NoopScope.(*scope)
 	}
 
-	key := scopeRegistryKey(prefix, parent.tags, tags)
+	keyPrefix := prefix
+	if separator != r.root.separator {
+		// NB: Scopes with the same prefix and tags but another separator
+		// are distinct, the keys of the others are unchanged.
+		keyPrefix = prefix + string(prefixSplitter) + separator
+	}
+	key := scopeRegistryKey(keyPrefix, parent.tags, tags)
 
 	r.mu.RLock()
 	if s, ok := r.lockedLookup(key); ok {
@@ -104,18 +161,40 @@ func (r *scopeRegistry) Subscope(parent *scope, prefix string, tags map[string]s
 
 	allTags := mergeRightTags(parent.tags, tags)
 	subscope := &scope{
-		separator: parent.separator,
+		separator: separator,
 		prefix:    prefix,
 		// NB(prateek): don't need to copy the tags here,
 		// we assume the map provided is immutable.
 		tags:           allTags,
+		reportTags:     withCommonTags(r.commonTags, allTags),
 		reporter:       parent.reporter,
 		cachedReporter: parent.cachedReporter,
 		baseReporter:   parent.baseReporter,
 		defaultBuckets: parent.defaultBuckets,
 		sanitizer:      parent.sanitizer,
+		sanitizeReject: parent.sanitizeReject,
 		registry:       parent.registry,
 
+		maxNameLength:      parent.maxNameLength,
+		nameLengthMode:     parent.nameLengthMode,
+		nameLengthExceeded: parent.nameLengthExceeded,
+
+		histogramReservoirSize: parent.histogramReservoirSize,
+		seriesRateLimit:        parent.seriesRateLimit,
+		seriesRateLimitBurst:   parent.seriesRateLimitBurst,
+		seriesRateLimitDropped: parent.seriesRateLimitDropped,
+
+		timerSnapshotLimit:         parent.timerSnapshotLimit,
+		timerSnapshotDropRemainder: parent.timerSnapshotDropRemainder,
+		timerSnapshotDropped:       parent.timerSnapshotDropped,
+
+		tagKeyCase:            parent.tagKeyCase,
+		tagValueCase:          parent.tagValueCase,
+		tagKeyCollisionPolicy: parent.tagKeyCollisionPolicy,
+
+		recoverCallbackPanics: parent.recoverCallbackPanics,
+		callbackPanicsCounter: parent.callbackPanicsCounter,
+
 		counters:        make(map[string]*counter),
 		countersSlice:   make([]*counter, 0, _defaultInitialSliceSize),
 		gauges:          make(map[string]*gauge),
@@ -123,7 +202,9 @@ func (r *scopeRegistry) Subscope(parent *scope, prefix string, tags map[string]s
 		histograms:      make(map[string]*histogram),
 		histogramsSlice: make([]*histogram, 0, _defaultInitialSliceSize),
 		timers:          make(map[string]*timer),
+		meters:          make(map[string]*meter),
 		bucketCache:     parent.bucketCache,
+		clock:           parent.clock,
 		done:            make(chan struct{}),
 	}
 	r.subscopes[key] = subscope
@@ -159,3 +240,18 @@ func (r *scopeRegistry) removeWithRLock(key string) {
 	defer r.mu.Unlock()
 	delete(r.subscopes, key)
 }
+
+// coarseHistogramBuckets counts a newly created histogram and returns the
+// coarse buckets it should use instead of its own if the coarsening
+// threshold has been reached.
+func (r *scopeRegistry) coarseHistogramBuckets(htype histogramType) (Buckets, bool) {
+	n := r.numHistograms.Inc()
+	if r.coarseningThreshold <= 0 || n <= r.coarseningThreshold {
+		return nil, false
+	}
+
+	if htype == durationHistogramType {
+		return r.coarseDurationBuckets, true
+	}
+	return r.coarseValueBuckets, true
+}
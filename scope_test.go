@@ -21,6 +21,7 @@
 package tally
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
@@ -638,6 +639,64 @@ func TestCounterSanitized(t *testing.T) {
 	assert.Nil(t, histograms["work1__"])
 }
 
+func TestCounterCumulative(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{Reporter: r}, 0)
+	defer closer.Close()
+
+	s := root.(*scope)
+	resetting := s.Counter("resetting")
+	cumulative := CounterWithOptions(s, "cumulative", CounterOptions{Cumulative: true})
+
+	for _, expected := range []struct {
+		resetting  int64
+		cumulative int64
+	}{
+		{resetting: 2, cumulative: 2},
+		{resetting: 2, cumulative: 4},
+	} {
+		resetting.Inc(2)
+		cumulative.Inc(2)
+
+		// The snapshot values are the totals of both, only reports differ.
+		snap := s.Snapshot().Counters()
+		assert.EqualValues(t, expected.cumulative, snap["resetting+"].Value())
+		assert.EqualValues(t, expected.cumulative, snap["cumulative+"].Value())
+
+		r.cg.Add(2)
+		s.report(r)
+		r.cg.Wait()
+
+		counters := r.getCounters()
+		assert.EqualValues(t, expected.resetting, counters["resetting"].val)
+		assert.EqualValues(t, expected.cumulative, counters["cumulative"].val)
+	}
+
+	// Existing counters are returned regardless of options.
+	assert.Equal(t, resetting, CounterWithOptions(s, "resetting", CounterOptions{Cumulative: true}))
+}
+
+func TestCachedCounterCumulative(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{CachedReporter: r}, 0)
+	defer closer.Close()
+
+	s := root.(*scope)
+	cumulative := CounterWithOptions(s, "cumulative", CounterOptions{Cumulative: true})
+
+	cumulative.Inc(3)
+	r.cg.Add(1)
+	s.cachedReport()
+	r.cg.Wait()
+	assert.EqualValues(t, 3, r.getCounters()["cumulative"].val)
+
+	cumulative.Inc(3)
+	r.cg.Add(1)
+	s.cachedReport()
+	r.cg.Wait()
+	assert.EqualValues(t, 6, r.getCounters()["cumulative"].val)
+}
+
 func TestCachedReporter(t *testing.T) {
 	r := newTestStatsReporter()
 
@@ -960,6 +1019,98 @@ func TestTaggedSubScope(t *testing.T) {
 	}, histograms["foo.bar"].tags)
 }
 
+func TestSubScopeFromTags(t *testing.T) {
+	root := NewTestScope("service", map[string]string{"env": "test"})
+
+	tags := map[string]string{"region": "us-east", "zone": "a", "host": "h1"}
+	s := SubScopeFromTags(root, []string{"region", "missing", "zone"}, tags)
+	s.Counter("requests").Inc(1)
+
+	counter, ok := root.Snapshot().Counters()["service.us-east.a.requests+env=test,host=h1,region=us-east,zone=a"]
+	require.True(t, ok, "missing counter in %v", root.Snapshot().Counters())
+	assert.EqualValues(t, 1, counter.Value())
+	assert.Equal(t, map[string]string{
+		"env": "test", "region": "us-east", "zone": "a", "host": "h1",
+	}, counter.Tags())
+
+	// Without a prefix the first value starts the prefix.
+	noPrefix := NewTestScope("", nil)
+	SubScopeFromTags(noPrefix, []string{"zone"}, tags).Gauge("queue").Update(1)
+	_, ok = noPrefix.Snapshot().Gauges()["a.queue+host=h1,region=us-east,zone=a"]
+	assert.True(t, ok, "missing gauge in %v", noPrefix.Snapshot().Gauges())
+}
+
+func TestSubScopeFromTagsExistingTags(t *testing.T) {
+	root := NewTestScope("service", map[string]string{"env": "test", "zone": "a"})
+
+	// Keys only present in the current tags are still appended, and given
+	// tags override current tags.
+	s := SubScopeFromTags(root, []string{"env", "zone"}, map[string]string{"zone": "b"})
+	s.Counter("requests").Inc(1)
+
+	counter, ok := root.Snapshot().Counters()["service.test.b.requests+env=test,zone=b"]
+	require.True(t, ok, "missing counter in %v", root.Snapshot().Counters())
+	assert.Equal(t, map[string]string{"env": "test", "zone": "b"}, counter.Tags())
+}
+
+type wrappedScope struct {
+	Scope
+}
+
+func TestSubScopeFromTagsOtherScope(t *testing.T) {
+	root := NewTestScope("service", map[string]string{"env": "test"})
+
+	// Only the given tags are consulted for other Scope implementations.
+	s := SubScopeFromTags(wrappedScope{root}, []string{"env", "zone"}, map[string]string{"zone": "a"})
+	s.Gauge("queue").Update(1)
+
+	_, ok := root.Snapshot().Gauges()["service.a.queue+env=test,zone=a"]
+	assert.True(t, ok, "missing gauge in %v", root.Snapshot().Gauges())
+}
+
+func TestChild(t *testing.T) {
+	root := NewTestScope("service", map[string]string{"env": "test"})
+
+	tags := map[string]string{"region": "us-east", "env": "prod"}
+	s := Child(root, ChildOptions{Prefix: "http", Tags: tags})
+	// The scope is the one created by chaining.
+	assert.True(t, s == root.Tagged(tags).SubScope("http"))
+	s.Counter("requests").Inc(1)
+
+	counter, ok := root.Snapshot().Counters()["service.http.requests+env=prod,region=us-east"]
+	require.True(t, ok, "missing counter in %v", root.Snapshot().Counters())
+	assert.EqualValues(t, 1, counter.Value())
+	assert.Equal(t, "service.http.requests", counter.Name())
+	assert.Equal(t, map[string]string{"env": "prod", "region": "us-east"}, counter.Tags())
+
+	// Without a prefix only the tags are added.
+	assert.True(t, Child(root, ChildOptions{Tags: tags}) == root.Tagged(tags))
+
+	// Other Scope implementations chain the scopes.
+	Child(wrappedScope{root}, ChildOptions{Prefix: "rpc", Tags: tags}).Gauge("queue").Update(1)
+	_, ok = root.Snapshot().Gauges()["service.rpc.queue+env=prod,region=us-east"]
+	assert.True(t, ok, "missing gauge in %v", root.Snapshot().Gauges())
+}
+
+func TestSubScopeWithSeparator(t *testing.T) {
+	root := NewTestScope("root", nil)
+
+	slash := SubScopeWithSeparator(root, "a", "/")
+	slash.Counter("c").Inc(1)
+	slash.SubScope("b").Tagged(map[string]string{"k": "v"}).Counter("d").Inc(1)
+	// The same prefix with the parent separator is a distinct scope.
+	dot := root.SubScope("a")
+	assert.False(t, dot == slash)
+	dot.Counter("c").Inc(1)
+	assert.True(t, slash == SubScopeWithSeparator(root, "a", "/"))
+
+	counters := root.Snapshot().Counters()
+	assert.Len(t, counters, 3)
+	assert.Contains(t, counters, "root.a/c+")
+	assert.Contains(t, counters, "root.a/b/d+k=v")
+	assert.Contains(t, counters, "root.a.c+")
+}
+
 func TestTaggedSanitizedSubScope(t *testing.T) {
 	r := newTestStatsReporter()
 
@@ -1073,6 +1224,309 @@ func TestSnapshot(t *testing.T) {
 	}
 }
 
+func TestTimeToFirstReport(t *testing.T) {
+	var (
+		now   = globalNow
+		start = time.Now()
+	)
+	globalNow = func() time.Time { return start }
+	defer func() { globalNow = now }()
+
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{
+		Reporter:               r,
+		TimeToFirstReportGauge: "tally.time_to_first_report_seconds",
+	}, 0)
+	s := root.(*scope)
+
+	globalNow = func() time.Time { return start.Add(2500 * time.Millisecond) }
+	r.gg.Add(1)
+	s.reportRegistry()
+	r.WaitAll()
+	assert.Equal(t, 2.5, r.getGauges()["tally.time_to_first_report_seconds"].val)
+
+	// Later reports, including the one on close, never update it again.
+	globalNow = func() time.Time { return start.Add(time.Minute) }
+	s.reportRegistry()
+	assert.NoError(t, closer.Close())
+	assert.Equal(t, 2.5, r.getGauges()["tally.time_to_first_report_seconds"].val)
+}
+
+func TestProcessStartTimeGauge(t *testing.T) {
+	now := globalNow
+	globalNow = func() time.Time { return time.Unix(1600000000, 500000000) }
+	defer func() { globalNow = now }()
+
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{
+		Reporter:              r,
+		ProcessStartTimeGauge: "tally.process_start_time_seconds",
+	}, 0)
+	s := root.(*scope)
+	globalNow = func() time.Time { return time.Unix(1600000060, 0) }
+
+	// The gauge is reported on every report, not only the first.
+	for i := 0; i < 2; i++ {
+		r.gg.Add(1)
+		s.reportRegistry()
+		r.WaitAll()
+		assert.Equal(t, 1600000000.5, r.getGauges()["tally.process_start_time_seconds"].val)
+	}
+
+	// Account for the report on close.
+	r.gg.Add(1)
+	assert.NoError(t, closer.Close())
+}
+
+func TestReportPhaseOffsetGauge(t *testing.T) {
+	now := globalNow
+	defer func() { globalNow = now }()
+
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{
+		Reporter:               r,
+		ReportPhaseOffsetGauge: "tally.report_phase_offset_ms",
+	}, time.Hour)
+	s := root.(*scope)
+
+	boundary := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		now  time.Time
+		want float64
+	}{
+		{now: boundary.Add(250 * time.Millisecond), want: 250},
+		{now: boundary.Add(-1500 * time.Millisecond), want: -1500},
+		{now: boundary, want: 0},
+	}
+	for _, tt := range tests {
+		reportNow := tt.now
+		globalNow = func() time.Time { return reportNow }
+		r.gg.Add(1)
+		s.reportRegistry()
+		r.WaitAll()
+		assert.Equal(t, tt.want, r.getGauges()["tally.report_phase_offset_ms"].val)
+	}
+
+	// Account for the report on close.
+	r.gg.Add(1)
+	assert.NoError(t, closer.Close())
+}
+
+func TestReportLoopHeartbeatCounter(t *testing.T) {
+	r := &heartbeatReporter{StatsReporter: NullStatsReporter}
+	_, closer := NewRootScope(ScopeOptions{
+		Reporter:                   r,
+		ReportLoopHeartbeatCounter: "tally.report_loop_heartbeat",
+	}, time.Millisecond)
+
+	for r.state().flushes < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.NoError(t, closer.Close())
+
+	// Each flush reports the increment after the previous one.
+	stopped := r.state()
+	assert.Equal(t, stopped.flushes-1, stopped.heartbeats)
+
+	// The heartbeat stops with the report loop.
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, stopped, r.state())
+}
+
+type heartbeatState struct {
+	flushes    int64
+	heartbeats int64
+}
+
+type heartbeatReporter struct {
+	StatsReporter
+
+	sync.Mutex
+	heartbeatState
+}
+
+func (r *heartbeatReporter) ReportCounter(name string, tags map[string]string, value int64) {
+	if name == "tally.report_loop_heartbeat" {
+		r.Lock()
+		r.heartbeats += value
+		r.Unlock()
+	}
+}
+
+func (r *heartbeatReporter) Flush() {
+	r.Lock()
+	r.flushes++
+	r.Unlock()
+}
+
+func (r *heartbeatReporter) state() heartbeatState {
+	r.Lock()
+	defer r.Unlock()
+	return r.heartbeatState
+}
+
+func TestTimerWithHistogramBuckets(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{Reporter: r}, 0)
+	s := root.(*scope)
+
+	timer := TimerWithOptions(s, "latency", TimerOptions{
+		HistogramBuckets: DurationBuckets{10 * time.Millisecond, 100 * time.Millisecond},
+	})
+
+	r.tg.Add(2)
+	timer.Record(5 * time.Millisecond)
+	timer.Record(50 * time.Millisecond)
+	r.tg.Wait()
+	assert.EqualValues(t, 50*time.Millisecond, r.getTimers()["latency"].val)
+
+	r.hg.Add(2)
+	s.report(r)
+	r.WaitAll()
+
+	histogram := r.getHistograms()["latency.histogram"]
+	require.NotNil(t, histogram)
+	assert.Equal(t, map[time.Duration]int{
+		10 * time.Millisecond:  1,
+		100 * time.Millisecond: 1,
+	}, histogram.durationSamples)
+
+	assert.NoError(t, closer.Close())
+}
+
+func TestSnapshotCounts(t *testing.T) {
+	s := NewTestScope("foo", nil)
+	child := s.Tagged(map[string]string{"service": "test"})
+
+	s.Counter("beep").Inc(1)
+	child.Counter("beep").Inc(1)
+	s.Gauge("bzzt").Update(2)
+	s.Timer("brrr").Record(time.Second)
+	s.Histogram("fizz", ValueBuckets{0, 2, 4}).RecordValue(1)
+	child.Histogram("fizz", ValueBuckets{0, 2, 4}).RecordValue(1)
+
+	snap := s.Snapshot()
+	assert.Equal(t, SnapshotCounts{
+		Counters:   len(snap.Counters()),
+		Gauges:     len(snap.Gauges()),
+		Timers:     len(snap.Timers()),
+		Histograms: len(snap.Histograms()),
+		Total:      6,
+	}, snap.Counts())
+	assert.Equal(t, 2, snap.Counts().Counters)
+}
+
+func TestSnapshotCounterValueFloat(t *testing.T) {
+	s := NewTestScope("", nil)
+
+	s.Counter("bytes").IncFloat(1.25)
+	s.Counter("bytes").Inc(1)
+	s.Counter("credits").IncFloat(0.25)
+
+	snap := s.Snapshot()
+	counters := snap.Counters()
+	assert.Equal(t, 2.25, counters["bytes+"].ValueFloat())
+	assert.Equal(t, int64(2), counters["bytes+"].Value())
+	assert.Equal(t, 0.25, counters["credits+"].ValueFloat())
+	assert.Equal(t, int64(0), counters["credits+"].Value())
+	// A fraction is not compacted away even if it rounds to zero.
+	assert.Contains(t, snap.Compact().Counters(), "credits+")
+}
+
+func TestSnapshotCompact(t *testing.T) {
+	s := NewTestScope("", nil)
+
+	s.Counter("active").Inc(1)
+	s.Counter("empty")
+	s.Gauge("active").Update(0)
+	s.Gauge("empty")
+	s.Timer("active").Record(time.Second)
+	s.Timer("empty")
+	s.Histogram("active", ValueBuckets{1, 2}).RecordValue(1)
+	s.Histogram("empty", ValueBuckets{1, 2})
+	s.Histogram("empty_durations", DurationBuckets{time.Second})
+
+	snap := s.Snapshot()
+	assert.Equal(t, 9, snap.Counts().Total)
+
+	compact := snap.Compact()
+	assert.Equal(t, SnapshotCounts{
+		Counters:   1,
+		Gauges:     1,
+		Timers:     1,
+		Histograms: 1,
+		Total:      4,
+	}, compact.Counts())
+	assert.Contains(t, compact.Counters(), "active+")
+	assert.Contains(t, compact.Gauges(), "active+")
+	assert.Contains(t, compact.Timers(), "active+")
+	assert.Contains(t, compact.Histograms(), "active+")
+
+	// The snapshot itself is unchanged.
+	assert.Equal(t, 9, snap.Counts().Total)
+}
+
+func TestSnapshotHistogramBuckets(t *testing.T) {
+	s := NewTestScope("", nil)
+
+	h := s.Histogram("values", ValueBuckets{20, 10})
+	for _, v := range []float64{5, 10, 10.5, 20, 25} {
+		h.RecordValue(v)
+	}
+	s.Histogram("durations", nil).RecordDuration(time.Hour)
+
+	histograms := s.Snapshot().Histograms()
+
+	values := histograms["values+"]
+	buckets := values.Buckets()
+	require.Len(t, buckets, 3)
+	for i, b := range []struct {
+		lower, upper float64
+		count        int64
+	}{
+		{-math.MaxFloat64, 10, 2},
+		{10, 20, 2},
+		{20, math.MaxFloat64, 1},
+	} {
+		assert.Equal(t, b.lower, buckets[i].LowerBoundValue())
+		assert.Equal(t, b.upper, buckets[i].UpperBoundValue())
+		assert.Equal(t, b.count, values.Values()[buckets[i].UpperBoundValue()])
+	}
+
+	// Nil buckets are the default buckets of the scope.
+	durations := histograms["durations+"]
+	buckets = durations.Buckets()
+	require.Len(t, buckets, len(defaultScopeBuckets)+1)
+	for i, d := range defaultScopeBuckets {
+		assert.Equal(t, d, buckets[i].UpperBoundDuration())
+	}
+	overflow := buckets[len(buckets)-1]
+	assert.Equal(t, time.Duration(math.MaxInt64), overflow.UpperBoundDuration())
+	assert.Equal(t, int64(1), durations.Durations()[overflow.UpperBoundDuration()])
+}
+
+func TestSnapshotTestScopeWithOptions(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{
+		Prefix:    "service",
+		Separator: "_",
+		Tags:      map[string]string{"env": "prod", "region": "us"},
+	})
+	child := s.SubScope("rpc").Tagged(map[string]string{"env": "canary"})
+
+	s.Counter("requests").Inc(1)
+	child.Gauge("inflight").Update(3)
+
+	snap := s.Snapshot()
+	counter, ok := snap.Counters()["service_requests+env=prod,region=us"]
+	require.True(t, ok, "missing counter in %v", snap.Counters())
+	assert.Equal(t, "service_requests", counter.Name())
+	assert.Equal(t, map[string]string{"env": "prod", "region": "us"}, counter.Tags())
+
+	gauge, ok := snap.Gauges()["service_rpc_inflight+env=canary,region=us"]
+	require.True(t, ok, "missing gauge in %v", snap.Gauges())
+	assert.Equal(t, map[string]string{"env": "canary", "region": "us"}, gauge.Tags())
+}
+
 func TestCapabilities(t *testing.T) {
 	r := newTestStatsReporter()
 	s, closer := NewRootScope(ScopeOptions{Reporter: r}, 0)
@@ -1121,6 +1575,256 @@ func TestScopeDefaultBuckets(t *testing.T) {
 	assert.EqualValues(t, 2, histograms["baz"].durationSamples[90*time.Millisecond])
 }
 
+func TestScopeDefaultBucketsInherited(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{
+		DefaultBuckets: ValueBuckets{1, 2},
+	})
+
+	sub := s.SubScope("sub").Tagged(map[string]string{"a": "b"})
+	sub.Histogram("nil", nil).RecordValue(1)
+	sub.Histogram("default", DefaultBuckets).RecordValue(2)
+	// Explicit buckets take precedence over the scope default.
+	sub.Histogram("explicit", ValueBuckets{10}).RecordValue(1)
+
+	histograms := s.Snapshot().Histograms()
+	for _, id := range []string{"sub.nil+a=b", "sub.default+a=b"} {
+		require.Contains(t, histograms, id)
+		buckets := histograms[id].Buckets()
+		require.Len(t, buckets, 3)
+		assert.Equal(t, float64(1), buckets[0].UpperBoundValue())
+		assert.Equal(t, float64(2), buckets[1].UpperBoundValue())
+	}
+	assert.Equal(t, int64(1), histograms["sub.nil+a=b"].Values()[1])
+	assert.Equal(t, int64(1), histograms["sub.default+a=b"].Values()[2])
+
+	buckets := histograms["sub.explicit+a=b"].Buckets()
+	require.Len(t, buckets, 2)
+	assert.Equal(t, float64(10), buckets[0].UpperBoundValue())
+}
+
+func TestHistogramCoarseningThreshold(t *testing.T) {
+	root, closer := NewRootScope(ScopeOptions{
+		HistogramCoarseningThreshold: 2,
+		CoarseHistogramBuckets:       DurationBuckets{time.Second},
+	}, 0)
+	defer closer.Close()
+
+	s := root.(*scope)
+	s.Histogram("fine-values", ValueBuckets{1, 2, 3}).RecordValue(1)
+	s.Histogram("fine-durations", DurationBuckets{time.Millisecond, time.Minute}).RecordDuration(time.Millisecond)
+	s.Histogram("coarse-values", ValueBuckets{1, 2, 3}).RecordValue(1)
+	s.SubScope("sub").Histogram("coarse-durations", DefaultBuckets).RecordDuration(time.Millisecond)
+
+	// Existing histograms are returned with their original buckets.
+	s.Histogram("fine-values", ValueBuckets{1, 2, 3}).RecordValue(2)
+
+	histograms := s.Snapshot().Histograms()
+	assert.Equal(t, map[float64]int64{
+		1: 1, 2: 1, 3: 0, math.MaxFloat64: 0,
+	}, histograms["fine-values+"].Values())
+	assert.Equal(t, map[time.Duration]int64{
+		time.Millisecond: 1, time.Minute: 0, math.MaxInt64: 0,
+	}, histograms["fine-durations+"].Durations())
+	assert.Equal(t, map[float64]int64{
+		1: 1, math.MaxFloat64: 0,
+	}, histograms["coarse-values+"].Values())
+	assert.Equal(t, map[time.Duration]int64{
+		time.Second: 1, math.MaxInt64: 0,
+	}, histograms["sub.coarse-durations+"].Durations())
+}
+
+type testEventReporter struct {
+	*testStatsReporter
+
+	events []testEvent
+}
+
+type testEvent struct {
+	title string
+	text  string
+	tags  map[string]string
+}
+
+func (r *testEventReporter) Event(title, text string, tags map[string]string) {
+	r.events = append(r.events, testEvent{title: title, text: text, tags: tags})
+}
+
+func TestEmitEvent(t *testing.T) {
+	r := &testEventReporter{testStatsReporter: newTestStatsReporter()}
+	root, closer := NewRootScope(ScopeOptions{
+		Reporter: r,
+		Tags:     map[string]string{"env": "test", "deploy": "none"},
+	}, 0)
+	defer closer.Close()
+
+	EmitEvent(root.Tagged(map[string]string{"service": "foo"}), "deploy", "v1.2.3",
+		map[string]string{"deploy": "canary"})
+
+	require.Equal(t, 1, len(r.events))
+	assert.Equal(t, testEvent{
+		title: "deploy",
+		text:  "v1.2.3",
+		tags:  map[string]string{"env": "test", "service": "foo", "deploy": "canary"},
+	}, r.events[0])
+}
+
+func TestEmitEventUnsupportedReporter(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{Reporter: r}, 0)
+	defer closer.Close()
+
+	assert.NotPanics(t, func() {
+		EmitEvent(root, "deploy", "v1.2.3", nil)
+		EmitEvent(NoopScope, "deploy", "v1.2.3", nil)
+	})
+}
+
+func TestFirstAlignedReport(t *testing.T) {
+	base := time.Date(2021, 3, 14, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		now      time.Time
+		interval time.Duration
+		expected time.Time
+	}{
+		{now: base, interval: 10 * time.Second, expected: base.Add(10 * time.Second)},
+		{now: base.Add(time.Millisecond), interval: 10 * time.Second, expected: base.Add(10 * time.Second)},
+		{now: base.Add(3 * time.Second), interval: 10 * time.Second, expected: base.Add(10 * time.Second)},
+		{now: base.Add(59 * time.Second), interval: time.Minute, expected: base.Add(time.Minute)},
+		{now: base.Add(90 * time.Minute), interval: time.Hour, expected: base.Add(2 * time.Hour)},
+		// Local time zones do not change the boundaries.
+		{now: base.Add(3 * time.Second).In(time.FixedZone("", 5*3600+1800)), interval: 10 * time.Second, expected: base.Add(10 * time.Second)},
+	}
+
+	for _, tt := range tests {
+		assert.True(t, tt.expected.Equal(firstAlignedReport(tt.now, tt.interval)), "now=%v", tt.now)
+	}
+}
+
+func TestNextAlignedReport(t *testing.T) {
+	var (
+		interval = 10 * time.Second
+		prev     = time.Date(2021, 3, 14, 10, 0, 0, 0, time.UTC)
+	)
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected time.Time
+	}{
+		{name: "on boundary", now: prev, expected: prev.Add(interval)},
+		{name: "just after boundary", now: prev.Add(time.Millisecond), expected: prev.Add(interval)},
+		// The wall clock lagging the monotonic timer must not schedule
+		// another report microseconds later at prev.
+		{name: "just before boundary", now: prev.Add(-time.Microsecond), expected: prev.Add(interval)},
+		{name: "late report", now: prev.Add(interval / 2), expected: prev.Add(interval)},
+		{name: "stepped forward", now: prev.Add(5*interval + time.Second), expected: prev.Add(6 * interval)},
+		{name: "stepped back", now: prev.Add(-3*interval - time.Second), expected: prev.Add(-3 * interval)},
+	}
+
+	for _, tt := range tests {
+		actual := nextAlignedReport(prev, tt.now, interval)
+		assert.True(t, tt.expected.Equal(actual), "%s: expected %v, actual %v", tt.name, tt.expected, actual)
+	}
+}
+
+type flushTimeReporter struct {
+	*testStatsReporter
+
+	flushes chan time.Time
+}
+
+func (r *flushTimeReporter) Flush() {
+	r.flushes <- globalNow()
+}
+
+func TestAlignedReportLoop(t *testing.T) {
+	var (
+		interval  = time.Hour
+		boundary  = time.Date(2021, 3, 14, 11, 0, 0, 0, time.UTC)
+		start     = boundary.Add(-20 * time.Millisecond)
+		realStart = time.Now()
+		now       = globalNow
+	)
+	globalNow = func() time.Time { return start.Add(time.Since(realStart)) }
+	defer func() { globalNow = now }()
+
+	r := &flushTimeReporter{
+		testStatsReporter: newTestStatsReporter(),
+		flushes:           make(chan time.Time, 2),
+	}
+	root, closer := NewRootScope(ScopeOptions{
+		Reporter:            r,
+		AlignReportsToClock: true,
+	}, interval)
+
+	select {
+	case flushed := <-r.flushes:
+		assert.False(t, flushed.Before(boundary), "flushed at %v", flushed)
+		assert.True(t, flushed.Before(boundary.Add(interval/2)), "flushed at %v", flushed)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for aligned flush")
+	}
+
+	// The next report is a full interval away, only the flush on close follows.
+	require.NoError(t, closer.Close())
+	root.(*scope).wg.Wait()
+	assert.Equal(t, 1, len(r.flushes))
+}
+
+func TestHistogramRetainedSamples(t *testing.T) {
+	s := newRootScope(ScopeOptions{HistogramReservoirSize: 5}, 0)
+	defer s.Close()
+
+	values := s.Histogram("values", ValueBuckets{10, 100})
+	durations := s.SubScope("sub").Histogram("durations", DurationBuckets{time.Second})
+	for i := 0; i < 3; i++ {
+		values.RecordValue(float64(i))
+	}
+	durations.RecordDuration(500 * time.Millisecond)
+
+	histograms := s.Snapshot().Histograms()
+	assert.Equal(t, []float64{0, 1, 2}, histograms["values+"].Samples())
+	assert.Equal(t, []float64{0.5}, histograms["sub.durations+"].Samples())
+
+	for i := 3; i < 200; i++ {
+		values.RecordValue(float64(i))
+	}
+
+	snap := s.Snapshot().Histograms()["values+"]
+	assert.Equal(t, 5, len(snap.Samples()))
+	assert.Equal(t, map[float64]int64{
+		10: 11, 100: 90, math.MaxFloat64: 99,
+	}, snap.Values())
+
+	// Retained samples are discarded on report along with bucket counts.
+	s.report(NullStatsReporter)
+	assert.Equal(t, 0, len(s.Snapshot().Histograms()["values+"].Samples()))
+}
+
+func TestHistogramRetainedSamplesSeed(t *testing.T) {
+	sample := func() []float64 {
+		s := NewTestScopeWithOptions(ScopeOptions{
+			HistogramReservoirSize: 10,
+			TimerSampleSeed:        1,
+		})
+		h := s.Histogram("values", ValueBuckets{10, 100})
+		for i := 0; i < 1000; i++ {
+			h.RecordValue(float64(i))
+		}
+		return s.Snapshot().Histograms()["values+"].Samples()
+	}
+
+	samples := sample()
+	assert.Len(t, samples, 10)
+	// The same seed retains the same sample.
+	assert.Equal(t, samples, sample())
+}
+
+func TestHistogramRetainedSamplesDisabled(t *testing.T) {
+	s := NewTestScope("", nil)
+	s.Histogram("values", ValueBuckets{10, 100}).RecordValue(1)
+	assert.Nil(t, s.Snapshot().Histograms()["values+"].Samples())
+}
+
 type testMets struct {
 	c Counter
 }
@@ -1179,3 +1883,117 @@ func TestScopeFlushOnClose(t *testing.T) {
 	assert.EqualValues(t, 1, counters["foo"].val)
 	assert.NoError(t, closer.Close())
 }
+
+type flushErrorReporter struct {
+	*testStatsReporter
+	err error
+}
+
+func (r flushErrorReporter) FlushWithError() error {
+	r.Flush()
+	return r.err
+}
+
+func TestScopeCloseReturnsFlushError(t *testing.T) {
+	errFlush := errors.New("flush failed")
+	r := flushErrorReporter{testStatsReporter: newTestStatsReporter(), err: errFlush}
+	root, closer := NewRootScope(ScopeOptions{Reporter: r}, 10*time.Millisecond)
+
+	r.cg.Add(1)
+	root.Counter("foo").Inc(1)
+
+	assert.Equal(t, errFlush, closer.Close())
+	r.cg.Wait()
+	assert.EqualValues(t, 1, r.getCounters()["foo"].val)
+
+	// The report loop has stopped, so nothing is flushed after close.
+	flushes := atomic.LoadInt32(&r.flushes)
+	assert.True(t, flushes > 0)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, flushes, atomic.LoadInt32(&r.flushes))
+
+	assert.NoError(t, closer.Close())
+}
+
+func TestSnapshotCounterDelta(t *testing.T) {
+	s := NewTestScope("", nil)
+	cumulative := CounterWithOptions(s, "total", CounterOptions{Cumulative: true})
+
+	s.Counter("requests").Inc(3)
+	cumulative.Inc(3)
+	first := s.Snapshot().Counters()
+	assert.Equal(t, int64(3), first["requests+"].Value())
+	assert.Equal(t, int64(3), first["requests+"].Delta())
+	assert.Equal(t, int64(3), first["total+"].Delta())
+
+	s.Counter("requests").Inc(2)
+	cumulative.Inc(2)
+	second := s.Snapshot().Counters()
+	assert.Equal(t, int64(5), second["requests+"].Value())
+	assert.Equal(t, int64(2), second["requests+"].Delta())
+	assert.Equal(t, int64(5), second["total+"].Value())
+	assert.Equal(t, int64(2), second["total+"].Delta())
+
+	// A report resets neither Value nor the interval of Delta.
+	s.(*scope).report(NullStatsReporter)
+	s.Counter("requests").Inc(1)
+	third := s.Snapshot().Counters()
+	assert.Equal(t, int64(6), third["requests+"].Value())
+	assert.Equal(t, int64(1), third["requests+"].Delta())
+
+	// A SubtreeSnapshot does not consume the delta of the next Snapshot.
+	s.Counter("requests").Inc(4)
+	subtree := SubtreeSnapshot(s).Counters()
+	assert.Equal(t, int64(10), subtree["requests+"].Value())
+	assert.Equal(t, int64(4), subtree["requests+"].Delta())
+	assert.Equal(t, int64(4), s.Snapshot().Counters()["requests+"].Delta())
+
+	s.Counter("requests").IncFloat(0.75)
+	fifth := s.Snapshot().Counters()
+	assert.Equal(t, int64(11), fifth["requests+"].Value())
+	assert.Equal(t, 10.75, fifth["requests+"].ValueFloat())
+	assert.Equal(t, int64(1), fifth["requests+"].Delta())
+	assert.Equal(t, int64(0), s.Snapshot().Counters()["requests+"].Delta())
+}
+
+func TestConcurrentMetricCreationSameName(t *testing.T) {
+	const goroutines = 64
+	s := newRootScope(ScopeOptions{}, 0)
+	defer s.Close()
+
+	var (
+		start      = make(chan struct{})
+		wg         sync.WaitGroup
+		counters   = make([]Counter, goroutines)
+		gauges     = make([]Gauge, goroutines)
+		timers     = make([]Timer, goroutines)
+		histograms = make([]Histogram, goroutines)
+	)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			counters[i] = s.Counter("foo")
+			gauges[i] = s.Gauge("foo")
+			timers[i] = s.Timer("foo")
+			histograms[i] = s.Histogram("foo", ValueBuckets{1})
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for i := 1; i < goroutines; i++ {
+		assert.True(t, counters[0] == counters[i], "counter %d", i)
+		assert.True(t, gauges[0] == gauges[i], "gauge %d", i)
+		assert.True(t, timers[0] == timers[i], "timer %d", i)
+		assert.True(t, histograms[0] == histograms[i], "histogram %d", i)
+	}
+	assert.Len(t, s.counters, 1)
+	assert.Len(t, s.countersSlice, 1)
+	assert.Len(t, s.gauges, 1)
+	assert.Len(t, s.gaugesSlice, 1)
+	assert.Len(t, s.timers, 1)
+	assert.Len(t, s.histograms, 1)
+	assert.Len(t, s.histogramsSlice, 1)
+}
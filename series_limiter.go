@@ -0,0 +1,95 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"sync"
+	"time"
+)
+
+// seriesLimiter is a token bucket limiting the rate of emissions to a single
+// series, i.e. a metric with a given name and set of tags.
+type seriesLimiter struct {
+	sync.Mutex
+	rate    float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+	dropped Counter
+	clock   Clock
+}
+
+func newSeriesLimiter(rate float64, burst int, dropped Counter, clock Clock) *seriesLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &seriesLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		tokens:  float64(burst),
+		last:    clockNow(clock),
+		dropped: dropped,
+		clock:   clock,
+	}
+}
+
+// Allow returns whether an emission is within the limit, counting it as
+// dropped if not.
+func (l *seriesLimiter) Allow() bool {
+	return l.allowN(1) == 1
+}
+
+// allowN returns how many of n emissions at once are within the limit,
+// counting the others as dropped, the same as calling Allow n times.
+func (l *seriesLimiter) allowN(n int) int {
+	l.Lock()
+	now := clockNow(l.clock)
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens += elapsed.Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+
+	allowed := n
+	if tokens := int(l.tokens); tokens < allowed {
+		allowed = tokens
+	}
+	l.tokens -= float64(allowed)
+	l.Unlock()
+
+	if dropped := n - allowed; dropped > 0 && l.dropped != nil {
+		l.dropped.Inc(int64(dropped))
+	}
+	return allowed
+}
+
+// seriesLimiter returns a new limiter for a series created by the scope, or
+// nil if series are not rate limited.
+func (s *scope) seriesLimiter() *seriesLimiter {
+	if s.seriesRateLimit <= 0 {
+		return nil
+	}
+	return newSeriesLimiter(
+		s.seriesRateLimit, s.seriesRateLimitBurst, s.seriesRateLimitDropped, s.clock,
+	)
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeriesRateLimit(t *testing.T) {
+	s := newRootScope(ScopeOptions{
+		SeriesRateLimit:               0.001,
+		SeriesRateLimitBurst:          2,
+		SeriesRateLimitDroppedCounter: "dropped",
+	}, 0)
+	defer s.Close()
+
+	hot := s.Tagged(map[string]string{"user": "hot"})
+	cold := s.Tagged(map[string]string{"user": "cold"})
+	for i := 0; i < 5; i++ {
+		hot.Counter("requests").Inc(1)
+		hot.Timer("latency").Record(time.Second)
+	}
+	for i := 0; i < 2; i++ {
+		cold.Counter("requests").Inc(1)
+		cold.Timer("latency").Record(time.Second)
+	}
+	s.Gauge("queue").Update(1)
+
+	snap := s.Snapshot()
+	counters, timers := snap.Counters(), snap.Timers()
+	assert.EqualValues(t, 2, counters["requests+user=hot"].Value())
+	assert.EqualValues(t, 2, counters["requests+user=cold"].Value())
+	assert.Equal(t, 2, len(timers["latency+user=hot"].Values()))
+	assert.Equal(t, 2, len(timers["latency+user=cold"].Values()))
+	assert.EqualValues(t, 1, snap.Gauges()["queue+"].Value())
+	assert.EqualValues(t, 6, counters["dropped+"].Value())
+}
+
+func TestSeriesLimiterRefill(t *testing.T) {
+	var (
+		now     = time.Unix(1600000000, 0)
+		prevNow = globalNow
+	)
+	globalNow = func() time.Time { return now }
+	defer func() { globalNow = prevNow }()
+
+	dropped := newCounter(nil)
+	l := newSeriesLimiter(2, 1, dropped, nil)
+	assert.True(t, l.Allow())
+	assert.False(t, l.Allow())
+
+	now = now.Add(250 * time.Millisecond)
+	assert.False(t, l.Allow())
+
+	now = now.Add(250 * time.Millisecond)
+	assert.True(t, l.Allow())
+
+	// Tokens never accumulate beyond the burst.
+	now = now.Add(time.Hour)
+	assert.True(t, l.Allow())
+	assert.False(t, l.Allow())
+	assert.EqualValues(t, 3, dropped.snapshot())
+}
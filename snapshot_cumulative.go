@@ -0,0 +1,70 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+func (s *histogramSnapshot) CumulativeValues() map[float64]int64 {
+	if s.values == nil {
+		return nil
+	}
+
+	bounds := make([]float64, 0, len(s.values))
+	for b := range s.values {
+		bounds = append(bounds, b)
+	}
+	sort.Float64s(bounds)
+
+	cumulative := make(map[float64]int64, len(bounds))
+	var total int64
+	for _, b := range bounds {
+		total += s.values[b]
+		if b == math.MaxFloat64 {
+			b = math.Inf(1)
+		}
+		cumulative[b] = total
+	}
+	return cumulative
+}
+
+func (s *histogramSnapshot) CumulativeDurations() map[time.Duration]int64 {
+	if s.durations == nil {
+		return nil
+	}
+
+	bounds := make([]time.Duration, 0, len(s.durations))
+	for b := range s.durations {
+		bounds = append(bounds, b)
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	cumulative := make(map[time.Duration]int64, len(bounds))
+	var total int64
+	for _, b := range bounds {
+		total += s.durations[b]
+		cumulative[b] = total
+	}
+	return cumulative
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogramSnapshotCumulativeValues(t *testing.T) {
+	s := NewTestScope("", nil)
+	h := s.Histogram("sizes", MustMakeLinearValueBuckets(0, 10, 3))
+	for _, v := range []float64{-1, 5, 5, 15, 15, 15, 100} {
+		h.RecordValue(v)
+	}
+
+	snap := s.Snapshot().Histograms()["sizes+"]
+	assert.Equal(t, map[float64]int64{
+		0:               1,
+		10:              2,
+		20:              3,
+		math.MaxFloat64: 1,
+	}, snap.Values())
+	assert.Equal(t, map[float64]int64{
+		0:           1,
+		10:          3,
+		20:          6,
+		math.Inf(1): 7,
+	}, snap.CumulativeValues())
+	assert.Nil(t, snap.CumulativeDurations())
+}
+
+func TestHistogramSnapshotCumulativeDurations(t *testing.T) {
+	s := NewTestScope("", nil)
+	h := s.Histogram("latency", MustMakeLinearDurationBuckets(0, time.Second, 2))
+	for _, d := range []time.Duration{time.Millisecond, time.Millisecond, time.Minute} {
+		h.RecordDuration(d)
+	}
+
+	snap := s.Snapshot().Histograms()["latency+"]
+	assert.Equal(t, map[time.Duration]int64{
+		0:             0,
+		time.Second:   2,
+		math.MaxInt64: 1,
+	}, snap.Durations())
+	assert.Equal(t, map[time.Duration]int64{
+		0:             0,
+		time.Second:   2,
+		math.MaxInt64: 3,
+	}, snap.CumulativeDurations())
+	assert.Nil(t, snap.CumulativeValues())
+}
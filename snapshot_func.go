@@ -0,0 +1,123 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import "time"
+
+// SnapshotValue is the value of a metric visited by SnapshotFunc, only the
+// fields of the type of the metric are set, the others are zero.
+type SnapshotValue struct {
+	// Counter and CounterFloat are the value of a counter since the last
+	// report, or its running total with CounterOptions.Cumulative.
+	Counter      int64
+	CounterFloat float64
+
+	// Gauge is the last value of a gauge.
+	Gauge float64
+
+	// Timer is the values of a timer since the last report.
+	Timer []time.Duration
+
+	// HistogramValues and HistogramDurations are the sample counts by upper
+	// bound of a value and a duration histogram respectively since the last
+	// report, like HistogramSnapshot.Values and Durations.
+	HistogramValues    map[float64]int64
+	HistogramDurations map[time.Duration]int64
+
+	// Meter is the values of a meter since the last report.
+	Meter []float64
+}
+
+// SnapshotFunc calls visit with the metadata and value of every metric of
+// the root scope of s and all its subscopes, the metrics of a
+// TestScope.Snapshot, without building the maps of a Snapshot, e.g. for
+// periodic scrapes of scopes with many metrics. The visitor is called with
+// the read lock of the metrics of each kind of each scope held, so it must
+// not create metrics or subscopes, and the tags of the metadata are those of
+// the scope rather than a copy, so they must not be modified. Metrics created
+// or updated while visiting may or may not be visited with their latest
+// value, so the values visited are not a consistent point in time view. On
+// Scope implementations other than those created by NewRootScope visit is
+// never called.
+func SnapshotFunc(s Scope, visit func(m MetricMetadata, v SnapshotValue)) {
+	if ss, ok := s.(snapshotFuncScope); ok {
+		ss.snapshotFunc(visit)
+	}
+}
+
+type snapshotFuncScope interface {
+	snapshotFunc(visit func(m MetricMetadata, v SnapshotValue))
+}
+
+func (s *scope) snapshotFunc(visit func(m MetricMetadata, v SnapshotValue)) {
+	if s.registry.transactions.Load() {
+		s.registry.txBarrier.Lock()
+		defer s.registry.txBarrier.Unlock()
+	}
+
+	s.registry.ForEachScope(func(ss *scope) {
+		// NB(r): tags are immutable, no lock required to read.
+		m := MetricMetadata{Tags: ss.tags}
+
+		ss.cm.RLock()
+		m.Type = CounterMetricType
+		for key, c := range ss.counters {
+			m.Name = ss.fullyQualifiedName(key)
+			visit(m, SnapshotValue{Counter: c.snapshot(), CounterFloat: c.snapshotFloat()})
+		}
+		ss.cm.RUnlock()
+
+		ss.gm.RLock()
+		m.Type = GaugeMetricType
+		for key, g := range ss.gauges {
+			m.Name = ss.fullyQualifiedName(key)
+			visit(m, SnapshotValue{Gauge: g.snapshot()})
+		}
+		ss.gm.RUnlock()
+
+		ss.tm.RLock()
+		m.Type = TimerMetricType
+		for key, t := range ss.timers {
+			m.Name = ss.fullyQualifiedName(key)
+			visit(m, SnapshotValue{Timer: t.snapshot()})
+		}
+		ss.tm.RUnlock()
+
+		ss.hm.RLock()
+		m.Type = HistogramMetricType
+		for key, h := range ss.histograms {
+			m.Name = ss.fullyQualifiedName(key)
+			visit(m, SnapshotValue{
+				HistogramValues:    h.snapshotValues(),
+				HistogramDurations: h.snapshotDurations(),
+			})
+		}
+		ss.hm.RUnlock()
+
+		ss.mm.RLock()
+		m.Type = MeterMetricType
+		for key, mt := range ss.meters {
+			m.Name = ss.fullyQualifiedName(key)
+			visit(m, SnapshotValue{Meter: mt.snapshot()})
+		}
+		ss.mm.RUnlock()
+	})
+}
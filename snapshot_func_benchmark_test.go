@@ -0,0 +1,59 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"fmt"
+	"testing"
+)
+
+func newSnapshotBenchmarkScope() TestScope {
+	s := NewTestScope("svc", nil)
+	for i := 0; i < 1000; i++ {
+		sub := s.Tagged(map[string]string{"shard": fmt.Sprint(i % 10)})
+		sub.Counter(fmt.Sprintf("counter%d", i)).Inc(1)
+		sub.Gauge(fmt.Sprintf("gauge%d", i)).Update(1)
+	}
+	return s
+}
+
+func BenchmarkSnapshot(b *testing.B) {
+	s := newSnapshotBenchmarkScope()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		s.Snapshot()
+	}
+}
+
+func BenchmarkSnapshotFunc(b *testing.B) {
+	s := newSnapshotBenchmarkScope()
+	var sum float64
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		SnapshotFunc(s, func(m MetricMetadata, v SnapshotValue) {
+			sum += float64(v.Counter) + v.Gauge
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotFuncMatchesSnapshot(t *testing.T) {
+	s := NewTestScope("svc", map[string]string{"env": "test"})
+	s.Counter("requests").Inc(3)
+	s.Counter("ratio").IncFloat(0.5)
+	db := s.SubScope("db").Tagged(map[string]string{"table": "users"})
+	db.Gauge("rows").Update(42)
+	db.Timer("latency").Record(time.Millisecond)
+	db.Timer("latency").Record(2 * time.Millisecond)
+	s.Histogram("sizes", ValueBuckets{1, 10}).RecordValue(5)
+	s.Histogram("waits", DurationBuckets{time.Second}).RecordDuration(time.Millisecond)
+	ScopeMeter(db, "bytes").Record(7)
+
+	var (
+		counters   = make(map[string]SnapshotValue)
+		gauges     = make(map[string]SnapshotValue)
+		timers     = make(map[string]SnapshotValue)
+		histograms = make(map[string]SnapshotValue)
+		meters     = make(map[string]SnapshotValue)
+	)
+	SnapshotFunc(db, func(m MetricMetadata, v SnapshotValue) {
+		id := KeyForPrefixedStringMap(m.Name, m.Tags)
+		switch m.Type {
+		case CounterMetricType:
+			counters[id] = v
+		case GaugeMetricType:
+			gauges[id] = v
+		case TimerMetricType:
+			timers[id] = v
+		case HistogramMetricType:
+			histograms[id] = v
+		case MeterMetricType:
+			meters[id] = v
+		}
+	})
+
+	snap := s.Snapshot()
+	assert.Len(t, counters, len(snap.Counters()))
+	for id, c := range snap.Counters() {
+		assert.Equal(t, c.Value(), counters[id].Counter, id)
+		assert.Equal(t, c.ValueFloat(), counters[id].CounterFloat, id)
+	}
+	assert.Len(t, gauges, len(snap.Gauges()))
+	for id, g := range snap.Gauges() {
+		assert.Equal(t, g.Value(), gauges[id].Gauge, id)
+	}
+	assert.Len(t, timers, len(snap.Timers()))
+	for id, tm := range snap.Timers() {
+		assert.Equal(t, tm.Values(), timers[id].Timer, id)
+	}
+	assert.Len(t, histograms, len(snap.Histograms()))
+	for id, h := range snap.Histograms() {
+		assert.Equal(t, h.Values(), histograms[id].HistogramValues, id)
+		assert.Equal(t, h.Durations(), histograms[id].HistogramDurations, id)
+	}
+	assert.Len(t, meters, len(snap.Meters()))
+	for id, m := range snap.Meters() {
+		assert.Equal(t, m.Values(), meters[id].Meter, id)
+	}
+
+	assert.Equal(t, int64(3), counters["svc.requests+env=test"].Counter)
+	assert.Equal(t, float64(42), gauges["svc.db.rows+env=test,table=users"].Gauge)
+}
+
+func TestSnapshotFuncOtherScopes(t *testing.T) {
+	s := NewNoopScope()
+	s.Counter("c").Inc(1)
+	SnapshotFunc(s, func(MetricMetadata, SnapshotValue) {
+		assert.Fail(t, "visited a metric of a noop scope")
+	})
+
+	ts := NewTestScope("", nil)
+	ts.Counter("c").Inc(1)
+	var visited int
+	SnapshotFunc(NewSourceTaggingScope(ts), func(MetricMetadata, SnapshotValue) {
+		visited++
+	})
+	assert.Equal(t, 1, visited)
+}
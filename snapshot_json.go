@@ -0,0 +1,169 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"encoding/json"
+	"math"
+)
+
+type snapshotJSON struct {
+	Counters   map[string]counterJSON   `json:"counters"`
+	Gauges     map[string]gaugeJSON     `json:"gauges"`
+	Timers     map[string]timerJSON     `json:"timers"`
+	Histograms map[string]histogramJSON `json:"histograms"`
+	Meters     map[string]meterJSON     `json:"meters,omitempty"`
+}
+
+type counterJSON struct {
+	Name       string            `json:"name"`
+	Tags       map[string]string `json:"tags"`
+	Value      int64             `json:"value"`
+	ValueFloat *jsonFloat        `json:"valueFloat,omitempty"`
+}
+
+type gaugeJSON struct {
+	Name  string            `json:"name"`
+	Tags  map[string]string `json:"tags"`
+	Value jsonFloat         `json:"value"`
+}
+
+type timerJSON struct {
+	Name   string            `json:"name"`
+	Tags   map[string]string `json:"tags"`
+	Values []int64           `json:"values"`
+}
+
+type histogramJSON struct {
+	Name    string                `json:"name"`
+	Tags    map[string]string     `json:"tags"`
+	Buckets []histogramBucketJSON `json:"buckets"`
+}
+
+type meterJSON struct {
+	Name   string            `json:"name"`
+	Tags   map[string]string `json:"tags"`
+	Values []jsonFloat       `json:"values"`
+}
+
+type histogramBucketJSON struct {
+	// UpperBound is in nanoseconds for duration histograms.
+	UpperBound interface{} `json:"upperBound"`
+	Count      int64       `json:"count"`
+}
+
+// MarshalSnapshotJSON returns a JSON document of a snapshot, e.g. to compare
+// a snapshot of a TestScope to a golden file. The document is an object of
+// the counters, gauges, timers and histograms of the snapshot, each an
+// object by the ID of the metric in the snapshot of its name, sorted tags
+// and value. Timer values are in nanoseconds in the order recorded, and
+// histogram values are the count of each bucket by upper bound in order of
+// the bounds, the bounds of duration histograms in nanoseconds. Meters,
+// whose values are in the order recorded, are only included if there are
+// any. Counters incremented by fractions also have their valueFloat.
+// Non-finite gauge, meter and bound values, which JSON numbers cannot
+// represent, are the strings "+Inf", "-Inf" and "NaN". Object keys are
+// sorted, so marshaling the same snapshot always returns the same document.
+func MarshalSnapshotJSON(s Snapshot) ([]byte, error) {
+	doc := snapshotJSON{
+		Counters:   make(map[string]counterJSON),
+		Gauges:     make(map[string]gaugeJSON),
+		Timers:     make(map[string]timerJSON),
+		Histograms: make(map[string]histogramJSON),
+	}
+	for id, c := range s.Counters() {
+		counter := counterJSON{Name: c.Name(), Tags: c.Tags(), Value: c.Value()}
+		if f := c.ValueFloat(); f != float64(c.Value()) {
+			counter.ValueFloat = (*jsonFloat)(&f)
+		}
+		doc.Counters[id] = counter
+	}
+	for id, g := range s.Gauges() {
+		doc.Gauges[id] = gaugeJSON{Name: g.Name(), Tags: g.Tags(), Value: jsonFloat(g.Value())}
+	}
+	for id, t := range s.Timers() {
+		values := make([]int64, 0, len(t.Values()))
+		for _, v := range t.Values() {
+			values = append(values, int64(v))
+		}
+		doc.Timers[id] = timerJSON{Name: t.Name(), Tags: t.Tags(), Values: values}
+	}
+	for id, h := range s.Histograms() {
+		doc.Histograms[id] = histogramJSON{
+			Name:    h.Name(),
+			Tags:    h.Tags(),
+			Buckets: histogramBucketsJSON(h),
+		}
+	}
+	for id, m := range s.Meters() {
+		if doc.Meters == nil {
+			doc.Meters = make(map[string]meterJSON)
+		}
+		values := make([]jsonFloat, 0, len(m.Values()))
+		for _, v := range m.Values() {
+			values = append(values, jsonFloat(v))
+		}
+		doc.Meters[id] = meterJSON{Name: m.Name(), Tags: m.Tags(), Values: values}
+	}
+	return json.Marshal(doc)
+}
+
+// jsonFloat is a float marshaled as a JSON number, or as a string if it is
+// not finite.
+type jsonFloat float64
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	switch {
+	case math.IsInf(v, 1):
+		return []byte(`"+Inf"`), nil
+	case math.IsInf(v, -1):
+		return []byte(`"-Inf"`), nil
+	case math.IsNaN(v):
+		return []byte(`"NaN"`), nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+func histogramBucketsJSON(h HistogramSnapshot) []histogramBucketJSON {
+	var (
+		pairs     = h.Buckets()
+		durations = h.Durations()
+		buckets   = make([]histogramBucketJSON, 0, len(pairs))
+	)
+	for _, p := range pairs {
+		if durations != nil {
+			upper := p.UpperBoundDuration()
+			buckets = append(buckets, histogramBucketJSON{
+				UpperBound: int64(upper),
+				Count:      durations[upper],
+			})
+			continue
+		}
+		upper := p.UpperBoundValue()
+		buckets = append(buckets, histogramBucketJSON{
+			UpperBound: jsonFloat(upper),
+			Count:      h.Values()[upper],
+		})
+	}
+	return buckets
+}
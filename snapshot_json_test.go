@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalSnapshotJSON(t *testing.T) {
+	s := NewTestScope("svc", map[string]string{"env": "test"})
+	s.Tagged(map[string]string{"region": "us", "az": "a"}).Counter("requests").Inc(2)
+	s.Gauge("queue").Update(1.5)
+	s.Timer("latency").Record(2 * time.Millisecond)
+	s.Timer("latency").Record(time.Millisecond)
+	s.Histogram("size", ValueBuckets{10}).RecordValue(5)
+	s.Histogram("wait", DurationBuckets{time.Second}).RecordDuration(time.Minute)
+
+	snap := s.Snapshot()
+	b, err := MarshalSnapshotJSON(snap)
+	require.NoError(t, err)
+	assert.Equal(t, `{"counters":{"svc.requests+az=a,env=test,region=us":`+
+		`{"name":"svc.requests","tags":{"az":"a","env":"test","region":"us"},"value":2}},`+
+		`"gauges":{"svc.queue+env=test":{"name":"svc.queue","tags":{"env":"test"},"value":1.5}},`+
+		`"timers":{"svc.latency+env=test":{"name":"svc.latency","tags":{"env":"test"},"values":[2000000,1000000]}},`+
+		`"histograms":{"svc.size+env=test":{"name":"svc.size","tags":{"env":"test"},`+
+		`"buckets":[{"upperBound":10,"count":1},{"upperBound":1.7976931348623157e+308,"count":0}]},`+
+		`"svc.wait+env=test":{"name":"svc.wait","tags":{"env":"test"},`+
+		`"buckets":[{"upperBound":1000000000,"count":0},{"upperBound":9223372036854775807,"count":1}]}}}`,
+		string(b))
+
+	// The output is stable across repeated marshals.
+	for i := 0; i < 10; i++ {
+		again, err := MarshalSnapshotJSON(snap)
+		require.NoError(t, err)
+		assert.Equal(t, b, again)
+	}
+}
+
+func TestMarshalSnapshotJSONFloats(t *testing.T) {
+	s := NewTestScope("", nil)
+	s.Gauge("inf").Update(math.Inf(1))
+	s.Gauge("minus_inf").Update(math.Inf(-1))
+	s.Gauge("nan").Update(math.NaN())
+	s.Counter("bytes").IncFloat(1.25)
+	s.Counter("requests").Inc(2)
+
+	b, err := MarshalSnapshotJSON(s.Snapshot())
+	require.NoError(t, err)
+	assert.Equal(t, `{"counters":{`+
+		`"bytes+":{"name":"bytes","tags":{},"value":1,"valueFloat":1.25},`+
+		`"requests+":{"name":"requests","tags":{},"value":2}},`+
+		`"gauges":{"inf+":{"name":"inf","tags":{},"value":"+Inf"},`+
+		`"minus_inf+":{"name":"minus_inf","tags":{},"value":"-Inf"},`+
+		`"nan+":{"name":"nan","tags":{},"value":"NaN"}},`+
+		`"timers":{},"histograms":{}}`,
+		string(b))
+}
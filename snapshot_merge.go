@@ -0,0 +1,134 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import "time"
+
+// MergeSnapshots combines the snapshots, e.g. of the TestScopes of parallel
+// test workers, into one snapshot keyed like Snapshot by name and sorted
+// tags, so that identically named and tagged series are merged:
+//   - counters are summed
+//   - timers and meters have the values of all snapshots, in the order of
+//     the snapshots
+//   - histograms have the sample counts summed by upper bound and the
+//     samples of all snapshots, the buckets are those of the first snapshot
+//     with the histogram so histograms are expected to share buckets
+//   - gauges have the value of the last snapshot in which the gauge was
+//     updated since the last report, or else of the last snapshot with it
+//
+// Series of different kinds are kept apart by kind as in a single snapshot
+// even when they have the same name and tags. The merged series do not share
+// values with the snapshots.
+func MergeSnapshots(snaps ...Snapshot) Snapshot {
+	merged := newSnapshot()
+	for _, snap := range snaps {
+		for id, c := range snap.Counters() {
+			m, ok := merged.counters[id].(*counterSnapshot)
+			if !ok {
+				m = &counterSnapshot{name: c.Name(), tags: c.Tags()}
+				merged.counters[id] = m
+			}
+			m.value += c.Value()
+			m.float += c.ValueFloat()
+			m.delta += c.Delta()
+		}
+		for id, g := range snap.Gauges() {
+			updated := gaugeSnapshotUpdated(g)
+			if m, ok := merged.gauges[id].(*gaugeSnapshot); ok && m.updated && !updated {
+				continue
+			}
+			merged.gauges[id] = &gaugeSnapshot{
+				name:    g.Name(),
+				tags:    g.Tags(),
+				value:   g.Value(),
+				updated: updated,
+			}
+		}
+		for id, t := range snap.Timers() {
+			m, ok := merged.timers[id].(*timerSnapshot)
+			if !ok {
+				m = &timerSnapshot{name: t.Name(), tags: t.Tags()}
+				merged.timers[id] = m
+			}
+			m.values = appendDurations(m.values, t.Values())
+			m.samples = appendDurations(m.samples, t.Samples())
+		}
+		for id, h := range snap.Histograms() {
+			m, ok := merged.histograms[id].(*histogramSnapshot)
+			if !ok {
+				m = &histogramSnapshot{
+					name:    h.Name(),
+					tags:    h.Tags(),
+					buckets: h.Buckets(),
+				}
+				merged.histograms[id] = m
+			}
+			if values := h.Values(); values != nil {
+				if m.values == nil {
+					m.values = make(map[float64]int64, len(values))
+				}
+				for upperBound, count := range values {
+					m.values[upperBound] += count
+				}
+			}
+			if durations := h.Durations(); durations != nil {
+				if m.durations == nil {
+					m.durations = make(map[time.Duration]int64, len(durations))
+				}
+				for upperBound, count := range durations {
+					m.durations[upperBound] += count
+				}
+			}
+			m.underflow += h.Underflow()
+			m.overflow += h.Overflow()
+			if samples := h.Samples(); len(samples) > 0 {
+				m.samples = append(m.samples, samples...)
+			}
+		}
+		for id, mt := range snap.Meters() {
+			m, ok := merged.meters[id].(*meterSnapshot)
+			if !ok {
+				m = &meterSnapshot{name: mt.Name(), tags: mt.Tags()}
+				merged.meters[id] = m
+			}
+			if values := mt.Values(); len(values) > 0 {
+				m.values = append(m.values, values...)
+			}
+		}
+	}
+	return merged
+}
+
+// gaugeSnapshotUpdated returns whether g was updated since the last report,
+// which gauge snapshots of other implementations are assumed to be.
+func gaugeSnapshotUpdated(g GaugeSnapshot) bool {
+	if gs, ok := g.(*gaugeSnapshot); ok {
+		return gs.updated
+	}
+	return true
+}
+
+func appendDurations(dst, src []time.Duration) []time.Duration {
+	if len(src) == 0 {
+		return dst
+	}
+	return append(dst, src...)
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeSnapshots(t *testing.T) {
+	a := NewTestScope("svc", map[string]string{"worker": "all"})
+	b := NewTestScope("svc", map[string]string{"worker": "all"})
+	buckets := ValueBuckets{1, 10}
+
+	a.Counter("requests").Inc(3)
+	b.Counter("requests").Inc(4)
+	a.Counter("ratio").IncFloat(0.25)
+	b.Counter("ratio").IncFloat(0.5)
+	a.Tagged(map[string]string{"only": "a"}).Counter("requests").Inc(1)
+	b.Counter("errors").Inc(2)
+
+	a.Gauge("queue").Update(1)
+	b.Gauge("queue").Update(2)
+	a.Gauge("stale").Update(5)
+	b.Gauge("stale")
+
+	a.Timer("latency").Record(time.Millisecond)
+	b.Timer("latency").Record(2 * time.Millisecond)
+
+	a.Histogram("sizes", buckets).RecordValue(5)
+	b.Histogram("sizes", buckets).RecordValue(5)
+	b.Histogram("sizes", buckets).RecordValue(50)
+	a.Histogram("waits", DurationBuckets{time.Second}).RecordDuration(time.Millisecond)
+
+	ScopeMeter(a, "bytes").Record(1)
+	ScopeMeter(b, "bytes").Record(2)
+
+	snap := MergeSnapshots(a.Snapshot(), b.Snapshot())
+	assert.Equal(t, SnapshotCounts{
+		Counters:   4,
+		Gauges:     2,
+		Timers:     1,
+		Histograms: 2,
+		Meters:     1,
+		Total:      10,
+	}, snap.Counts())
+
+	counters := snap.Counters()
+	assert.EqualValues(t, 7, counters["svc.requests+worker=all"].Value())
+	assert.EqualValues(t, 7, counters["svc.requests+worker=all"].Delta())
+	assert.Equal(t, map[string]string{"worker": "all"},
+		counters["svc.requests+worker=all"].Tags())
+	assert.Equal(t, 0.75, counters["svc.ratio+worker=all"].ValueFloat())
+	assert.EqualValues(t, 1, counters["svc.requests+only=a,worker=all"].Value())
+	assert.EqualValues(t, 2, counters["svc.errors+worker=all"].Value())
+
+	// The last updated value of a gauge wins.
+	assert.Equal(t, float64(2), snap.Gauges()["svc.queue+worker=all"].Value())
+	assert.Equal(t, float64(5), snap.Gauges()["svc.stale+worker=all"].Value())
+
+	assert.Equal(t, []time.Duration{time.Millisecond, 2 * time.Millisecond},
+		snap.Timers()["svc.latency+worker=all"].Values())
+
+	sizes := snap.Histograms()["svc.sizes+worker=all"]
+	assert.Equal(t, map[float64]int64{1: 0, 10: 2, math.MaxFloat64: 1}, sizes.Values())
+	assert.Nil(t, sizes.Durations())
+	assert.Len(t, sizes.Buckets(), 3)
+	waits := snap.Histograms()["svc.waits+worker=all"]
+	assert.EqualValues(t, 1, waits.Durations()[time.Second])
+
+	assert.Equal(t, []float64{1, 2}, snap.Meters()["svc.bytes+worker=all"].Values())
+
+	// The merged snapshot does not share values with the snapshots.
+	first := a.Snapshot()
+	merged := MergeSnapshots(first)
+	merged.Timers()["svc.latency+worker=all"].Values()[0] = 0
+	assert.Equal(t, time.Millisecond,
+		first.Timers()["svc.latency+worker=all"].Values()[0])
+}
+
+func TestMergeSnapshotsEmpty(t *testing.T) {
+	snap := MergeSnapshots()
+	require.NotNil(t, snap)
+	assert.Equal(t, SnapshotCounts{}, snap.Counts())
+	assert.Equal(t, SnapshotCounts{}, MergeSnapshots(newSnapshot()).Counts())
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+// SnapshotOptions are the options of SnapshotWithOptions.
+type SnapshotOptions struct {
+	// IncludeZeroValues includes the metrics still at their zero value,
+	// counters never incremented, gauges never updated and timers,
+	// histograms and meters without samples, e.g. so that every registered
+	// series is exported. When false they are omitted like Snapshot.Compact.
+	IncludeZeroValues bool
+}
+
+// SnapshotWithOptions returns the TestScope.Snapshot of s with the given
+// options. With IncludeZeroValues it is the same as s.Snapshot().
+func SnapshotWithOptions(s TestScope, opts SnapshotOptions) Snapshot {
+	snap := s.Snapshot()
+	if opts.IncludeZeroValues {
+		return snap
+	}
+	return snap.Compact()
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotWithOptions(t *testing.T) {
+	s := NewTestScope("", nil)
+
+	s.Counter("untouched")
+	s.Gauge("untouched")
+	s.Timer("untouched")
+	s.Histogram("untouched", ValueBuckets{1, 2})
+	s.Counter("touched").Inc(1)
+	s.Timer("touched").Record(time.Second)
+
+	all := SnapshotWithOptions(s, SnapshotOptions{IncludeZeroValues: true})
+	assert.Equal(t, SnapshotCounts{
+		Counters:   2,
+		Gauges:     1,
+		Timers:     2,
+		Histograms: 1,
+		Total:      6,
+	}, all.Counts())
+	assert.Equal(t, int64(0), all.Counters()["untouched+"].Value())
+	assert.Contains(t, all.Gauges(), "untouched+")
+	assert.Contains(t, all.Histograms(), "untouched+")
+
+	nonZero := SnapshotWithOptions(s, SnapshotOptions{})
+	assert.Equal(t, SnapshotCounts{
+		Counters: 1,
+		Timers:   1,
+		Total:    2,
+	}, nonZero.Counts())
+	assert.Equal(t, int64(1), nonZero.Counters()["touched+"].Value())
+	assert.NotContains(t, nonZero.Counters(), "untouched+")
+	assert.NotContains(t, nonZero.Timers(), "untouched+")
+}
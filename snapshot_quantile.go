@@ -0,0 +1,103 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// clampQuantile clamps a quantile to the range [0, 1].
+func clampQuantile(q float64) float64 {
+	switch {
+	case q < 0 || math.IsNaN(q):
+		return 0
+	case q > 1:
+		return 1
+	default:
+		return q
+	}
+}
+
+func (s *timerSnapshot) Quantile(q float64) time.Duration {
+	if len(s.values) == 0 {
+		return 0
+	}
+
+	values := make([]time.Duration, len(s.values))
+	copy(values, s.values)
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	return sortedDurationsQuantile(values, q)
+}
+
+// sortedDurationsQuantile returns the quantile of the sorted non-empty
+// values, interpolated linearly between the closest ranks.
+func sortedDurationsQuantile(values []time.Duration, q float64) time.Duration {
+	pos := clampQuantile(q) * float64(len(values)-1)
+	lower := int(math.Floor(pos))
+	if lower == len(values)-1 {
+		return values[lower]
+	}
+	frac := pos - float64(lower)
+	return values[lower] + time.Duration(frac*float64(values[lower+1]-values[lower]))
+}
+
+func (s *histogramSnapshot) Quantile(q float64) float64 {
+	durations := s.durations != nil
+	counts := make([]int64, len(s.buckets))
+	var total int64
+	for i, b := range s.buckets {
+		if durations {
+			counts[i] = s.durations[b.UpperBoundDuration()]
+		} else {
+			counts[i] = s.values[b.UpperBoundValue()]
+		}
+		total += counts[i]
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := clampQuantile(q) * float64(total)
+	var cumulative int64
+	for i, b := range s.buckets {
+		if counts[i] == 0 || float64(cumulative+counts[i]) < rank {
+			cumulative += counts[i]
+			continue
+		}
+
+		lower, upper := b.LowerBoundValue(), b.UpperBoundValue()
+		if durations {
+			lower = b.LowerBoundDuration().Seconds()
+			upper = b.UpperBoundDuration().Seconds()
+		}
+		switch {
+		case i == len(s.buckets)-1:
+			return lower
+		case i == 0:
+			return upper
+		}
+		frac := (rank - float64(cumulative)) / float64(counts[i])
+		return lower + frac*(upper-lower)
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimerSnapshotQuantile(t *testing.T) {
+	s := NewTestScope("", nil)
+	timer := s.Timer("latency")
+	for _, i := range rand.Perm(100) {
+		timer.Record(time.Duration(i+1) * time.Millisecond)
+	}
+	s.Timer("empty")
+
+	timers := s.Snapshot().Timers()
+	snap := timers["latency+"]
+	tests := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{q: 0, want: time.Millisecond},
+		{q: 0.5, want: 50500 * time.Microsecond},
+		{q: 0.99, want: 99010 * time.Microsecond},
+		{q: 1, want: 100 * time.Millisecond},
+		// Out of range quantiles are clamped.
+		{q: -1, want: time.Millisecond},
+		{q: 2, want: 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, snap.Quantile(tt.q), "quantile %v", tt.q)
+	}
+	assert.Equal(t, time.Duration(0), timers["empty+"].Quantile(0.5))
+}
+
+func TestHistogramSnapshotQuantile(t *testing.T) {
+	s := NewTestScope("", nil)
+	h := s.Histogram("values", ValueBuckets{10, 20, 30})
+	h.RecordValue(5)
+	for v := 11; v <= 20; v++ {
+		h.RecordValue(float64(v))
+	}
+	h.RecordValue(100)
+	s.Histogram("empty", ValueBuckets{10})
+
+	histograms := s.Snapshot().Histograms()
+	snap := histograms["values+"]
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		// The first bucket has no finite lower bound.
+		{q: 0, want: 10},
+		{q: 0.5, want: 15},
+		{q: 0.75, want: 18},
+		// The overflow bucket has no finite upper bound.
+		{q: 1, want: 30},
+		{q: 2, want: 30},
+	}
+	for _, tt := range tests {
+		assert.InDelta(t, tt.want, snap.Quantile(tt.q), 1e-9, "quantile %v", tt.q)
+	}
+	assert.Equal(t, float64(0), histograms["empty+"].Quantile(0.5))
+}
+
+func TestHistogramSnapshotQuantileDurations(t *testing.T) {
+	s := NewTestScope("", nil)
+	h := s.Histogram("latency", DurationBuckets{time.Second, 2 * time.Second})
+	for i := 0; i < 4; i++ {
+		h.RecordDuration(1500 * time.Millisecond)
+	}
+
+	snap := s.Snapshot().Histograms()["latency+"]
+	assert.InDelta(t, 1.25, snap.Quantile(0.25), 1e-9)
+	assert.InDelta(t, 2, snap.Quantile(1), 1e-9)
+}
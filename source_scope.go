@@ -0,0 +1,233 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// SourceTagKey is the tag key used by scopes created with NewSourceTaggingScope.
+const SourceTagKey = "source"
+
+// NewSourceTaggingScope returns a Scope that tags every metric it creates
+// with the file and line of the call site that created it, using the
+// SourceTagKey tag, e.g. source=handler.go:42.
+//
+// IMPORTANT: This is for debugging only and must never be used in production.
+// Every metric creation calls runtime.Caller which is expensive, and every
+// distinct call site creates a new series which can greatly increase
+// cardinality.
+func NewSourceTaggingScope(s Scope) Scope {
+	return &sourceTaggingScope{Scope: s}
+}
+
+type sourceTaggingScope struct {
+	Scope
+}
+
+func (s *sourceTaggingScope) Counter(name string) Counter {
+	return s.sourceTagged(0).Counter(name)
+}
+
+func (s *sourceTaggingScope) counterWithOptions(name string, opts CounterOptions) Counter {
+	// Skip the CounterWithOptions helper calling this method.
+	return CounterWithOptions(s.sourceTagged(1), name, opts)
+}
+
+func (s *sourceTaggingScope) Gauge(name string) Gauge {
+	return s.sourceTagged(0).Gauge(name)
+}
+
+func (s *sourceTaggingScope) gaugeWithOptions(name string, opts GaugeOptions) Gauge {
+	// Skip the GaugeWithOptions helper calling this method.
+	return GaugeWithOptions(s.sourceTagged(1), name, opts)
+}
+
+func (s *sourceTaggingScope) Timer(name string) Timer {
+	return s.sourceTagged(0).Timer(name)
+}
+
+func (s *sourceTaggingScope) timerWithOptions(name string, opts TimerOptions) Timer {
+	// Skip the TimerWithOptions helper calling this method.
+	return TimerWithOptions(s.sourceTagged(1), name, opts)
+}
+
+func (s *sourceTaggingScope) Histogram(name string, buckets Buckets) Histogram {
+	return s.sourceTagged(0).Histogram(name, buckets)
+}
+
+func (s *sourceTaggingScope) histogramWithOptions(
+	name string,
+	buckets Buckets,
+	opts HistogramOptions,
+) Histogram {
+	// Skip the HistogramWithOptions helper calling this method, the options
+	// were already validated by it.
+	h, _ := HistogramWithOptions(s.sourceTagged(1), name, buckets, opts)
+	return h
+}
+
+func (s *sourceTaggingScope) Tagged(tags map[string]string) Scope {
+	return &sourceTaggingScope{Scope: s.Scope.Tagged(tags)}
+}
+
+func (s *sourceTaggingScope) tryTagged(tags map[string]string) (Scope, error) {
+	child, err := TryTagged(s.Scope, tags)
+	if err != nil {
+		return nil, err
+	}
+	return &sourceTaggingScope{Scope: child}, nil
+}
+
+func (s *sourceTaggingScope) SubScope(name string) Scope {
+	return &sourceTaggingScope{Scope: s.Scope.SubScope(name)}
+}
+
+func (s *sourceTaggingScope) subScopeFromTags(tagKeys []string, tags map[string]string) Scope {
+	return &sourceTaggingScope{Scope: SubScopeFromTags(s.Scope, tagKeys, tags)}
+}
+
+func (s *sourceTaggingScope) subScopeWithSeparator(name, separator string) Scope {
+	return &sourceTaggingScope{Scope: SubScopeWithSeparator(s.Scope, name, separator)}
+}
+
+func (s *sourceTaggingScope) child(opts ChildOptions) Scope {
+	return &sourceTaggingScope{Scope: Child(s.Scope, opts)}
+}
+
+func (s *sourceTaggingScope) emitEvent(title, text string, tags map[string]string) {
+	EmitEvent(s.Scope, title, text, tags)
+}
+
+func (s *sourceTaggingScope) registerRatio(name, numerator, denominator string, opts RatioOptions) {
+	RegisterRatio(s.Scope, name, numerator, denominator, opts)
+}
+
+func (s *sourceTaggingScope) registerInfo(name string, labels map[string]string) {
+	Info(s.Scope, name, labels)
+}
+
+func (s *sourceTaggingScope) registerGaugeFunc(name string, fn func() float64) {
+	GaugeFunc(s.Scope, name, fn)
+}
+
+func (s *sourceTaggingScope) transaction(counterName, timerName string) Transaction {
+	return NewTransaction(s.Scope, counterName, timerName)
+}
+
+func (s *sourceTaggingScope) snapshotFunc(visit func(m MetricMetadata, v SnapshotValue)) {
+	SnapshotFunc(s.Scope, visit)
+}
+
+func (s *sourceTaggingScope) subtreeSnapshot() Snapshot {
+	return SubtreeSnapshot(s.Scope)
+}
+
+func (s *sourceTaggingScope) recorderClock() Clock {
+	return recorderClock(s.Scope)
+}
+
+func (s *sourceTaggingScope) rateLimitedCounter(name string, minInterval time.Duration) Counter {
+	// Skip the RateLimitedCounter helper calling this method.
+	return RateLimitedCounter(s.sourceTagged(1), name, minInterval)
+}
+
+func (s *sourceTaggingScope) trackingGauge(name string) Gauge {
+	// Skip the TrackingGauge helper calling this method.
+	return TrackingGauge(s.sourceTagged(1), name)
+}
+
+func (s *sourceTaggingScope) meter(name string) Meter {
+	// Skip the ScopeMeter helper calling this method.
+	return ScopeMeter(s.sourceTagged(1), name)
+}
+
+func (s *sourceTaggingScope) reportsMeters() bool {
+	return ReportsMeters(s.Scope)
+}
+
+// NB: Metrics are looked up on the wrapped scope as the source tag of the
+// scope they were created on is unknown.
+func (s *sourceTaggingScope) lookupCounter(name string) (Counter, bool) {
+	return LookupCounter(s.Scope, name)
+}
+
+func (s *sourceTaggingScope) lookupGauge(name string) (Gauge, bool) {
+	return LookupGauge(s.Scope, name)
+}
+
+func (s *sourceTaggingScope) lookupTimer(name string) (Timer, bool) {
+	return LookupTimer(s.Scope, name)
+}
+
+func (s *sourceTaggingScope) lookupHistogram(name string) (Histogram, bool) {
+	return LookupHistogram(s.Scope, name)
+}
+
+func (s *sourceTaggingScope) metricMetadata() []MetricMetadata {
+	var metrics []MetricMetadata
+	ForEachMetric(s.Scope, func(m MetricMetadata) {
+		metrics = append(metrics, m)
+	})
+	return metrics
+}
+
+func (s *sourceTaggingScope) aliasMetric(canonical, alias string) {
+	AliasMetric(s.Scope, canonical, alias)
+}
+
+func (s *sourceTaggingScope) removeMetricAlias(canonical, alias string) {
+	RemoveMetricAlias(s.Scope, canonical, alias)
+}
+
+func (s *sourceTaggingScope) gaugeChannelDone() (scopeDone, rootDone <-chan struct{}) {
+	if ss, ok := s.Scope.(gaugeFromChannelScope); ok {
+		return ss.gaugeChannelDone()
+	}
+	return nil, nil
+}
+
+// sourceTagged returns the wrapped scope tagged with the call site of the
+// exported method that called it, skipping a further number of frames for
+// methods only reached through a package level helper.
+func (s *sourceTaggingScope) sourceTagged(skip int) Scope {
+	// Skip this function and the method calling it.
+	_, file, line, ok := runtime.Caller(2 + skip)
+	if !ok {
+		return s.Scope
+	}
+	return s.Scope.Tagged(map[string]string{
+		SourceTagKey: fmt.Sprintf("%s:%d", filepath.Base(file), line),
+	})
+}
+
+func (s *sourceTaggingScope) setCardinalityBudget(opts CardinalityBudgetOptions) {
+	SetCardinalityBudget(s.Scope, opts)
+}
+
+func (s *sourceTaggingScope) percentileTimer(name string, percentiles []float64) Timer {
+	// Skip the PercentileTimer helper calling this method.
+	t, _ := PercentileTimer(s.sourceTagged(1), name, percentiles)
+	return t
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceTaggingScope(t *testing.T) {
+	root := NewTestScope("", nil)
+	s := NewSourceTaggingScope(root).SubScope("foo")
+
+	_, _, line, ok := runtime.Caller(0)
+	require.True(t, ok)
+	s.Counter("bar").Inc(1)
+	s.Tagged(map[string]string{"a": "b"}).Gauge("baz").Update(1)
+	CounterWithOptions(s, "qux", CounterOptions{Cumulative: true}).Inc(1)
+
+	snap := root.Snapshot()
+
+	counterSource := fmt.Sprintf("source_scope_test.go:%d", line+2)
+	counter, ok := snap.Counters()["foo.bar+source="+counterSource]
+	require.True(t, ok, "missing counter in %v", snap.Counters())
+	assert.EqualValues(t, 1, counter.Value())
+	assert.Equal(t, map[string]string{"source": counterSource}, counter.Tags())
+
+	gaugeSource := fmt.Sprintf("source_scope_test.go:%d", line+3)
+	gauge, ok := snap.Gauges()["foo.baz+a=b,source="+gaugeSource]
+	require.True(t, ok, "missing gauge in %v", snap.Gauges())
+	assert.Equal(t, map[string]string{"a": "b", "source": gaugeSource}, gauge.Tags())
+
+	optionsSource := fmt.Sprintf("source_scope_test.go:%d", line+4)
+	_, ok = snap.Counters()["foo.qux+source="+optionsSource]
+	assert.True(t, ok, "missing counter in %v", snap.Counters())
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ssf
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// Metric is the type of an SSF sample.
+type Metric int32
+
+// The SSF sample types as defined by ssf/sample.proto in Veneur.
+const (
+	Counter Metric = iota
+	Gauge
+	Histogram
+	Set
+	Status
+)
+
+// Field numbers and wire types of the SSFSample and SSFSpan protobuf
+// messages used by the reporter.
+const (
+	wireVarint  = 0
+	wireBytes   = 2
+	wireFixed32 = 5
+
+	sampleMetricField     = 1
+	sampleNameField       = 2
+	sampleValueField      = 3
+	sampleTimestampField  = 4
+	sampleSampleRateField = 7
+	sampleTagsField       = 8
+	sampleUnitField       = 9
+
+	spanMetricsField = 10
+
+	mapKeyField   = 1
+	mapValueField = 2
+)
+
+// sample is a single SSF sample.
+type sample struct {
+	metric     Metric
+	name       string
+	value      float32
+	timestamp  int64
+	sampleRate float32
+	tags       map[string]string
+	unit       string
+}
+
+// encodeSample appends the protobuf encoding of the sample to b.
+func encodeSample(b []byte, s sample) []byte {
+	if s.metric != Counter {
+		b = appendTag(b, sampleMetricField, wireVarint)
+		b = appendVarint(b, uint64(s.metric))
+	}
+	b = appendString(b, sampleNameField, s.name)
+	if s.value != 0 {
+		b = appendTag(b, sampleValueField, wireFixed32)
+		b = appendFixed32(b, math.Float32bits(s.value))
+	}
+	if s.timestamp != 0 {
+		b = appendTag(b, sampleTimestampField, wireVarint)
+		b = appendVarint(b, uint64(s.timestamp))
+	}
+	if s.sampleRate != 0 {
+		b = appendTag(b, sampleSampleRateField, wireFixed32)
+		b = appendFixed32(b, math.Float32bits(s.sampleRate))
+	}
+
+	// Sort the tags so the encoding of a sample is deterministic.
+	keys := make([]string, 0, len(s.tags))
+	for k := range s.tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := s.tags[k]
+		b = appendTag(b, sampleTagsField, wireBytes)
+		b = appendVarint(b, uint64(stringFieldSize(mapKeyField, k)+stringFieldSize(mapValueField, v)))
+		b = appendString(b, mapKeyField, k)
+		b = appendString(b, mapValueField, v)
+	}
+
+	if s.unit != "" {
+		b = appendString(b, sampleUnitField, s.unit)
+	}
+	return b
+}
+
+// appendSpanMetric appends an encoded sample to b as an element of the
+// repeated metrics field of an SSFSpan.
+func appendSpanMetric(b, encodedSample []byte) []byte {
+	b = appendTag(b, spanMetricsField, wireBytes)
+	b = appendVarint(b, uint64(len(encodedSample)))
+	return append(b, encodedSample...)
+}
+
+// spanMetricSize returns the number of bytes appendSpanMetric appends for an
+// encoded sample of the given size.
+func spanMetricSize(n int) int {
+	return varintSize(uint64(spanMetricsField<<3|wireBytes)) + varintSize(uint64(n)) + n
+}
+
+func appendTag(b []byte, field int, wireType int) []byte {
+	return appendVarint(b, uint64(field<<3|wireType))
+}
+
+func appendString(b []byte, field int, s string) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = appendVarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func stringFieldSize(field int, s string) int {
+	return varintSize(uint64(field<<3|wireBytes)) + varintSize(uint64(len(s))) + len(s)
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func varintSize(v uint64) int {
+	n := 1
+	for v >= 0x80 {
+		v >>= 7
+		n++
+	}
+	return n
+}
+
+func appendFixed32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
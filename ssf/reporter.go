@@ -0,0 +1,297 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package ssf provides a reporter that emits metrics as SSF (Sensor
+// Sensibility Format) samples to a Veneur instance.
+package ssf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/uber-go/tally"
+)
+
+const (
+	// DefaultAddress is the default address of the Veneur SSF listener.
+	DefaultAddress = "udp://127.0.0.1:8128"
+	// DefaultMaxPacketSize is the default maximum size in bytes of a UDP
+	// packet, chosen to fit the common MTU of 1500 bytes.
+	DefaultMaxPacketSize = 1432
+	// DefaultMaxUnixPacketSize is the default maximum size in bytes of a
+	// datagram or frame sent over a Unix domain socket.
+	DefaultMaxUnixPacketSize = 16384
+	// BucketTag is the name of the tag holding the bucket of a histogram
+	// sample count.
+	BucketTag = "bucket"
+	// TimerUnit is the unit timers are reported in.
+	TimerUnit = "ms"
+
+	frameHeaderSize = 5
+	frameVersion    = 0
+)
+
+// Reporter is a tally.StatsReporter that emits metrics as SSF samples.
+type Reporter interface {
+	tally.StatsReporter
+	io.Closer
+}
+
+// Options is a set of options for the SSF reporter.
+type Options struct {
+	// Address is the address of the Veneur SSF listener as a URL with one of
+	// the schemes udp, unixgram or unix, e.g. "udp://127.0.0.1:8128" or
+	// "unix:///var/run/veneur/ssf.sock". Spans are sent as one datagram each
+	// over udp and unixgram, and framed as expected by Veneur over stream
+	// (unix) sockets. If you do not set this value it will be set to
+	// DefaultAddress.
+	Address string
+
+	// SampleRate is the metrics emission sample rate. If you do not set this
+	// value it will be set to 1. Samples emitted at a lower rate carry it, so
+	// Veneur scales counters back up.
+	SampleRate float32
+
+	// MaxPacketSize is the maximum size in bytes of a datagram or frame, samples
+	// are batched into spans up to this size. If you do not set this value it
+	// will be set to DefaultMaxPacketSize for udp and DefaultMaxUnixPacketSize
+	// for Unix domain sockets. A single sample larger than this is sent in a
+	// span on its own.
+	MaxPacketSize int
+
+	// OnError if set is called with errors writing to the socket.
+	OnError func(err error)
+}
+
+type reporter struct {
+	sampleRate    float32
+	maxPacketSize int
+	framed        bool
+	onError       func(err error)
+	now           func() time.Time
+
+	mu      sync.Mutex
+	conn    net.Conn
+	rand    *rand.Rand
+	buf     []byte
+	scratch []byte
+}
+
+// NewReporter creates a new SSF reporter connected to the Veneur listener at
+// opts.Address. Counters and gauges are emitted as SSF counter and gauge
+// samples, timers as histogram samples in TimerUnit and the bucket counts of
+// histograms as counter samples tagged with BucketTag.
+func NewReporter(opts Options) (Reporter, error) {
+	if opts.Address == "" {
+		opts.Address = DefaultAddress
+	}
+	if opts.SampleRate == 0 {
+		opts.SampleRate = 1
+	}
+	if opts.SampleRate < 0 || opts.SampleRate > 1 {
+		return nil, fmt.Errorf("ssf: sample rate %v not in range (0, 1]", opts.SampleRate)
+	}
+
+	u, err := url.Parse(opts.Address)
+	if err != nil {
+		return nil, fmt.Errorf("ssf: invalid address %q: %v", opts.Address, err)
+	}
+
+	var (
+		addr          string
+		framed        bool
+		maxPacketSize = DefaultMaxUnixPacketSize
+	)
+	switch u.Scheme {
+	case "udp":
+		addr = u.Host
+		maxPacketSize = DefaultMaxPacketSize
+	case "unixgram":
+		addr = u.Path
+	case "unix":
+		addr = u.Path
+		framed = true
+	default:
+		return nil, fmt.Errorf("ssf: unsupported address scheme %q", u.Scheme)
+	}
+	if opts.MaxPacketSize > 0 {
+		maxPacketSize = opts.MaxPacketSize
+	}
+
+	conn, err := net.Dial(u.Scheme, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reporter{
+		sampleRate:    opts.SampleRate,
+		maxPacketSize: maxPacketSize,
+		framed:        framed,
+		onError:       opts.OnError,
+		now:           time.Now,
+		conn:          conn,
+		rand:          rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+func (r *reporter) ReportCounter(name string, tags map[string]string, value int64) {
+	r.report(sample{metric: Counter, name: name, value: float32(value), tags: tags})
+}
+
+func (r *reporter) ReportGauge(name string, tags map[string]string, value float64) {
+	r.report(sample{metric: Gauge, name: name, value: float32(value), tags: tags})
+}
+
+func (r *reporter) ReportTimer(name string, tags map[string]string, interval time.Duration) {
+	r.report(sample{
+		metric: Histogram,
+		name:   name,
+		value:  float32(interval) / float32(time.Millisecond),
+		tags:   tags,
+		unit:   TimerUnit,
+	})
+}
+
+func (r *reporter) ReportHistogramValueSamples(
+	name string,
+	tags map[string]string,
+	buckets tally.Buckets,
+	bucketLowerBound,
+	bucketUpperBound float64,
+	samples int64,
+) {
+	bucket := valueBucketString(bucketLowerBound) + "-" + valueBucketString(bucketUpperBound)
+	r.reportBucket(name, tags, bucket, samples)
+}
+
+func (r *reporter) ReportHistogramDurationSamples(
+	name string,
+	tags map[string]string,
+	buckets tally.Buckets,
+	bucketLowerBound,
+	bucketUpperBound time.Duration,
+	samples int64,
+) {
+	bucket := durationBucketString(bucketLowerBound) + "-" + durationBucketString(bucketUpperBound)
+	r.reportBucket(name, tags, bucket, samples)
+}
+
+func (r *reporter) reportBucket(name string, tags map[string]string, bucket string, samples int64) {
+	bucketTags := make(map[string]string, len(tags)+1)
+	for k, v := range tags {
+		bucketTags[k] = v
+	}
+	bucketTags[BucketTag] = bucket
+	r.report(sample{metric: Counter, name: name, value: float32(samples), tags: bucketTags})
+}
+
+func (r *reporter) report(s sample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.sampleRate < 1 {
+		if r.rand.Float32() >= r.sampleRate {
+			return
+		}
+		s.sampleRate = r.sampleRate
+	}
+	s.timestamp = r.now().UnixNano()
+
+	r.scratch = encodeSample(r.scratch[:0], s)
+	if len(r.buf) > 0 && len(r.buf)+spanMetricSize(len(r.scratch)) > r.maxPacketSize {
+		r.flushLocked()
+	}
+	r.buf = appendSpanMetric(r.buf, r.scratch)
+	if len(r.buf) >= r.maxPacketSize {
+		r.flushLocked()
+	}
+}
+
+func (r *reporter) flushLocked() {
+	if len(r.buf) == 0 {
+		return
+	}
+
+	packet := r.buf
+	if r.framed {
+		packet = make([]byte, frameHeaderSize, frameHeaderSize+len(r.buf))
+		packet[0] = frameVersion
+		binary.BigEndian.PutUint32(packet[1:], uint32(len(r.buf)))
+		packet = append(packet, r.buf...)
+	}
+	if _, err := r.conn.Write(packet); err != nil && r.onError != nil {
+		r.onError(err)
+	}
+	r.buf = r.buf[:0]
+}
+
+func (r *reporter) Capabilities() tally.Capabilities {
+	return r
+}
+
+func (r *reporter) Reporting() bool {
+	return true
+}
+
+func (r *reporter) Tagging() bool {
+	return true
+}
+
+func (r *reporter) Flush() {
+	r.mu.Lock()
+	r.flushLocked()
+	r.mu.Unlock()
+}
+
+// Close flushes any pending samples and closes the connection.
+func (r *reporter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.flushLocked()
+	return r.conn.Close()
+}
+
+func valueBucketString(bound float64) string {
+	if bound == math.MaxFloat64 {
+		return "infinity"
+	}
+	if bound == -math.MaxFloat64 {
+		return "-infinity"
+	}
+	return fmt.Sprintf("%.6f", bound)
+}
+
+func durationBucketString(bound time.Duration) string {
+	if bound == time.Duration(math.MaxInt64) {
+		return "infinity"
+	}
+	if bound == time.Duration(math.MinInt64) {
+		return "-infinity"
+	}
+	return bound.String()
+}
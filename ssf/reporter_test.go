@@ -0,0 +1,303 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ssf
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+)
+
+func TestReporterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	r, err := NewReporter(Options{Address: "udp://" + conn.LocalAddr().String()})
+	require.NoError(t, err)
+	defer r.Close()
+
+	now := time.Unix(1600000000, 0)
+	r.(*reporter).now = func() time.Time { return now }
+
+	tags := map[string]string{"env": "test"}
+	r.ReportCounter("requests", tags, 3)
+	r.ReportGauge("queue", tags, 1.5)
+	r.ReportTimer("latency", tags, 250*time.Millisecond)
+	r.ReportHistogramDurationSamples("sizes", tags, nil, 10*time.Millisecond, 20*time.Millisecond, 4)
+	r.Flush()
+
+	samples := decodeSpan(t, readPacket(t, conn))
+	require.Len(t, samples, 4)
+
+	assert.Equal(t, sample{
+		metric: Counter, name: "requests", value: 3, timestamp: now.UnixNano(), tags: tags,
+	}, samples[0])
+	assert.Equal(t, sample{
+		metric: Gauge, name: "queue", value: 1.5, timestamp: now.UnixNano(), tags: tags,
+	}, samples[1])
+	assert.Equal(t, sample{
+		metric: Histogram, name: "latency", value: 250, timestamp: now.UnixNano(), tags: tags, unit: TimerUnit,
+	}, samples[2])
+	assert.Equal(t, sample{
+		metric:    Counter,
+		name:      "sizes",
+		value:     4,
+		timestamp: now.UnixNano(),
+		tags:      map[string]string{"env": "test", BucketTag: "10ms-20ms"},
+	}, samples[3])
+}
+
+func TestReporterPacketSize(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	const maxPacketSize = 100
+	r, err := NewReporter(Options{
+		Address:       "udp://" + conn.LocalAddr().String(),
+		MaxPacketSize: maxPacketSize,
+	})
+	require.NoError(t, err)
+	defer r.Close()
+
+	for i := 0; i < 20; i++ {
+		r.ReportCounter("counter", map[string]string{"a": "b"}, int64(i))
+	}
+	r.Flush()
+
+	var values []float32
+	for len(values) < 20 {
+		packet := readPacket(t, conn)
+		assert.True(t, len(packet) <= maxPacketSize, "packet of %d bytes", len(packet))
+		samples := decodeSpan(t, packet)
+		require.NotEmpty(t, samples)
+		for _, s := range samples {
+			values = append(values, s.value)
+		}
+	}
+	for i, v := range values {
+		assert.Equal(t, float32(i), v)
+	}
+}
+
+func TestReporterUnixStream(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ssf.sock")
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer l.Close()
+
+	r, err := NewReporter(Options{Address: "unix://" + path, MaxPacketSize: 64})
+	require.NoError(t, err)
+
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	r.ReportCounter("first", nil, 1)
+	r.ReportCounter("second", nil, 2)
+	r.ReportGauge("third", map[string]string{"k": "v"}, 3)
+	require.NoError(t, r.Close())
+
+	var names []string
+	for {
+		var header [frameHeaderSize]byte
+		if _, err := io.ReadFull(conn, header[:]); err == io.EOF {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+		assert.Equal(t, byte(frameVersion), header[0])
+
+		frame := make([]byte, binary.BigEndian.Uint32(header[1:]))
+		_, err := io.ReadFull(conn, frame)
+		require.NoError(t, err)
+		for _, s := range decodeSpan(t, frame) {
+			names = append(names, s.name)
+		}
+	}
+	assert.Equal(t, []string{"first", "second", "third"}, names)
+}
+
+func TestReporterUnixgram(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ssf.sock")
+	conn, err := net.ListenPacket("unixgram", path)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	r, err := NewReporter(Options{Address: "unixgram://" + path})
+	require.NoError(t, err)
+	defer r.Close()
+
+	r.ReportCounter("counter", nil, 7)
+	r.Flush()
+
+	samples := decodeSpan(t, readPacket(t, conn))
+	require.Len(t, samples, 1)
+	assert.Equal(t, "counter", samples[0].name)
+	assert.Equal(t, float32(7), samples[0].value)
+}
+
+func TestReporterSampleRate(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	r, err := NewReporter(Options{
+		Address:       "udp://" + conn.LocalAddr().String(),
+		SampleRate:    0.5,
+		MaxPacketSize: 65000,
+	})
+	require.NoError(t, err)
+	defer r.Close()
+
+	for i := 0; i < 1000; i++ {
+		r.ReportCounter("counter", nil, 1)
+	}
+	r.Flush()
+
+	samples := decodeSpan(t, readPacket(t, conn))
+	assert.InDelta(t, 500, len(samples), 100)
+	for _, s := range samples {
+		assert.Equal(t, float32(0.5), s.sampleRate)
+	}
+}
+
+func TestNewReporterInvalidOptions(t *testing.T) {
+	_, err := NewReporter(Options{Address: "tcp://127.0.0.1:8128"})
+	assert.Error(t, err)
+
+	_, err = NewReporter(Options{SampleRate: 2})
+	assert.Error(t, err)
+}
+
+func TestReporterCapabilities(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	r, err := NewReporter(Options{Address: "udp://" + conn.LocalAddr().String()})
+	require.NoError(t, err)
+	defer r.Close()
+
+	var _ tally.StatsReporter = r
+	assert.True(t, r.Capabilities().Reporting())
+	assert.True(t, r.Capabilities().Tagging())
+}
+
+func readPacket(t *testing.T, conn net.PacketConn) []byte {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 65536)
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	return buf[:n]
+}
+
+// decodeSpan decodes the metrics field of an encoded SSFSpan.
+func decodeSpan(t *testing.T, b []byte) []sample {
+	var samples []sample
+	forEachField(t, b, func(field, wireType int, v uint64, data []byte) {
+		if field == spanMetricsField && wireType == wireBytes {
+			samples = append(samples, decodeSample(t, data))
+		}
+	})
+	return samples
+}
+
+func decodeSample(t *testing.T, b []byte) sample {
+	var s sample
+	forEachField(t, b, func(field, wireType int, v uint64, data []byte) {
+		switch field {
+		case sampleMetricField:
+			s.metric = Metric(v)
+		case sampleNameField:
+			s.name = string(data)
+		case sampleValueField:
+			s.value = math.Float32frombits(uint32(v))
+		case sampleTimestampField:
+			s.timestamp = int64(v)
+		case sampleSampleRateField:
+			s.sampleRate = math.Float32frombits(uint32(v))
+		case sampleTagsField:
+			var key, value string
+			forEachField(t, data, func(field, wireType int, v uint64, data []byte) {
+				if field == mapKeyField {
+					key = string(data)
+				} else {
+					value = string(data)
+				}
+			})
+			if s.tags == nil {
+				s.tags = make(map[string]string)
+			}
+			s.tags[key] = value
+		case sampleUnitField:
+			s.unit = string(data)
+		}
+	})
+	return s
+}
+
+func forEachField(t *testing.T, b []byte, fn func(field, wireType int, v uint64, data []byte)) {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		require.True(t, n > 0)
+		b = b[n:]
+
+		field, wireType := int(key>>3), int(key&7)
+		switch wireType {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			require.True(t, n > 0)
+			b = b[n:]
+			fn(field, wireType, v, nil)
+		case wireFixed32:
+			require.True(t, len(b) >= 4)
+			fn(field, wireType, uint64(binary.LittleEndian.Uint32(b)), nil)
+			b = b[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			require.True(t, n > 0 && len(b) >= n+int(l))
+			fn(field, wireType, 0, b[n:n+int(l)])
+			b = b[n+int(l):]
+		default:
+			t.Fatalf("unexpected wire type %d", wireType)
+		}
+	}
+}
@@ -21,9 +21,9 @@
 package tally
 
 import (
+	"context"
 	"fmt"
 	"math"
-	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -60,9 +60,17 @@ func (c *capabilities) Tagging() bool {
 }
 
 type counter struct {
+	idle        idleTracker
 	prev        int64
 	curr        int64
+	prevFloat   uint64 // float64 bits
+	currFloat   uint64 // float64 bits
+	reported    int64
+	snapshotted int64 // total at the last snapshot, see snapshotDelta
 	cachedCount CachedCount
+	cumulative  bool
+	limiter     *seriesLimiter
+	aliases     atomic.Value // []Counter
 }
 
 func newCounter(cachedCount CachedCount) *counter {
@@ -70,7 +78,40 @@ func newCounter(cachedCount CachedCount) *counter {
 }
 
 func (c *counter) Inc(v int64) {
+	if c.limiter != nil && !c.limiter.Allow() {
+		return
+	}
 	atomic.AddInt64(&c.curr, v)
+	c.idle.touch()
+	c.incAliases(v)
+}
+
+func (c *counter) Dec(v int64) {
+	c.Inc(-v)
+}
+
+func (c *counter) IncFloat(v float64) {
+	if c.limiter != nil && !c.limiter.Allow() {
+		return
+	}
+	for {
+		old := atomic.LoadUint64(&c.currFloat)
+		updated := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(&c.currFloat, old, updated) {
+			break
+		}
+	}
+	c.idle.touch()
+	c.incFloatAliases(v)
+}
+
+// roundedFloatDelta returns the delta between two totals of the IncFloat
+// increments of a counter, as reported to integer-only reporters. Rounding
+// the totals rather than each delta means the fractions are carried over to
+// the next reports instead of being lost, e.g. 0.4 each interval reports 0,
+// 1, 0, 1, 0 and so on. The totals are rounded half away from zero.
+func roundedFloatDelta(prev, curr float64) int64 {
+	return int64(math.Round(curr)) - int64(math.Round(prev))
 }
 
 func (c *counter) value() int64 {
@@ -84,32 +125,122 @@ func (c *counter) value() int64 {
 	return curr - prev
 }
 
+// counterValue is a value of a counter to report.
+type counterValue struct {
+	// value includes the IncFloat increments rounded, see roundedFloatDelta.
+	value      int64
+	floatValue float64
+	hasFloat   bool
+}
+
+// reportValue returns the value to report and whether the counter has
+// changed since the last report. Cumulative counters report their running
+// total, all others report the delta since the last report.
+func (c *counter) reportValue() (counterValue, bool) {
+	curr := atomic.LoadInt64(&c.curr)
+	currFloat := math.Float64frombits(atomic.LoadUint64(&c.currFloat))
+
+	prev := atomic.LoadInt64(&c.prev)
+	prevFloat := math.Float64frombits(atomic.LoadUint64(&c.prevFloat))
+	delta := curr - prev + roundedFloatDelta(prevFloat, currFloat)
+	atomic.StoreInt64(&c.reported, delta)
+	if prev == curr && prevFloat == currFloat {
+		return counterValue{}, false
+	}
+	atomic.StoreInt64(&c.prev, curr)
+	atomic.StoreUint64(&c.prevFloat, math.Float64bits(currFloat))
+
+	v := counterValue{hasFloat: currFloat != 0}
+	if c.cumulative {
+		v.value = curr + roundedFloatDelta(0, currFloat)
+		v.floatValue = float64(curr) + currFloat
+	} else {
+		v.value = delta
+		v.floatValue = float64(curr-prev) + (currFloat - prevFloat)
+	}
+	return v, true
+}
+
 func (c *counter) report(name string, tags map[string]string, r StatsReporter) {
-	delta := c.value()
-	if delta == 0 {
+	v, ok := c.reportValue()
+	if !ok {
 		return
 	}
 
-	r.ReportCounter(name, tags, delta)
+	if v.hasFloat {
+		if fr, ok := r.(FloatCounterReporter); ok {
+			fr.ReportCounterFloat(name, tags, v.floatValue)
+			return
+		}
+	}
+	r.ReportCounter(name, tags, v.value)
 }
 
 func (c *counter) cachedReport() {
-	delta := c.value()
-	if delta == 0 {
+	v, ok := c.reportValue()
+	if !ok {
 		return
 	}
 
-	c.cachedCount.ReportCount(delta)
+	if v.hasFloat {
+		if fc, ok := c.cachedCount.(CachedFloatCount); ok {
+			fc.ReportCountFloat(v.floatValue)
+			return
+		}
+	}
+	c.cachedCount.ReportCount(v.value)
 }
 
 func (c *counter) snapshot() int64 {
-	return atomic.LoadInt64(&c.curr) - atomic.LoadInt64(&c.prev)
+	curr := atomic.LoadInt64(&c.curr)
+	currFloat := math.Float64frombits(atomic.LoadUint64(&c.currFloat))
+	if c.cumulative {
+		return curr + roundedFloatDelta(0, currFloat)
+	}
+	prevFloat := math.Float64frombits(atomic.LoadUint64(&c.prevFloat))
+	return curr - atomic.LoadInt64(&c.prev) + roundedFloatDelta(prevFloat, currFloat)
+}
+
+// snapshotTotal returns the total of the counter since it was created, with
+// the IncFloat increments rounded, regardless of reports.
+func (c *counter) snapshotTotal() int64 {
+	curr := atomic.LoadInt64(&c.curr)
+	currFloat := math.Float64frombits(atomic.LoadUint64(&c.currFloat))
+	return curr + roundedFloatDelta(0, currFloat)
+}
+
+func (c *counter) snapshotTotalFloat() float64 {
+	curr := atomic.LoadInt64(&c.curr)
+	currFloat := math.Float64frombits(atomic.LoadUint64(&c.currFloat))
+	return float64(curr) + currFloat
+}
+
+// snapshotDelta returns the change of total since the last call that
+// advanced, and records total for the next one if advance is true.
+func (c *counter) snapshotDelta(total int64, advance bool) int64 {
+	if !advance {
+		return total - atomic.LoadInt64(&c.snapshotted)
+	}
+	return total - atomic.SwapInt64(&c.snapshotted, total)
+}
+
+func (c *counter) snapshotFloat() float64 {
+	curr := atomic.LoadInt64(&c.curr)
+	currFloat := math.Float64frombits(atomic.LoadUint64(&c.currFloat))
+	if c.cumulative {
+		return float64(curr) + currFloat
+	}
+	prevFloat := math.Float64frombits(atomic.LoadUint64(&c.prevFloat))
+	return float64(curr-atomic.LoadInt64(&c.prev)) + (currFloat - prevFloat)
 }
 
 type gauge struct {
+	idle        idleTracker
 	updated     uint64
 	curr        uint64
 	cachedGauge CachedGauge
+	limiter     *seriesLimiter
+	aliases     atomic.Value // []Gauge
 }
 
 func newGauge(cachedGauge CachedGauge) *gauge {
@@ -117,8 +248,29 @@ func newGauge(cachedGauge CachedGauge) *gauge {
 }
 
 func (g *gauge) Update(v float64) {
+	if g.limiter != nil && !g.limiter.Allow() {
+		return
+	}
 	atomic.StoreUint64(&g.curr, math.Float64bits(v))
 	atomic.StoreUint64(&g.updated, 1)
+	g.idle.touch()
+	g.updateAliases(v)
+}
+
+func (g *gauge) Add(delta float64) {
+	if g.limiter != nil && !g.limiter.Allow() {
+		return
+	}
+	for {
+		old := atomic.LoadUint64(&g.curr)
+		v := math.Float64frombits(old) + delta
+		if atomic.CompareAndSwapUint64(&g.curr, old, math.Float64bits(v)) {
+			atomic.StoreUint64(&g.updated, 1)
+			g.idle.touch()
+			g.updateAliases(v)
+			return
+		}
+	}
 }
 
 func (g *gauge) value() float64 {
@@ -141,20 +293,37 @@ func (g *gauge) snapshot() float64 {
 	return math.Float64frombits(atomic.LoadUint64(&g.curr))
 }
 
+func (g *gauge) snapshotUpdated() bool {
+	return atomic.LoadUint64(&g.updated) == 1
+}
+
 // NB(jra3): timers are a little special because they do no aggregate any data
 // at the timer level. The reporter buffers may timer entries and periodically
 // flushes.
 type timer struct {
+	idle        idleTracker
 	name        string
 	tags        map[string]string
 	reporter    StatsReporter
 	cachedTimer CachedTimer
 	unreported  timerValues
+	retained    *decayingReservoir
+	histogram   Histogram
+	limiter     *seriesLimiter
+	aliases     atomic.Value // []Timer
+	mean        *timerMean
+	percentiles *timerPercentiles
+	clock       Clock
+
+	snapshotLimit         int
+	snapshotDropRemainder bool
+	snapshotDropped       Counter
 }
 
 type timerValues struct {
 	sync.RWMutex
 	values []time.Duration
+	sample reservoir // of the interval, see MaxTimerSamplesPerInterval
 }
 
 func newTimer(
@@ -176,23 +345,66 @@ func newTimer(
 }
 
 func (t *timer) Record(interval time.Duration) {
+	t.record(interval, float64(interval))
+}
+
+// record records the interval, which is ns rounded to whole nanoseconds.
+func (t *timer) record(interval time.Duration, ns float64) {
+	if t.limiter != nil && !t.limiter.Allow() {
+		return
+	}
+	t.idle.touch()
+	if t.mean != nil {
+		t.mean.add(ns)
+	}
+	if t.percentiles != nil {
+		t.percentiles.add(interval)
+	}
+	if t.retained != nil {
+		t.retained.Update(interval)
+	}
+	if t.histogram != nil {
+		t.histogram.RecordDuration(interval)
+	}
 	if t.cachedTimer != nil {
 		t.cachedTimer.ReportTimer(interval)
 	} else {
 		t.reporter.ReportTimer(t.name, t.tags, interval)
 	}
+	t.recordAliases(interval)
 }
 
 func (t *timer) Start() Stopwatch {
-	return NewStopwatch(globalNow(), t)
+	return NewStopwatch(clockNow(t.clock), t)
+}
+
+func (t *timer) RecordFunc(f func()) {
+	defer t.Start().Stop()
+	f()
+}
+
+func (t *timer) StartContext(ctx context.Context) Stopwatch {
+	return NewStopwatch(clockNow(t.clock), NewContextRecorder(ctx, t, ContextRecorderOptions{}))
 }
 
 func (t *timer) RecordStopwatch(stopwatchStart time.Time) {
-	d := globalNow().Sub(stopwatchStart)
+	d := clockNow(t.clock).Sub(stopwatchStart)
 	t.Record(d)
 }
 
+func (t *timer) recordElapsed(stopwatchStart, now time.Time) {
+	t.Record(now.Sub(stopwatchStart))
+}
+
+func (t *timer) recorderClock() Clock {
+	return t.clock
+}
+
 func (t *timer) snapshot() []time.Duration {
+	if t.snapshotLimit > 0 {
+		return t.drain()
+	}
+
 	t.unreported.RLock()
 	snap := make([]time.Duration, len(t.unreported.values))
 	for i := range t.unreported.values {
@@ -202,6 +414,39 @@ func (t *timer) snapshot() []time.Duration {
 	return snap
 }
 
+func (t *timer) snapshotSamples() []time.Duration {
+	if t.retained == nil {
+		return nil
+	}
+	return t.retained.Values()
+}
+
+// drain removes and returns up to the snapshot limit of the oldest unreported
+// values, carrying over or dropping the rest.
+func (t *timer) drain() []time.Duration {
+	t.unreported.Lock()
+	n := len(t.unreported.values)
+	if n > t.snapshotLimit {
+		n = t.snapshotLimit
+	}
+	snap := make([]time.Duration, n)
+	copy(snap, t.unreported.values)
+
+	remaining := len(t.unreported.values) - n
+	if t.snapshotDropRemainder {
+		t.unreported.values = t.unreported.values[:0]
+	} else {
+		t.unreported.values = append(t.unreported.values[:0], t.unreported.values[n:]...)
+	}
+	t.unreported.sample.count = int64(len(t.unreported.values))
+	t.unreported.Unlock()
+
+	if t.snapshotDropRemainder && remaining > 0 && t.snapshotDropped != nil {
+		t.snapshotDropped.Inc(int64(remaining))
+	}
+	return snap
+}
+
 type timerNoReporterSink struct {
 	sync.RWMutex
 	timer *timer
@@ -226,9 +471,10 @@ func (r *timerNoReporterSink) ReportTimer(
 	tags map[string]string,
 	interval time.Duration,
 ) {
-	r.timer.unreported.Lock()
-	r.timer.unreported.values = append(r.timer.unreported.values, interval)
-	r.timer.unreported.Unlock()
+	u := &r.timer.unreported
+	u.Lock()
+	u.values = u.sample.appendDuration(u.values, interval)
+	u.Unlock()
 }
 
 func (r *timerNoReporterSink) ReportHistogramValueSamples(
@@ -264,6 +510,7 @@ type sampleCounter struct {
 }
 
 type histogram struct {
+	idle          idleTracker
 	htype         histogramType
 	name          string
 	tags          map[string]string
@@ -271,6 +518,17 @@ type histogram struct {
 	specification Buckets
 	buckets       []histogramBucket
 	samples       []sampleCounter
+	underflow     *counter
+	overflow      *counter
+	retained      *valueReservoir
+	percentiles   *histogramPercentiles
+	scale         float64
+	clamp         *histogramClamp
+	boundary      BucketBoundary
+	limiter       *seriesLimiter
+	aliases       atomic.Value // []Histogram
+	sampled       atomic.Value // Histogram
+	clock         Clock
 }
 
 type histogramType int
@@ -296,6 +554,8 @@ func newHistogram(
 		specification: storage.buckets,
 		buckets:       storage.hbuckets,
 		samples:       make([]sampleCounter, len(storage.hbuckets)),
+		underflow:     newCounter(nil),
+		overflow:      newCounter(nil),
 	}
 
 	for i := range h.samples {
@@ -321,11 +581,27 @@ func newHistogram(
 }
 
 func (h *histogram) report(name string, tags map[string]string, r StatsReporter) {
+	// NB: Discard the retained samples before the buckets are read so that
+	// no sample counted in the next interval's buckets is missing from the
+	// reservoir.
+	if h.retained != nil {
+		h.retained.Reset()
+	}
+	h.resetOutOfRange()
+
+	var counts []int64
+	if h.percentiles != nil {
+		counts = make([]int64, len(h.buckets))
+	}
+
 	for i := range h.buckets {
 		samples := h.samples[i].counter.value()
 		if samples == 0 {
 			continue
 		}
+		if counts != nil {
+			counts[i] = samples
+		}
 
 		switch h.htype {
 		case valueHistogramType:
@@ -348,14 +624,34 @@ func (h *histogram) report(name string, tags map[string]string, r StatsReporter)
 			)
 		}
 	}
+
+	if h.percentiles != nil {
+		h.percentiles.report(name, tags, h, counts, r)
+	}
 }
 
 func (h *histogram) cachedReport() {
+	// NB: Discard the retained samples before the buckets are read so that
+	// no sample counted in the next interval's buckets is missing from the
+	// reservoir.
+	if h.retained != nil {
+		h.retained.Reset()
+	}
+	h.resetOutOfRange()
+
+	var counts []int64
+	if h.percentiles != nil {
+		counts = make([]int64, len(h.buckets))
+	}
+
 	for i := range h.buckets {
 		samples := h.samples[i].counter.value()
 		if samples == 0 {
 			continue
 		}
+		if counts != nil {
+			counts[i] = samples
+		}
 
 		switch h.htype {
 		case valueHistogramType:
@@ -364,47 +660,83 @@ func (h *histogram) cachedReport() {
 			h.samples[i].cachedBucket.ReportSamples(samples)
 		}
 	}
+
+	if h.percentiles != nil {
+		h.percentiles.cachedReport(h, counts)
+	}
 }
 
 func (h *histogram) RecordValue(value float64) {
 	if h.htype != valueHistogramType {
 		return
 	}
+	if h.limiter != nil && !h.limiter.Allow() {
+		return
+	}
+	h.idle.touch()
+	if h.scale != 0 {
+		value *= h.scale
+	}
+	h.countValueOutOfRange(value)
+	if h.clamp != nil {
+		value = h.clamp.value(value)
+	}
+
+	h.samples[h.valueBucket(value)].counter.Inc(1)
 
-	// Find the highest inclusive of the bucket upper bound
-	// and emit directly to it. Since we use BucketPairs to derive
-	// buckets there will always be an inclusive bucket as
-	// we always have a math.MaxFloat64 bucket.
-	idx := sort.Search(len(h.buckets), func(i int) bool {
-		return h.buckets[i].valueUpperBound >= value
-	})
-	h.samples[idx].counter.Inc(1)
+	if h.retained != nil {
+		h.retained.Update(value)
+	}
+	h.recordValueAliases(value)
 }
 
 func (h *histogram) RecordDuration(value time.Duration) {
 	if h.htype != durationHistogramType {
 		return
 	}
+	if h.limiter != nil && !h.limiter.Allow() {
+		return
+	}
+	h.idle.touch()
+	h.countDurationOutOfRange(value)
+	if h.clamp != nil {
+		value = h.clamp.duration(value)
+	}
 
-	// Find the highest inclusive of the bucket upper bound
-	// and emit directly to it. Since we use BucketPairs to derive
-	// buckets there will always be an inclusive bucket as
-	// we always have a math.MaxInt64 bucket.
-	idx := sort.Search(len(h.buckets), func(i int) bool {
-		return h.buckets[i].durationUpperBound >= value
-	})
-	h.samples[idx].counter.Inc(1)
+	h.samples[h.durationBucket(value)].counter.Inc(1)
+
+	if h.retained != nil {
+		h.retained.Update(float64(value) / float64(time.Second))
+	}
+	h.recordDurationAliases(value)
 }
 
 func (h *histogram) Start() Stopwatch {
-	return NewStopwatch(globalNow(), h)
+	return NewStopwatch(clockNow(h.clock), h)
+}
+
+func (h *histogram) RecordDurationFunc(f func()) {
+	defer h.Start().Stop()
+	f()
+}
+
+func (h *histogram) StartContext(ctx context.Context) Stopwatch {
+	return NewStopwatch(clockNow(h.clock), NewContextRecorder(ctx, h, ContextRecorderOptions{}))
 }
 
 func (h *histogram) RecordStopwatch(stopwatchStart time.Time) {
-	d := globalNow().Sub(stopwatchStart)
+	d := clockNow(h.clock).Sub(stopwatchStart)
 	h.RecordDuration(d)
 }
 
+func (h *histogram) recordElapsed(stopwatchStart, now time.Time) {
+	h.RecordDuration(now.Sub(stopwatchStart))
+}
+
+func (h *histogram) recorderClock() Clock {
+	return h.clock
+}
+
 func (h *histogram) snapshotValues() map[float64]int64 {
 	if h.htype != valueHistogramType {
 		return nil
@@ -431,6 +763,25 @@ func (h *histogram) snapshotDurations() map[time.Duration]int64 {
 	return durations
 }
 
+func (h *histogram) snapshotSamples() []float64 {
+	if h.retained == nil {
+		return nil
+	}
+	return h.retained.Values()
+}
+
+func (h *histogram) snapshotUnderflow() int64 {
+	return h.underflow.snapshot()
+}
+
+func (h *histogram) snapshotOverflow() int64 {
+	return h.overflow.snapshot()
+}
+
+func (h *histogram) snapshotBuckets() []BucketPair {
+	return BucketPairs(h.specification)
+}
+
 type histogramBucket struct {
 	valueUpperBound      float64
 	durationUpperBound   time.Duration
@@ -91,3 +91,37 @@ func BenchmarkTimerReport(b *testing.B) {
 		t.Record(time.Since(start))
 	}
 }
+
+func benchmarkHistogramValues() []float64 {
+	values := make([]float64, 1000)
+	for i := range values {
+		values[i] = float64(i % 100)
+	}
+	return values
+}
+
+func BenchmarkHistogramRecordValueLoop(b *testing.B) {
+	s := newRootScope(ScopeOptions{SeriesRateLimit: 1e12, SeriesRateLimitBurst: 1e9}, 0)
+	h := s.Histogram("h", MustMakeLinearValueBuckets(0, 10, 10))
+	values := benchmarkHistogramValues()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			for _, v := range values {
+				h.RecordValue(v)
+			}
+		}
+	})
+}
+
+func BenchmarkHistogramRecordValues(b *testing.B) {
+	s := newRootScope(ScopeOptions{SeriesRateLimit: 1e12, SeriesRateLimitBurst: 1e9}, 0)
+	h := s.Histogram("h", MustMakeLinearValueBuckets(0, 10, 10))
+	values := benchmarkHistogramValues()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			RecordValues(h, values)
+		}
+	})
+}
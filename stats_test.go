@@ -22,6 +22,7 @@ package tally
 
 import (
 	"math/rand"
+	"sync"
 	"testing"
 	"time"
 
@@ -100,6 +101,134 @@ func TestCounter(t *testing.T) {
 	assert.Equal(t, int64(1), r.last)
 }
 
+func TestCounterDec(t *testing.T) {
+	counter := newCounter(nil)
+	r := newStatsTestReporter()
+
+	counter.Inc(5)
+	counter.Dec(2)
+	counter.Inc(1)
+	counter.report("", nil, r)
+	assert.Equal(t, int64(4), r.last)
+
+	counter.Dec(3)
+	counter.Inc(1)
+	counter.Dec(1)
+	counter.report("", nil, r)
+	assert.Equal(t, int64(-3), r.last)
+}
+
+func TestCachedCounterDec(t *testing.T) {
+	r := newTestStatsReporter()
+	cached := &testIntValue{reporter: r}
+	counter := newCounter(cached)
+
+	counter.Inc(3)
+	counter.Dec(1)
+	r.cg.Add(1)
+	counter.cachedReport()
+	assert.Equal(t, int64(2), cached.val)
+
+	counter.Dec(4)
+	counter.Inc(1)
+	r.cg.Add(1)
+	counter.cachedReport()
+	assert.Equal(t, int64(-3), cached.val)
+
+	// No movement since the last report is not reported again.
+	counter.cachedReport()
+	assert.Equal(t, int64(-3), cached.val)
+}
+
+type floatStatsTestReporter struct {
+	*statsTestReporter
+}
+
+func (r floatStatsTestReporter) ReportCounterFloat(name string, tags map[string]string, value float64) {
+	r.last = value
+}
+
+type floatCachedCount struct {
+	last interface{}
+}
+
+func (c *floatCachedCount) ReportCount(value int64) {
+	c.last = value
+}
+
+func (c *floatCachedCount) ReportCountFloat(value float64) {
+	c.last = value
+}
+
+func TestCounterIncFloat(t *testing.T) {
+	counter := newCounter(nil)
+	r := newStatsTestReporter()
+
+	// Integer-only reporters get the totals rounded, with the fractions
+	// carried over to the next reports.
+	for _, expected := range []int64{0, 1, 0, 1, 0} {
+		counter.IncFloat(0.4)
+		counter.report("", nil, r)
+		assert.Equal(t, expected, r.last)
+	}
+
+	counter.Inc(2)
+	counter.IncFloat(0.6)
+	counter.report("", nil, r)
+	assert.Equal(t, int64(3), r.last)
+
+	fr := floatStatsTestReporter{r}
+	counter.Inc(1)
+	counter.IncFloat(0.25)
+	counter.report("", nil, fr)
+	assert.InDelta(t, 1.25, r.last, 1e-9)
+
+	// Integer increments alone are still reported as integers.
+	counter = newCounter(nil)
+	counter.Inc(1)
+	counter.report("", nil, fr)
+	assert.Equal(t, int64(1), r.last)
+}
+
+func TestCounterIncFloatPrecision(t *testing.T) {
+	counter := newCounter(nil)
+	for i := 0; i < 1000000; i++ {
+		counter.IncFloat(0.1)
+	}
+
+	// The float64 sum is not exact, but rounds to the exact integer.
+	assert.NotEqual(t, 100000.0, counter.snapshotFloat())
+	assert.InDelta(t, 100000.0, counter.snapshotFloat(), 1e-5)
+	assert.Equal(t, int64(100000), counter.snapshot())
+
+	r := newStatsTestReporter()
+	counter.report("", nil, r)
+	assert.Equal(t, int64(100000), r.last)
+}
+
+func TestCachedCounterIncFloat(t *testing.T) {
+	cached := &floatCachedCount{}
+	counter := newCounter(cached)
+
+	counter.IncFloat(0.5)
+	counter.IncFloat(0.25)
+	counter.cachedReport()
+	assert.Equal(t, 0.75, cached.last)
+
+	counter.Inc(2)
+	counter.cachedReport()
+	assert.Equal(t, 2.0, cached.last)
+
+	// A cumulative counter reports the running total.
+	counter = newCounter(cached)
+	counter.cumulative = true
+	counter.IncFloat(0.5)
+	counter.cachedReport()
+	counter.Inc(1)
+	counter.cachedReport()
+	assert.Equal(t, 1.5, cached.last)
+}
+
 func TestGauge(t *testing.T) {
 	gauge := newGauge(nil)
 	r := newStatsTestReporter()
@@ -114,6 +243,46 @@ func TestGauge(t *testing.T) {
 	assert.Equal(t, float64(5678), r.last)
 }
 
+func TestGaugeAdd(t *testing.T) {
+	gauge := newGauge(nil)
+	r := newStatsTestReporter()
+
+	gauge.Update(10)
+	gauge.Add(5)
+	gauge.Add(-2.5)
+	gauge.report("", nil, r)
+	assert.Equal(t, 12.5, r.last)
+
+	// Adds are not reset by reporting.
+	gauge.Add(1)
+	gauge.report("", nil, r)
+	assert.Equal(t, 13.5, r.last)
+}
+
+func TestGaugeAddConcurrent(t *testing.T) {
+	gauge := newGauge(nil)
+	r := newStatsTestReporter()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if i%2 == 0 {
+					gauge.Add(3)
+				} else {
+					gauge.Add(-1)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	gauge.report("", nil, r)
+	assert.Equal(t, float64(25*1000*3-25*1000), r.last)
+}
+
 func TestTimer(t *testing.T) {
 	r := newStatsTestReporter()
 	timer := newTimer("t1", nil, r, nil)
@@ -125,6 +294,59 @@ func TestTimer(t *testing.T) {
 	assert.Equal(t, 128*time.Millisecond, r.last)
 }
 
+func TestTimerRecordFunc(t *testing.T) {
+	r := newStatsTestReporter()
+	timer := newTimer("t1", nil, r, nil)
+
+	sleep := 10 * time.Millisecond
+	start := time.Now()
+	timer.RecordFunc(func() { time.Sleep(sleep) })
+	elapsed := time.Since(start)
+
+	recorded := r.last.(time.Duration)
+	assert.True(t, recorded >= sleep)
+	assert.True(t, recorded <= elapsed)
+}
+
+func TestTimerRecordFuncPanic(t *testing.T) {
+	r := newStatsTestReporter()
+	timer := newTimer("t1", nil, r, nil)
+
+	func() {
+		defer func() {
+			assert.Equal(t, "boom", recover())
+		}()
+		timer.RecordFunc(func() {
+			time.Sleep(time.Millisecond)
+			panic("boom")
+		})
+	}()
+	assert.True(t, r.last.(time.Duration) >= time.Millisecond)
+}
+
+func TestHistogramRecordDurationFunc(t *testing.T) {
+	r := newStatsTestReporter()
+	buckets := MustMakeLinearDurationBuckets(0, 10*time.Millisecond, 10)
+	storage := newBucketStorage(durationHistogramType, buckets)
+	h := newHistogram(durationHistogramType, "h1", nil, r, storage, nil)
+
+	h.RecordDurationFunc(func() { time.Sleep(10 * time.Millisecond) })
+	assert.Panics(t, func() {
+		h.RecordDurationFunc(func() { panic("boom") })
+	})
+
+	h.report(h.name, h.tags, r)
+
+	var recorded int
+	for upperBound, samples := range r.durationSamples {
+		if upperBound >= 20*time.Millisecond {
+			recorded += samples
+		}
+	}
+	assert.Equal(t, 1, recorded)
+	assert.Equal(t, 1, r.durationSamples[10*time.Millisecond])
+}
+
 func TestHistogramValueSamples(t *testing.T) {
 	r := newStatsTestReporter()
 	buckets := MustMakeLinearValueBuckets(0, 10, 10)
@@ -170,3 +392,39 @@ func TestHistogramDurationSamples(t *testing.T) {
 	assert.Equal(t, 5, r.durationSamples[60*time.Millisecond])
 	assert.Equal(t, buckets, r.buckets)
 }
+
+func TestTimerSnapshotLimit(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{TimerSnapshotLimit: 3})
+	timer := s.Timer("t1")
+	for i := 1; i <= 5; i++ {
+		timer.Record(time.Duration(i))
+	}
+
+	snapshotValues := func() []time.Duration {
+		return s.Snapshot().Timers()["t1+"].Values()
+	}
+
+	// The values over the limit are carried over to the next snapshot.
+	assert.Equal(t, []time.Duration{1, 2, 3}, snapshotValues())
+	assert.Equal(t, []time.Duration{4, 5}, snapshotValues())
+	assert.Equal(t, []time.Duration{}, snapshotValues())
+}
+
+func TestTimerSnapshotLimitDropRemainder(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{
+		TimerSnapshotLimit:          3,
+		TimerSnapshotDropRemainder:  true,
+		TimerSnapshotDroppedCounter: "dropped",
+	})
+	timer := s.Timer("t1")
+	for i := 1; i <= 5; i++ {
+		timer.Record(time.Duration(i))
+	}
+
+	snap := s.Snapshot()
+	assert.Equal(t, []time.Duration{1, 2, 3}, snap.Timers()["t1+"].Values())
+
+	snap = s.Snapshot()
+	assert.Equal(t, []time.Duration{}, snap.Timers()["t1+"].Values())
+	assert.EqualValues(t, 2, snap.Counters()["dropped+"].Value())
+}
@@ -0,0 +1,243 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"context"
+	"time"
+)
+
+// DeadlineExceededTagKey is the tag key set by NewDeadlineTimerRecorder.
+const DeadlineExceededTagKey = "deadline_exceeded"
+
+// StartStopwatch creates a new stopwatch started now that records to the
+// given stopwatch recorder when stopped. The time is read from the Clock of
+// the scope of the timers and histograms r records to, including through the
+// recorders of this package.
+func StartStopwatch(r StopwatchRecorder) Stopwatch {
+	return NewStopwatch(stopwatchNow(r), r)
+}
+
+// clockRecorder is a stopwatch recorder measuring the elapsed time with the
+// Clock of its scope, or a scope or recorder wrapping one.
+type clockRecorder interface {
+	recorderClock() Clock
+}
+
+// recorderClock returns the clock of v, or nil if it does not have one.
+func recorderClock(v interface{}) Clock {
+	if c, ok := v.(clockRecorder); ok {
+		return c.recorderClock()
+	}
+	return nil
+}
+
+// stopwatchNow returns the current time of the clock of r.
+func stopwatchNow(r StopwatchRecorder) time.Time {
+	return clockNow(recorderClock(r))
+}
+
+// elapsedRecorder is a stopwatch recorder that records a stopwatch stopped at
+// a given time, so that the time is read only once, e.g. by StopAndReset.
+type elapsedRecorder interface {
+	recordElapsed(stopwatchStart, now time.Time)
+}
+
+// recordElapsed records the stopwatch started at stopwatchStart and stopped
+// at now to r, or stopped when r reads its clock if r records on its own.
+func recordElapsed(r StopwatchRecorder, stopwatchStart, now time.Time) {
+	if er, ok := r.(elapsedRecorder); ok {
+		er.recordElapsed(stopwatchStart, now)
+		return
+	}
+	r.RecordStopwatch(stopwatchStart)
+}
+
+// NewMultiTimerRecorder returns a stopwatch recorder that records the elapsed
+// time of a stopwatch to each of the given timers.
+func NewMultiTimerRecorder(timers ...Timer) StopwatchRecorder {
+	return multiTimerRecorder(timers)
+}
+
+type multiTimerRecorder []Timer
+
+func (r multiTimerRecorder) RecordStopwatch(stopwatchStart time.Time) {
+	r.recordElapsed(stopwatchStart, stopwatchNow(r))
+}
+
+func (r multiTimerRecorder) recordElapsed(stopwatchStart, now time.Time) {
+	d := now.Sub(stopwatchStart)
+	for _, t := range r {
+		t.Record(d)
+	}
+}
+
+// recorderClock returns the clock of the first timer that has one.
+func (r multiTimerRecorder) recorderClock() Clock {
+	for _, t := range r {
+		if c := recorderClock(t); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// NewTransformTimerRecorder returns a stopwatch recorder that records the
+// elapsed time of a stopwatch to the given timer after applying transform
+// to it, e.g. to subtract a known overhead or to round it.
+func NewTransformTimerRecorder(
+	t Timer,
+	transform func(time.Duration) time.Duration,
+) StopwatchRecorder {
+	return transformTimerRecorder{timer: t, transform: transform}
+}
+
+type transformTimerRecorder struct {
+	timer     Timer
+	transform func(time.Duration) time.Duration
+}
+
+func (r transformTimerRecorder) RecordStopwatch(stopwatchStart time.Time) {
+	r.recordElapsed(stopwatchStart, stopwatchNow(r))
+}
+
+func (r transformTimerRecorder) recordElapsed(stopwatchStart, now time.Time) {
+	r.timer.Record(r.transform(now.Sub(stopwatchStart)))
+}
+
+func (r transformTimerRecorder) recorderClock() Clock {
+	return recorderClock(r.timer)
+}
+
+// NewConditionalRecorder returns a stopwatch recorder that forwards to the
+// given stopwatch recorder only when predicate returns true for the elapsed
+// time of a stopwatch, e.g. to only record slow operations.
+func NewConditionalRecorder(
+	r StopwatchRecorder,
+	predicate func(time.Duration) bool,
+) StopwatchRecorder {
+	return conditionalRecorder{recorder: r, predicate: predicate}
+}
+
+type conditionalRecorder struct {
+	recorder  StopwatchRecorder
+	predicate func(time.Duration) bool
+}
+
+func (r conditionalRecorder) RecordStopwatch(stopwatchStart time.Time) {
+	r.recordElapsed(stopwatchStart, stopwatchNow(r))
+}
+
+func (r conditionalRecorder) recordElapsed(stopwatchStart, now time.Time) {
+	if r.predicate(now.Sub(stopwatchStart)) {
+		recordElapsed(r.recorder, stopwatchStart, now)
+	}
+}
+
+func (r conditionalRecorder) recorderClock() Clock {
+	return recorderClock(r.recorder)
+}
+
+// NewDeadlineTimerRecorder returns a stopwatch recorder that records the
+// elapsed time of a stopwatch to the timer with the given name of s, tagged
+// with DeadlineExceededTagKey set to "true" if the deadline of ctx had passed
+// when the stopwatch was stopped and "false" otherwise, e.g. to compare the
+// latency of operations that did or did not meet their deadline. A context
+// without a deadline never exceeds it and is tagged "false".
+func NewDeadlineTimerRecorder(ctx context.Context, s Scope, name string) StopwatchRecorder {
+	return deadlineTimerRecorder{ctx: ctx, scope: s, name: name}
+}
+
+type deadlineTimerRecorder struct {
+	ctx   context.Context
+	scope Scope
+	name  string
+}
+
+func (r deadlineTimerRecorder) RecordStopwatch(stopwatchStart time.Time) {
+	r.recordElapsed(stopwatchStart, stopwatchNow(r))
+}
+
+func (r deadlineTimerRecorder) recordElapsed(stopwatchStart, now time.Time) {
+	exceeded := "false"
+	if deadline, ok := r.ctx.Deadline(); ok && !now.Before(deadline) {
+		exceeded = "true"
+	}
+	r.scope.Tagged(map[string]string{
+		DeadlineExceededTagKey: exceeded,
+	}).Timer(r.name).Record(now.Sub(stopwatchStart))
+}
+
+func (r deadlineTimerRecorder) recorderClock() Clock {
+	return recorderClock(r.scope)
+}
+
+// recorderClock returns the Clock of the scope, e.g. for the recorders of
+// NewDeadlineTimerRecorder.
+func (s *scope) recorderClock() Clock {
+	return s.clock
+}
+
+// ContextRecorderOptions is a set of options for NewContextRecorder.
+type ContextRecorderOptions struct {
+	// CancelledCounter, if set, is incremented by one for every stopwatch
+	// stopped after its context was done instead of recording nothing at all.
+	CancelledCounter Counter
+}
+
+// NewContextRecorder returns a stopwatch recorder that forwards to the given
+// stopwatch recorder only if ctx is not done when a stopwatch is stopped, so
+// that operations cut short by a cancellation or an exceeded deadline do not
+// record a misleadingly short duration. When ctx is done nothing is recorded,
+// unless CancelledCounter is set in which case it is incremented instead.
+// Timer.StartContext and Histogram.StartContext start stopwatches recording
+// through it without a CancelledCounter.
+func NewContextRecorder(
+	ctx context.Context,
+	r StopwatchRecorder,
+	opts ContextRecorderOptions,
+) StopwatchRecorder {
+	return contextRecorder{ctx: ctx, recorder: r, cancelled: opts.CancelledCounter}
+}
+
+type contextRecorder struct {
+	ctx       context.Context
+	recorder  StopwatchRecorder
+	cancelled Counter
+}
+
+func (r contextRecorder) RecordStopwatch(stopwatchStart time.Time) {
+	r.recordElapsed(stopwatchStart, stopwatchNow(r))
+}
+
+func (r contextRecorder) recordElapsed(stopwatchStart, now time.Time) {
+	if r.ctx.Err() == nil {
+		recordElapsed(r.recorder, stopwatchStart, now)
+		return
+	}
+	if r.cancelled != nil {
+		r.cancelled.Inc(1)
+	}
+}
+
+func (r contextRecorder) recorderClock() Clock {
+	return recorderClock(r.recorder)
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingTimer struct {
+	values []time.Duration
+}
+
+func (t *recordingTimer) Record(value time.Duration) {
+	t.values = append(t.values, value)
+}
+
+func (t *recordingTimer) Start() Stopwatch {
+	return StartStopwatch(NewMultiTimerRecorder(t))
+}
+
+func (t *recordingTimer) RecordFunc(f func()) {
+	defer t.Start().Stop()
+	f()
+}
+
+func (t *recordingTimer) StartContext(ctx context.Context) Stopwatch {
+	return StartStopwatch(NewContextRecorder(ctx, NewMultiTimerRecorder(t), ContextRecorderOptions{}))
+}
+
+func withFixedNow(now time.Time) func() {
+	prev := globalNow
+	globalNow = func() time.Time { return now }
+	return func() { globalNow = prev }
+}
+
+func TestMultiTimerRecorder(t *testing.T) {
+	now := time.Now()
+	defer withFixedNow(now)()
+
+	a, b := &recordingTimer{}, &recordingTimer{}
+	NewStopwatch(now.Add(-time.Second), NewMultiTimerRecorder(a, b)).Stop()
+
+	assert.Equal(t, []time.Duration{time.Second}, a.values)
+	assert.Equal(t, []time.Duration{time.Second}, b.values)
+}
+
+func TestTransformTimerRecorder(t *testing.T) {
+	now := time.Now()
+	defer withFixedNow(now)()
+
+	timer := &recordingTimer{}
+	r := NewTransformTimerRecorder(timer, func(d time.Duration) time.Duration {
+		return d - 100*time.Millisecond
+	})
+	NewStopwatch(now.Add(-time.Second), r).Stop()
+
+	assert.Equal(t, []time.Duration{900 * time.Millisecond}, timer.values)
+}
+
+func TestConditionalRecorder(t *testing.T) {
+	now := time.Now()
+	defer withFixedNow(now)()
+
+	timer := &recordingTimer{}
+	r := NewConditionalRecorder(NewMultiTimerRecorder(timer), func(d time.Duration) bool {
+		return d >= time.Second
+	})
+	NewStopwatch(now.Add(-time.Millisecond), r).Stop()
+	NewStopwatch(now.Add(-2*time.Second), r).Stop()
+
+	assert.Equal(t, []time.Duration{2 * time.Second}, timer.values)
+}
+
+func TestDeadlineTimerRecorder(t *testing.T) {
+	now := time.Now()
+	defer withFixedNow(now)()
+
+	s := NewTestScope("", nil)
+	met, cancel := context.WithDeadline(context.Background(), now.Add(time.Second))
+	defer cancel()
+	exceeded, cancel := context.WithDeadline(context.Background(), now.Add(-time.Second))
+	defer cancel()
+
+	start := now.Add(-2 * time.Second)
+	NewStopwatch(start, NewDeadlineTimerRecorder(met, s, "handler")).Stop()
+	NewStopwatch(start, NewDeadlineTimerRecorder(exceeded, s, "handler")).Stop()
+	NewStopwatch(start, NewDeadlineTimerRecorder(context.Background(), s, "handler")).Stop()
+
+	timers := s.Snapshot().Timers()
+	assert.Len(t, timers, 2)
+	assert.Equal(t, []time.Duration{2 * time.Second, 2 * time.Second},
+		timers["handler+deadline_exceeded=false"].Values())
+	assert.Equal(t, []time.Duration{2 * time.Second},
+		timers["handler+deadline_exceeded=true"].Values())
+}
+
+func TestContextRecorder(t *testing.T) {
+	now := time.Now()
+	defer withFixedNow(now)()
+
+	s := NewTestScope("", nil)
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	timer := &recordingTimer{}
+	opts := ContextRecorderOptions{CancelledCounter: s.Counter("cancelled")}
+	start := now.Add(-time.Second)
+	NewStopwatch(start, NewContextRecorder(context.Background(),
+		NewMultiTimerRecorder(timer), opts)).Stop()
+	NewStopwatch(start, NewContextRecorder(cancelledCtx,
+		NewMultiTimerRecorder(timer), opts)).Stop()
+	NewStopwatch(start, NewContextRecorder(cancelledCtx,
+		NewMultiTimerRecorder(timer), ContextRecorderOptions{})).Stop()
+
+	assert.Equal(t, []time.Duration{time.Second}, timer.values)
+	assert.Equal(t, int64(1), s.Snapshot().Counters()["cancelled+"].Value())
+}
+
+func TestStartContext(t *testing.T) {
+	s := NewTestScope("", nil)
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tm := s.Timer("latency")
+	tm.StartContext(context.Background()).Stop()
+	tm.StartContext(cancelledCtx).Stop()
+
+	h := s.Histogram("duration", MustMakeLinearDurationBuckets(0, time.Hour, 2))
+	h.StartContext(context.Background()).Stop()
+	h.StartContext(cancelledCtx).Stop()
+
+	snap := s.Snapshot()
+	assert.Len(t, snap.Timers()["latency+"].Values(), 1)
+	assert.Equal(t, int64(1), snap.Histograms()["duration+"].Durations()[time.Hour])
+}
+
+func TestStopwatchReset(t *testing.T) {
+	now := time.Now()
+	defer withFixedNow(now)()
+
+	timer := &recordingTimer{}
+	sw := NewStopwatch(now.Add(-time.Second), NewMultiTimerRecorder(timer))
+	sw.Stop()
+	sw.Reset(now.Add(-time.Millisecond))
+	sw.Stop()
+	sw.Reset(now.Add(-time.Minute))
+	sw.Stop()
+
+	assert.Equal(t, []time.Duration{time.Second, time.Millisecond, time.Minute}, timer.values)
+}
+
+func TestStopwatchStopAndReset(t *testing.T) {
+	now := time.Now()
+	globalNow = func() time.Time { return now }
+	defer func() { globalNow = time.Now }()
+
+	s := NewTestScope("", nil)
+	sw := s.Timer("latency").Start()
+	elapsed := []time.Duration{time.Millisecond, time.Second, 3 * time.Millisecond}
+	for _, d := range elapsed {
+		now = now.Add(d)
+		assert.Equal(t, d, sw.StopAndReset())
+	}
+	assert.Equal(t, elapsed, s.Snapshot().Timers()["latency+"].Values())
+
+	timer := &recordingTimer{}
+	sw = NewStopwatch(now, NewMultiTimerRecorder(timer))
+	now = now.Add(time.Second)
+	assert.Equal(t, time.Second, sw.StopAndReset())
+	now = now.Add(time.Second)
+	assert.Equal(t, time.Second, sw.StopAndReset())
+	assert.Equal(t, []time.Duration{time.Second, time.Second}, timer.values)
+}
+
+// steppingClock is a clock that advances by step on every read.
+type steppingClock struct {
+	now  time.Time
+	step time.Duration
+}
+
+func (c *steppingClock) Now() time.Time {
+	c.now = c.now.Add(c.step)
+	return c.now
+}
+
+func (c *steppingClock) NewTicker(d time.Duration) Ticker {
+	return wallClock{}.NewTicker(d)
+}
+
+func TestStopwatchStopAndResetReadsClockOnce(t *testing.T) {
+	clock := &steppingClock{now: time.Now(), step: time.Millisecond}
+	s := NewTestScopeWithOptions(ScopeOptions{Clock: clock})
+
+	sw := s.Timer("latency").Start()
+	hsw := s.Histogram("duration", DurationBuckets{time.Millisecond}).Start()
+	csw := StartStopwatch(NewConditionalRecorder(
+		s.Timer("slow").(StopwatchRecorder),
+		func(d time.Duration) bool { return d >= 3*time.Millisecond },
+	))
+
+	// Each stopwatch reads the clock once to start and once to stop.
+	assert.Equal(t, 3*time.Millisecond, sw.StopAndReset())
+	assert.Equal(t, 3*time.Millisecond, hsw.StopAndReset())
+	assert.Equal(t, 3*time.Millisecond, csw.StopAndReset())
+
+	snap := s.Snapshot()
+	assert.Equal(t, []time.Duration{3 * time.Millisecond}, snap.Timers()["latency+"].Values())
+	assert.Equal(t, int64(1), snap.Histograms()["duration+"].Durations()[math.MaxInt64])
+	assert.Equal(t, []time.Duration{3 * time.Millisecond}, snap.Timers()["slow+"].Values())
+}
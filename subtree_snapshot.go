@@ -0,0 +1,60 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import "strings"
+
+// SubtreeSnapshot returns a snapshot of only the scope s and its descendants,
+// i.e. the scopes created from it with SubScope and Tagged, like
+// TestScope.Snapshot does for the whole root scope. A scope belongs to the
+// subtree when its prefix is the prefix of s or nested below it, and its tags
+// include all tags of s. Like Snapshot this is expensive and should only be
+// used for testing purposes, but unlike it the CounterSnapshot.Delta of the
+// counters is not consumed. On Scope implementations other than those created
+// by NewRootScope the snapshot is empty.
+func SubtreeSnapshot(s Scope) Snapshot {
+	if ss, ok := s.(subtreeSnapshotScope); ok {
+		return ss.subtreeSnapshot()
+	}
+	return newSnapshot()
+}
+
+type subtreeSnapshotScope interface {
+	subtreeSnapshot() Snapshot
+}
+
+func (s *scope) subtreeSnapshot() Snapshot {
+	return s.snapshot(s.inSubtree, false)
+}
+
+// inSubtree returns whether ss is s or one of its descendants.
+func (s *scope) inSubtree(ss *scope) bool {
+	if s.prefix != "" && ss.prefix != s.prefix &&
+		!strings.HasPrefix(ss.prefix, s.prefix+s.separator) {
+		return false
+	}
+	for k, v := range s.tags {
+		if tv, ok := ss.tags[k]; !ok || tv != v {
+			return false
+		}
+	}
+	return true
+}
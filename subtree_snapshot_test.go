@@ -0,0 +1,85 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtreeSnapshot(t *testing.T) {
+	s := NewTestScope("", nil)
+	s.Counter("root").Inc(1)
+
+	db := s.SubScope("db")
+	db.Counter("queries").Inc(1)
+	db.SubScope("pool").Gauge("size").Update(4)
+	db.Tagged(map[string]string{"table": "users"}).Timer("latency").Record(1)
+
+	s.SubScope("dbx").Counter("queries").Inc(1)
+	s.SubScope("http").Counter("requests").Inc(1)
+	s.SubScope("http").SubScope("db").Counter("calls").Inc(1)
+
+	snap := SubtreeSnapshot(db)
+	assert.Equal(t, []string{"db.queries+"}, snapshotKeys(snap.Counters()))
+	assert.Contains(t, snap.Gauges(), "db.pool.size+")
+	assert.Contains(t, snap.Timers(), "db.latency+table=users")
+	assert.Equal(t, SnapshotCounts{Counters: 1, Gauges: 1, Timers: 1, Total: 3}, snap.Counts())
+
+	assert.Equal(t, s.Snapshot().Counts(), SubtreeSnapshot(s).Counts())
+}
+
+func TestSubtreeSnapshotTagged(t *testing.T) {
+	s := NewTestScope("", nil)
+
+	users := s.Tagged(map[string]string{"table": "users"})
+	users.Counter("queries").Inc(1)
+	users.SubScope("cache").Tagged(map[string]string{"tier": "l1"}).Counter("hits").Inc(1)
+
+	s.Tagged(map[string]string{"table": "orders"}).Counter("queries").Inc(1)
+	s.Counter("queries").Inc(1)
+
+	snap := SubtreeSnapshot(users)
+	assert.Equal(t, []string{
+		"cache.hits+table=users,tier=l1",
+		"queries+table=users",
+	}, snapshotKeys(snap.Counters()))
+}
+
+func TestSubtreeSnapshotSourceTaggingScope(t *testing.T) {
+	s := NewTestScope("", nil)
+	db := NewSourceTaggingScope(s).SubScope("db")
+	db.Counter("queries").Inc(1)
+	s.SubScope("http").Counter("requests").Inc(1)
+
+	assert.Len(t, SubtreeSnapshot(db).Counters(), 1)
+}
+
+func snapshotKeys(counters map[string]CounterSnapshot) []string {
+	keys := make([]string, 0, len(counters))
+	for k := range counters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
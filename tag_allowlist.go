@@ -0,0 +1,70 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"fmt"
+	"sort"
+)
+
+func (r *scopeRegistry) allowTagKeys(keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	root := r.root
+	r.allowedTagKeys = make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		r.allowedTagKeys[root.tagKeyCase.apply(root.sanitizer.Key(k))] = struct{}{}
+	}
+}
+
+// dropDisallowedTagKeys returns the given sanitized tags without the keys
+// missing from ScopeOptions.AllowedTagKeys, reporting each dropped key to the
+// error hook, copying them only if a key is dropped.
+func (s *scope) dropDisallowedTagKeys(tags map[string]string) map[string]string {
+	allowed := s.registry.allowedTagKeys
+	if allowed == nil || len(tags) == 0 {
+		return tags
+	}
+
+	var dropped []string
+	for k := range tags {
+		if _, ok := allowed[k]; !ok {
+			dropped = append(dropped, k)
+		}
+	}
+	if len(dropped) == 0 {
+		return tags
+	}
+
+	sort.Strings(dropped)
+	result := make(map[string]string, len(tags)-len(dropped))
+	for k, v := range tags {
+		if _, ok := allowed[k]; ok {
+			result[k] = v
+		}
+	}
+	for _, k := range dropped {
+		s.registry.errors.report(TagKeyErrorPhase,
+			fmt.Errorf("dropped tag key %q not in AllowedTagKeys", k))
+	}
+	return result
+}
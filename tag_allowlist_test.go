@@ -0,0 +1,63 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowedTagKeys(t *testing.T) {
+	errs := make(chan error, errorQueueSize)
+	s := NewTestScopeWithOptions(ScopeOptions{
+		Tags:           map[string]string{"host": "a"},
+		AllowedTagKeys: []string{"region", "zone"},
+		OnError:        func(err error) { errs <- err },
+	})
+
+	s.Tagged(map[string]string{"region": "east"}).Counter("allowed").Inc(1)
+	s.Tagged(map[string]string{"region": "east", "user": "x"}).Counter("dropped").Inc(1)
+	// The check propagates to the subscopes of tagged scopes.
+	s.Tagged(map[string]string{"zone": "1"}).SubScope("sub").
+		Tagged(map[string]string{"user": "y"}).Counter("nested").Inc(1)
+
+	counters := s.Snapshot().Counters()
+	assert.Contains(t, counters, "allowed+host=a,region=east")
+	// The metric is still emitted, without the disallowed key.
+	require.Contains(t, counters, "dropped+host=a,region=east")
+	assert.Equal(t, int64(1), counters["dropped+host=a,region=east"].Value())
+	assert.Contains(t, counters, "sub.nested+host=a,zone=1")
+
+	for i := 0; i < 2; i++ {
+		se := receiveScopeError(t, errs)
+		assert.Equal(t, TagKeyErrorPhase, se.Phase)
+		assert.Equal(t, `tally: tag key: dropped tag key "user" not in AllowedTagKeys`, se.Error())
+	}
+}
+
+func TestAllowedTagKeysEmpty(t *testing.T) {
+	s := NewTestScope("", nil)
+
+	s.Tagged(map[string]string{"anything": "goes"}).Counter("c").Inc(1)
+	assert.Contains(t, s.Snapshot().Counters(), "c+anything=goes")
+}
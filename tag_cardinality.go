@@ -0,0 +1,91 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import "sync"
+
+// DefaultTagOverflowValue is the default tag value replacing values over
+// ScopeOptions.MaxTagCardinality.
+const DefaultTagOverflowValue = "__overflow__"
+
+// tagCardinality tracks the distinct values of each tag key of a registry to
+// enforce ScopeOptions.MaxTagCardinality.
+type tagCardinality struct {
+	sync.Mutex
+	max      int
+	values   map[string]map[string]struct{}
+	overflow string
+}
+
+func (r *scopeRegistry) limitTagCardinality(max int, overflow string) {
+	if max <= 0 {
+		return
+	}
+	if overflow == "" {
+		overflow = DefaultTagOverflowValue
+	}
+	root := r.root
+	r.tagValues.max = max
+	r.tagValues.values = make(map[string]map[string]struct{})
+	r.tagValues.overflow = root.tagValueCase.apply(root.sanitizer.Value(overflow))
+}
+
+// limitTagCardinality returns the given sanitized tags with the values over
+// the maximum number of values of their key replaced by the overflow value,
+// copying them only if a value is replaced.
+func (s *scope) limitTagCardinality(tags map[string]string) map[string]string {
+	limit := &s.registry.tagValues
+	if limit.max <= 0 || len(tags) == 0 {
+		return tags
+	}
+
+	limit.Lock()
+	defer limit.Unlock()
+
+	limited := tags
+	copied := false
+	for k, v := range tags {
+		if v == limit.overflow {
+			continue
+		}
+		values, ok := limit.values[k]
+		if !ok {
+			values = make(map[string]struct{})
+			limit.values[k] = values
+		}
+		if _, ok := values[v]; ok {
+			continue
+		}
+		if len(values) < limit.max {
+			values[v] = struct{}{}
+			continue
+		}
+		if !copied {
+			limited = make(map[string]string, len(tags))
+			for tk, tv := range tags {
+				limited[tk] = tv
+			}
+			copied = true
+		}
+		limited[k] = limit.overflow
+	}
+	return limited
+}
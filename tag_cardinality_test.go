@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxTagCardinality(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{MaxTagCardinality: 2})
+
+	user := func(id int) Scope {
+		return s.Tagged(map[string]string{"user": fmt.Sprint(id), "env": "prod"})
+	}
+	first, second := user(1), user(2)
+
+	// Over the limit new values share the overflow scope.
+	overflow := user(3)
+	assert.True(t, overflow == user(4))
+	overflow.Counter("requests").Inc(1)
+	user(4).Counter("requests").Inc(1)
+
+	// Existing values keep their identity.
+	assert.True(t, first == user(1))
+	assert.True(t, second == user(2))
+	first.Counter("requests").Inc(1)
+
+	// Keys are limited independently.
+	assert.False(t, first == s.Tagged(map[string]string{"user": "1", "env": "dev"}))
+
+	counters := s.Snapshot().Counters()
+	assert.EqualValues(t, 1, counters["requests+env=prod,user=1"].Value())
+	assert.EqualValues(t, 2, counters["requests+env=prod,user=__overflow__"].Value())
+	assert.NotContains(t, counters, "requests+env=prod,user=3")
+}
+
+func TestMaxTagCardinalityOverflowValue(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{
+		MaxTagCardinality: 1,
+		TagOverflowValue:  "other",
+	})
+	tags := map[string]string{"user": "a"}
+	s.Tagged(tags)
+
+	tags["user"] = "b"
+	s.Tagged(tags).SubScope("db").Counter("queries").Inc(1)
+	// The tags passed in are not modified.
+	assert.Equal(t, "b", tags["user"])
+
+	assert.Contains(t, s.Snapshot().Counters(), "db.queries+user=other")
+}
+
+func TestMaxTagCardinalityDisabled(t *testing.T) {
+	s := NewTestScope("", nil)
+	for i := 0; i < 100; i++ {
+		s.Tagged(map[string]string{"user": fmt.Sprint(i)}).Counter("c").Inc(1)
+	}
+	assert.Len(t, s.Snapshot().Counters(), 100)
+}
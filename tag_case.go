@@ -0,0 +1,46 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import "strings"
+
+// TagCase is a casing normalization applied to tag keys or values.
+type TagCase int
+
+const (
+	// UnchangedTagCase leaves the casing unchanged, this is the default.
+	UnchangedTagCase TagCase = iota
+	// LowerTagCase normalizes to lower case.
+	LowerTagCase
+	// UpperTagCase normalizes to upper case.
+	UpperTagCase
+)
+
+func (c TagCase) apply(s string) string {
+	switch c {
+	case LowerTagCase:
+		return strings.ToLower(s)
+	case UpperTagCase:
+		return strings.ToUpper(s)
+	default:
+		return s
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagKeyCase(t *testing.T) {
+	tests := []struct {
+		tagCase  TagCase
+		expected string
+	}{
+		{UnchangedTagCase, "requests+Region=us"},
+		{LowerTagCase, "requests+region=us"},
+		{UpperTagCase, "requests+REGION=us"},
+	}
+
+	for _, tt := range tests {
+		s := NewTestScopeWithOptions(ScopeOptions{TagKeyCase: tt.tagCase})
+		s.Tagged(map[string]string{"Region": "us"}).Counter("requests").Inc(1)
+
+		counters := s.Snapshot().Counters()
+		assert.Len(t, counters, 1)
+		assert.Contains(t, counters, tt.expected, "tag case %v", tt.tagCase)
+	}
+}
+
+func TestTagKeyCaseMergesSeries(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{TagKeyCase: LowerTagCase})
+	s.Tagged(map[string]string{"Region": "us"}).Counter("requests").Inc(1)
+	s.Tagged(map[string]string{"region": "us"}).Counter("requests").Inc(2)
+
+	counters := s.Snapshot().Counters()
+	assert.Len(t, counters, 1)
+	assert.EqualValues(t, 3, counters["requests+region=us"].Value())
+}
+
+func TestTagKeyCaseCollision(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{TagKeyCase: LowerTagCase})
+
+	// The value of the original key that sorts first wins, on every call.
+	for i := 0; i < 10; i++ {
+		s.Tagged(map[string]string{
+			"region": "eu",
+			"Region": "us",
+			"REGION": "ap",
+		}).Counter("requests").Inc(1)
+	}
+
+	counters := s.Snapshot().Counters()
+	assert.Len(t, counters, 1)
+	assert.EqualValues(t, 10, counters["requests+region=ap"].Value())
+}
+
+func TestTagValueCase(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{
+		Tags:         map[string]string{"Env": "Prod"},
+		TagValueCase: LowerTagCase,
+	})
+	s.Tagged(map[string]string{"Region": "US"}).Counter("requests").Inc(1)
+
+	assert.Contains(t, s.Snapshot().Counters(), "requests+Env=prod,Region=us")
+}
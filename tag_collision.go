@@ -0,0 +1,63 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+// TagKeyCollisionPolicy resolves tag keys of the same tags that collide once
+// normalized, e.g. "Region" and "region" with LowerTagCase or keys that the
+// sanitizer maps to the same key. Since map iteration order is random, the
+// order of keys is the sort order of the original keys.
+type TagKeyCollisionPolicy int
+
+const (
+	// FirstTagKeyWins uses the value of the original key that sorts first,
+	// this is the default.
+	FirstTagKeyWins TagKeyCollisionPolicy = iota
+	// LastTagKeyWins uses the value of the original key that sorts last.
+	LastTagKeyWins
+	// ErrorOnTagKeyCollision fails the creation of the scope. Tagged and the
+	// other methods that take tags panic, use TryTagged to handle the error.
+	ErrorOnTagKeyCollision
+)
+
+// TryTagged returns a new child scope of s with the given tags like Tagged,
+// but returns an error rather than panicking when the tag keys collide with
+// ErrorOnTagKeyCollision. On other Scope implementations it calls Tagged.
+func TryTagged(s Scope, tags map[string]string) (Scope, error) {
+	if ts, ok := s.(tryTaggedScope); ok {
+		return ts.tryTagged(tags)
+	}
+	return s.Tagged(tags), nil
+}
+
+type tryTaggedScope interface {
+	tryTagged(tags map[string]string) (Scope, error)
+}
+
+func (s *scope) tryTagged(tags map[string]string) (Scope, error) {
+	if s.sanitizeReject.rejectTags(tags) {
+		return NewNoopScope(), nil
+	}
+	tags, err := s.sanitizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	return s.subscope(s.prefix, tags), nil
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var collidingTags = map[string]string{
+	"region": "eu",
+	"Region": "us",
+	"REGION": "ap",
+}
+
+func TestTagKeyCollisionPolicies(t *testing.T) {
+	tests := []struct {
+		policy TagKeyCollisionPolicy
+		want   string
+	}{
+		{policy: FirstTagKeyWins, want: "ap"},
+		{policy: LastTagKeyWins, want: "eu"},
+	}
+	for _, tt := range tests {
+		s := NewTestScopeWithOptions(ScopeOptions{
+			TagKeyCase:            LowerTagCase,
+			TagKeyCollisionPolicy: tt.policy,
+		})
+
+		for i := 0; i < 10; i++ {
+			s.Tagged(collidingTags).Counter("requests").Inc(1)
+		}
+
+		counters := s.Snapshot().Counters()
+		assert.Len(t, counters, 1)
+		assert.EqualValues(t, 10, counters["requests+region="+tt.want].Value(), "policy %v", tt.policy)
+	}
+}
+
+func TestTagKeyCollisionError(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{
+		TagKeyCase:            LowerTagCase,
+		TagKeyCollisionPolicy: ErrorOnTagKeyCollision,
+	})
+
+	_, err := TryTagged(s, map[string]string{"Region": "us", "region": "eu"})
+	require.Error(t, err)
+	assert.Equal(t, `tag keys "Region" and "region" both normalize to "region"`, err.Error())
+
+	assert.Panics(t, func() {
+		s.Tagged(map[string]string{"Region": "us", "region": "eu"})
+	})
+
+	child, err := TryTagged(s, map[string]string{"Region": "us", "env": "prod"})
+	require.NoError(t, err)
+	child.Counter("requests").Inc(1)
+	assert.Contains(t, s.Snapshot().Counters(), "requests+env=prod,region=us")
+}
+
+func TestTagKeyCollisionSanitizer(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{
+		SanitizeOptions: &SanitizeOptions{
+			NameCharacters:       ValidCharacters{Ranges: AlphanumericRange},
+			KeyCharacters:        ValidCharacters{Ranges: AlphanumericRange},
+			ValueCharacters:      ValidCharacters{Ranges: AlphanumericRange},
+			ReplacementCharacter: '_',
+		},
+		TagKeyCollisionPolicy: LastTagKeyWins,
+	})
+
+	s.Tagged(map[string]string{"a-b": "first", "a_b": "last"}).Counter("requests").Inc(1)
+	assert.Contains(t, s.Snapshot().Counters(), "requests+a_b=last")
+}
+
+func TestTryTaggedSourceTaggingScope(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{
+		TagKeyCase:            LowerTagCase,
+		TagKeyCollisionPolicy: ErrorOnTagKeyCollision,
+	})
+
+	_, err := TryTagged(NewSourceTaggingScope(s), collidingTags)
+	assert.Error(t, err)
+
+	child, err := TryTagged(NewSourceTaggingScope(s), map[string]string{"env": "prod"})
+	require.NoError(t, err)
+	_, ok := child.(*sourceTaggingScope)
+	assert.True(t, ok)
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import "fmt"
+
+// TagValidationMode is how a scope handles a metric whose tags fail the
+// validator of ScopeOptions.MetricTagValidators.
+type TagValidationMode int
+
+const (
+	// StrictTagValidation panics when the metric is created, this is the
+	// default.
+	StrictTagValidation TagValidationMode = iota
+	// LenientTagValidation still creates the metric and increments the
+	// TagValidationFailedCounter.
+	LenientTagValidation
+)
+
+// tagValidators are the per metric tag validators of a registry.
+type tagValidators struct {
+	validators map[string]func(tags map[string]string) error
+	mode       TagValidationMode
+	failed     Counter
+	panics     map[string]Counter
+}
+
+// validateTags validates the tags of the scope against the validator of the
+// metric with the given name, if any.
+func (s *scope) validateTags(name string) {
+	v := &s.registry.tagValidators
+	if len(v.validators) == 0 {
+		return
+	}
+
+	fullyQualifiedName := s.fullyQualifiedName(name)
+	validate, ok := v.validators[fullyQualifiedName]
+	if !ok {
+		return
+	}
+	err := s.runTagValidator(fullyQualifiedName, validate)
+	if err == nil {
+		return
+	}
+
+	if v.mode == LenientTagValidation {
+		if v.failed != nil {
+			v.failed.Inc(1)
+		}
+		return
+	}
+	panic(fmt.Sprintf("tally: invalid tags %v for metric %s: %v", s.tags, fullyQualifiedName, err))
+}
+
+// runTagValidator runs the validator of the metric with the given name, a
+// panic recovered with RecoverCallbackPanics leaves the tags valid.
+func (s *scope) runTagValidator(name string, validate func(tags map[string]string) error) error {
+	if s.recoverCallbackPanics {
+		defer s.registry.recoverCallback("tag validator", name, s.registry.tagValidators.panics[name])
+	}
+	return validate(s.tags)
+}
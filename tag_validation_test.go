@@ -0,0 +1,101 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func requireTags(keys ...string) func(map[string]string) error {
+	return func(tags map[string]string) error {
+		for _, k := range keys {
+			if _, ok := tags[k]; !ok {
+				return errors.New("missing tag " + k)
+			}
+		}
+		return nil
+	}
+}
+
+func TestTagValidationStrict(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{
+		Prefix: "svc",
+		MetricTagValidators: map[string]func(map[string]string) error{
+			"svc.http.latency": requireTags("method", "status"),
+		},
+	})
+
+	assert.NotPanics(t, func() {
+		s.Tagged(map[string]string{"method": "GET", "status": "200"}).Timer("http.latency")
+	})
+	assert.Panics(t, func() {
+		s.Tagged(map[string]string{"method": "GET"}).Timer("http.latency")
+	})
+	// Metrics without a validator are not validated.
+	assert.NotPanics(t, func() {
+		s.Counter("http.requests")
+	})
+}
+
+func TestTagValidationLenient(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{
+		MetricTagValidators: map[string]func(map[string]string) error{
+			"requests": requireTags("method"),
+		},
+		TagValidationMode:          LenientTagValidation,
+		TagValidationFailedCounter: "tag_validation_failed",
+	})
+
+	s.Counter("requests").Inc(1)
+	s.Tagged(map[string]string{"method": "GET"}).Counter("requests").Inc(1)
+	// The validator runs once on creation, not per emission.
+	s.Counter("requests").Inc(1)
+
+	counters := s.Snapshot().Counters()
+	require.Contains(t, counters, "tag_validation_failed+")
+	assert.Equal(t, int64(1), counters["tag_validation_failed+"].Value())
+	require.Contains(t, counters, "requests+")
+	assert.Equal(t, int64(2), counters["requests+"].Value())
+	assert.Contains(t, counters, "requests+method=GET")
+}
+
+func TestTagValidationMetaMetricsEnabled(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{
+		MetricTagValidators: map[string]func(map[string]string) error{
+			"requests": requireTags("method"),
+		},
+		TagValidationMode: LenientTagValidation,
+		MetaMetrics: MetaMetricsOptions{
+			Mode:    MetaMetricsEnabled,
+			Metrics: []MetaMetric{TagValidationFailedMetaMetric},
+		},
+	})
+
+	s.Gauge("requests").Update(1)
+
+	counters := s.Snapshot().Counters()
+	require.Contains(t, counters, "tally.tag_validation_failed+")
+	assert.Equal(t, int64(1), counters["tally.tag_validation_failed+"].Value())
+}
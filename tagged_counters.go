@@ -0,0 +1,39 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+// TaggedCounters creates a counter with the given name for each of the given
+// values of a tag key on the scope, and returns them by tag value, e.g. a
+// requests counter by HTTP method. Incrementing the counter for a value is
+// then a single map read rather than the lookups of s.Tagged(...).Counter(...)
+// on every call.
+//
+// It assumes the values are a known and bounded set, such as an enum, since
+// every value creates a series up front. Values missing from the returned map
+// have no counter, callers should handle them explicitly, e.g. by mapping
+// them to an "other" value.
+func TaggedCounters(s Scope, name, tagKey string, values []string) map[string]Counter {
+	counters := make(map[string]Counter, len(values))
+	for _, v := range values {
+		counters[v] = s.Tagged(map[string]string{tagKey: v}).Counter(name)
+	}
+	return counters
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaggedCounters(t *testing.T) {
+	s := NewTestScope("", nil)
+	counters := TaggedCounters(s, "requests", "method", []string{"GET", "POST"})
+	assert.Len(t, counters, 2)
+
+	counters["GET"].Inc(2)
+	counters["POST"].Inc(1)
+
+	// The same counters as created through Tagged are returned.
+	s.Tagged(map[string]string{"method": "GET"}).Counter("requests").Inc(1)
+
+	snap := s.Snapshot().Counters()
+	assert.EqualValues(t, 3, snap["requests+method=GET"].Value())
+	assert.EqualValues(t, 1, snap["requests+method=POST"].Value())
+}
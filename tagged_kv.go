@@ -0,0 +1,39 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import "fmt"
+
+// TaggedKV returns s.Tagged with the tags of the alternating keys and values
+// of kv, e.g. TaggedKV(s, "region", "east", "zone", "1"), to avoid building
+// a map for a few tags, so it returns the same scope as the equivalent call
+// to Tagged. A key repeated in kv takes its last value. It panics if kv has
+// an odd number of strings.
+func TaggedKV(s Scope, kv ...string) Scope {
+	if len(kv)%2 != 0 {
+		panic(fmt.Sprintf("tally: odd number of tag keys and values, key %q has no value", kv[len(kv)-1]))
+	}
+	tags := make(map[string]string, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		tags[kv[i]] = kv[i+1]
+	}
+	return s.Tagged(tags)
+}
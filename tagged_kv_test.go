@@ -0,0 +1,44 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaggedKV(t *testing.T) {
+	s := NewTestScope("", map[string]string{"host": "a"})
+
+	tagged := s.Tagged(map[string]string{"region": "east", "zone": "1"})
+	assert.True(t, tagged == TaggedKV(s, "region", "east", "zone", "1"))
+	assert.True(t, tagged == TaggedKV(s, "zone", "1", "region", "west", "region", "east"))
+	assert.True(t, s.Tagged(nil) == TaggedKV(s))
+
+	TaggedKV(s, "region", "east", "zone", "1").Counter("c").Inc(1)
+	assert.Contains(t, s.Snapshot().Counters(), "c+host=a,region=east,zone=1")
+}
+
+func TestTaggedKVOdd(t *testing.T) {
+	s := NewTestScope("", nil)
+	assert.Panics(t, func() { TaggedKV(s, "region", "east", "zone") })
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package testutil provides helpers for testing code instrumented with tally.
+package testutil
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber-go/tally"
+)
+
+// TestClock is a tally.Clock whose time only moves when advanced, e.g. to
+// set ScopeOptions.Clock so that stopwatches record exact durations and the
+// report loop reports on demand. It is safe for concurrent use.
+type TestClock struct {
+	sync.Mutex
+	now     time.Time
+	tickers []*testTicker
+}
+
+var _ tally.Clock = (*TestClock)(nil)
+
+// NewTestClock returns a TestClock set to a fixed point in time.
+func NewTestClock() *TestClock {
+	return &TestClock{now: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// Now returns the current time of the clock.
+func (c *TestClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+// NewTicker returns a ticker that ticks every period d as the clock is
+// advanced. Like a time.Ticker, its channel holds a single tick and ticks
+// are dropped while one is pending. It panics if d is not positive.
+func (c *TestClock) NewTicker(d time.Duration) tally.Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	t := &testTicker{
+		c:      make(chan time.Time, 1),
+		period: d,
+		next:   c.now.Add(d),
+	}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Advance moves the clock forward by d, delivering the ticks of the tickers
+// that fall due.
+func (c *TestClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.now = c.now.Add(d)
+	tickers := c.tickers[:0]
+	for _, t := range c.tickers {
+		t.Lock()
+		stopped := t.stopped
+		for !stopped && !t.next.After(c.now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.period)
+		}
+		t.Unlock()
+		if !stopped {
+			tickers = append(tickers, t)
+		}
+	}
+	c.tickers = tickers
+}
+
+type testTicker struct {
+	sync.Mutex
+	c       chan time.Time
+	period  time.Duration
+	next    time.Time
+	stopped bool
+}
+
+func (t *testTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *testTicker) Stop() {
+	t.Lock()
+	t.stopped = true
+	t.Unlock()
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package testutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+)
+
+type flushReporter struct {
+	flushes chan struct{}
+}
+
+func (r flushReporter) ReportCounter(name string, tags map[string]string, value int64) {
+}
+
+func (r flushReporter) ReportGauge(name string, tags map[string]string, value float64) {
+}
+
+func (r flushReporter) ReportTimer(name string, tags map[string]string, interval time.Duration) {
+}
+
+func (r flushReporter) ReportHistogramValueSamples(
+	name string,
+	tags map[string]string,
+	buckets tally.Buckets,
+	bucketLowerBound,
+	bucketUpperBound float64,
+	samples int64,
+) {
+}
+
+func (r flushReporter) ReportHistogramDurationSamples(
+	name string,
+	tags map[string]string,
+	buckets tally.Buckets,
+	bucketLowerBound,
+	bucketUpperBound time.Duration,
+	samples int64,
+) {
+}
+
+func (r flushReporter) Capabilities() tally.Capabilities {
+	return tally.NullStatsReporter.Capabilities()
+}
+
+func (r flushReporter) Flush() {
+	r.flushes <- struct{}{}
+}
+
+func TestTestClockStopwatch(t *testing.T) {
+	clock := NewTestClock()
+	s := tally.NewTestScopeWithOptions(tally.ScopeOptions{Clock: clock})
+
+	sw := s.SubScope("sub").Timer("latency").Start()
+	hsw := s.Histogram("duration", tally.DurationBuckets{time.Second}).Start()
+	clock.Advance(150 * time.Millisecond)
+	sw.Stop()
+	clock.Advance(time.Second)
+	hsw.Stop()
+
+	snap := s.Snapshot()
+	assert.Equal(t, []time.Duration{150 * time.Millisecond},
+		snap.Timers()["sub.latency+"].Values())
+	assert.Equal(t, map[time.Duration]int64{time.Second: 0, 1<<63 - 1: 1},
+		snap.Histograms()["duration+"].Durations())
+}
+
+func TestTestClockStopwatchRecorders(t *testing.T) {
+	clock := NewTestClock()
+	s := tally.NewTestScopeWithOptions(tally.ScopeOptions{Clock: clock})
+	timer := s.Timer("latency")
+	recorder := timer.(tally.StopwatchRecorder)
+	always := func(time.Duration) bool { return true }
+	identity := func(d time.Duration) time.Duration { return d }
+
+	stopwatches := []tally.Stopwatch{
+		tally.StartStopwatch(tally.NewMultiTimerRecorder(timer)),
+		tally.StartStopwatch(tally.NewTransformTimerRecorder(timer, identity)),
+		tally.StartStopwatch(tally.NewConditionalRecorder(recorder, always)),
+		tally.StartStopwatch(tally.NewContextRecorder(
+			context.Background(), recorder, tally.ContextRecorderOptions{})),
+		tally.StartStopwatch(tally.NewDeadlineTimerRecorder(
+			context.Background(), s, "latency")),
+		tally.FanoutScope(s, tally.NoopScope).Timer("latency").Start(),
+	}
+	clock.Advance(150 * time.Millisecond)
+	for _, sw := range stopwatches {
+		sw.Stop()
+	}
+
+	snap := s.Snapshot()
+	assert.Equal(t, []time.Duration{
+		150 * time.Millisecond, 150 * time.Millisecond, 150 * time.Millisecond,
+		150 * time.Millisecond, 150 * time.Millisecond,
+	}, snap.Timers()["latency+"].Values())
+	assert.Equal(t, []time.Duration{150 * time.Millisecond},
+		snap.Timers()["latency+deadline_exceeded=false"].Values())
+}
+
+func TestTestClockSeriesRateLimit(t *testing.T) {
+	clock := NewTestClock()
+	s := tally.NewTestScopeWithOptions(tally.ScopeOptions{
+		Clock:           clock,
+		SeriesRateLimit: 1,
+	})
+
+	c := s.Counter("requests")
+	c.Inc(1)
+	c.Inc(1)
+	assert.EqualValues(t, 1, s.Snapshot().Counters()["requests+"].Value())
+
+	// The limit only refills as the clock advances.
+	clock.Advance(time.Second)
+	c.Inc(1)
+	assert.EqualValues(t, 2, s.Snapshot().Counters()["requests+"].Value())
+}
+
+func TestTestClockReportLoop(t *testing.T) {
+	clock := NewTestClock()
+	r := flushReporter{flushes: make(chan struct{}, 1)}
+	_, closer := tally.NewRootScope(tally.ScopeOptions{
+		Reporter: r,
+		Clock:    clock,
+	}, time.Second)
+
+	for i := 0; i < 3; i++ {
+		clock.Advance(time.Second)
+		select {
+		case <-r.flushes:
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timed out waiting for report", "tick %d", i)
+		}
+	}
+
+	// The loop only reports on ticks.
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case <-r.flushes:
+		assert.Fail(t, "reported before the tick")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	go func() { <-r.flushes }()
+	require.NoError(t, closer.Close())
+}
+
+func TestTestClockTicker(t *testing.T) {
+	clock := NewTestClock()
+	start := clock.Now()
+	ticker := clock.NewTicker(time.Second)
+
+	clock.Advance(999 * time.Millisecond)
+	assert.Len(t, ticker.C(), 0)
+
+	// A pending tick is not replaced by later ones.
+	clock.Advance(5 * time.Second)
+	assert.Equal(t, start.Add(time.Second), <-ticker.C())
+
+	ticker.Stop()
+	clock.Advance(time.Minute)
+	assert.Len(t, ticker.C(), 0)
+	assert.Equal(t, start.Add(time.Minute+5999*time.Millisecond), clock.Now())
+}
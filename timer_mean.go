@@ -0,0 +1,75 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// RecordNanoseconds records a duration in nanoseconds, which may include a
+// fraction of a nanosecond, to the timer. A timer created with
+// TimerOptions.MeanNanoseconds aggregates the fraction in its mean gauge,
+// other timers record ns rounded to a whole time.Duration.
+func RecordNanoseconds(t Timer, ns float64) {
+	if r, ok := t.(nanosecondsRecorder); ok {
+		r.recordNanoseconds(ns)
+		return
+	}
+	t.Record(time.Duration(math.Round(ns)))
+}
+
+type nanosecondsRecorder interface {
+	recordNanoseconds(ns float64)
+}
+
+func (t *timer) recordNanoseconds(ns float64) {
+	t.record(time.Duration(math.Round(ns)), ns)
+}
+
+// timerMean is the mean of the values of a timer in float nanoseconds.
+type timerMean struct {
+	sync.Mutex
+	sum   float64
+	count int64
+	last  float64
+}
+
+func (m *timerMean) add(ns float64) {
+	m.Lock()
+	m.sum += ns
+	m.count++
+	m.Unlock()
+}
+
+// value returns the mean of the values since it was last called, or the
+// previous mean if there were none, and resets it.
+func (m *timerMean) value() float64 {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.count > 0 {
+		m.last = m.sum / float64(m.count)
+		m.sum, m.count = 0, 0
+	}
+	return m.last
+}
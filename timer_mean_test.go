@@ -0,0 +1,77 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimerMeanNanoseconds(t *testing.T) {
+	r := newTestStatsReporter()
+	root, closer := NewRootScope(ScopeOptions{Reporter: r}, 0)
+	s := root.(*scope)
+
+	timer := TimerWithOptions(s, "op", TimerOptions{MeanNanoseconds: true})
+
+	// A batch of 3 operations took 1us, so each took 333.33ns.
+	const n = 300
+	perOp := float64(time.Microsecond) / 3
+	r.tg.Add(n)
+	for i := 0; i < n; i++ {
+		RecordNanoseconds(timer, perOp)
+	}
+	r.tg.Wait()
+
+	r.gg.Add(1)
+	s.reportRegistry()
+	r.WaitAll()
+
+	mean := r.getGauges()["op.mean_ns"].val
+	assert.InDelta(t, perOp, mean, 1e-6)
+	// The whole nanosecond values of the timer itself lose the fraction.
+	integerMean := float64(time.Duration(math.Round(perOp)))
+	assert.Equal(t, 333.0, integerMean)
+	assert.InDelta(t, 1.0/3, mean-integerMean, 1e-6)
+
+	// An interval without values keeps the previous mean.
+	r.gg.Add(1)
+	s.reportRegistry()
+	r.WaitAll()
+	assert.InDelta(t, perOp, r.getGauges()["op.mean_ns"].val, 1e-6)
+
+	// Account for the report on close.
+	r.gg.Add(1)
+	assert.NoError(t, closer.Close())
+}
+
+func TestRecordNanosecondsWithoutMean(t *testing.T) {
+	s := NewTestScope("", nil)
+	RecordNanoseconds(s.Timer("op"), 2.6)
+	RecordNanoseconds(&recordingTimer{}, 2.6)
+
+	snap := s.Snapshot()
+	assert.Equal(t, []time.Duration{3}, snap.Timers()["op+"].Values())
+	assert.Empty(t, snap.Gauges())
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// PercentileTimer returns the Timer object corresponding to the name on a
+// scope created by NewRootScope like TimerWithOptions, creating it if it does
+// not yet exist with the given percentiles of the values recorded in each
+// interval also emitted as gauges, e.g. for backends that only accept
+// counters and gauges. Each percentile is in the range (0, 100] like
+// HistogramOptions.Percentiles, e.g. 99.9 emits a gauge with the name suffix
+// "p99_9" joined by the separator of the scope, and is emitted once if
+// repeated. Each percentile is computed from all the values recorded in the
+// interval, interpolated like TimerSnapshot.Quantile, and emitted in seconds. The values are buffered
+// until the report, and no gauge is updated for an interval without values,
+// so they keep their previous value. The timer never expires with
+// ScopeOptions.MetricIdleTTL. It returns an error if a percentile is out of
+// range. The percentiles are ignored if the timer already exists, and on
+// other Scope implementations which just return s.Timer(name).
+func PercentileTimer(s Scope, name string, percentiles []float64) (Timer, error) {
+	if err := validatePercentiles(percentiles); err != nil {
+		return nil, err
+	}
+	if p, ok := s.(percentileTimerScope); ok {
+		return p.percentileTimer(name, percentiles), nil
+	}
+	return s.Timer(name), nil
+}
+
+type percentileTimerScope interface {
+	percentileTimer(name string, percentiles []float64) Timer
+}
+
+func (s *scope) percentileTimer(name string, percentiles []float64) Timer {
+	return s.timerWithPercentiles(name, TimerOptions{}, percentiles)
+}
+
+// timerPercentiles buffers the values of a timer recorded in an interval to
+// emit the percentiles of PercentileTimer.
+type timerPercentiles struct {
+	sync.Mutex
+	values []time.Duration
+	sample reservoir
+
+	percentiles []float64
+	gauges      []Gauge
+}
+
+// newTimerPercentiles returns the validated percentiles to emit for a timer
+// without duplicates, or nil if there are none, computed from at most
+// maxSamples values per interval.
+func newTimerPercentiles(
+	percentiles []float64,
+	maxSamples int,
+	sampleRand *sampleRand,
+) *timerPercentiles {
+	var valid []float64
+	seen := make(map[float64]struct{}, len(percentiles))
+	for _, p := range percentiles {
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		valid = append(valid, p)
+	}
+	if len(valid) == 0 {
+		return nil
+	}
+	return &timerPercentiles{
+		sample:      newReservoir(maxSamples, sampleRand),
+		percentiles: valid,
+	}
+}
+
+func (p *timerPercentiles) add(value time.Duration) {
+	p.Lock()
+	p.values = p.sample.appendDuration(p.values, value)
+	p.Unlock()
+}
+
+// registerTimerPercentiles creates the gauges of the percentiles of the timer
+// with the given name and registers them to be updated on each report. The
+// timer is pinned so that it is never evicted and registered again.
+func (s *scope) registerTimerPercentiles(name string, t *timer) {
+	t.idle.pin()
+	p := t.percentiles
+	p.gauges = make([]Gauge, 0, len(p.percentiles))
+	for _, pct := range p.percentiles {
+		g := s.Gauge(name + percentileSuffix(s.separator, pct))
+		if g, ok := g.(*gauge); ok {
+			g.idle.pin()
+		}
+		p.gauges = append(p.gauges, g)
+	}
+
+	s.gm.Lock()
+	s.timerPercentiles = append(s.timerPercentiles, p)
+	s.gm.Unlock()
+}
+
+// updateTimerPercentiles updates the percentile gauges of all timers of
+// PercentileTimer from the values of the interval and starts the
+// next interval, must be called with the gauges lock held.
+func (s *scope) updateTimerPercentiles() {
+	for _, p := range s.timerPercentiles {
+		p.update()
+	}
+}
+
+func (p *timerPercentiles) update() {
+	p.Lock()
+	values := p.values
+	p.values = nil
+	p.sample.reset()
+	p.Unlock()
+
+	if len(values) == 0 {
+		return
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	for i, pct := range p.percentiles {
+		p.gauges[i].Update(sortedDurationsQuantile(values, pct/100).Seconds())
+	}
+}
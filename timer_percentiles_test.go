@@ -0,0 +1,99 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPercentileTimer(t *testing.T) {
+	s := newRootScope(ScopeOptions{Reporter: NullStatsReporter}, 0)
+	defer s.Close()
+
+	timer, err := PercentileTimer(s, "latency", []float64{50, 99, 100, 50})
+	require.NoError(t, err)
+	for i := 100; i > 0; i-- {
+		timer.Record(time.Duration(i) * time.Millisecond)
+	}
+	s.report(NullStatsReporter)
+
+	gauges := s.Snapshot().Gauges()
+	assert.Len(t, gauges, 3)
+	assert.InDelta(t, 0.0505, gauges["latency.p50+"].Value(), 1e-9)
+	assert.InDelta(t, 0.09901, gauges["latency.p99+"].Value(), 1e-9)
+	assert.InDelta(t, 0.1, gauges["latency.p100+"].Value(), 1e-9)
+
+	// The next interval only has its own values.
+	timer.Record(time.Second)
+	s.report(NullStatsReporter)
+	gauges = s.Snapshot().Gauges()
+	assert.Equal(t, float64(1), gauges["latency.p50+"].Value())
+	assert.Equal(t, float64(1), gauges["latency.p100+"].Value())
+
+	// An interval without values updates nothing.
+	r := newTestStatsReporter()
+	s.report(r)
+	assert.Empty(t, r.gauges)
+}
+
+func TestPercentileTimerSeparator(t *testing.T) {
+	s := newRootScope(ScopeOptions{Separator: "_", Reporter: NullStatsReporter}, 0)
+	defer s.Close()
+
+	timer, err := PercentileTimer(s, "latency", []float64{99.9})
+	require.NoError(t, err)
+	timer.Record(time.Second)
+	s.report(NullStatsReporter)
+
+	gauges := s.Snapshot().Gauges()
+	assert.Len(t, gauges, 1)
+	assert.Equal(t, float64(1), gauges["latency_p99_9+"].Value())
+}
+
+func TestPercentileTimerInvalidPercentiles(t *testing.T) {
+	s := NewTestScope("", nil)
+	for _, p := range []float64{0, -1, 101} {
+		_, err := PercentileTimer(s, "latency", []float64{50, p})
+		assert.Error(t, err, "percentile %v", p)
+	}
+	assert.Empty(t, s.Snapshot().Timers())
+}
+
+func TestPercentileTimerNeverExpires(t *testing.T) {
+	s := newRootScope(ScopeOptions{
+		Reporter:      NullStatsReporter,
+		MetricIdleTTL: time.Minute,
+	}, 0)
+	defer s.Close()
+
+	timer, err := PercentileTimer(s, "latency", []float64{50})
+	require.NoError(t, err)
+	s.registry.evictIdleMetrics(time.Now().Add(time.Hour).UnixNano())
+
+	again, err := PercentileTimer(s, "latency", []float64{50})
+	require.NoError(t, err)
+	assert.True(t, timer == again)
+	assert.Len(t, s.timerPercentiles, 1)
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// sampleRand is the random source of the reservoirs of
+// ScopeOptions.MaxTimerSamplesPerInterval and HistogramReservoirSize, a nil
+// sampleRand uses the global source of math/rand.
+type sampleRand struct {
+	sync.Mutex
+	rand *rand.Rand
+}
+
+// newSampleRand returns a random source seeded with seed, or nil to use the
+// global source if seed is zero.
+func newSampleRand(seed int64) *sampleRand {
+	if seed == 0 {
+		return nil
+	}
+	return &sampleRand{rand: rand.New(rand.NewSource(seed))}
+}
+
+func (r *sampleRand) int63n(n int64) int64 {
+	if r == nil {
+		return rand.Int63n(n)
+	}
+	r.Lock()
+	defer r.Unlock()
+	return r.rand.Int63n(n)
+}
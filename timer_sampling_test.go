@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxTimerSamplesPerInterval(t *testing.T) {
+	newScope := func() TestScope {
+		return NewTestScopeWithOptions(ScopeOptions{
+			MaxTimerSamplesPerInterval: 100,
+			TimerSampleSeed:            1,
+		})
+	}
+	record := func(s Scope) {
+		for i := 1; i <= 10000; i++ {
+			s.Timer("latency").Record(time.Duration(i) * time.Millisecond)
+		}
+	}
+
+	s := newScope()
+	record(s)
+	values := s.Snapshot().Timers()["latency+"].Values()
+	require.Len(t, values, 100)
+
+	seen := make(map[time.Duration]struct{}, len(values))
+	var late int
+	for _, v := range values {
+		assert.True(t, v >= time.Millisecond && v <= 10000*time.Millisecond, "value %v", v)
+		assert.Equal(t, time.Duration(0), v%time.Millisecond, "value %v", v)
+		seen[v] = struct{}{}
+		if v > 100*time.Millisecond {
+			late++
+		}
+	}
+	assert.Len(t, seen, 100)
+	// A uniform sample of the interval, not just its first values.
+	assert.True(t, late > 90, "%d values after the first 100", late)
+
+	// The same seed retains the same sample.
+	other := newScope()
+	record(other)
+	assert.Equal(t, values, other.Snapshot().Timers()["latency+"].Values())
+}
+
+func TestMaxTimerSamplesPerIntervalReset(t *testing.T) {
+	s := NewTestScopeWithOptions(ScopeOptions{
+		MaxTimerSamplesPerInterval: 10,
+		TimerSnapshotLimit:         10,
+		TimerSnapshotDropRemainder: true,
+	})
+	for i := 0; i < 1000; i++ {
+		s.Timer("latency").Record(time.Second)
+	}
+	assert.Len(t, s.Snapshot().Timers()["latency+"].Values(), 10)
+
+	// The next interval starts with an empty reservoir.
+	var want []time.Duration
+	for i := 1; i <= 5; i++ {
+		s.Timer("latency").Record(time.Duration(i))
+		want = append(want, time.Duration(i))
+	}
+	assert.Equal(t, want, s.Snapshot().Timers()["latency+"].Values())
+}
+
+func TestMaxTimerSamplesPerIntervalPercentiles(t *testing.T) {
+	s := newRootScope(ScopeOptions{
+		Reporter:                   NullStatsReporter,
+		MaxTimerSamplesPerInterval: 50,
+		TimerSampleSeed:            1,
+	}, 0)
+	defer s.Close()
+
+	tm, err := PercentileTimer(s, "latency", []float64{100})
+	require.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		tm.Record(time.Second)
+	}
+	p := tm.(*timer).percentiles
+	assert.Len(t, p.values, 50)
+
+	s.report(NullStatsReporter)
+	assert.Equal(t, float64(1), s.Snapshot().Gauges()["latency.p100+"].Value())
+	assert.Empty(t, p.values)
+	assert.Equal(t, int64(0), p.sample.count)
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import "sync"
+
+// TrackingGauge returns a gauge with the given name on a scope created by
+// NewRootScope that tracks the minimum, maximum and last values it is set to
+// over each reporting interval, reported as the gauges of the scope named
+// with the suffixes ".min", ".max" and ".last", e.g. to see the spikes of a
+// capacity metric between reports. The three gauges are updated on each
+// report and only if the gauge was set during the interval, so they are not
+// visible in snapshots until then. Add adjusts the last value. Calling it
+// again with the same name returns the same gauge. On other Scope
+// implementations it returns s.Gauge(name).
+func TrackingGauge(s Scope, name string) Gauge {
+	if t, ok := s.(trackingGaugeScope); ok {
+		return t.trackingGauge(name)
+	}
+	return s.Gauge(name)
+}
+
+type trackingGaugeScope interface {
+	trackingGauge(name string) Gauge
+}
+
+type trackingGauge struct {
+	sync.Mutex
+	observed       bool
+	min, max, last float64
+
+	name                          string
+	minGauge, maxGauge, lastGauge *gauge
+}
+
+func (s *scope) trackingGauge(name string) Gauge {
+	minGauge, minOK := s.Gauge(name + ".min").(*gauge)
+	maxGauge, maxOK := s.Gauge(name + ".max").(*gauge)
+	lastGauge, lastOK := s.Gauge(name + ".last").(*gauge)
+	if !minOK || !maxOK || !lastOK {
+		return s.Gauge(name)
+	}
+	minGauge.idle.pin()
+	maxGauge.idle.pin()
+	lastGauge.idle.pin()
+
+	s.gm.Lock()
+	defer s.gm.Unlock()
+
+	for _, t := range s.trackingGauges {
+		if t.name == name {
+			return t
+		}
+	}
+	t := &trackingGauge{
+		name:      name,
+		minGauge:  minGauge,
+		maxGauge:  maxGauge,
+		lastGauge: lastGauge,
+	}
+	s.trackingGauges = append(s.trackingGauges, t)
+	return t
+}
+
+// updateTrackingGauges updates the gauges of all gauges returned by
+// TrackingGauge and starts their next interval, must be called with the
+// gauges lock held.
+func (s *scope) updateTrackingGauges() {
+	for _, t := range s.trackingGauges {
+		t.update()
+	}
+}
+
+func (t *trackingGauge) Update(value float64) {
+	t.Lock()
+	t.observe(value)
+	t.Unlock()
+}
+
+func (t *trackingGauge) Add(delta float64) {
+	t.Lock()
+	t.observe(t.last + delta)
+	t.Unlock()
+}
+
+func (t *trackingGauge) observe(value float64) {
+	if !t.observed || value < t.min {
+		t.min = value
+	}
+	if !t.observed || value > t.max {
+		t.max = value
+	}
+	t.last = value
+	t.observed = true
+}
+
+func (t *trackingGauge) update() {
+	t.Lock()
+	defer t.Unlock()
+
+	if !t.observed {
+		return
+	}
+	t.minGauge.Update(t.min)
+	t.maxGauge.Update(t.max)
+	t.lastGauge.Update(t.last)
+	t.observed = false
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackingGauge(t *testing.T) {
+	s := newRootScope(ScopeOptions{}, 0)
+	defer s.Close()
+
+	g := TrackingGauge(s, "capacity")
+	assert.Equal(t, g, TrackingGauge(s, "capacity"))
+
+	g.Update(5)
+	g.Update(12)
+	g.Update(1)
+	g.Update(7)
+
+	r := newTestStatsReporter()
+	r.gg.Add(3)
+	s.report(r)
+	r.WaitAll()
+	assert.EqualValues(t, 1, r.gauges["capacity.min"].val)
+	assert.EqualValues(t, 12, r.gauges["capacity.max"].val)
+	assert.EqualValues(t, 7, r.gauges["capacity.last"].val)
+	assert.NotContains(t, r.gauges, "capacity")
+
+	// The next interval starts over, and Add adjusts the last value.
+	g.Add(2)
+	g.Update(8)
+
+	r = newTestStatsReporter()
+	r.gg.Add(3)
+	s.report(r)
+	r.WaitAll()
+	assert.EqualValues(t, 8, r.gauges["capacity.min"].val)
+	assert.EqualValues(t, 9, r.gauges["capacity.max"].val)
+	assert.EqualValues(t, 8, r.gauges["capacity.last"].val)
+
+	// Nothing is reported for an interval without updates.
+	r = newTestStatsReporter()
+	s.report(r)
+	assert.Empty(t, r.gauges)
+}
+
+func TestTrackingGaugeNoop(t *testing.T) {
+	g := TrackingGauge(NewNoopScope(), "capacity")
+	g.Update(1)
+	g.Add(1)
+}
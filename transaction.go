@@ -0,0 +1,81 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"sync"
+	"time"
+)
+
+// Transaction records an observation of an operation to both a counter and a
+// timer consistently.
+type Transaction interface {
+	// Observe increments the counter by one and records the duration to the
+	// timer.
+	Observe(d time.Duration)
+}
+
+// NewTransaction returns a Transaction for the counter and timer with the
+// given names on a scope created by NewRootScope, e.g. so that an average
+// latency computed by the backend from the timer sum and the counter is
+// exact. Each observation updates both under a barrier shared by the root
+// scope and all its subscopes, so a report, including the reporter flush, or
+// a snapshot never includes the counter increment of an observation without
+// its timer value or vice versa.
+//
+// The cost is a read lock per observation, and observations of every
+// transaction of the root scope block while it reports or takes a snapshot.
+// On other Scope implementations the counter and timer are updated without a
+// barrier.
+func NewTransaction(s Scope, counterName, timerName string) Transaction {
+	if t, ok := s.(transactionScope); ok {
+		return t.transaction(counterName, timerName)
+	}
+	return transaction{counter: s.Counter(counterName), timer: s.Timer(timerName)}
+}
+
+type transactionScope interface {
+	transaction(counterName, timerName string) Transaction
+}
+
+func (s *scope) transaction(counterName, timerName string) Transaction {
+	s.registry.transactions.Store(true)
+	return transaction{
+		counter: s.Counter(counterName),
+		timer:   s.Timer(timerName),
+		barrier: &s.registry.txBarrier,
+	}
+}
+
+type transaction struct {
+	counter Counter
+	timer   Timer
+	barrier *sync.RWMutex
+}
+
+func (t transaction) Observe(d time.Duration) {
+	if t.barrier != nil {
+		t.barrier.RLock()
+		defer t.barrier.RUnlock()
+	}
+	t.counter.Inc(1)
+	t.timer.Record(d)
+}
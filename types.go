@@ -21,6 +21,7 @@
 package tally
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"time"
@@ -68,12 +69,48 @@ type Scope interface {
 type Counter interface {
 	// Inc increments the counter by a delta.
 	Inc(delta int64)
+
+	// Dec decrements the counter by a delta, e.g. Dec(1) is Inc(-1).
+	Dec(delta int64)
+
+	// IncFloat increments the counter by a fractional delta. The float
+	// increments are summed as a float64, which is exact for integers but
+	// accumulates rounding errors for most fractions, e.g. adding 0.1 a
+	// million times sums to about 100000.0000013. Reporters implementing
+	// FloatCounterReporter or CachedFloatCount get the fractional value,
+	// others get it rounded half away from zero with the fractions carried
+	// over to the next reports.
+	IncFloat(delta float64)
+}
+
+// CounterOptions is a set of options to construct a counter.
+type CounterOptions struct {
+	// Cumulative reports the running total of the counter rather than the
+	// delta since the last report. Defaults to false, in which case the
+	// counter resets after each report. A cumulative counter never expires
+	// with ScopeOptions.MetricIdleTTL.
+	Cumulative bool
+
+	// Help is a description of the counter passed to reporters that support
+	// Metadata, such as exposition formats.
+	Help string
 }
 
 // Gauge is the interface for emitting gauge metrics.
 type Gauge interface {
 	// Update sets the gauges absolute value.
 	Update(value float64)
+
+	// Add atomically adjusts the gauges value by a delta, the resulting
+	// absolute value is reported like one set by Update.
+	Add(delta float64)
+}
+
+// GaugeOptions is a set of options to construct a gauge.
+type GaugeOptions struct {
+	// Help is a description of the gauge passed to reporters that support
+	// Metadata, such as exposition formats.
+	Help string
 }
 
 // Timer is the interface for emitting timer metrics.
@@ -83,6 +120,116 @@ type Timer interface {
 
 	// Start gives you back a specific point in time to report via Stop.
 	Start() Stopwatch
+
+	// RecordFunc records the duration of calling f, also if f panics, in
+	// which case the panic is propagated once recorded.
+	RecordFunc(f func())
+
+	// StartContext is Start for a stopwatch that records nothing when
+	// stopped if ctx was cancelled or its deadline exceeded by then, use
+	// NewContextRecorder to count those stopwatches instead.
+	StartContext(ctx context.Context) Stopwatch
+}
+
+// TimerOptions is a set of options to construct a timer.
+type TimerOptions struct {
+	// Help is a description of the timer passed to reporters that support
+	// Metadata, such as exposition formats.
+	Help string
+
+	// DecayingReservoirSize if greater than zero retains a random sample of
+	// up to this many values recorded by the timer since the last report,
+	// available from TimerSnapshot.Samples. The sample is biased toward
+	// recent values by weighting each value by exp(alpha * t) for the
+	// seconds t since the last report, so for long reporting intervals in
+	// which latency changes, percentiles of the sample mostly reflect the
+	// latency near the end of the interval rather than its whole duration.
+	DecayingReservoirSize int
+
+	// DecayingReservoirAlpha is the decay factor per second of the reservoir,
+	// higher values bias the sample more strongly toward recent values.
+	// Defaults to DefaultDecayingReservoirAlpha.
+	DecayingReservoirAlpha float64
+
+	// HistogramBuckets if set also records each value of the timer into a
+	// duration histogram with these buckets, named the timer name joined by
+	// the scope separator with HistogramNameSuffix, e.g. to emit a timer as
+	// both percentiles computed by the reporter and a bucketed histogram
+	// while migrating dashboards. This costs the memory of the histogram
+	// buckets on top of the timer, which reporters computing percentiles
+	// such as StatsD keep the raw values of for each interval.
+	HistogramBuckets Buckets
+
+	// HistogramNameSuffix is the suffix of the histogram name for
+	// HistogramBuckets. Defaults to DefaultTimerHistogramNameSuffix.
+	HistogramNameSuffix string
+
+	// MeanNanoseconds if set also aggregates the values of the timer as
+	// float nanoseconds, including fractions of a nanosecond recorded with
+	// RecordNanoseconds, and reports their mean over each interval as a
+	// gauge named the timer name joined by the scope separator with
+	// MeanNameSuffix. A time.Duration is a whole number of nanoseconds, so
+	// e.g. durations per operation computed from a batch lose their fraction,
+	// which biases the mean of very fast operations by up to a nanosecond.
+	// The values of the timer itself, and of its histogram, remain whole
+	// time.Duration values. The gauge keeps its value over intervals without
+	// values.
+	MeanNanoseconds bool
+
+	// MeanNameSuffix is the suffix of the gauge name for MeanNanoseconds.
+	// Defaults to DefaultTimerMeanNameSuffix.
+	MeanNameSuffix string
+}
+
+// DefaultTimerHistogramNameSuffix is the default name suffix of histograms
+// of timers created with TimerOptions.HistogramBuckets.
+const DefaultTimerHistogramNameSuffix = "histogram"
+
+// DefaultTimerMeanNameSuffix is the default name suffix of the mean gauges of
+// timers created with TimerOptions.MeanNanoseconds.
+const DefaultTimerMeanNameSuffix = "mean_ns"
+
+// HistogramOptions is a set of options to construct a histogram.
+type HistogramOptions struct {
+	// Percentiles to emit as gauges for the histogram on each report, each
+	// in the range (0, 100], e.g. 99.9 emits a gauge with the name suffix
+	// "p99_9" joined by the separator of the scope. Each percentile is estimated as the upper bound of the
+	// bucket it falls into within the reported interval. Durations are
+	// emitted in seconds.
+	Percentiles []float64
+
+	// Help is a description of the histogram passed to reporters that
+	// support Metadata, such as exposition formats.
+	Help string
+
+	// InputUnit and StorageUnit convert each value recorded by a value
+	// histogram from the input unit to the storage unit before bucketing,
+	// e.g. kilobytes to bytes, so that call sites pass values in a declared
+	// unit. The buckets are defined in the storage unit. Both must be set
+	// to units of the same dimension, or neither.
+	InputUnit   Unit
+	StorageUnit Unit
+
+	// Clamp if set clamps each recorded value to a range, after the unit
+	// conversion, so that outliers do not distort the distribution. Clamping
+	// changes the recorded value, the number of clamped observations is
+	// counted separately.
+	Clamp *ClampOptions
+
+	// BucketBoundary is the bucket a value equal to the boundary between two
+	// buckets is recorded into, UpperInclusiveBoundary by default.
+	BucketBoundary BucketBoundary
+
+	// TraceSampled creates a sibling histogram, in the scope of the
+	// histogram and with the same buckets and options, that RecordValueTraced
+	// and RecordDurationTraced record the observations of sampled traces to,
+	// so that their distribution can be compared to the one of all the
+	// observations. The sibling is named with the name of the histogram and
+	// SampledNameSuffix, e.g. "latency.sampled".
+	TraceSampled bool
+	// SampledNameSuffix is the name suffix of the sibling histogram.
+	// Defaults to DefaultSampledHistogramSuffix.
+	SampledNameSuffix string
 }
 
 // Histogram is the interface for emitting histogram metrics
@@ -98,6 +245,24 @@ type Histogram interface {
 	// Start gives you a specific point in time to then record a duration.
 	// Will use the configured duration buckets for the histogram.
 	Start() Stopwatch
+
+	// RecordDurationFunc records the duration of calling f like
+	// RecordDuration, also if f panics, in which case the panic is
+	// propagated once recorded.
+	RecordDurationFunc(f func())
+
+	// StartContext is Start for a stopwatch that records nothing when
+	// stopped if ctx was cancelled or its deadline exceeded by then, use
+	// NewContextRecorder to count those stopwatches instead.
+	StartContext(ctx context.Context) Stopwatch
+}
+
+// Meter is the interface for emitting summary style metrics, the raw values
+// recorded by a meter are reported as they are and summarized by the backend
+// rather than bucketed or aggregated by the client.
+type Meter interface {
+	// Record a specific value directly.
+	Record(value float64)
 }
 
 // Stopwatch is a helper for simpler tracking of elapsed time, use the
@@ -108,7 +273,7 @@ type Stopwatch struct {
 	recorder StopwatchRecorder
 }
 
-// NewStopwatch creates a new immutable stopwatch for recording the start
+// NewStopwatch creates a new stopwatch for recording the start
 // time to a stopwatch reporter.
 func NewStopwatch(start time.Time, r StopwatchRecorder) Stopwatch {
 	return Stopwatch{start: start, recorder: r}
@@ -119,6 +284,25 @@ func (sw Stopwatch) Stop() {
 	sw.recorder.RecordStopwatch(sw.start)
 }
 
+// Reset restarts the stopwatch at start with the same recorder, so that a
+// single stopwatch can be stopped and restarted, e.g. to time each iteration
+// of a loop without starting a new stopwatch.
+func (sw *Stopwatch) Reset(start time.Time) {
+	sw.start = start
+}
+
+// StopAndReset reports the time elapsed since the stopwatch start to the
+// recorder like Stop, restarts the stopwatch now and returns the elapsed
+// time. Now is the time of the Clock of the scope for the stopwatches of
+// timers and histograms, and the time reported to them is the one returned.
+func (sw *Stopwatch) StopAndReset() time.Duration {
+	now := stopwatchNow(sw.recorder)
+	recordElapsed(sw.recorder, sw.start, now)
+	elapsed := now.Sub(sw.start)
+	sw.start = now
+	return elapsed
+}
+
 // StopwatchRecorder is a recorder that is called when a stopwatch is
 // stopped with Stop().
 type StopwatchRecorder interface {
@@ -155,3 +339,11 @@ type Capabilities interface {
 	// Tagging returns whether the reporter has the capability for tagged metrics.
 	Tagging() bool
 }
+
+// HistogramCapabilities is an optional interface implemented by the
+// Capabilities of reporters that do not support every kind of metric, see
+// SupportsHistograms.
+type HistogramCapabilities interface {
+	// Histograms returns whether the reporter has the capability for histograms.
+	Histograms() bool
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Unit is a unit of the values recorded by a value histogram.
+type Unit int
+
+// The units supported for conversions, units of the same dimension can be
+// converted into each other.
+const (
+	// NoUnit disables unit conversion, this is the default.
+	NoUnit Unit = iota
+
+	Bytes
+	Kilobytes
+	Megabytes
+	Gigabytes
+	Kibibytes
+	Mebibytes
+	Gibibytes
+
+	Nanoseconds
+	Microseconds
+	Milliseconds
+	Seconds
+)
+
+type unitDimension int
+
+const (
+	noDimension unitDimension = iota
+	sizeDimension
+	timeDimension
+)
+
+var units = map[Unit]struct {
+	name      string
+	dimension unitDimension
+	scale     float64
+}{
+	Bytes:        {"bytes", sizeDimension, 1},
+	Kilobytes:    {"kilobytes", sizeDimension, 1e3},
+	Megabytes:    {"megabytes", sizeDimension, 1e6},
+	Gigabytes:    {"gigabytes", sizeDimension, 1e9},
+	Kibibytes:    {"kibibytes", sizeDimension, 1 << 10},
+	Mebibytes:    {"mebibytes", sizeDimension, 1 << 20},
+	Gibibytes:    {"gibibytes", sizeDimension, 1 << 30},
+	Nanoseconds:  {"nanoseconds", timeDimension, 1e-9},
+	Microseconds: {"microseconds", timeDimension, 1e-6},
+	Milliseconds: {"milliseconds", timeDimension, 1e-3},
+	Seconds:      {"seconds", timeDimension, 1},
+}
+
+var (
+	errUnitPair              = errors.New("input and storage units must be set together")
+	errUnitDurationHistogram = errors.New("units are only supported by value histograms")
+)
+
+// String returns the name of the unit.
+func (u Unit) String() string {
+	if u == NoUnit {
+		return "none"
+	}
+	if info, ok := units[u]; ok {
+		return info.name
+	}
+	return fmt.Sprintf("Unit(%d)", int(u))
+}
+
+// unitScale returns the factor converting values in the input unit to the
+// storage unit, or zero if there is no conversion.
+func unitScale(input, storage Unit) (float64, error) {
+	if input == NoUnit && storage == NoUnit {
+		return 0, nil
+	}
+	if input == NoUnit || storage == NoUnit {
+		return 0, errUnitPair
+	}
+
+	in, ok := units[input]
+	if !ok {
+		return 0, fmt.Errorf("unknown input unit %v", input)
+	}
+	out, ok := units[storage]
+	if !ok {
+		return 0, fmt.Errorf("unknown storage unit %v", storage)
+	}
+	if in.dimension != out.dimension {
+		return 0, fmt.Errorf("cannot convert %v to %v", input, storage)
+	}
+	return in.scale / out.scale, nil
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tally
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramUnitConversion(t *testing.T) {
+	s := NewTestScope("", nil)
+	buckets := ValueBuckets{1000, 2000}
+
+	bytes, err := HistogramWithOptions(s, "bytes", buckets,
+		HistogramOptions{InputUnit: Bytes, StorageUnit: Bytes})
+	require.NoError(t, err)
+	kilobytes, err := HistogramWithOptions(s, "kilobytes", buckets,
+		HistogramOptions{InputUnit: Kilobytes, StorageUnit: Bytes})
+	require.NoError(t, err)
+
+	// The same logical size lands in the same bucket.
+	bytes.RecordValue(1500)
+	kilobytes.RecordValue(1.5)
+
+	histograms := s.Snapshot().Histograms()
+	expected := map[float64]int64{1000: 0, 2000: 1, math.MaxFloat64: 0}
+	assert.Equal(t, expected, histograms["bytes+"].Values())
+	assert.Equal(t, expected, histograms["kilobytes+"].Values())
+}
+
+func TestHistogramUnitValidation(t *testing.T) {
+	s := NewTestScope("", nil)
+	tests := []struct {
+		buckets Buckets
+		opts    HistogramOptions
+	}{
+		{ValueBuckets{1}, HistogramOptions{InputUnit: Kilobytes}},
+		{ValueBuckets{1}, HistogramOptions{StorageUnit: Bytes}},
+		{ValueBuckets{1}, HistogramOptions{InputUnit: Kilobytes, StorageUnit: Seconds}},
+		{ValueBuckets{1}, HistogramOptions{InputUnit: Unit(100), StorageUnit: Bytes}},
+		{DurationBuckets{time.Second}, HistogramOptions{InputUnit: Seconds, StorageUnit: Seconds}},
+	}
+
+	for _, tt := range tests {
+		_, err := HistogramWithOptions(s, "h", tt.buckets, tt.opts)
+		assert.Error(t, err, "%v to %v", tt.opts.InputUnit, tt.opts.StorageUnit)
+	}
+}